@@ -36,7 +36,6 @@ import (
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/trie"
-	"github.com/syndtr/goleveldb/leveldb/util"
 	"gopkg.in/urfave/cli.v1"
 )
 
@@ -48,6 +47,7 @@ var (
 		ArgsUsage: "<genesisPath>",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
 			utils.LightModeFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
@@ -65,9 +65,11 @@ It expects the genesis file as argument.`,
 		ArgsUsage: "<filename> (<filename 2> ... <filename N>) ",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
 			utils.CacheFlag,
 			utils.LightModeFlag,
 			utils.GCModeFlag,
+			utils.AncientThresholdFlag,
 			utils.CacheDatabaseFlag,
 			utils.CacheGCFlag,
 		},
@@ -86,6 +88,7 @@ processing will proceed even if an individual RLP-file import failure occurs.`,
 		ArgsUsage: "<filename> [<blockNumFirst> <blockNumLast>]",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
 			utils.CacheFlag,
 			utils.LightModeFlag,
 		},
@@ -103,6 +106,7 @@ if already existing.`,
 		ArgsUsage: "<datafile>",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
 			utils.CacheFlag,
 			utils.LightModeFlag,
 		},
@@ -117,6 +121,7 @@ if already existing.`,
 		ArgsUsage: "<dumpfile>",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
 			utils.CacheFlag,
 			utils.LightModeFlag,
 		},
@@ -131,6 +136,7 @@ The export-preimages command export hash preimages to an RLP encoded stream`,
 		ArgsUsage: "<sourceChaindataDir>",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
 			utils.CacheFlag,
 			utils.SyncModeFlag,
 			utils.FakePoWFlag,
@@ -148,6 +154,7 @@ The first argument must be the directory containing the blockchain to download f
 		ArgsUsage: " ",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
 			utils.LightModeFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
@@ -161,6 +168,7 @@ Remove blockchain and state databases`,
 		ArgsUsage: "[<blockHash> | <blockNum>]...",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
 			utils.CacheFlag,
 			utils.LightModeFlag,
 		},
@@ -246,19 +254,7 @@ func importChain(ctx *cli.Context) error {
 	fmt.Printf("Import done in %v.\n\n", time.Since(start))
 
 	// Output pre-compaction stats mostly to see the import trashing
-	db := chainDb.(*ethdb.LDBDatabase)
-
-	stats, err := db.LDB().GetProperty("leveldb.stats")
-	if err != nil {
-		utils.Fatalf("Failed to read database stats: %v", err)
-	}
-	fmt.Println(stats)
-
-	ioStats, err := db.LDB().GetProperty("leveldb.iostats")
-	if err != nil {
-		utils.Fatalf("Failed to read database iostats: %v", err)
-	}
-	fmt.Println(ioStats)
+	showLeveldbStats(chainDb)
 
 	fmt.Printf("Trie cache misses:  %d\n", trie.CacheMisses())
 	fmt.Printf("Trie cache unloads: %d\n\n", trie.CacheUnloads())
@@ -279,24 +275,32 @@ func importChain(ctx *cli.Context) error {
 	// Compact the entire database to more accurately measure disk io and print the stats
 	start = time.Now()
 	fmt.Println("Compacting entire database...")
-	if err = db.LDB().CompactRange(util.Range{}); err != nil {
+	switch err := chainDb.Compact(nil, nil); err {
+	case nil:
+		fmt.Printf("Compaction done in %v.\n\n", time.Since(start))
+	case ethdb.ErrCompactionUnsupported:
+		fmt.Printf("Database doesn't support compaction, skipped.\n\n")
+	default:
 		utils.Fatalf("Compaction failed: %v", err)
 	}
-	fmt.Printf("Compaction done in %v.\n\n", time.Since(start))
 
-	stats, err = db.LDB().GetProperty("leveldb.stats")
-	if err != nil {
-		utils.Fatalf("Failed to read database stats: %v", err)
-	}
-	fmt.Println(stats)
+	showLeveldbStats(chainDb)
+	return nil
+}
 
-	ioStats, err = db.LDB().GetProperty("leveldb.iostats")
-	if err != nil {
-		utils.Fatalf("Failed to read database iostats: %v", err)
+// showLeveldbStats prints the internal stats of the database, if it is backed
+// by LevelDB.
+func showLeveldbStats(db ethdb.Database) {
+	if stats, err := db.Stat("leveldb.stats"); err != nil {
+		log.Warn("Failed to read database stats", "error", err)
+	} else {
+		fmt.Println(stats)
+	}
+	if ioStats, err := db.Stat("leveldb.iostats"); err != nil {
+		log.Warn("Failed to read database iostats", "error", err)
+	} else {
+		fmt.Println(ioStats)
 	}
-	fmt.Println(ioStats)
-
-	return nil
 }
 
 func exportChain(ctx *cli.Context) error {
@@ -337,7 +341,7 @@ func importPreimages(ctx *cli.Context) error {
 		utils.Fatalf("This command requires an argument.")
 	}
 	stack, _ := makeFullNode(ctx)
-	diskdb := utils.MakeChainDatabase(ctx, stack).(*ethdb.LDBDatabase)
+	diskdb := utils.MakeChainDatabase(ctx, stack)
 
 	start := time.Now()
 	if err := utils.ImportPreimages(diskdb, ctx.Args().First()); err != nil {
@@ -353,7 +357,7 @@ func exportPreimages(ctx *cli.Context) error {
 		utils.Fatalf("This command requires an argument.")
 	}
 	stack, _ := makeFullNode(ctx)
-	diskdb := utils.MakeChainDatabase(ctx, stack).(*ethdb.LDBDatabase)
+	diskdb := utils.MakeChainDatabase(ctx, stack)
 
 	start := time.Now()
 	if err := utils.ExportPreimages(diskdb, ctx.Args().First()); err != nil {
@@ -376,7 +380,7 @@ func copyDb(ctx *cli.Context) error {
 	dl := downloader.New(syncmode, chainDb, new(event.TypeMux), chain, nil, nil)
 
 	// Create a source peer to satisfy downloader requests from
-	db, err := ethdb.NewLDBDatabase(ctx.Args().First(), ctx.GlobalInt(utils.CacheFlag.Name), 256)
+	db, err := ethdb.NewDatabase("", ctx.Args().First(), ctx.GlobalInt(utils.CacheFlag.Name), 256)
 	if err != nil {
 		return err
 	}
@@ -403,10 +407,14 @@ func copyDb(ctx *cli.Context) error {
 	// Compact the entire database to remove any sync overhead
 	start = time.Now()
 	fmt.Println("Compacting entire database...")
-	if err = chainDb.(*ethdb.LDBDatabase).LDB().CompactRange(util.Range{}); err != nil {
+	switch err = chainDb.Compact(nil, nil); err {
+	case nil:
+		fmt.Printf("Compaction done in %v.\n\n", time.Since(start))
+	case ethdb.ErrCompactionUnsupported:
+		fmt.Printf("Database doesn't support compaction, skipped.\n\n")
+	default:
 		utils.Fatalf("Compaction failed: %v", err)
 	}
-	fmt.Printf("Compaction done in %v.\n\n", time.Since(start))
 
 	return nil
 }
@@ -414,11 +422,22 @@ func copyDb(ctx *cli.Context) error {
 func removeDB(ctx *cli.Context) error {
 	stack, _ := makeConfigNode(ctx)
 
-	for _, name := range []string{"chaindata", "lightchaindata"} {
+	dbdirs := map[string]string{
+		"chaindata":      stack.ResolvePath("chaindata"),
+		"lightchaindata": stack.ResolvePath("lightchaindata"),
+	}
+	// The ancient store is only separate from the chain database if relocated
+	if ancient := ctx.GlobalString(utils.AncientFlag.Name); ancient != "" {
+		dbdirs["ancient"] = stack.ResolvePath(ancient)
+	}
+	for _, name := range []string{"chaindata", "lightchaindata", "ancient"} {
 		// Ensure the database exists in the first place
 		logger := log.New("database", name)
 
-		dbdir := stack.ResolvePath(name)
+		dbdir, ok := dbdirs[name]
+		if !ok {
+			continue
+		}
 		if !common.FileExist(dbdir) {
 			logger.Info("Database doesn't exist, skipping", "path", dbdir)
 			continue
@@ -0,0 +1,215 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/posv"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/olekukonko/tablewriter"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	dbCommand = cli.Command{
+		Name:     "db",
+		Usage:    "Low level database operations",
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The db commands check and inspect the chain database.`,
+		Subcommands: []cli.Command{
+			{
+				Action:    utils.MigrateFlags(verifyDatabase),
+				Name:      "verify",
+				Usage:     "Verify the integrity of the chain database",
+				ArgsUsage: " ",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.AncientFlag,
+					utils.CacheFlag,
+					utils.VerifyStateFlag,
+					utils.VerifyFromFlag,
+				},
+				Description: `
+tomo db verify
+
+will walk the canonical chain from the genesis (or --verify.from) up to the head,
+checking that every header, body and receipt list is present and matches the
+hashes and roots committing to it, and that every header is valid and sealed per
+the consensus rules. With --verify.state the entire state trie of the head block
+is walked too, checking that every node and contract code is present and intact.
+
+The first inconsistent block is reported and the command exits with an error.
+The database is only read. The node must be stopped while verifying.`,
+			},
+			{
+				Action:    utils.MigrateFlags(inspectDatabase),
+				Name:      "inspect",
+				Usage:     "Inspect the storage size of each category of the chain database",
+				ArgsUsage: " ",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.AncientFlag,
+					utils.CacheFlag,
+				},
+				Description: `
+tomo db inspect
+
+will iterate the entire key space of the chain database, grouping the entries by
+the schema they belong to (headers, bodies, receipts, trie nodes, Posv snapshots
+etc.), and print the number and total size of the entries of each category. The
+sizes of the ancient store tables are listed too.
+
+The node must be stopped while inspecting.`,
+			},
+		},
+	}
+)
+
+// verifyDatabase checks the integrity of the chain stored in the database. The
+// database is only read, no blockchain is created on top of it.
+func verifyDatabase(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	config, err := core.GetChainConfig(chainDb, core.GetCanonicalHash(chainDb, 0))
+	if err != nil {
+		utils.Fatalf("Failed to load chain config: %v", err)
+	}
+	if config.Posv == nil {
+		utils.Fatalf("Only support posv consensus")
+	}
+	// The engine persists its checkpoint snapshots, keep them in memory instead
+	engine := posv.New(config.Posv, newOverlayDatabase(chainDb))
+
+	// No contract can be called without a running node, resolve the checkpoint
+	// signers from the stored state instead
+	engine.HookGetSignersFromContract = func(hash common.Hash) ([]common.Address, error) {
+		return core.SignersFromState(chainDb, hash)
+	}
+
+	// Abort the verification on interrupt
+	stop := make(chan struct{})
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, os.Interrupt)
+	defer signal.Stop(sigc)
+
+	go func() {
+		<-sigc
+		log.Info("Got interrupt, stopping database verification")
+		close(stop)
+	}()
+	start := time.Now()
+	head := core.GetHeadBlockHash(chainDb)
+	if err := core.VerifyChain(chainDb, config, engine, ctx.Uint64(utils.VerifyFromFlag.Name), ctx.Bool(utils.VerifyStateFlag.Name), stop); err != nil {
+		if err == core.ErrVerifyInterrupted {
+			log.Warn("Database verification interrupted")
+			return nil
+		}
+		utils.Fatalf("Database verification failed: %v", err)
+	}
+	fmt.Printf("Database verified up to block #%d [%x…] in %v\n", core.GetBlockNumber(chainDb, head), head.Bytes()[:4], time.Since(start))
+	return nil
+}
+
+// errOverlayReadOnly is returned by the overlay database for the operations which
+// can't be kept in memory.
+var errOverlayReadOnly = errors.New("database is read-only")
+
+// overlayDatabase serves the reads from an underlying database while keeping all
+// the writes in memory, so components persisting their own data can run against
+// a database which must not be modified.
+type overlayDatabase struct {
+	ethdb.Database
+	writes *ethdb.MemDatabase
+}
+
+// newOverlayDatabase wraps a database into an in-memory write overlay.
+func newOverlayDatabase(db ethdb.Database) *overlayDatabase {
+	writes, _ := ethdb.NewMemDatabase()
+	return &overlayDatabase{Database: db, writes: writes}
+}
+
+func (db *overlayDatabase) Get(key []byte) ([]byte, error) {
+	if value, err := db.writes.Get(key); err == nil {
+		return value, nil
+	}
+	return db.Database.Get(key)
+}
+
+func (db *overlayDatabase) Has(key []byte) (bool, error) {
+	if ok, _ := db.writes.Has(key); ok {
+		return true, nil
+	}
+	return db.Database.Has(key)
+}
+
+func (db *overlayDatabase) Put(key []byte, value []byte) error       { return db.writes.Put(key, value) }
+func (db *overlayDatabase) Delete(key []byte) error                  { return errOverlayReadOnly }
+func (db *overlayDatabase) DeleteRange(start, limit []byte) error    { return errOverlayReadOnly }
+func (db *overlayDatabase) Compact(start []byte, limit []byte) error { return errOverlayReadOnly }
+func (db *overlayDatabase) NewBatch() ethdb.Batch                    { return db.writes.NewBatch() }
+func (db *overlayDatabase) Close()                                   {}
+
+// inspectDatabase prints the storage size of each category of the chain database.
+func inspectDatabase(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	// Abort the inspection on interrupt
+	stop := make(chan struct{})
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, os.Interrupt)
+	defer signal.Stop(sigc)
+
+	go func() {
+		<-sigc
+		log.Info("Got interrupt, stopping database inspection")
+		close(stop)
+	}()
+	stats, err := core.InspectDatabase(chainDb, stop)
+	if err != nil {
+		if err == core.ErrInspectInterrupted {
+			log.Warn("Database inspection interrupted")
+			return nil
+		}
+		utils.Fatalf("Database inspection failed: %v", err)
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Category", "Items", "Size"})
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+
+	var total common.StorageSize
+	for _, stat := range stats {
+		table.Append([]string{stat.Category, fmt.Sprintf("%d", stat.Count), stat.Size.String()})
+		total += stat.Size
+	}
+	table.Append([]string{"Total", "", total.String()})
+	table.Render()
+	return nil
+}
@@ -58,6 +58,7 @@ var (
 		utils.BootnodesV4Flag,
 		utils.BootnodesV5Flag,
 		utils.DataDirFlag,
+		utils.DBEngineFlag,
 		utils.KeyStoreDirFlag,
 		//utils.NoUSBFlag,
 		//utils.DashboardEnabledFlag,
@@ -73,6 +74,9 @@ var (
 		utils.TxPoolNoLocalsFlag,
 		utils.TxPoolJournalFlag,
 		utils.TxPoolRejournalFlag,
+		utils.TxPoolRemoteJournalFlag,
+		utils.TxPoolRemoteJournalSizeFlag,
+		utils.TxPoolRemoteJournalLifetimeFlag,
 		utils.TxPoolPriceLimitFlag,
 		utils.TxPoolPriceBumpFlag,
 		utils.TxPoolAccountSlotsFlag,
@@ -80,10 +84,23 @@ var (
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
 		utils.TxPoolLifetimeFlag,
+		utils.TxPoolNonceThresholdFlag,
+		utils.TxPoolSpecialSlotsFlag,
+		utils.TxPoolSpecialGlobalSlotsFlag,
+		utils.TxPoolTrackLifetimeFlag,
+		utils.TxPoolRateLimitFlag,
+		utils.TxPoolRateIntervalFlag,
 		utils.FastSyncFlag,
 		utils.LightModeFlag,
 		utils.SyncModeFlag,
 		utils.GCModeFlag,
+		utils.AncientFlag,
+		utils.AncientThresholdFlag,
+		utils.SnapshotFlag,
+		utils.CacheSnapshotFlag,
+		utils.StateDiffsFlag,
+		utils.StateDiffsRetainFlag,
+		utils.VMProfileFlag,
 		//utils.LightServFlag,
 		//utils.LightPeersFlag,
 		//utils.LightKDFFlag,
@@ -171,6 +188,10 @@ func init() {
 		versionCommand,
 		// See config.go
 		dumpConfigCommand,
+		// See snapshot.go
+		snapshotCommand,
+		// See dbcmd.go
+		dbCommand,
 	}
 	sort.Sort(cli.CommandsByName(app.Commands))
 
@@ -0,0 +1,122 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state/pruner"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	snapshotCommand = cli.Command{
+		Name:     "snapshot",
+		Usage:    "A set of commands based on the state database",
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The snapshot commands maintain the state stored in the chain database.`,
+		Subcommands: []cli.Command{
+			{
+				Action:    utils.MigrateFlags(pruneState),
+				Name:      "prune-state",
+				Usage:     "Prune stale state trie nodes from the database offline",
+				ArgsUsage: " ",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.AncientFlag,
+					utils.CacheFlag,
+					utils.PruneBloomSizeFlag,
+					utils.PruneRetainFlag,
+				},
+				Description: `
+tomo snapshot prune-state
+
+will delete all the state trie nodes which are not reachable from the state of
+the recent blocks or the genesis. The live state is marked in a bloom filter
+first, which is persisted into the data directory, after which the rest of the
+trie nodes are deleted and the database compacted. Contract codes are retained.
+
+The node must be stopped while pruning, a running node can prune its state in the
+background through the admin_pruneState RPC method instead. The pruning can be interrupted at any time
+and will be resumed on the next run, picking up the persisted bloom filter.`,
+			},
+		},
+	}
+)
+
+// pruneState deletes the stale state data from the chain database.
+func pruneState(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	retain := ctx.Uint64(utils.PruneRetainFlag.Name)
+	if retain == 0 {
+		config, _ := core.GetChainConfig(chainDb, core.GetCanonicalHash(chainDb, 0))
+		retain = pruner.DefaultRetain(config)
+	}
+	prune, err := pruner.NewPruner(chainDb, pruner.Config{
+		Datadir:   stack.ResolvePath(""),
+		BloomSize: ctx.Uint64(utils.PruneBloomSizeFlag.Name),
+		Retain:    retain,
+	})
+	if err != nil {
+		utils.Fatalf("Failed to create state pruner: %v", err)
+	}
+	// Abort the pruning gracefully on interrupt, it will be resumed on the next run
+	stop := make(chan struct{})
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, os.Interrupt)
+	defer signal.Stop(sigc)
+
+	go func() {
+		<-sigc
+		log.Info("Got interrupt, stopping state pruning")
+		close(stop)
+	}()
+	start := time.Now()
+	if err := prune.Prune(stop); err != nil {
+		if err == pruner.ErrInterrupted {
+			log.Warn("State pruning interrupted, rerun the command to resume")
+			return nil
+		}
+		utils.Fatalf("Failed to prune state: %v", err)
+	}
+	fmt.Printf("State pruning done in %v\n", time.Since(start))
+
+	// Compact the entire database to actually reclaim the freed space
+	start = time.Now()
+	fmt.Println("Compacting entire database...")
+	switch err = chainDb.Compact(nil, nil); err {
+	case nil:
+		fmt.Printf("Compaction done in %v.\n\n", time.Since(start))
+	case ethdb.ErrCompactionUnsupported:
+		fmt.Printf("Database doesn't support compaction, skipped.\n\n")
+	default:
+		utils.Fatalf("Compaction failed: %v", err)
+	}
+
+	return nil
+}
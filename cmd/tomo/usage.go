@@ -69,6 +69,8 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			configFileFlag,
 			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.DBEngineFlag,
 			utils.KeyStoreDirFlag,
 			//utils.NoUSBFlag,
 			utils.NetworkIdFlag,
@@ -76,6 +78,12 @@ var AppHelpFlagGroups = []flagGroup{
 			//utils.RinkebyFlag,
 			utils.SyncModeFlag,
 			utils.GCModeFlag,
+			utils.AncientThresholdFlag,
+			utils.SnapshotFlag,
+			utils.CacheSnapshotFlag,
+			utils.StateDiffsFlag,
+			utils.StateDiffsRetainFlag,
+			utils.VMProfileFlag,
 			utils.EthStatsURLFlag,
 			utils.IdentityFlag,
 			//utils.LightServFlag,
@@ -116,6 +124,9 @@ var AppHelpFlagGroups = []flagGroup{
 	//		utils.TxPoolNoLocalsFlag,
 	//		utils.TxPoolJournalFlag,
 	//		utils.TxPoolRejournalFlag,
+	//		utils.TxPoolRemoteJournalFlag,
+	//		utils.TxPoolRemoteJournalSizeFlag,
+	//		utils.TxPoolRemoteJournalLifetimeFlag,
 	//		utils.TxPoolPriceLimitFlag,
 	//		utils.TxPoolPriceBumpFlag,
 	//		utils.TxPoolAccountSlotsFlag,
@@ -123,6 +134,12 @@ var AppHelpFlagGroups = []flagGroup{
 	//		utils.TxPoolAccountQueueFlag,
 	//		utils.TxPoolGlobalQueueFlag,
 	//		utils.TxPoolLifetimeFlag,
+	//		utils.TxPoolNonceThresholdFlag,
+	//		utils.TxPoolSpecialSlotsFlag,
+	//		utils.TxPoolSpecialGlobalSlotsFlag,
+	//		utils.TxPoolTrackLifetimeFlag,
+	//		utils.TxPoolRateLimitFlag,
+	//		utils.TxPoolRateIntervalFlag,
 	//	},
 	//},
 	//{
@@ -37,6 +37,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/posv"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/pruner"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/dashboard"
@@ -132,6 +133,14 @@ var (
 		Usage: "Data directory for the databases and keystore",
 		Value: DirectoryString{node.DefaultDataDir()},
 	}
+	AncientFlag = DirectoryFlag{
+		Name:  "datadir.ancient",
+		Usage: "Data directory for ancient chain segments (default = inside chaindata)",
+	}
+	DBEngineFlag = cli.StringFlag{
+		Name:  "db.engine",
+		Usage: "Backing database implementation to use (\"leveldb\" or \"bolt\")",
+	}
 	KeyStoreDirFlag = DirectoryFlag{
 		Name:  "keystore",
 		Usage: "Directory for the keystore (default = inside the datadir)",
@@ -193,6 +202,24 @@ var (
 		Usage: `Blockchain garbage collection mode ("full", "archive")`,
 		Value: "full",
 	}
+	AncientThresholdFlag = cli.Uint64Flag{
+		Name:  "ancient.threshold",
+		Usage: "Number of recent blocks kept in the chain database, older ones are moved irreversibly into the ancient store, unreadable by previous releases (0 = disabled, recommended 90000)",
+		Value: eth.DefaultConfig.AncientThreshold,
+	}
+	SnapshotFlag = cli.BoolFlag{
+		Name:  "snapshot",
+		Usage: "Enables the flat state snapshot for faster state reads (generated in the background on first use)",
+	}
+	StateDiffsFlag = cli.BoolFlag{
+		Name:  "statediffs",
+		Usage: "Store the state changes made by every imported block (debug_getStateDiff)",
+	}
+	StateDiffsRetainFlag = cli.Uint64Flag{
+		Name:  "statediffs.retain",
+		Usage: "Number of recent blocks whose state changes are kept (0 = all)",
+		Value: eth.DefaultConfig.StateDiffsRetain,
+	}
 	LightServFlag = cli.IntFlag{
 		Name:  "lightserv",
 		Usage: "Maximum percentage of time allowed for serving LES requests (0-90)",
@@ -272,6 +299,21 @@ var (
 		Usage: "Time interval to regenerate the local transaction journal",
 		Value: core.DefaultTxPoolConfig.Rejournal,
 	}
+	TxPoolRemoteJournalFlag = cli.StringFlag{
+		Name:  "txpool.remotejournal",
+		Usage: "Disk journal for remote transactions to survive node restarts (disabled if empty)",
+		Value: core.DefaultTxPoolConfig.RemoteJournal,
+	}
+	TxPoolRemoteJournalSizeFlag = cli.Uint64Flag{
+		Name:  "txpool.remotejournalsize",
+		Usage: "Maximum number of remote transactions kept in the journal",
+		Value: core.DefaultTxPoolConfig.RemoteJournalSize,
+	}
+	TxPoolRemoteJournalLifetimeFlag = cli.DurationFlag{
+		Name:  "txpool.remotejournallifetime",
+		Usage: "Maximum age of remote transactions reloaded from the journal",
+		Value: core.DefaultTxPoolConfig.RemoteJournalLifetime,
+	}
 	TxPoolPriceLimitFlag = cli.Uint64Flag{
 		Name:  "txpool.pricelimit",
 		Usage: "Minimum gas price limit to enforce for acceptance into the pool",
@@ -307,6 +349,36 @@ var (
 		Usage: "Maximum amount of time non-executable transaction are queued",
 		Value: eth.DefaultConfig.TxPool.Lifetime,
 	}
+	TxPoolNonceThresholdFlag = cli.Uint64Flag{
+		Name:  "txpool.noncethreshold",
+		Usage: "Maximum gap permitted between an account's pending nonce and a new transaction",
+		Value: eth.DefaultConfig.TxPool.NonceThreshold,
+	}
+	TxPoolSpecialSlotsFlag = cli.Uint64Flag{
+		Name:  "txpool.specialslots",
+		Usage: "Maximum number of special transaction slots permitted per masternode",
+		Value: eth.DefaultConfig.TxPool.SpecialSlots,
+	}
+	TxPoolSpecialGlobalSlotsFlag = cli.Uint64Flag{
+		Name:  "txpool.specialglobalslots",
+		Usage: "Maximum number of special transaction slots for all masternodes",
+		Value: eth.DefaultConfig.TxPool.SpecialGlobalSlots,
+	}
+	TxPoolTrackLifetimeFlag = cli.DurationFlag{
+		Name:  "txpool.tracklifetime",
+		Usage: "Amount of time dropped transactions and their reasons are remembered (0 = disabled)",
+		Value: eth.DefaultConfig.TxPool.TrackLifetime,
+	}
+	TxPoolRateLimitFlag = cli.Uint64Flag{
+		Name:  "txpool.ratelimit",
+		Usage: "Maximum number of remote transactions accepted per sender within the rate interval (0 = disabled)",
+		Value: eth.DefaultConfig.TxPool.Policy.RateLimit,
+	}
+	TxPoolRateIntervalFlag = cli.DurationFlag{
+		Name:  "txpool.rateinterval",
+		Usage: "Time window over which the per sender rate limit is enforced",
+		Value: eth.DefaultConfig.TxPool.Policy.RateInterval,
+	}
 	// Performance tuning settings
 	CacheFlag = cli.IntFlag{
 		Name:  "cache",
@@ -323,6 +395,28 @@ var (
 		Usage: "Percentage of cache memory allowance to use for trie pruning",
 		Value: 25,
 	}
+	CacheSnapshotFlag = cli.IntFlag{
+		Name:  "cache.snapshot",
+		Usage: "Percentage of cache memory allowance to use for snapshot caching (requires --snapshot)",
+		Value: 10,
+	}
+	PruneBloomSizeFlag = cli.Uint64Flag{
+		Name:  "bloomfilter.size",
+		Usage: "Megabytes of memory allocated to the bloom filter of the live state during pruning",
+		Value: pruner.DefaultBloomSize,
+	}
+	PruneRetainFlag = cli.Uint64Flag{
+		Name:  "prune.retain",
+		Usage: "Number of recent blocks to retain the state of during pruning (0 = two epochs or 128 blocks)",
+	}
+	VerifyStateFlag = cli.BoolFlag{
+		Name:  "verify.state",
+		Usage: "Verify the entire state trie of the head block during database verification",
+	}
+	VerifyFromFlag = cli.Uint64Flag{
+		Name:  "verify.from",
+		Usage: "Block number to start the database verification from",
+	}
 	TrieCacheGenFlag = cli.IntFlag{
 		Name:  "trie-cache-gens",
 		Usage: "Number of trie node generations to keep in memory",
@@ -373,6 +467,10 @@ var (
 		Name:  "vmdebug",
 		Usage: "Record information useful for VM and contract debugging",
 	}
+	VMProfileFlag = cli.BoolFlag{
+		Name:  "vmprofile",
+		Usage: "Profile the time and gas spent in the VM per contract and opcode (debug_evmProfile)",
+	}
 	// Logging and debug settings
 	EthStatsURLFlag = cli.StringFlag{
 		Name:  "ethstats",
@@ -909,6 +1007,9 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 		cfg.DataDir = filepath.Join(node.DefaultDataDir(), "rinkeby")
 	}
 
+	if ctx.GlobalIsSet(DBEngineFlag.Name) {
+		cfg.DBEngine = ctx.GlobalString(DBEngineFlag.Name)
+	}
 	if ctx.GlobalIsSet(KeyStoreDirFlag.Name) {
 		cfg.KeyStoreDir = ctx.GlobalString(KeyStoreDirFlag.Name)
 	}
@@ -942,6 +1043,15 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolRejournalFlag.Name) {
 		cfg.Rejournal = ctx.GlobalDuration(TxPoolRejournalFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolRemoteJournalFlag.Name) {
+		cfg.RemoteJournal = ctx.GlobalString(TxPoolRemoteJournalFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolRemoteJournalSizeFlag.Name) {
+		cfg.RemoteJournalSize = ctx.GlobalUint64(TxPoolRemoteJournalSizeFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolRemoteJournalLifetimeFlag.Name) {
+		cfg.RemoteJournalLifetime = ctx.GlobalDuration(TxPoolRemoteJournalLifetimeFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolPriceLimitFlag.Name) {
 		cfg.PriceLimit = ctx.GlobalUint64(TxPoolPriceLimitFlag.Name)
 	}
@@ -963,6 +1073,24 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.GlobalDuration(TxPoolLifetimeFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolNonceThresholdFlag.Name) {
+		cfg.NonceThreshold = ctx.GlobalUint64(TxPoolNonceThresholdFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolSpecialSlotsFlag.Name) {
+		cfg.SpecialSlots = ctx.GlobalUint64(TxPoolSpecialSlotsFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolSpecialGlobalSlotsFlag.Name) {
+		cfg.SpecialGlobalSlots = ctx.GlobalUint64(TxPoolSpecialGlobalSlotsFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolTrackLifetimeFlag.Name) {
+		cfg.TrackLifetime = ctx.GlobalDuration(TxPoolTrackLifetimeFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolRateLimitFlag.Name) {
+		cfg.Policy.RateLimit = ctx.GlobalUint64(TxPoolRateLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolRateIntervalFlag.Name) {
+		cfg.Policy.RateInterval = ctx.GlobalDuration(TxPoolRateIntervalFlag.Name)
+	}
 }
 
 func setEthash(ctx *cli.Context, cfg *eth.Config) {
@@ -1076,9 +1204,25 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	}
 	cfg.NoPruning = ctx.GlobalString(GCModeFlag.Name) == "archive"
 
+	if ctx.GlobalIsSet(AncientFlag.Name) {
+		cfg.DatabaseFreezer = ctx.GlobalString(AncientFlag.Name)
+	}
+	if ctx.GlobalIsSet(AncientThresholdFlag.Name) {
+		cfg.AncientThreshold = ctx.GlobalUint64(AncientThresholdFlag.Name)
+	}
+
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cfg.TrieCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
 	}
+	if ctx.GlobalBool(SnapshotFlag.Name) {
+		cfg.SnapshotCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheSnapshotFlag.Name) / 100
+	}
+	if ctx.GlobalIsSet(StateDiffsFlag.Name) {
+		cfg.StateDiffs = ctx.GlobalBool(StateDiffsFlag.Name)
+	}
+	if ctx.GlobalIsSet(StateDiffsRetainFlag.Name) {
+		cfg.StateDiffsRetain = ctx.GlobalUint64(StateDiffsRetainFlag.Name)
+	}
 	if ctx.GlobalIsSet(StakerThreadsFlag.Name) {
 		cfg.MinerThreads = ctx.GlobalInt(StakerThreadsFlag.Name)
 	}
@@ -1095,6 +1239,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 		// TODO(fjl): force-enable this in --dev mode
 		cfg.EnablePreimageRecording = ctx.GlobalBool(VMEnableDebugFlag.Name)
 	}
+	if ctx.GlobalIsSet(VMProfileFlag.Name) {
+		cfg.EVMProfile = ctx.GlobalBool(VMProfileFlag.Name)
+	}
 	if ctx.GlobalIsSet(StoreRewardFlag.Name) {
 		common.StoreRewardFolder = filepath.Join(stack.DataDir(), "tomo", "rewards")
 		if _, err := os.Stat(common.StoreRewardFolder); os.IsNotExist(err) {
@@ -1221,7 +1368,15 @@ func MakeChainDatabase(ctx *cli.Context, stack *node.Node) ethdb.Database {
 	if ctx.GlobalBool(LightModeFlag.Name) {
 		name = "lightchaindata"
 	}
-	chainDb, err := stack.OpenDatabase(name, cache, handles)
+	var (
+		chainDb ethdb.Database
+		err     error
+	)
+	if ctx.GlobalBool(LightModeFlag.Name) {
+		chainDb, err = stack.OpenDatabase(name, cache, handles)
+	} else {
+		chainDb, err = stack.OpenDatabaseWithFreezer(name, cache, handles, ctx.GlobalString(AncientFlag.Name), core.FreezerTables)
+	}
 	if err != nil {
 		Fatalf("Could not open database: %v", err)
 	}
@@ -1274,10 +1429,17 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 		Disabled:      ctx.GlobalString(GCModeFlag.Name) == "archive",
 		TrieNodeLimit: eth.DefaultConfig.TrieCache,
 		TrieTimeLimit: eth.DefaultConfig.TrieTimeout,
+
+		AncientThreshold: ctx.GlobalUint64(AncientThresholdFlag.Name),
+		StateDiffs:       ctx.GlobalBool(StateDiffsFlag.Name),
+		StateDiffsRetain: ctx.GlobalUint64(StateDiffsRetainFlag.Name),
 	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cache.TrieNodeLimit = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
 	}
+	if ctx.GlobalBool(SnapshotFlag.Name) {
+		cache.SnapshotLimit = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheSnapshotFlag.Name) / 100
+	}
 	vmcfg := vm.Config{EnablePreimageRecording: ctx.GlobalBool(VMEnableDebugFlag.Name)}
 	chain, err = core.NewBlockChain(chainDb, cache, config, engine, vmcfg)
 	if err != nil {
@@ -27,6 +27,9 @@ const (
 var TIP2019Block = big.NewInt(1050000)
 var TIPSigning = big.NewInt(3000000)
 var TIPRandomize = big.NewInt(3464000)
+var TIPCleanSigners = big.NewInt(13000500) // Reward checkpoint, the sign records of blocks up to 3 checkpoints before it are kept
+var TIPCryptoPrecompiles = big.NewInt(14000000)
+var TIPConsensusPrecompile = big.NewInt(14500000)
 var BlackListHFNumber = uint64(9349100)
 var IsTestnet bool = false
 var StoreRewardFolder string
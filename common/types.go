@@ -33,6 +33,7 @@ const (
 	BlockSigners        = "0x0000000000000000000000000000000000000089"
 	MasternodeVotingSMC = "0x0000000000000000000000000000000000000088"
 	RandomizeSMC        = "0x0000000000000000000000000000000000000090"
+	ConsensusPrecompile = "0x0000000000000000000000000000000000000087"
 	FoudationAddr       = "0x0000000000000000000000000000000000000068"
 	TeamAddr            = "0x0000000000000000000000000000000000000099"
 	VoteMethod          = "0x6dd7d8ea"
@@ -51,6 +51,7 @@ import (
 
 const (
 	inmemorySnapshots      = 128 // Number of recent vote snapshots to keep in memory
+	inmemoryMasternodes    = 16  // Number of recent checkpoint masternode sets to keep in memory
 	blockSignersCacheLimit = 9000
 	M2ByteLength           = 4
 )
@@ -221,6 +222,7 @@ type Posv struct {
 	signatures          *lru.ARCCache // Signatures of recent blocks to speed up mining
 	validatorSignatures *lru.ARCCache // Signatures of recent blocks to speed up mining
 	verifiedHeaders     *lru.ARCCache
+	masternodes         *lru.ARCCache           // Masternodes of recent checkpoints, keyed by header hash
 	proposals           map[common.Address]bool // Current list of proposals we are pushing
 
 	signer common.Address  // Ethereum address of the signing key
@@ -250,6 +252,7 @@ func New(config *params.PosvConfig, db ethdb.Database) *Posv {
 	signatures, _ := lru.NewARC(inmemorySnapshots)
 	validatorSignatures, _ := lru.NewARC(inmemorySnapshots)
 	verifiedHeaders, _ := lru.NewARC(inmemorySnapshots)
+	masternodes, _ := lru.NewARC(inmemoryMasternodes)
 	return &Posv{
 		config:              &conf,
 		db:                  db,
@@ -258,6 +261,7 @@ func New(config *params.PosvConfig, db ethdb.Database) *Posv {
 		signatures:          signatures,
 		verifiedHeaders:     verifiedHeaders,
 		validatorSignatures: validatorSignatures,
+		masternodes:         masternodes,
 		proposals:           make(map[common.Address]bool),
 	}
 }
@@ -420,6 +424,9 @@ func (c *Posv) verifyCascadingFields(chain consensus.ChainReader, header *types.
 
 	// try again the progress with signers querying from smart contract
 	// for example the checkpoint is 886500 -> the start gap block is 886495
+	if c.HookGetSignersFromContract == nil {
+		return err
+	}
 	startGapBlockHeader := header
 	for step := uint64(1); step <= chain.Config().Posv.Gap; step++ {
 		startGapBlockHeader = chain.GetHeader(startGapBlockHeader.ParentHash, number-step)
@@ -536,6 +543,9 @@ func (c *Posv) GetMasternodes(chain consensus.ChainReader, header *types.Header)
 
 func (c *Posv) GetPeriod() uint64 { return c.config.Period }
 
+// GetEpoch returns the number of blocks after which the masternodes are rotated.
+func (c *Posv) GetEpoch() uint64 { return c.config.Epoch }
+
 func whoIsCreator(snap *Snapshot, header *types.Header) (common.Address, error) {
 	if header.Number.Uint64() == 0 {
 		return common.Address{}, errors.New("Don't take block 0")
@@ -949,6 +959,9 @@ func (c *Posv) Finalize(chain consensus.ChainReader, header *types.Header, state
 		}
 	}
 
+	// Clear the sign records which were already rewarded and can't be signed anymore
+	clearRewardedSigners(chain.Config(), state, number)
+
 	// the state remains as is and uncles are dropped
 	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
 	header.UncleHash = types.CalcUncleHash(nil)
@@ -957,6 +970,35 @@ func (c *Posv) Finalize(chain consensus.ChainReader, header *types.Header, state
 	return types.NewBlock(header, txs, nil, receipts), nil
 }
 
+// clearRewardedSigners clears the sign records rewarded at the previous checkpoint
+// if the block is a checkpoint past the TIPCleanSigners fork. Only that window is
+// cleared, so the records older than the first window after the fork are kept.
+func clearRewardedSigners(config *params.ChainConfig, statedb *state.StateDB, number uint64) {
+	rCheckpoint := config.Posv.RewardCheckpoint
+	if config.IsTIPCleanSigners(new(big.Int).SetUint64(number)) && number%rCheckpoint == 0 {
+		clearBlockSigners(statedb, number, rCheckpoint)
+	}
+}
+
+// clearBlockSigners deletes the BlockSigners contract records of the blocks older
+// than the reward window of the given checkpoint. These were rewarded at the
+// previous checkpoint and the contract rejects signing them from now on.
+func clearBlockSigners(statedb *state.StateDB, number uint64, rCheckpoint uint64) {
+	if number <= rCheckpoint*2 {
+		return
+	}
+	end := number - rCheckpoint*2
+	start := uint64(1)
+	if end > rCheckpoint {
+		start = end - rCheckpoint + 1
+	}
+	cleared := 0
+	for i := start; i <= end; i++ {
+		cleared += state.ClearBlockSigners(statedb, i)
+	}
+	log.Debug("Cleared block signers", "number", number, "from", start, "to", end, "slots", cleared)
+}
+
 // Authorize injects a private key into the consensus engine to mint new blocks
 // with.
 func (c *Posv) Authorize(signer common.Address, signFn clique.SignerFn) {
@@ -1111,6 +1153,18 @@ func (c *Posv) GetMasternodesFromCheckpointHeader(preCheckpointHeader *types.Hea
 	return masternodes
 }
 
+// CheckpointMasternodes returns the masternodes recorded in a checkpoint header,
+// caching them by the hash of the header.
+func (c *Posv) CheckpointMasternodes(checkpoint *types.Header) []common.Address {
+	hash := checkpoint.Hash()
+	if cached, ok := c.masternodes.Get(hash); ok {
+		return cached.([]common.Address)
+	}
+	masternodes := c.GetMasternodesFromCheckpointHeader(checkpoint, checkpoint.Number.Uint64(), c.config.Epoch)
+	c.masternodes.Add(hash, masternodes)
+	return masternodes
+}
+
 func (c *Posv) CacheData(header *types.Header, txs []*types.Transaction, receipts []*types.Receipt) []*types.Transaction {
 	signTxs := []*types.Transaction{}
 	for _, tx := range txs {
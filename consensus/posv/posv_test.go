@@ -6,7 +6,9 @@ import (
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -48,6 +50,69 @@ func TestGetM1M2FromCheckpointHeader(t *testing.T) {
 	}
 }
 
+// Tests that the fork clearing the sign records is scheduled at a reward checkpoint
+// of the mainnet, so that the records are cleared from the fork block on.
+func TestTIPCleanSignersCheckpoint(t *testing.T) {
+	rCheckpoint := params.TomoMainnetChainConfig.Posv.RewardCheckpoint
+	if fork := common.TIPCleanSigners.Uint64(); fork%rCheckpoint != 0 {
+		t.Fatalf("fork block %d not a reward checkpoint (%d blocks)", fork, rCheckpoint)
+	}
+}
+
+// recordSigners records a signed hash of a block in the BlockSigners contract.
+func recordSigners(statedb *state.StateDB, number uint64) {
+	addr := common.HexToAddress(common.BlockSigners)
+	slot := common.BigToHash(state.GetLocMappingAtKey(common.BigToHash(new(big.Int).SetUint64(number)), 1))
+	statedb.SetState(addr, slot, common.BigToHash(common.Big1))
+	statedb.SetState(addr, state.GetLocDynamicArrAtElement(slot, 0, 1), common.Hash{0x01})
+}
+
+// hasSigners reports whether the BlockSigners contract holds records of a block.
+func hasSigners(statedb *state.StateDB, number uint64) bool {
+	addr := common.HexToAddress(common.BlockSigners)
+	slot := common.BigToHash(state.GetLocMappingAtKey(common.BigToHash(new(big.Int).SetUint64(number)), 1))
+	return statedb.GetState(addr, slot) != (common.Hash{})
+}
+
+// Tests that the sign records rewarded at the previous checkpoint are cleared at a
+// checkpoint after the fork, and only there, leaving the other ones untouched.
+func TestClearRewardedSigners(t *testing.T) {
+	var (
+		config = params.TomoMainnetChainConfig
+		r      = config.Posv.RewardCheckpoint
+		n      = common.TIPCleanSigners.Uint64() + 2*r
+	)
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	cleared := []uint64{n - 3*r + 1, n - 3*r + r/2, n - 2*r}
+	kept := []uint64{n - 3*r, n - 2*r + 1, n - r, n}
+	for _, number := range append(append([]uint64{}, cleared...), kept...) {
+		recordSigners(statedb, number)
+	}
+	// Nothing may be cleared at the blocks around the checkpoint
+	for _, number := range []uint64{n - 1, n + 1} {
+		clearRewardedSigners(config, statedb, number)
+		for _, block := range append(append([]uint64{}, cleared...), kept...) {
+			if !hasSigners(statedb, block) {
+				t.Fatalf("block %d: records of block %d cleared at non-checkpoint", number, block)
+			}
+		}
+	}
+	// Only the rewarded window may be cleared at the checkpoint
+	clearRewardedSigners(config, statedb, n)
+	for _, number := range cleared {
+		if hasSigners(statedb, number) {
+			t.Errorf("block %d: rewarded records not cleared", number)
+		}
+	}
+	for _, number := range kept {
+		if !hasSigners(statedb, number) {
+			t.Errorf("block %d: records outside the rewarded window cleared", number)
+		}
+	}
+}
+
 func TestCompareSignersLists(t *testing.T) {
 	list1 := []common.Address{
 		common.StringToAddress("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"),
@@ -35,6 +35,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/posv"
 	contractValidator "github.com/ethereum/go-ethereum/contracts/validator/contract"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -64,6 +65,10 @@ const (
 	badBlockLimit       = 10
 	triesInMemory       = 128
 
+	// snapshotLayers is the number of recent blocks whose state is kept in memory
+	// diff layers of the snapshot tree, the disk layer being the one below.
+	snapshotLayers = 128
+
 	// BlockChainVersion ensures that an incompatible database forces a resync from scratch.
 	BlockChainVersion = 3
 
@@ -77,6 +82,11 @@ type CacheConfig struct {
 	Disabled      bool          // Whether to disable trie write caching (archive node)
 	TrieNodeLimit int           // Memory limit (MB) at which to flush the current in-memory trie to disk
 	TrieTimeLimit time.Duration // Time limit after which to flush the current in-memory trie to disk
+
+	AncientThreshold uint64 // Number of recent blocks kept out of the ancient store (0 = freezing disabled)
+	SnapshotLimit    int    // Memory allowance (MB) to use for caching snapshot entries in memory (0 = snapshot disabled)
+	StateDiffs       bool   // Whether to store the state changes made by every block
+	StateDiffsRetain uint64 // Number of recent blocks whose state changes are kept (0 = all)
 }
 type ResultProcessBlock struct {
 	logs     []*types.Log
@@ -114,18 +124,21 @@ type BlockChain struct {
 	chainSideFeed event.Feed
 	chainHeadFeed event.Feed
 	logsFeed      event.Feed
+	stateDiffFeed event.Feed
 	scope         event.SubscriptionScope
 	genesisBlock  *types.Block
 
-	mu      sync.RWMutex // global mutex for locking chain operations
-	chainmu sync.RWMutex // blockchain insertion lock
-	procmu  sync.RWMutex // block processor lock
+	mu       sync.RWMutex // global mutex for locking chain operations
+	chainmu  sync.RWMutex // blockchain insertion lock
+	procmu   sync.RWMutex // block processor lock
+	freezemu sync.Mutex   // ancient store lock, acquired before mu if both are needed
 
 	checkpoint       int          // checkpoint counts towards the new checkpoint
 	currentBlock     atomic.Value // Current head of the block chain
 	currentFastBlock atomic.Value // Current head of the fast-sync chain (may be above the block chain!)
 
 	stateCache       state.Database // State database to reuse between imports (contains state cache)
+	snaps            *snapshot.Tree // Snapshot tree for fast trie leaf access
 	bodyCache        *lru.Cache     // Cache for the most recent block bodies
 	bodyRLPCache     *lru.Cache     // Cache for the most recent block bodies in RLP encoded format
 	blockCache       *lru.Cache     // Cache for the most recent entire blocks
@@ -163,6 +176,11 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 			TrieTimeLimit: 5 * time.Minute,
 		}
 	}
+	// The ancient store can't be rewound by a reorg, only truncated by an explicit
+	// rewind, warn if reorgs are likely to reach the frozen blocks
+	if cacheConfig.AncientThreshold > 0 && cacheConfig.AncientThreshold < RecommendedAncientThreshold {
+		log.Warn("Low ancient threshold, deeper reorgs will be rejected", "threshold", cacheConfig.AncientThreshold, "recommended", RecommendedAncientThreshold)
+	}
 	bodyCache, _ := lru.New(bodyCacheLimit)
 	bodyRLPCache, _ := lru.New(bodyCacheLimit)
 	blockCache, _ := lru.New(blockCacheLimit)
@@ -206,6 +224,10 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 	if err := bc.loadLastState(); err != nil {
 		return nil, err
 	}
+	// Load any existing snapshot, regenerating it if loading failed
+	if bc.cacheConfig.SnapshotLimit > 0 {
+		bc.snaps = snapshot.New(bc.db, bc.stateCache.TrieDB(), bc.cacheConfig.SnapshotLimit, bc.CurrentBlock().Root())
+	}
 	// Check the current state of the block hashes and make sure that we do not have any of the bad blocks in our chain
 	for hash := range BadHashes {
 		if header := bc.GetHeaderByHash(hash); header != nil {
@@ -221,6 +243,12 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 	}
 	// Take ownership of this particular state
 	go bc.update()
+
+	// Move the finalized blocks into the ancient store if the database has one
+	if _, ok := db.(ancientStore); ok && cacheConfig.AncientThreshold > 0 {
+		bc.wg.Add(1)
+		go bc.freezeLoop()
+	}
 	return bc, nil
 }
 
@@ -294,12 +322,17 @@ func (bc *BlockChain) loadLastState() error {
 func (bc *BlockChain) SetHead(head uint64) error {
 	log.Warn("Rewinding blockchain", "target", head)
 
+	// Wait for any running freeze, the rewind may truncate the ancient store
+	bc.freezemu.Lock()
+	defer bc.freezemu.Unlock()
+
 	bc.mu.Lock()
 	defer bc.mu.Unlock()
 
 	// Rewind the header chain, deleting all block bodies until then
 	delFn := func(hash common.Hash, num uint64) {
 		DeleteBody(bc.db, hash, num)
+		DeleteStateDiff(bc.db, hash, num)
 	}
 	bc.hc.SetHead(head, delFn)
 	currentHeader := bc.hc.CurrentHeader()
@@ -334,6 +367,11 @@ func (bc *BlockChain) SetHead(head uint64) error {
 	}
 	currentBlock := bc.CurrentBlock()
 	currentFastBlock := bc.CurrentFastBlock()
+
+	// The snapshot can't be rewound, rebuild it for the new head state
+	if bc.snaps != nil {
+		bc.snaps.Rebuild(currentBlock.Root())
+	}
 	if err := WriteHeadBlockHash(bc.db, currentBlock.Hash()); err != nil {
 		log.Crit("Failed to reset head full block", "err", err)
 	}
@@ -415,7 +453,12 @@ func (bc *BlockChain) State() (*state.StateDB, error) {
 
 // StateAt returns a new mutable state based on a particular point in time.
 func (bc *BlockChain) StateAt(root common.Hash) (*state.StateDB, error) {
-	return state.New(root, bc.stateCache)
+	return state.NewWithSnapshot(root, bc.stateCache, bc.snaps)
+}
+
+// StateCache returns the caching database underpinning the blockchain instance.
+func (bc *BlockChain) StateCache() state.Database {
+	return bc.stateCache
 }
 
 // Reset purges the entire blockchain, restoring it to its genesis state.
@@ -576,8 +619,10 @@ func (bc *BlockChain) HasBlock(hash common.Hash, number uint64) bool {
 	if bc.blockCache.Contains(hash) {
 		return true
 	}
-	ok, _ := bc.db.Has(blockBodyKey(hash, number))
-	return ok
+	if ok, _ := bc.db.Has(blockBodyKey(hash, number)); ok {
+		return true
+	}
+	return isAncient(bc.db, hash, number)
 }
 
 // HasState checks if state trie is fully present in the database or not.
@@ -704,6 +749,14 @@ func (bc *BlockChain) Stop() {
 
 	bc.wg.Wait()
 
+	// Flatten the snapshot diff layers into the disk layer, so the snapshot of the
+	// head state can be reused on the next start, and stop any generation.
+	if bc.snaps != nil {
+		if err := bc.snaps.Cap(bc.CurrentBlock().Root(), 0); err != nil {
+			log.Error("Failed to persist state snapshot", "err", err)
+		}
+		bc.snaps.Release()
+	}
 	// Ensure the state of a recent block is also stored to disk before exiting.
 	// We're writing three different states to catch different restart scenarios:
 	//  - HEAD:     So we don't need to reprocess any blocks in the general case
@@ -932,7 +985,7 @@ func (bc *BlockChain) WriteBlockWithoutState(block *types.Block, td *big.Int) (e
 }
 
 // WriteBlockWithState writes the block and all associated state to the database.
-func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.Receipt, state *state.StateDB) (status WriteStatus, err error) {
+func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.Receipt, statedb *state.StateDB) (status WriteStatus, err error) {
 	bc.wg.Add(1)
 	defer bc.wg.Done()
 
@@ -958,10 +1011,33 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 	if err := WriteBlock(batch, block); err != nil {
 		return NonStatTy, err
 	}
-	root, err := state.Commit(bc.chainConfig.IsEIP158(block.Number()))
+	var (
+		root common.Hash
+		diff *state.StateDiff
+	)
+	if bc.cacheConfig.StateDiffs {
+		root, diff, err = statedb.CommitWithDiff(bc.chainConfig.IsEIP158(block.Number()))
+	} else {
+		root, err = statedb.Commit(bc.chainConfig.IsEIP158(block.Number()))
+	}
 	if err != nil {
 		return NonStatTy, err
 	}
+	// Add the state changes to the snapshot tree, side chains included
+	if err := statedb.UpdateSnapshot(); err != nil {
+		log.Warn("Failed to update snapshot tree", "number", block.Number(), "root", root, "err", err)
+	}
+	if diff != nil {
+		if err := WriteStateDiff(batch, block.Hash(), block.NumberU64(), diff); err != nil {
+			return NonStatTy, err
+		}
+		// Drop the changes which fell out of the retention window as the chain grows
+		if retain := bc.cacheConfig.StateDiffsRetain; retain > 0 && block.NumberU64() > retain && block.NumberU64() > currentBlock.NumberU64() {
+			if err := DeleteStateDiffs(bc.db, batch, block.NumberU64()-retain); err != nil {
+				return NonStatTy, err
+			}
+		}
+	}
 	triedb := bc.stateCache.TrieDB()
 
 	// If we're running an archive node, always flush
@@ -1038,7 +1114,7 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 			return NonStatTy, err
 		}
 		// Write hash preimages
-		if err := WritePreimages(bc.db, block.NumberU64(), state.Preimages()); err != nil {
+		if err := WritePreimages(bc.db, block.NumberU64(), statedb.Preimages()); err != nil {
 			return NonStatTy, err
 		}
 		status = CanonStatTy
@@ -1052,6 +1128,13 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 	// Set new head.
 	if status == CanonStatTy {
 		bc.insert(block)
+
+		// Keep the recent diff layers of the canonical chain in memory only
+		if bc.snaps != nil && bc.snaps.Snapshot(root) != nil {
+			if err := bc.snaps.Cap(root, snapshotLayers); err != nil {
+				log.Warn("Failed to cap snapshot tree", "root", root, "layers", snapshotLayers, "err", err)
+			}
+		}
 	}
 	// save cache BlockSigners
 	if bc.chainConfig.Posv != nil && bc.chainConfig.IsTIPSigning(block.Number()) {
@@ -1207,7 +1290,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 		} else {
 			parent = chain[i-1]
 		}
-		statedb, err := state.New(parent.Root(), bc.stateCache)
+		statedb, err := state.NewWithSnapshot(parent.Root(), bc.stateCache, bc.snaps)
 		if err != nil {
 			return i, events, coalescedLogs, err
 		}
@@ -1394,7 +1477,7 @@ func (bc *BlockChain) getResultBlock(block *types.Block, verifiedM2 bool) (*Resu
 	// Create a new statedb using the parent block and report an
 	// error if it fails.
 	var parent = bc.GetBlock(block.ParentHash(), block.NumberU64()-1)
-	statedb, err := state.New(parent.Root(), bc.stateCache)
+	statedb, err := state.NewWithSnapshot(parent.Root(), bc.stateCache, bc.snaps)
 	if err != nil {
 		return nil, err
 	}
@@ -1650,6 +1733,13 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 			return fmt.Errorf("Invalid new chain")
 		}
 	}
+	// The frozen blocks can't be rewritten, only discarded by an explicit rewind
+	if db, ok := bc.db.(ancientStore); ok {
+		if frozen := db.Ancients(); commonBlock.NumberU64()+1 < frozen {
+			log.Error("Reorg below the ancient store, rewind the chain to switch", "number", commonBlock.Number(), "hash", commonBlock.Hash(), "frozen", frozen)
+			return ErrReorgBelowAncients
+		}
+	}
 	// Ensure the user sees large reorgs
 	if len(oldChain) > 0 && len(newChain) > 0 {
 		logFn := log.Debug
@@ -1706,6 +1796,12 @@ func (bc *BlockChain) PostChainEvents(events []interface{}, logs []*types.Log) {
 		case ChainEvent:
 			bc.chainFeed.Send(ev)
 
+			if bc.cacheConfig.StateDiffs {
+				if diff := GetStateDiff(bc.db, ev.Hash, ev.Block.NumberU64()); diff != nil {
+					bc.stateDiffFeed.Send(StateDiffEvent{Block: ev.Block, Diff: diff})
+				}
+			}
+
 		case ChainHeadEvent:
 			bc.chainHeadFeed.Send(ev)
 
@@ -1878,6 +1974,9 @@ func (bc *BlockChain) Config() *params.ChainConfig { return bc.chainConfig }
 // Engine retrieves the blockchain's consensus engine.
 func (bc *BlockChain) Engine() consensus.Engine { return bc.engine }
 
+// GetVMConfig returns a copy of the block chain VM config.
+func (bc *BlockChain) GetVMConfig() vm.Config { return bc.vmConfig }
+
 // SubscribeRemovedLogsEvent registers a subscription of RemovedLogsEvent.
 func (bc *BlockChain) SubscribeRemovedLogsEvent(ch chan<- RemovedLogsEvent) event.Subscription {
 	return bc.scope.Track(bc.rmLogsFeed.Subscribe(ch))
@@ -1898,6 +1997,11 @@ func (bc *BlockChain) SubscribeChainSideEvent(ch chan<- ChainSideEvent) event.Su
 	return bc.scope.Track(bc.chainSideFeed.Subscribe(ch))
 }
 
+// SubscribeStateDiffEvent registers a subscription of StateDiffEvent.
+func (bc *BlockChain) SubscribeStateDiffEvent(ch chan<- StateDiffEvent) event.Subscription {
+	return bc.scope.Track(bc.stateDiffFeed.Subscribe(ch))
+}
+
 // SubscribeLogsEvent registers a subscription of []*types.Log.
 func (bc *BlockChain) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription {
 	return bc.scope.Track(bc.logsFeed.Subscribe(ch))
@@ -0,0 +1,130 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the state snapshot follows the imported blocks, and that it is
+// persisted on shutdown so it can be reused on the next start.
+func TestSnapshotPersistence(t *testing.T) {
+	var (
+		db, _   = ethdb.NewMemDatabase()
+		key, _  = crypto.GenerateKey()
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &Genesis{Config: params.TestChainConfig, Alloc: GenesisAlloc{address: {Balance: big.NewInt(1000000000)}}}
+		genesis = gspec.MustCommit(db)
+		signer  = types.HomesteadSigner{}
+		config  = &CacheConfig{TrieNodeLimit: 256 * 1024 * 1024, TrieTimeLimit: 5 * time.Minute, SnapshotLimit: 16}
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 10, func(i int, block *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{byte(i)}, big.NewInt(1), 21000, new(big.Int), nil), signer, key)
+		block.AddTx(tx)
+	})
+	chain, err := NewBlockChain(db, config, gspec.Config, ethash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	head := chain.CurrentBlock().Root()
+	if chain.snaps.Snapshot(head) == nil {
+		t.Fatalf("snapshot missing for head state")
+	}
+	chain.Stop()
+
+	// Restart the chain and ensure the persisted snapshot is loaded, not regenerated
+	chain, err = NewBlockChain(db, config, gspec.Config, ethash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to recreate blockchain: %v", err)
+	}
+	defer chain.Stop()
+
+	snap := chain.snaps.Snapshot(head)
+	if snap == nil {
+		t.Fatalf("snapshot missing for head state after restart")
+	}
+	tr, err := chain.stateCache.OpenTrie(head)
+	if err != nil {
+		t.Fatalf("failed to open head state trie: %v", err)
+	}
+	for _, addr := range []common.Address{address, {0x00}, {0x09}} {
+		blob, err := snap.Account(crypto.Keccak256Hash(addr[:]))
+		if err != nil {
+			t.Fatalf("account %x: failed to read snapshot: %v", addr, err)
+		}
+		want, _ := tr.TryGet(addr[:])
+		if !bytes.Equal(blob, want) {
+			t.Errorf("account %x: snapshot mismatch: have %x, want %x", addr, blob, want)
+		}
+	}
+}
+
+// Tests that side chain blocks add their state to the snapshot tree too, while
+// the canonical head remains available.
+func TestSnapshotSideChain(t *testing.T) {
+	var (
+		db, _   = ethdb.NewMemDatabase()
+		key, _  = crypto.GenerateKey()
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &Genesis{Config: params.TestChainConfig, Alloc: GenesisAlloc{address: {Balance: big.NewInt(1000000000)}}}
+		genesis = gspec.MustCommit(db)
+		signer  = types.HomesteadSigner{}
+		config  = &CacheConfig{TrieNodeLimit: 256 * 1024 * 1024, TrieTimeLimit: 5 * time.Minute, SnapshotLimit: 16}
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 10, func(i int, block *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{byte(i)}, big.NewInt(1), 21000, new(big.Int), nil), signer, key)
+		block.AddTx(tx)
+	})
+	forks, _ := GenerateChain(gspec.Config, blocks[4], ethash.NewFaker(), db, 3, func(i int, block *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{0xff, byte(i)}, big.NewInt(1), 21000, new(big.Int), nil), signer, key)
+		block.AddTx(tx)
+	})
+	chain, err := NewBlockChain(db, config, gspec.Config, ethash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if _, err := chain.InsertChain(forks); err != nil {
+		t.Fatalf("failed to insert side chain: %v", err)
+	}
+	if head := chain.CurrentBlock(); head.Hash() != blocks[len(blocks)-1].Hash() {
+		t.Fatalf("head block mismatch: have %d, want %d", head.NumberU64(), len(blocks))
+	}
+	for _, block := range append(blocks, forks...) {
+		if chain.snaps.Snapshot(block.Root()) == nil {
+			t.Errorf("block %d [%x]: snapshot missing", block.NumberU64(), block.Hash().Bytes()[:4])
+		}
+	}
+}
@@ -1411,3 +1411,87 @@ func TestAreTwoBlocksSamePath(t *testing.T) {
 	})
 
 }
+
+// Tests that the state changes of the imported blocks are stored and announced
+// if enabled.
+func TestStateDiffs(t *testing.T) {
+	var (
+		db, _   = ethdb.NewMemDatabase()
+		key, _  = crypto.GenerateKey()
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		funds   = big.NewInt(1000000000)
+		gspec   = &Genesis{Config: params.TestChainConfig, Alloc: GenesisAlloc{address: {Balance: funds}}}
+		genesis = gspec.MustCommit(db)
+		signer  = types.HomesteadSigner{}
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 3, func(i int, block *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{0xaa}, big.NewInt(1), 21000, new(big.Int), nil), signer, key)
+		block.AddTx(tx)
+	})
+	chain, err := NewBlockChain(db, &CacheConfig{TrieNodeLimit: 256 * 1024 * 1024, TrieTimeLimit: 5 * time.Minute, StateDiffs: true}, gspec.Config, ethash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	defer chain.Stop()
+
+	diffs := make(chan StateDiffEvent, len(blocks))
+	sub := chain.SubscribeStateDiffEvent(diffs)
+	defer sub.Unsubscribe()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	for i, block := range blocks {
+		diff := GetStateDiff(db, block.Hash(), block.NumberU64())
+		if diff == nil {
+			t.Fatalf("block %d: state diff missing", block.NumberU64())
+		}
+		// The sender, the recipient and the coinbase are changed by every block
+		if len(diff.Accounts) != 3 {
+			t.Fatalf("block %d: changed account count mismatch: have %d, want %d", block.NumberU64(), len(diff.Accounts), 3)
+		}
+		for _, account := range diff.Accounts {
+			if account.Address != (common.Address{0xaa}) {
+				continue
+			}
+			if account.BalanceFrom.Int64() != int64(i) || account.BalanceTo.Int64() != int64(i+1) || account.Existed != (i > 0) {
+				t.Errorf("block %d: recipient change mismatch: have %v -> %v (existed %v)", block.NumberU64(), account.BalanceFrom, account.BalanceTo, account.Existed)
+			}
+		}
+		select {
+		case ev := <-diffs:
+			if ev.Block.Hash() != block.Hash() || len(ev.Diff.Accounts) != len(diff.Accounts) {
+				t.Errorf("block %d: state diff event mismatch", block.NumberU64())
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("block %d: state diff event missing", block.NumberU64())
+		}
+	}
+}
+
+// Tests that the state changes of the blocks falling out of the retention window
+// are deleted as the chain grows.
+func TestStateDiffsRetain(t *testing.T) {
+	var (
+		db, _   = ethdb.NewMemDatabase()
+		gspec   = &Genesis{Config: params.TestChainConfig}
+		genesis = gspec.MustCommit(db)
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 5, func(i int, block *BlockGen) {})
+
+	chain, err := NewBlockChain(db, &CacheConfig{TrieNodeLimit: 256 * 1024 * 1024, TrieTimeLimit: 5 * time.Minute, StateDiffs: true, StateDiffsRetain: 2}, gspec.Config, ethash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	for _, block := range blocks {
+		diff := GetStateDiff(db, block.Hash(), block.NumberU64())
+		if want := block.NumberU64() > 3; (diff != nil) != want {
+			t.Errorf("block %d: state diff presence mismatch: have %v, want %v", block.NumberU64(), diff != nil, want)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// freezerRecheckInterval is the frequency to check the key-value database for
+	// chain segments that can be moved into the ancient store.
+	freezerRecheckInterval = time.Minute
+
+	// freezerBatchLimit is the maximum number of blocks to freeze in one batch
+	// before doing an fsync and deleting them from the key-value store.
+	freezerBatchLimit = 30000
+
+	// RecommendedAncientThreshold is the number of recent blocks to keep out of the
+	// ancient store when freezing is enabled. Posv has no finality, so it is chosen
+	// well beyond any reorg seen in practice rather than derived from the epoch.
+	RecommendedAncientThreshold = 90000
+)
+
+// ancientStore is a chain database with an attached ancient store.
+type ancientStore interface {
+	ethdb.Database
+	ethdb.AncientReader
+	ethdb.AncientWriter
+}
+
+// freezeLoop periodically moves the canonical blocks which are older than the
+// ancient threshold out of the key-value store into the ancient store.
+func (bc *BlockChain) freezeLoop() {
+	defer bc.wg.Done()
+
+	ticker := time.NewTicker(freezerRecheckInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			frozen, err := bc.freeze()
+			if err != nil {
+				log.Error("Failed to freeze ancient blocks", "err", err)
+				return
+			}
+			if frozen < freezerBatchLimit {
+				break
+			}
+			select {
+			case <-bc.quit:
+				return
+			default:
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-bc.quit:
+			return
+		}
+	}
+}
+
+// freeze moves a batch of finalized canonical blocks from the key-value store
+// into the ancient store, returning the number of blocks moved. The headers,
+// bodies, receipts and total difficulties of the blocks are appended into the
+// ancient store, after which they and any side chain at the same heights are
+// deleted from the key-value store. The hash to number mappings are kept, as
+// they are needed to look the blocks up by hash.
+//
+// Only explicit rewinds are blocked while moving the blocks, the import of new
+// blocks is not. Nothing guarantees that the frozen blocks are final: a reorg
+// reaching below them is rejected, the chain has to be rewound explicitly with
+// SetHead first, which truncates the ancient store.
+//
+// Freezing is opt-in and one way: the moved blocks, including their canonical
+// hashes, are gone from the key-value store, so releases without an ancient
+// store can no longer read them from the migrated database.
+func (bc *BlockChain) freeze() (int, error) {
+	db, ok := bc.db.(ancientStore)
+	if !ok {
+		return 0, fmt.Errorf("database has no ancient store")
+	}
+	bc.freezemu.Lock()
+	defer bc.freezemu.Unlock()
+
+	threshold := bc.cacheConfig.AncientThreshold
+	head := bc.CurrentBlock().NumberU64()
+	if threshold == 0 || head < threshold {
+		return 0, nil
+	}
+	var (
+		start  = time.Now()
+		first  = db.Ancients()
+		limit  = head - threshold
+		hashes []common.Hash
+	)
+	if first > limit {
+		return 0, nil
+	}
+	if first == 0 {
+		log.Warn("Migrating old blocks into the ancient store, older releases can't read them anymore", "blocks", limit+1)
+	}
+	if limit-first >= freezerBatchLimit {
+		limit = first + freezerBatchLimit - 1
+	}
+	for number := first; number <= limit; number++ {
+		hash := GetCanonicalHash(db, number)
+		if hash == (common.Hash{}) {
+			return len(hashes), fmt.Errorf("canonical hash missing, can't freeze block %d", number)
+		}
+		items := map[string][]byte{freezerHashTable: hash.Bytes()}
+		for kind, key := range map[string][]byte{
+			freezerHeaderTable:     headerKey(hash, number),
+			freezerBodiesTable:     blockBodyKey(hash, number),
+			freezerReceiptTable:    blockReceiptsKey(hash, number),
+			freezerDifficultyTable: tdKey(hash, number),
+		} {
+			if data, _ := db.Get(key); len(data) > 0 {
+				items[kind] = data
+			} else {
+				log.Debug("Block data missing, can't freeze yet", "number", number, "hash", hash, "table", kind)
+			}
+		}
+		// Data may be legitimately missing after a fast sync, retry later
+		if len(items) < len(FreezerTables) {
+			break
+		}
+		if err := db.AppendAncient(number, items); err != nil {
+			return len(hashes), err
+		}
+		hashes = append(hashes, hash)
+	}
+	if len(hashes) == 0 {
+		return 0, nil
+	}
+	// Ensure the ancients are persisted before wiping them from the key-value store
+	if err := db.Sync(); err != nil {
+		return 0, err
+	}
+	batch := db.NewBatch()
+	for i, hash := range hashes {
+		number := first + uint64(i)
+		if number == 0 {
+			// Keep the genesis around, it's needed to set up and verify the chain
+			continue
+		}
+		DeleteCanonicalHash(batch, number)
+		batch.Delete(headerKey(hash, number))
+		DeleteBody(batch, hash, number)
+		DeleteBlockReceipts(batch, hash, number)
+		DeleteTd(batch, hash, number)
+
+		if err := bc.deleteSideChains(db, batch, hash, number); err != nil {
+			return 0, err
+		}
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return 0, err
+			}
+			batch.Reset()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return 0, err
+	}
+	log.Info("Moved blocks into ancient store", "count", len(hashes), "number", first+uint64(len(hashes))-1, "elapsed", common.PrettyDuration(time.Since(start)))
+	return len(hashes), nil
+}
+
+// deleteSideChains deletes all the non-canonical blocks at the given height from
+// the key-value store.
+func (bc *BlockChain) deleteSideChains(db ethdb.Database, batch ethdb.Batch, canonical common.Hash, number uint64) error {
+	it := db.NewIteratorWithPrefix(append(headerPrefix, encodeBlockNumber(number)...))
+	defer it.Release()
+
+	for it.Next() {
+		// Only the header entries are keyed by the plain number and hash
+		key := it.Key()
+		if len(key) != len(headerPrefix)+8+common.HashLength {
+			continue
+		}
+		if hash := common.BytesToHash(key[len(key)-common.HashLength:]); hash != canonical {
+			DeleteBlock(batch, hash, number)
+			DeleteStateDiff(batch, hash, number)
+		}
+	}
+	return it.Error()
+}
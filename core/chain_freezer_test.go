@@ -0,0 +1,196 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that finalized blocks are moved into the ancient store, remaining
+// available through the database accessors, and that side chains at the frozen
+// heights are deleted.
+func TestFreezeAncientBlocks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chain-freezer")
+	if err != nil {
+		t.Fatalf("failed to create temporary datadir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	kvdb, err := ethdb.NewLDBDatabase(filepath.Join(dir, "chaindata"), 0, 0)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	db, err := ethdb.NewDatabaseWithFreezer(kvdb, filepath.Join(dir, "ancient"), FreezerTables)
+	if err != nil {
+		t.Fatalf("failed to create ancient store: %v", err)
+	}
+	defer db.Close()
+
+	var (
+		key, _  = crypto.GenerateKey()
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &Genesis{Config: params.TestChainConfig, Alloc: GenesisAlloc{address: {Balance: big.NewInt(1000000000)}}}
+		genesis = gspec.MustCommit(db)
+		signer  = types.HomesteadSigner{}
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 20, func(i int, block *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{0x01}, big.NewInt(1), 21000, new(big.Int), nil), signer, key)
+		block.AddTx(tx)
+	})
+	forks, _ := GenerateChain(gspec.Config, blocks[2], ethash.NewFaker(), db, 2, func(i int, block *BlockGen) {
+		block.SetCoinbase(common.Address{0x02})
+	})
+	chain, err := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if _, err := chain.InsertChain(forks); err != nil {
+		t.Fatalf("failed to insert side chain: %v", err)
+	}
+	for _, fork := range forks {
+		if GetHeader(db, fork.Hash(), fork.NumberU64()) == nil {
+			t.Fatalf("side chain block %d not stored", fork.NumberU64())
+		}
+	}
+	chain.cacheConfig.AncientThreshold = 8
+	if frozen, err := chain.freeze(); err != nil || frozen != 13 {
+		t.Fatalf("frozen blocks mismatch: have %d/%v, want %d", frozen, err, 13)
+	}
+	if frozen, err := chain.freeze(); err != nil || frozen != 0 {
+		t.Fatalf("frozen blocks mismatch on second run: have %d/%v, want %d", frozen, err, 0)
+	}
+	for _, block := range append([]*types.Block{genesis}, blocks...) {
+		number, hash := block.NumberU64(), block.Hash()
+		if GetCanonicalHash(db, number) != hash {
+			t.Errorf("block %d: canonical hash mismatch", number)
+		}
+		if stored := GetBlock(db, hash, number); stored == nil || stored.Hash() != hash {
+			t.Errorf("block %d: block not available", number)
+		}
+		if GetTd(db, hash, number) == nil {
+			t.Errorf("block %d: total difficulty not available", number)
+		}
+		if number > 0 && len(GetBlockReceipts(db, hash, number)) != 1 {
+			t.Errorf("block %d: receipts not available", number)
+		}
+		if !chain.HasBlock(hash, number) || !chain.HasHeader(hash, number) {
+			t.Errorf("block %d: block not known", number)
+		}
+		if ok, _ := db.Has(headerKey(hash, number)); ok != (number == 0 || number > 12) {
+			t.Errorf("block %d: key-value store header presence mismatch: have %v", number, ok)
+		}
+	}
+	for _, fork := range forks {
+		if GetHeader(db, fork.Hash(), fork.NumberU64()) != nil {
+			t.Errorf("side chain block %d not deleted", fork.NumberU64())
+		}
+	}
+	// Rewinding the chain below the frozen blocks must discard them
+	if err := chain.SetHead(10); err != nil {
+		t.Fatalf("failed to rewind chain: %v", err)
+	}
+	if frozen := db.(ethdb.AncientReader).Ancients(); frozen != 11 {
+		t.Errorf("ancients mismatch after rewind: have %d, want %d", frozen, 11)
+	}
+	if GetCanonicalHash(db, 11) != (common.Hash{}) {
+		t.Errorf("rewound canonical hash still available")
+	}
+	if head := chain.CurrentBlock(); head.Hash() != blocks[9].Hash() {
+		t.Errorf("head block mismatch after rewind: have %d, want %d", head.NumberU64(), 10)
+	}
+}
+
+// Tests that a reorg reaching below the frozen blocks is rejected, leaving the
+// frozen canonical chain intact, until the chain is rewound explicitly.
+func TestReorgBelowAncients(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chain-freezer")
+	if err != nil {
+		t.Fatalf("failed to create temporary datadir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	kvdb, err := ethdb.NewLDBDatabase(filepath.Join(dir, "chaindata"), 0, 0)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	db, err := ethdb.NewDatabaseWithFreezer(kvdb, filepath.Join(dir, "ancient"), FreezerTables)
+	if err != nil {
+		t.Fatalf("failed to create ancient store: %v", err)
+	}
+	defer db.Close()
+
+	var (
+		gspec   = &Genesis{Config: params.TestChainConfig}
+		genesis = gspec.MustCommit(db)
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 20, nil)
+	forks, _ := GenerateChain(gspec.Config, blocks[2], ethash.NewFaker(), db, 20, func(i int, block *BlockGen) {
+		block.SetCoinbase(common.Address{0x02})
+	})
+	chain, err := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	chain.cacheConfig.AncientThreshold = 8
+	if frozen, err := chain.freeze(); err != nil || frozen != 13 {
+		t.Fatalf("frozen blocks mismatch: have %d/%v, want %d", frozen, err, 13)
+	}
+	// Import a heavier fork branching off below the frozen blocks
+	if _, err := chain.InsertChain(forks); err != ErrReorgBelowAncients {
+		t.Fatalf("fork import error mismatch: have %v, want %v", err, ErrReorgBelowAncients)
+	}
+	if head := chain.CurrentBlock(); head.Hash() != blocks[19].Hash() {
+		t.Errorf("head block mismatch after rejected reorg: have %d, want %d", head.NumberU64(), 20)
+	}
+	if frozen := db.(ethdb.AncientReader).Ancients(); frozen != 13 {
+		t.Errorf("ancients mismatch after rejected reorg: have %d, want %d", frozen, 13)
+	}
+	for _, block := range blocks[:12] {
+		if GetCanonicalHash(db, block.NumberU64()) != block.Hash() {
+			t.Errorf("block %d: frozen canonical hash replaced", block.NumberU64())
+		}
+	}
+	// Rewinding below the fork point discards the frozen blocks in the way
+	if err := chain.SetHead(2); err != nil {
+		t.Fatalf("failed to rewind chain: %v", err)
+	}
+	if frozen := db.(ethdb.AncientReader).Ancients(); frozen > 3 {
+		t.Errorf("ancients mismatch after rewind: have %d, want at most %d", frozen, 3)
+	}
+}
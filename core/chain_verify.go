@@ -0,0 +1,290 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/posv"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	// ErrVerifyInterrupted is returned by VerifyChain if it was interrupted.
+	ErrVerifyInterrupted = errors.New("verification interrupted")
+
+	errMissingHead          = errors.New("missing head block")
+	errMissingCanonicalHash = errors.New("missing canonical hash")
+	errMissingHeader        = errors.New("missing or corrupted header")
+	errMissingBody          = errors.New("missing or corrupted body")
+	errMissingReceipts      = errors.New("missing receipts")
+	errHeaderHashMismatch   = errors.New("header hash mismatch")
+	errParentHashMismatch   = errors.New("parent hash mismatch")
+	errTxRootMismatch       = errors.New("transaction root mismatch")
+	errUncleHashMismatch    = errors.New("uncle hash mismatch")
+	errReceiptRootMismatch  = errors.New("receipt root mismatch")
+	errStateNodeMismatch    = errors.New("state node hash mismatch")
+)
+
+// VerifyError reports the first inconsistent block found by VerifyChain.
+type VerifyError struct {
+	Number uint64      // Number of the inconsistent block
+	Hash   common.Hash // Canonical hash of the inconsistent block, if known
+	Err    error       // Inconsistency found in the block
+}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("block #%d [%x…]: %v", e.Number, e.Hash[:4], e.Err)
+}
+
+// verifyChainReader implements consensus.ChainReader directly on top of the
+// database, so the consensus engine can check the stored headers without a
+// BlockChain being created. The current header is the one being verified, only
+// its ancestors are served so the engine doesn't treat it as already known.
+type verifyChainReader struct {
+	db      ethdb.Database
+	config  *params.ChainConfig
+	current *types.Header
+}
+
+func (r *verifyChainReader) Config() *params.ChainConfig  { return r.config }
+func (r *verifyChainReader) CurrentHeader() *types.Header { return r.current }
+
+// ancestor reports whether the given block number precedes the current header.
+func (r *verifyChainReader) ancestor(number uint64) bool {
+	return r.current != nil && number < r.current.Number.Uint64()
+}
+
+func (r *verifyChainReader) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if !r.ancestor(number) {
+		return nil
+	}
+	return GetHeader(r.db, hash, number)
+}
+
+func (r *verifyChainReader) GetHeaderByNumber(number uint64) *types.Header {
+	if !r.ancestor(number) {
+		return nil
+	}
+	return GetHeader(r.db, GetCanonicalHash(r.db, number), number)
+}
+
+func (r *verifyChainReader) GetHeaderByHash(hash common.Hash) *types.Header {
+	return r.GetHeader(hash, GetBlockNumber(r.db, hash))
+}
+
+func (r *verifyChainReader) GetBlock(hash common.Hash, number uint64) *types.Block {
+	if !r.ancestor(number) {
+		return nil
+	}
+	return GetBlock(r.db, hash, number)
+}
+
+// VerifyChain checks the integrity of the canonical chain stored in the database
+// from the given block up to the head block. The headers, bodies and receipts
+// are checked against the hashes and roots committing to them, and the headers
+// and their seals are verified by the consensus engine. If verifyState is set,
+// the entire state trie of the head block is also checked to be reachable and
+// untampered.
+//
+// The database is only read, but the engine may persist its own data (e.g. the
+// Posv checkpoint snapshots) into the database it was created with.
+//
+// The first inconsistency found is returned as a *VerifyError.
+func VerifyChain(db ethdb.Database, config *params.ChainConfig, engine consensus.Engine, from uint64, verifyState bool, stop <-chan struct{}) error {
+	hash := GetHeadBlockHash(db)
+	head := GetHeader(db, hash, GetBlockNumber(db, hash))
+	if head == nil {
+		return errMissingHead
+	}
+	var (
+		chain  = &verifyChainReader{db: db, config: config}
+		parent common.Hash
+		start  = time.Now()
+		logged = time.Now()
+	)
+	if from > 0 {
+		parent = GetCanonicalHash(db, from-1)
+	}
+	for number := from; number <= head.Number.Uint64(); number++ {
+		select {
+		case <-stop:
+			return ErrVerifyInterrupted
+		default:
+		}
+		hash, err := verifyBlock(chain, engine, number, parent, from)
+		if err != nil {
+			return &VerifyError{Number: number, Hash: hash, Err: err}
+		}
+		parent = hash
+
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Verifying chain", "number", number, "hash", hash, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	log.Info("Verified chain", "blocks", head.Number.Uint64()-from+1, "elapsed", common.PrettyDuration(time.Since(start)))
+
+	if verifyState {
+		if err := verifyStateTrie(db, head.Root, stop); err != nil {
+			if err == ErrVerifyInterrupted {
+				return err
+			}
+			return &VerifyError{Number: head.Number.Uint64(), Hash: head.Hash(), Err: err}
+		}
+	}
+	return nil
+}
+
+// verifyBlock checks the integrity of a single canonical block, returning its
+// hash. The parent hash is only checked if the block is not the first one.
+func verifyBlock(chain *verifyChainReader, engine consensus.Engine, number uint64, parent common.Hash, first uint64) (common.Hash, error) {
+	db := chain.db
+
+	hash := GetCanonicalHash(db, number)
+	if hash == (common.Hash{}) {
+		return hash, errMissingCanonicalHash
+	}
+	header := GetHeader(db, hash, number)
+	if header == nil {
+		return hash, errMissingHeader
+	}
+	if header.Hash() != hash || header.Number.Uint64() != number {
+		return hash, errHeaderHashMismatch
+	}
+	if number > first && header.ParentHash != parent {
+		return hash, errParentHashMismatch
+	}
+	body := GetBody(db, hash, number)
+	if body == nil {
+		return hash, errMissingBody
+	}
+	if types.DeriveSha(types.Transactions(body.Transactions)) != header.TxHash {
+		return hash, errTxRootMismatch
+	}
+	if types.CalcUncleHash(body.Uncles) != header.UncleHash {
+		return hash, errUncleHashMismatch
+	}
+	receipts := GetBlockReceipts(db, hash, number)
+	if receipts == nil && len(body.Transactions) > 0 {
+		return hash, errMissingReceipts
+	}
+	if types.DeriveSha(receipts) != header.ReceiptHash {
+		return hash, errReceiptRootMismatch
+	}
+	// The genesis block carries no seal
+	if number > 0 {
+		chain.current = header
+		if err := engine.VerifyHeader(chain, header, false); err != nil {
+			return hash, fmt.Errorf("invalid header: %v", err)
+		}
+		if err := engine.VerifySeal(chain, header); err != nil {
+			return hash, fmt.Errorf("invalid seal: %v", err)
+		}
+	}
+	return hash, nil
+}
+
+// SignersFromState returns the masternode candidates of the validator contract
+// with the highest caps, read from the state of the given block. It resolves the
+// same checkpoint signers as the Posv hook calling the contract, without needing
+// a running node.
+func SignersFromState(db ethdb.Database, hash common.Hash) ([]common.Address, error) {
+	header := GetHeader(db, hash, GetBlockNumber(db, hash))
+	if header == nil {
+		return nil, errMissingHeader
+	}
+	statedb, err := state.New(header.Root, state.NewDatabase(db))
+	if err != nil {
+		return nil, err
+	}
+	var candidates []posv.Masternode
+	for _, candidate := range state.GetCandidates(statedb) {
+		if candidate != (common.Address{}) {
+			candidates = append(candidates, posv.Masternode{Address: candidate, Stake: state.GetCandidateCap(statedb, candidate)})
+		}
+	}
+	if err := statedb.Error(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Stake.Cmp(candidates[j].Stake) > 0
+	})
+	if len(candidates) > common.MaxMasternodes {
+		candidates = candidates[:common.MaxMasternodes]
+	}
+	signers := make([]common.Address, len(candidates))
+	for i, candidate := range candidates {
+		signers[i] = candidate.Address
+	}
+	return signers, nil
+}
+
+// verifyStateTrie walks the entire state trie rooted at the given hash, including the
+// storage tries and contract codes, checking that every node is present and
+// matches its hash.
+func verifyStateTrie(db ethdb.Database, root common.Hash, stop <-chan struct{}) error {
+	statedb, err := state.New(root, state.NewDatabase(db))
+	if err != nil {
+		return err
+	}
+	var (
+		nodes  int
+		start  = time.Now()
+		logged = time.Now()
+	)
+	it := state.NewNodeIterator(statedb)
+	for it.Next() {
+		select {
+		case <-stop:
+			return ErrVerifyInterrupted
+		default:
+		}
+		// Embedded nodes have no hash of their own, their parent covers them
+		if it.Hash == (common.Hash{}) {
+			continue
+		}
+		blob, err := db.Get(it.Hash[:])
+		if err != nil {
+			return fmt.Errorf("missing state node %x: %v", it.Hash, err)
+		}
+		if crypto.Keccak256Hash(blob) != it.Hash {
+			return fmt.Errorf("%v: %x", errStateNodeMismatch, it.Hash)
+		}
+		nodes++
+
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Verifying state", "nodes", nodes, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if it.Error != nil {
+		return it.Error
+	}
+	log.Info("Verified state", "root", root, "nodes", nodes, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
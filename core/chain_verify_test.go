@@ -0,0 +1,228 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/consensus/posv"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// newVerifyTestChain creates an archive chain of a few blocks with transactions,
+// and a contract in its state.
+func newVerifyTestChain(t *testing.T) (*BlockChain, ethdb.Database, []*types.Block) {
+	var (
+		db, _   = ethdb.NewMemDatabase()
+		key, _  = crypto.GenerateKey()
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: GenesisAlloc{
+				address:           {Balance: big.NewInt(1000000000)},
+				common.Address{1}: {Balance: new(big.Int), Code: []byte{0x60, 0x00}},
+			},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.HomesteadSigner{}
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 8, func(i int, block *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{0xaa}, big.NewInt(1), 21000, new(big.Int), nil), signer, key)
+		block.AddTx(tx)
+	})
+	chain, err := NewBlockChain(db, &CacheConfig{Disabled: true}, gspec.Config, ethash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	return chain, db, blocks
+}
+
+// Tests that an intact chain passes verification, and that corrupted blocks are
+// reported.
+func TestVerifyChain(t *testing.T) {
+	tests := []struct {
+		corrupt func(db ethdb.Database, blocks []*types.Block)
+		number  uint64 // Number of the reported block, 0 if the chain is intact
+		err     error  // Reported error, nil for any consensus error
+	}{
+		// Intact chain
+		{func(db ethdb.Database, blocks []*types.Block) {}, 0, nil},
+		// Missing body
+		{func(db ethdb.Database, blocks []*types.Block) {
+			db.Delete(blockBodyKey(blocks[4].Hash(), 5))
+		}, 5, errMissingBody},
+		// Body of another block
+		{func(db ethdb.Database, blocks []*types.Block) {
+			WriteBody(db, blocks[2].Hash(), 3, blocks[3].Body())
+		}, 3, errTxRootMismatch},
+		// Tampered receipts
+		{func(db ethdb.Database, blocks []*types.Block) {
+			receipts := GetBlockReceipts(db, blocks[6].Hash(), 7)
+			receipts[0].CumulativeGasUsed++
+			WriteBlockReceipts(db, blocks[6].Hash(), 7, receipts)
+		}, 7, errReceiptRootMismatch},
+		// Canonical hash pointing to a missing header
+		{func(db ethdb.Database, blocks []*types.Block) {
+			WriteCanonicalHash(db, common.Hash{0xff}, 2)
+		}, 2, errMissingHeader},
+		// Header rejected by the consensus engine
+		{func(db ethdb.Database, blocks []*types.Block) {
+			header := blocks[3].Header()
+			header.Time = new(big.Int).Set(blocks[2].Time())
+			WriteHeader(db, header)
+			WriteBody(db, header.Hash(), 4, blocks[3].Body())
+			WriteBlockReceipts(db, header.Hash(), 4, GetBlockReceipts(db, blocks[3].Hash(), 4))
+			WriteCanonicalHash(db, header.Hash(), 4)
+		}, 4, nil},
+	}
+	for i, tt := range tests {
+		chain, db, blocks := newVerifyTestChain(t)
+		tt.corrupt(db, blocks)
+
+		err := VerifyChain(db, chain.Config(), ethash.NewFaker(), 0, false, nil)
+		if tt.number == 0 {
+			if err != nil {
+				t.Errorf("test %d: intact chain failed verification: %v", i, err)
+			}
+		} else if verr, ok := err.(*VerifyError); !ok || verr.Number != tt.number || (tt.err != nil && verr.Err != tt.err) {
+			t.Errorf("test %d: error mismatch: have %v, want block #%d: %v", i, err, tt.number, tt.err)
+		}
+		chain.Stop()
+	}
+}
+
+// Tests that the state verification detects missing and tampered state data.
+func TestVerifyChainState(t *testing.T) {
+	chain, db, blocks := newVerifyTestChain(t)
+	defer chain.Stop()
+
+	if err := VerifyChain(db, chain.Config(), ethash.NewFaker(), 0, true, nil); err != nil {
+		t.Fatalf("intact state failed verification: %v", err)
+	}
+	// Tamper with the contract code and ensure it's detected
+	db.Put(crypto.Keccak256([]byte{0x60, 0x00}), []byte{0x60, 0x01})
+
+	err := VerifyChain(db, chain.Config(), ethash.NewFaker(), 0, true, nil)
+	if verr, ok := err.(*VerifyError); !ok || verr.Number != blocks[len(blocks)-1].NumberU64() {
+		t.Fatalf("tampered state error mismatch: have %v", err)
+	}
+}
+
+// newVerifyPosvChain creates a Posv chain of an epoch signed by a single signer,
+// ending with a checkpoint listing the given masternodes instead of the signer.
+// The validator contract ranks the candidate as the only masternode.
+func newVerifyPosvChain(t *testing.T, masternodes []common.Address, candidate common.Address) (ethdb.Database, *params.ChainConfig) {
+	var (
+		db, _  = ethdb.NewMemDatabase()
+		key, _ = crypto.GenerateKey()
+		signer = crypto.PubkeyToAddress(key.PublicKey)
+		config = &params.ChainConfig{ChainId: big.NewInt(1), Posv: &params.PosvConfig{Epoch: 3, Gap: 1}}
+		slot   = common.BigToHash(big.NewInt(3))
+	)
+	genesis := (&Genesis{
+		Config:    config,
+		ExtraData: append(append(make([]byte, 32), signer[:]...), make([]byte, 65)...),
+		Alloc: GenesisAlloc{
+			common.HexToAddress(common.MasternodeVotingSMC): {Balance: new(big.Int), Storage: map[common.Hash]common.Hash{
+				slot: common.BigToHash(big.NewInt(1)),
+				state.GetLocDynamicArrAtElement(slot, 0, 1): candidate.Hash(),
+				state.CandidateCapSlot(candidate):           common.BigToHash(big.NewInt(params.Ether)),
+			}},
+		},
+	}).MustCommit(db)
+
+	// The blocks leave the beneficiary empty, so the signer doesn't vote itself out
+	parent := genesis.Header()
+	for number := uint64(1); number <= config.Posv.Epoch; number++ {
+		header := &types.Header{
+			ParentHash:  parent.Hash(),
+			UncleHash:   types.EmptyUncleHash,
+			Root:        parent.Root,
+			TxHash:      types.EmptyRootHash,
+			ReceiptHash: types.EmptyRootHash,
+			Difficulty:  big.NewInt(1),
+			Number:      new(big.Int).SetUint64(number),
+			GasLimit:    parent.GasLimit,
+			Time:        new(big.Int).SetUint64(parent.Time.Uint64() + 1),
+			Extra:       make([]byte, 32),
+		}
+		if number == config.Posv.Epoch {
+			for _, masternode := range masternodes {
+				header.Extra = append(header.Extra, masternode[:]...)
+			}
+		}
+		header.Extra = append(header.Extra, make([]byte, 65)...)
+		sig, err := crypto.Sign(posv.SigHash(header).Bytes(), key)
+		if err != nil {
+			t.Fatalf("failed to seal block #%d: %v", number, err)
+		}
+		copy(header.Extra[len(header.Extra)-65:], sig)
+
+		block := types.NewBlockWithHeader(header)
+		WriteBlock(db, block)
+		WriteBlockReceipts(db, block.Hash(), number, nil)
+		WriteCanonicalHash(db, block.Hash(), number)
+		WriteHeadBlockHash(db, block.Hash())
+		parent = header
+	}
+	return db, config
+}
+
+// Tests that the checkpoint signers differing from the snapshot are resolved by
+// the Posv hook, reporting the checkpoint if the engine has no hook or if the
+// signers mismatch the state too.
+func TestVerifyChainCheckpointSigners(t *testing.T) {
+	candidate := common.Address{0xbb}
+	tests := []struct {
+		masternodes []common.Address
+		hook        bool
+		valid       bool
+	}{
+		{[]common.Address{candidate}, true, true},
+		{[]common.Address{candidate}, false, false},
+		{[]common.Address{{0xcc}}, true, false},
+	}
+	for i, tt := range tests {
+		db, config := newVerifyPosvChain(t, tt.masternodes, candidate)
+
+		engine := posv.New(config.Posv, db)
+		if tt.hook {
+			engine.HookGetSignersFromContract = func(hash common.Hash) ([]common.Address, error) {
+				return SignersFromState(db, hash)
+			}
+		}
+		err := VerifyChain(db, config, engine, 0, false, nil)
+		if tt.valid {
+			if err != nil {
+				t.Errorf("test %d: valid checkpoint failed verification: %v", i, err)
+			}
+		} else if verr, ok := err.(*VerifyError); !ok || verr.Number != config.Posv.Epoch {
+			t.Errorf("test %d: error mismatch: have %v, want block #%d", i, err, config.Posv.Epoch)
+		}
+	}
+}
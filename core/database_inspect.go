@@ -0,0 +1,180 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"errors"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// ErrInspectInterrupted is returned by InspectDatabase if it was interrupted.
+var ErrInspectInterrupted = errors.New("inspection interrupted")
+
+var (
+	// posvSnapshotPrefix is the prefix of the Posv signer snapshots, written by
+	// the consensus engine into the chain database.
+	posvSnapshotPrefix = []byte("posv-")
+
+	// randomizeKey is the key of the secret the masternode uses for randomizing
+	// the validators, written by the contracts package.
+	randomizeKey = []byte("randomizeKey")
+
+	// metadataKeys are the single entries tracking the status of the database.
+	metadataKeys = [][]byte{headHeaderKey, headBlockKey, headFastKey, trieSyncKey, []byte("BlockchainVersion"), []byte("SnapshotRoot"), []byte("SnapshotGenerator")}
+)
+
+// DatabaseStat is the number and total size (keys included) of the entries of a
+// category in the chain database.
+type DatabaseStat struct {
+	Category string
+	Count    uint64
+	Size     common.StorageSize
+}
+
+// add accounts an entry in the category.
+func (s *DatabaseStat) add(size int) {
+	s.Count++
+	s.Size += common.StorageSize(size)
+}
+
+// InspectDatabase iterates the entire key space of the database, grouping the
+// entries by the schema they belong to. If the database has an ancient store, the
+// sizes of its tables are listed after the key-value store categories.
+func InspectDatabase(db ethdb.Database, stop <-chan struct{}) ([]*DatabaseStat, error) {
+	var (
+		headers      = &DatabaseStat{Category: "Headers"}
+		tds          = &DatabaseStat{Category: "Total difficulties"}
+		numHashes    = &DatabaseStat{Category: "Canonical hashes"}
+		hashNums     = &DatabaseStat{Category: "Header numbers"}
+		bodies       = &DatabaseStat{Category: "Bodies"}
+		receipts     = &DatabaseStat{Category: "Receipts"}
+		lookups      = &DatabaseStat{Category: "Transaction lookups"}
+		bloomBits    = &DatabaseStat{Category: "Bloombits"}
+		stateDiffs   = &DatabaseStat{Category: "State diffs"}
+		storageHist  = &DatabaseStat{Category: "Storage history"}
+		indexes      = &DatabaseStat{Category: "Chain indexer metadata"}
+		tries        = &DatabaseStat{Category: "Trie nodes and codes"}
+		preimages    = &DatabaseStat{Category: "Trie preimages"}
+		snapAccounts = &DatabaseStat{Category: "Snapshot accounts"}
+		snapStorage  = &DatabaseStat{Category: "Snapshot storage"}
+		posvSnaps    = &DatabaseStat{Category: "Posv snapshots"}
+		randomize    = &DatabaseStat{Category: "Randomize keys"}
+		configs      = &DatabaseStat{Category: "Chain configs"}
+		metadata     = &DatabaseStat{Category: "Metadata"}
+		unaccounted  = &DatabaseStat{Category: "Unaccounted"}
+
+		count  uint64
+		start  = time.Now()
+		logged = time.Now()
+	)
+	it := db.NewIterator()
+	defer it.Release()
+
+	for it.Next() {
+		select {
+		case <-stop:
+			return nil, ErrInspectInterrupted
+		default:
+		}
+		var (
+			key  = it.Key()
+			size = len(key) + len(it.Value())
+		)
+		switch {
+		case bytes.HasPrefix(key, headerPrefix) && len(key) == len(headerPrefix)+8+common.HashLength:
+			headers.add(size)
+		case bytes.HasPrefix(key, headerPrefix) && bytes.HasSuffix(key, tdSuffix) && len(key) == len(headerPrefix)+8+common.HashLength+len(tdSuffix):
+			tds.add(size)
+		case bytes.HasPrefix(key, headerPrefix) && bytes.HasSuffix(key, numSuffix) && len(key) == len(headerPrefix)+8+len(numSuffix):
+			numHashes.add(size)
+		case bytes.HasPrefix(key, blockHashPrefix) && len(key) == len(blockHashPrefix)+common.HashLength:
+			hashNums.add(size)
+		case bytes.HasPrefix(key, bodyPrefix) && len(key) == len(bodyPrefix)+8+common.HashLength:
+			bodies.add(size)
+		case bytes.HasPrefix(key, blockReceiptsPrefix) && len(key) == len(blockReceiptsPrefix)+8+common.HashLength:
+			receipts.add(size)
+		case bytes.HasPrefix(key, lookupPrefix) && len(key) == len(lookupPrefix)+common.HashLength:
+			lookups.add(size)
+		case bytes.HasPrefix(key, bloomBitsPrefix) && len(key) == len(bloomBitsPrefix)+2+8+common.HashLength:
+			bloomBits.add(size)
+		case bytes.HasPrefix(key, stateDiffPrefix) && len(key) == len(stateDiffPrefix)+8+common.HashLength:
+			stateDiffs.add(size)
+		case bytes.HasPrefix(key, storageHistPrefix) && len(key) == len(storageHistPrefix)+common.AddressLength+common.HashLength+8+common.HashLength:
+			storageHist.add(size)
+		case bytes.HasPrefix(key, destructHistPrefix) && len(key) == len(destructHistPrefix)+common.AddressLength+8+common.HashLength:
+			storageHist.add(size)
+		case bytes.HasPrefix(key, BloomBitsIndexPrefix) || bytes.HasPrefix(key, StorageHistIndexPrefix):
+			indexes.add(size)
+		case len(key) == common.HashLength:
+			tries.add(size)
+		case bytes.HasPrefix(key, []byte(preimagePrefix)) && len(key) == len(preimagePrefix)+common.HashLength:
+			preimages.add(size)
+		case bytes.HasPrefix(key, snapshot.SnapshotAccountPrefix) && len(key) == len(snapshot.SnapshotAccountPrefix)+common.HashLength:
+			snapAccounts.add(size)
+		case bytes.HasPrefix(key, snapshot.SnapshotStoragePrefix) && len(key) == len(snapshot.SnapshotStoragePrefix)+2*common.HashLength:
+			snapStorage.add(size)
+		case bytes.HasPrefix(key, posvSnapshotPrefix) && len(key) == len(posvSnapshotPrefix)+common.HashLength:
+			posvSnaps.add(size)
+		case bytes.Equal(key, randomizeKey):
+			randomize.add(size)
+		case bytes.HasPrefix(key, configPrefix) && len(key) == len(configPrefix)+common.HashLength:
+			configs.add(size)
+		default:
+			accounted := false
+			for _, meta := range metadataKeys {
+				if bytes.Equal(key, meta) {
+					metadata.add(size)
+					accounted = true
+					break
+				}
+			}
+			if !accounted {
+				unaccounted.add(size)
+			}
+		}
+		count++
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Inspecting database", "count", count, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	stats := []*DatabaseStat{
+		headers, tds, numHashes, hashNums, bodies, receipts, lookups, bloomBits, stateDiffs, storageHist, indexes,
+		tries, preimages, snapAccounts, snapStorage, posvSnaps, randomize, configs, metadata, unaccounted,
+	}
+	// Append the tables of the ancient store, every one holding an item per block
+	if ancients, ok := db.(ethdb.AncientReader); ok {
+		for _, table := range FreezerTables {
+			size, err := ancients.AncientSize(table)
+			if err != nil {
+				return nil, err
+			}
+			stats = append(stats, &DatabaseStat{Category: "Ancient " + table, Count: ancients.Ancients(), Size: common.StorageSize(size)})
+		}
+	}
+	log.Info("Inspected database", "count", count, "elapsed", common.PrettyDuration(time.Since(start)))
+	return stats, nil
+}
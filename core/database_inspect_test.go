@@ -0,0 +1,69 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Tests that the database inspection groups the entries by their schema.
+func TestInspectDatabase(t *testing.T) {
+	chain, db, _ := newVerifyTestChain(t)
+	chain.Stop()
+
+	db.Put(append(append([]byte{}, posvSnapshotPrefix...), common.Hash{0x01}.Bytes()...), []byte{0x01})
+	db.Put(randomizeKey, []byte{0x01})
+	db.Put([]byte("unknown"), []byte{0x01})
+
+	WriteStorageHistory(db, common.Address{0x01}, common.Hash{0x02}, 0, common.Hash{0x03}, []uint64{1})
+	WriteDestructHistory(db, common.Address{0x01}, 0, common.Hash{0x03}, []uint64{2})
+
+	stats, err := InspectDatabase(db, nil)
+	if err != nil {
+		t.Fatalf("failed to inspect database: %v", err)
+	}
+	counts := make(map[string]uint64)
+	for _, stat := range stats {
+		counts[stat.Category] = stat.Count
+		if (stat.Count == 0) != (stat.Size == 0) {
+			t.Errorf("%s: count and size mismatch: %d entries of %v", stat.Category, stat.Count, stat.Size)
+		}
+	}
+	want := map[string]uint64{
+		"Headers":             9,
+		"Total difficulties":  9,
+		"Canonical hashes":    9,
+		"Header numbers":      9,
+		"Bodies":              9,
+		"Transaction lookups": 8,
+		"Storage history":     2,
+		"Posv snapshots":      1,
+		"Randomize keys":      1,
+		"Chain configs":       1,
+		"Unaccounted":         1,
+	}
+	for category, count := range want {
+		if counts[category] != count {
+			t.Errorf("%s: count mismatch: have %d, want %d", category, counts[category], count)
+		}
+	}
+	if counts["Trie nodes and codes"] == 0 {
+		t.Errorf("no trie nodes found")
+	}
+}
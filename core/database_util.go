@@ -25,6 +25,7 @@ import (
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
@@ -58,12 +59,16 @@ var (
 	blockReceiptsPrefix = []byte("r") // blockReceiptsPrefix + num (uint64 big endian) + hash -> block receipts
 	lookupPrefix        = []byte("l") // lookupPrefix + hash -> transaction/receipt lookup metadata
 	bloomBitsPrefix     = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
+	stateDiffPrefix     = []byte("d") // stateDiffPrefix + num (uint64 big endian) + hash -> block state diff
+	storageHistPrefix   = []byte("S") // storageHistPrefix + address + slot + section (uint64 big endian) + hash -> changed block numbers
+	destructHistPrefix  = []byte("D") // destructHistPrefix + address + section (uint64 big endian) + hash -> destructing block numbers
 
 	preimagePrefix = "secure-key-"              // preimagePrefix + hash -> preimage
 	configPrefix   = []byte("ethereum-config-") // config prefix for the db
 
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
-	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	BloomBitsIndexPrefix   = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	StorageHistIndexPrefix = []byte("iS") // StorageHistIndexPrefix is the data table of the storage history indexer to track its progress
 
 	// used by old db, now only used for conversion
 	oldReceiptsPrefix = []byte("receipts-")
@@ -83,6 +88,42 @@ type TxLookupEntry struct {
 	Index      uint64
 }
 
+// The tables of the ancient store, holding the canonical chain segments which
+// were moved out of the key-value store.
+const (
+	freezerHashTable       = "hashes"
+	freezerHeaderTable     = "headers"
+	freezerBodiesTable     = "bodies"
+	freezerReceiptTable    = "receipts"
+	freezerDifficultyTable = "diffs"
+)
+
+// FreezerTables are the tables the ancient store of a chain database must hold.
+var FreezerTables = []string{freezerHashTable, freezerHeaderTable, freezerBodiesTable, freezerReceiptTable, freezerDifficultyTable}
+
+// getAncient retrieves an item of a frozen canonical block from the ancient store
+// of the database, nil if the database has none or the block isn't frozen. If the
+// hash is non-empty, it must match the hash of the frozen block.
+func getAncient(db DatabaseReader, kind string, hash common.Hash, number uint64) []byte {
+	ancients, ok := db.(ethdb.AncientReader)
+	if !ok || ancients.Ancients() <= number {
+		return nil
+	}
+	if hash != (common.Hash{}) {
+		if data, _ := ancients.Ancient(freezerHashTable, number); common.BytesToHash(data) != hash {
+			return nil
+		}
+	}
+	data, _ := ancients.Ancient(kind, number)
+	return data
+}
+
+// isAncient reports whether the canonical block with the given hash and number
+// was moved into the ancient store of the database.
+func isAncient(db DatabaseReader, hash common.Hash, number uint64) bool {
+	return len(getAncient(db, freezerHashTable, hash, number)) > 0
+}
+
 // encodeBlockNumber encodes a block number as big endian uint64
 func encodeBlockNumber(number uint64) []byte {
 	enc := make([]byte, 8)
@@ -93,6 +134,9 @@ func encodeBlockNumber(number uint64) []byte {
 // GetCanonicalHash retrieves a hash assigned to a canonical block number.
 func GetCanonicalHash(db DatabaseReader, number uint64) common.Hash {
 	data, _ := db.Get(append(append(headerPrefix, encodeBlockNumber(number)...), numSuffix...))
+	if len(data) == 0 {
+		data = getAncient(db, freezerHashTable, common.Hash{}, number)
+	}
 	if len(data) == 0 {
 		return common.Hash{}
 	}
@@ -161,6 +205,9 @@ func GetTrieSyncProgress(db DatabaseReader) uint64 {
 // if the header's not found.
 func GetHeaderRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
 	data, _ := db.Get(headerKey(hash, number))
+	if len(data) == 0 {
+		data = getAncient(db, freezerHeaderTable, hash, number)
+	}
 	return data
 }
 
@@ -182,6 +229,9 @@ func GetHeader(db DatabaseReader, hash common.Hash, number uint64) *types.Header
 // GetBodyRLP retrieves the block body (transactions and uncles) in RLP encoding.
 func GetBodyRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
 	data, _ := db.Get(blockBodyKey(hash, number))
+	if len(data) == 0 {
+		data = getAncient(db, freezerBodiesTable, hash, number)
+	}
 	return data
 }
 
@@ -193,6 +243,28 @@ func blockBodyKey(hash common.Hash, number uint64) []byte {
 	return append(append(bodyPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
 }
 
+func tdKey(hash common.Hash, number uint64) []byte {
+	return append(append(append(headerPrefix, encodeBlockNumber(number)...), hash.Bytes()...), tdSuffix...)
+}
+
+func blockReceiptsKey(hash common.Hash, number uint64) []byte {
+	return append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
+func stateDiffKey(hash common.Hash, number uint64) []byte {
+	return append(append(stateDiffPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
+func storageHistoryKey(address common.Address, slot common.Hash, section uint64, head common.Hash) []byte {
+	key := append(append(storageHistPrefix, address.Bytes()...), slot.Bytes()...)
+	return append(append(key, encodeBlockNumber(section)...), head.Bytes()...)
+}
+
+func destructHistoryKey(address common.Address, section uint64, head common.Hash) []byte {
+	key := append(destructHistPrefix, address.Bytes()...)
+	return append(append(key, encodeBlockNumber(section)...), head.Bytes()...)
+}
+
 // GetBody retrieves the block body (transactons, uncles) corresponding to the
 // hash, nil if none found.
 func GetBody(db DatabaseReader, hash common.Hash, number uint64) *types.Body {
@@ -211,7 +283,10 @@ func GetBody(db DatabaseReader, hash common.Hash, number uint64) *types.Body {
 // GetTd retrieves a block's total difficulty corresponding to the hash, nil if
 // none found.
 func GetTd(db DatabaseReader, hash common.Hash, number uint64) *big.Int {
-	data, _ := db.Get(append(append(append(headerPrefix, encodeBlockNumber(number)...), hash[:]...), tdSuffix...))
+	data, _ := db.Get(tdKey(hash, number))
+	if len(data) == 0 {
+		data = getAncient(db, freezerDifficultyTable, hash, number)
+	}
 	if len(data) == 0 {
 		return nil
 	}
@@ -246,7 +321,10 @@ func GetBlock(db DatabaseReader, hash common.Hash, number uint64) *types.Block {
 // GetBlockReceipts retrieves the receipts generated by the transactions included
 // in a block given by its hash.
 func GetBlockReceipts(db DatabaseReader, hash common.Hash, number uint64) types.Receipts {
-	data, _ := db.Get(append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash[:]...))
+	data, _ := db.Get(blockReceiptsKey(hash, number))
+	if len(data) == 0 {
+		data = getAncient(db, freezerReceiptTable, hash, number)
+	}
 	if len(data) == 0 {
 		return nil
 	}
@@ -262,6 +340,51 @@ func GetBlockReceipts(db DatabaseReader, hash common.Hash, number uint64) types.
 	return receipts
 }
 
+// GetStateDiff retrieves the state changes made by a block, nil if they were not
+// recorded.
+func GetStateDiff(db DatabaseReader, hash common.Hash, number uint64) *state.StateDiff {
+	data, _ := db.Get(stateDiffKey(hash, number))
+	if len(data) == 0 {
+		return nil
+	}
+	diff := new(state.StateDiff)
+	if err := rlp.DecodeBytes(data, diff); err != nil {
+		log.Error("Invalid state diff RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return diff
+}
+
+// GetStorageHistory retrieves the numbers of the blocks in a section which changed
+// a storage slot of an account, the section being identified by its head hash.
+func GetStorageHistory(db DatabaseReader, address common.Address, slot common.Hash, section uint64, head common.Hash) []uint64 {
+	data, _ := db.Get(storageHistoryKey(address, slot, section, head))
+	if len(data) == 0 {
+		return nil
+	}
+	var numbers []uint64
+	if err := rlp.DecodeBytes(data, &numbers); err != nil {
+		log.Error("Invalid storage history RLP", "address", address, "slot", slot, "section", section, "err", err)
+		return nil
+	}
+	return numbers
+}
+
+// GetDestructHistory retrieves the numbers of the blocks in a section which
+// destructed an account, wiping its storage.
+func GetDestructHistory(db DatabaseReader, address common.Address, section uint64, head common.Hash) []uint64 {
+	data, _ := db.Get(destructHistoryKey(address, section, head))
+	if len(data) == 0 {
+		return nil
+	}
+	var numbers []uint64
+	if err := rlp.DecodeBytes(data, &numbers); err != nil {
+		log.Error("Invalid destruct history RLP", "address", address, "section", section, "err", err)
+		return nil
+	}
+	return numbers
+}
+
 // GetTxLookupEntry retrieves the positional metadata associated with a transaction
 // hash to allow retrieving the transaction or receipt by hash.
 func GetTxLookupEntry(db DatabaseReader, hash common.Hash) (common.Hash, uint64, uint64) {
@@ -479,6 +602,44 @@ func WriteBlockReceipts(db ethdb.Putter, hash common.Hash, number uint64, receip
 	return nil
 }
 
+// WriteStateDiff stores the state changes made by a block.
+func WriteStateDiff(db ethdb.Putter, hash common.Hash, number uint64, diff *state.StateDiff) error {
+	data, err := rlp.EncodeToBytes(diff)
+	if err != nil {
+		return err
+	}
+	if err := db.Put(stateDiffKey(hash, number), data); err != nil {
+		log.Crit("Failed to store block state diff", "err", err)
+	}
+	return nil
+}
+
+// WriteStorageHistory stores the numbers of the blocks in a section which changed
+// a storage slot of an account.
+func WriteStorageHistory(db ethdb.Putter, address common.Address, slot common.Hash, section uint64, head common.Hash, numbers []uint64) error {
+	data, err := rlp.EncodeToBytes(numbers)
+	if err != nil {
+		return err
+	}
+	if err := db.Put(storageHistoryKey(address, slot, section, head), data); err != nil {
+		log.Crit("Failed to store storage history", "err", err)
+	}
+	return nil
+}
+
+// WriteDestructHistory stores the numbers of the blocks in a section which
+// destructed an account.
+func WriteDestructHistory(db ethdb.Putter, address common.Address, section uint64, head common.Hash, numbers []uint64) error {
+	data, err := rlp.EncodeToBytes(numbers)
+	if err != nil {
+		return err
+	}
+	if err := db.Put(destructHistoryKey(address, section, head), data); err != nil {
+		log.Crit("Failed to store destruct history", "err", err)
+	}
+	return nil
+}
+
 // WriteTxLookupEntries stores a positional metadata for every transaction from
 // a block, enabling hash based transaction and receipt lookups.
 func WriteTxLookupEntries(db ethdb.Putter, block *types.Block) error {
@@ -547,6 +708,27 @@ func DeleteBlockReceipts(db DatabaseDeleter, hash common.Hash, number uint64) {
 	db.Delete(append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash.Bytes()...))
 }
 
+// DeleteStateDiff removes the state changes recorded for a block.
+func DeleteStateDiff(db DatabaseDeleter, hash common.Hash, number uint64) {
+	db.Delete(stateDiffKey(hash, number))
+}
+
+// DeleteStateDiffs removes the state changes recorded for all the blocks at the
+// given height, side chains included.
+func DeleteStateDiffs(db ethdb.Database, batch DatabaseDeleter, number uint64) error {
+	prefix := append(append([]byte{}, stateDiffPrefix...), encodeBlockNumber(number)...)
+
+	it := db.NewIteratorWithPrefix(prefix)
+	defer it.Release()
+
+	for it.Next() {
+		if key := it.Key(); len(key) == len(prefix)+common.HashLength {
+			batch.Delete(common.CopyBytes(key))
+		}
+	}
+	return it.Error()
+}
+
 // DeleteTxLookupEntry removes all transaction data associated with a hash.
 func DeleteTxLookupEntry(db DatabaseDeleter, hash common.Hash) {
 	db.Delete(append(lookupPrefix, hash.Bytes()...))
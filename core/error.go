@@ -33,6 +33,10 @@ var (
 	// next one expected based on the local chain.
 	ErrNonceTooHigh = errors.New("nonce too high")
 
+	// ErrReorgBelowAncients is returned if a reorg would replace blocks which were
+	// already moved into the append-only ancient store.
+	ErrReorgBelowAncients = errors.New("reorg below the ancient store")
+
 	ErrNotPoSV = errors.New("Posv not found in config")
 
 	ErrNotFoundM1 = errors.New("list M1 not found ")
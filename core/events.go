@@ -18,12 +18,20 @@ package core
 
 import (
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 )
 
 // TxPreEvent is posted when a transaction enters the transaction pool.
 type TxPreEvent struct{ Tx *types.Transaction }
 
+// TxDropEvent is posted when a transaction is rejected from or dropped out of
+// the transaction pool.
+type TxDropEvent struct {
+	Tx   *types.Transaction
+	Drop *TxDrop
+}
+
 // PendingLogsEvent is posted pre mining and notifies of pending logs.
 type PendingLogsEvent struct {
 	Logs []*types.Log
@@ -52,3 +60,10 @@ type ChainSideEvent struct {
 }
 
 type ChainHeadEvent struct{ Block *types.Block }
+
+// StateDiffEvent is posted when a block is imported, carrying the changes the
+// block made to the state.
+type StateDiffEvent struct {
+	Block *types.Block
+	Diff  *state.StateDiff
+}
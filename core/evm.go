@@ -17,14 +17,21 @@
 package core
 
 import (
+	"errors"
+	"fmt"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/posv"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 )
 
+// errNotPosv is returned when the consensus state is requested from a chain which
+// isn't run by the Posv engine.
+var errNotPosv = errors.New("chain not run by posv")
+
 // ChainContext supports retrieving headers and consensus parameters from the
 // current blockchain to be used during transaction processing.
 type ChainContext interface {
@@ -44,7 +51,7 @@ func NewEVMContext(msg Message, header *types.Header, chain ChainContext, author
 	} else {
 		beneficiary = *author
 	}
-	return vm.Context{
+	context := vm.Context{
 		CanTransfer: CanTransfer,
 		Transfer:    Transfer,
 		GetHash:     GetHashFn(header, chain),
@@ -56,6 +63,75 @@ func NewEVMContext(msg Message, header *types.Header, chain ChainContext, author
 		GasLimit:    header.GasLimit,
 		GasPrice:    new(big.Int).Set(msg.GasPrice()),
 	}
+	// Expose the consensus state to the EVM if the chain is run by Posv
+	if chain != nil {
+		context.GetMasternodes = GetMasternodesFn(header, context.GetHash, chain)
+		context.GetValidator = GetValidatorFn(header, context.GetHash, chain)
+	}
+	return context
+}
+
+// GetMasternodesFn returns a GetMasternodesFunc which retrieves the masternodes
+// of the epoch the referenced header belongs to from its checkpoint header. The
+// checkpoint is resolved through the given hash getter, i.e. on the ancestors
+// of the referenced header. An error is returned if the chain isn't run by the
+// Posv engine or the checkpoint header is unavailable.
+func GetMasternodesFn(ref *types.Header, getHash vm.GetHashFunc, chain ChainContext) func() ([]common.Address, error) {
+	var (
+		masternodes []common.Address
+		err         error
+		resolved    bool
+	)
+	return func() ([]common.Address, error) {
+		if !resolved {
+			masternodes, err = getMasternodes(ref, getHash, chain)
+			resolved = true
+		}
+		return masternodes, err
+	}
+}
+
+// getMasternodes retrieves the masternodes of the epoch the referenced header
+// belongs to, from the header itself if it's a checkpoint or from the checkpoint
+// ancestor otherwise.
+func getMasternodes(ref *types.Header, getHash vm.GetHashFunc, chain ChainContext) ([]common.Address, error) {
+	engine, ok := chain.Engine().(*posv.Posv)
+	if !ok || engine.GetEpoch() == 0 {
+		return nil, errNotPosv
+	}
+	var (
+		number     = ref.Number.Uint64()
+		epoch      = engine.GetEpoch()
+		checkpoint = ref
+	)
+	if number%epoch != 0 {
+		number -= number % epoch
+		if checkpoint = chain.GetHeader(getHash(number), number); checkpoint == nil {
+			return nil, fmt.Errorf("checkpoint #%d unavailable", number)
+		}
+	}
+	return engine.CheckpointMasternodes(checkpoint), nil
+}
+
+// GetValidatorFn returns a GetValidatorFunc which recovers the M2 validator of
+// an ancestor of the referenced header, resolved through the given hash getter.
+// An error is returned if the chain isn't run by the Posv engine, the block is
+// not an ancestor or its validator can't be recovered.
+func GetValidatorFn(ref *types.Header, getHash vm.GetHashFunc, chain ChainContext) func(n uint64) (common.Address, error) {
+	return func(n uint64) (common.Address, error) {
+		engine, ok := chain.Engine().(*posv.Posv)
+		if !ok {
+			return common.Address{}, errNotPosv
+		}
+		if n >= ref.Number.Uint64() {
+			return common.Address{}, fmt.Errorf("block #%d not an ancestor", n)
+		}
+		header := chain.GetHeader(getHash(n), n)
+		if header == nil {
+			return common.Address{}, fmt.Errorf("header #%d unavailable", n)
+		}
+		return engine.RecoverValidator(header)
+	}
 }
 
 // GetHashFn returns a GetHashFunc which retrieves header hashes by number
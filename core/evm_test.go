@@ -0,0 +1,100 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/posv"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// testChainContext is a ChainContext serving headers from a map, regardless of
+// them being canonical or not.
+type testChainContext struct {
+	engine  consensus.Engine
+	headers map[common.Hash]*types.Header
+}
+
+func (c *testChainContext) Engine() consensus.Engine { return c.engine }
+
+func (c *testChainContext) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := c.headers[hash]; header != nil && header.Number.Uint64() == number {
+		return header
+	}
+	return nil
+}
+
+// Tests that the masternodes exposed to the EVM are read from the checkpoint
+// ancestor of the referenced header, even if it's on a side chain.
+func TestGetMasternodesFn(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	chain := &testChainContext{
+		engine:  posv.New(&params.PosvConfig{Epoch: 10}, db),
+		headers: make(map[common.Hash]*types.Header),
+	}
+	add := func(parent *types.Header, masternodes ...common.Address) *types.Header {
+		header := &types.Header{Number: new(big.Int), Extra: make([]byte, 32)}
+		if parent != nil {
+			header.ParentHash, header.Number = parent.Hash(), new(big.Int).Add(parent.Number, common.Big1)
+		}
+		for _, masternode := range masternodes {
+			header.Extra = append(header.Extra, masternode[:]...)
+		}
+		header.Extra = append(header.Extra, make([]byte, 65)...)
+		chain.headers[header.Hash()] = header
+		return header
+	}
+	parent := add(nil)
+	for parent.Number.Uint64() < 9 {
+		parent = add(parent)
+	}
+	var (
+		canon = add(parent, common.Address{0x01})
+		side  = add(parent, common.Address{0x02}, common.Address{0x03})
+		ref   = &types.Header{ParentHash: add(side).Hash(), Number: big.NewInt(12)}
+	)
+	masternodes, err := GetMasternodesFn(ref, GetHashFn(ref, chain), chain)()
+	if want := []common.Address{{0x02}, {0x03}}; err != nil || !reflect.DeepEqual(masternodes, want) {
+		t.Errorf("side chain masternodes mismatch: have %x (%v), want %x", masternodes, err, want)
+	}
+	if masternodes, err := GetMasternodesFn(canon, GetHashFn(canon, chain), chain)(); err != nil || !reflect.DeepEqual(masternodes, []common.Address{{0x01}}) {
+		t.Errorf("checkpoint masternodes mismatch: have %x (%v), want %x", masternodes, err, []common.Address{{0x01}})
+	}
+	// A missing checkpoint must be reported instead of an empty masternode set
+	delete(chain.headers, side.Hash())
+	if masternodes, err := GetMasternodesFn(ref, GetHashFn(ref, chain), chain)(); err == nil {
+		t.Errorf("masternodes of missing checkpoint returned: %x", masternodes)
+	}
+	// Unavailable and unrecoverable validators must be reported too
+	getValidator := GetValidatorFn(ref, GetHashFn(ref, chain), chain)
+	if validator, err := getValidator(10); err == nil {
+		t.Errorf("validator of missing header returned: %x", validator)
+	}
+	if validator, err := getValidator(11); err == nil {
+		t.Errorf("validator of unsigned header returned: %x", validator)
+	}
+	if validator, err := getValidator(12); err == nil {
+		t.Errorf("validator of non ancestor returned: %x", validator)
+	}
+}
@@ -84,6 +84,20 @@ func TestSetupGenesis(t *testing.T) {
 			wantHash:   params.TomoMainnetGenesisHash,
 			wantConfig: params.TomoMainnetChainConfig,
 		},
+		{
+			name: "mainnet block in DB with config predating the TomoChain forks, genesis == nil",
+			fn: func(db ethdb.Database) (*params.ChainConfig, common.Hash, error) {
+				DefaultGenesisBlock().MustCommit(db)
+
+				config := *params.TomoMainnetChainConfig
+				config.TIPConstantinopleBlock, config.TIPIstanbulBlock = nil, nil
+				WriteChainConfig(db, params.TomoMainnetGenesisHash, &config)
+
+				return SetupGenesisBlock(db, nil)
+			},
+			wantHash:   params.TomoMainnetGenesisHash,
+			wantConfig: params.TomoMainnetChainConfig,
+		},
 		{
 			name: "custom block in DB, genesis == nil",
 			fn: func(db ethdb.Database) (*params.ChainConfig, common.Hash, error) {
@@ -368,8 +368,10 @@ func (hc *HeaderChain) HasHeader(hash common.Hash, number uint64) bool {
 	if hc.numberCache.Contains(hash) || hc.headerCache.Contains(hash) {
 		return true
 	}
-	ok, _ := hc.chainDb.Has(headerKey(hash, number))
-	return ok
+	if ok, _ := hc.chainDb.Has(headerKey(hash, number)); ok {
+		return true
+	}
+	return isAncient(hc.chainDb, hash, number)
 }
 
 // GetHeaderByNumber retrieves a block header from the database by number,
@@ -424,6 +426,12 @@ func (hc *HeaderChain) SetHead(head uint64, delFn DeleteCallback) {
 	for i := height; i > head; i-- {
 		DeleteCanonicalHash(hc.chainDb, i)
 	}
+	// Discard any frozen blocks above the new head, the ancient store is append-only
+	if ancients, ok := hc.chainDb.(ancientStore); ok && ancients.Ancients() > head+1 {
+		if err := ancients.TruncateAncients(head + 1); err != nil {
+			log.Crit("Failed to truncate ancient store", "err", err)
+		}
+	}
 	// Clear out any stale content from the caches
 	hc.headerCache.Purge()
 	hc.tdCache.Purge()
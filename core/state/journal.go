@@ -34,7 +34,8 @@ type (
 		account *common.Address
 	}
 	resetObjectChange struct {
-		prev *stateObject
+		prev         *stateObject
+		prevdestruct bool // whether the snapshot already tracked the account as destructed
 	}
 	suicideChange struct {
 		account     *common.Address
@@ -84,6 +85,9 @@ func (ch createObjectChange) undo(s *StateDB) {
 
 func (ch resetObjectChange) undo(s *StateDB) {
 	s.setStateObject(ch.prev)
+	if !ch.prevdestruct && s.snap != nil {
+		delete(s.snapDestructs, ch.prev.addrHash)
+	}
 }
 
 func (ch suicideChange) undo(s *StateDB) {
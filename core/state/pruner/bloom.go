@@ -0,0 +1,151 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// bloomHashes is the number of bit positions set for every key in the bloom
+// filter. The keys are cryptographic hashes themselves, so the positions are
+// taken directly from non-overlapping 8 byte chunks of them.
+const bloomHashes = 4
+
+// errBloomCorrupted is returned if a persisted bloom filter cannot be loaded.
+var errBloomCorrupted = errors.New("state bloom filter corrupted")
+
+// bloomHeader is the metadata persisted in front of the bloom filter bits,
+// describing the chain state the filter was generated for.
+type bloomHeader struct {
+	Head  common.Hash   // Hash of the chain head at the time of marking
+	Roots []common.Hash // State roots marked as live
+	Words uint64        // Number of 64 bit words in the filter
+}
+
+// stateBloom is a bloom filter of all the trie nodes and contract codes that
+// are reachable from the retained state roots. False positives only cause a
+// stale entry to survive the pruning, there are no false negatives.
+type stateBloom struct {
+	head  common.Hash
+	roots []common.Hash
+	bits  []uint64
+}
+
+// newStateBloom allocates a bloom filter of the given size in megabytes.
+func newStateBloom(size uint64) *stateBloom {
+	words := size * 1024 * 1024 / 8
+	if words == 0 {
+		words = 1
+	}
+	return &stateBloom{bits: make([]uint64, words)}
+}
+
+// add inserts a 32 byte hash key into the bloom filter.
+func (b *stateBloom) add(key []byte) {
+	n := uint64(len(b.bits)) * 64
+	for i := 0; i < bloomHashes; i++ {
+		bit := binary.BigEndian.Uint64(key[i*8:]) % n
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// contains checks whether a 32 byte hash key might have been inserted.
+func (b *stateBloom) contains(key []byte) bool {
+	n := uint64(len(b.bits)) * 64
+	for i := 0; i < bloomHashes; i++ {
+		bit := binary.BigEndian.Uint64(key[i*8:]) % n
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// save atomically persists the bloom filter into the given file, so that an
+// interrupted pruning can be resumed without marking the live state again.
+func (b *stateBloom) save(path string) error {
+	tmp := path + ".tmp"
+
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if err := rlp.Encode(w, &bloomHeader{Head: b.head, Roots: b.roots, Words: uint64(len(b.bits))}); err != nil {
+		f.Close()
+		return err
+	}
+	var word [8]byte
+	for _, bits := range b.bits {
+		binary.BigEndian.PutUint64(word[:], bits)
+		if _, err := w.Write(word[:]); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// loadStateBloom reads a previously persisted bloom filter from disk.
+func loadStateBloom(path string) (*stateBloom, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+
+	var header bloomHeader
+	if err := rlp.NewStream(r, 0).Decode(&header); err != nil {
+		return nil, errBloomCorrupted
+	}
+	if header.Words == 0 {
+		return nil, errBloomCorrupted
+	}
+	bloom := &stateBloom{
+		head:  header.Head,
+		roots: header.Roots,
+		bits:  make([]uint64, header.Words),
+	}
+	var word [8]byte
+	for i := range bloom.bits {
+		if _, err := io.ReadFull(r, word[:]); err != nil {
+			return nil, errBloomCorrupted
+		}
+		bloom.bits[i] = binary.BigEndian.Uint64(word[:])
+	}
+	return bloom, nil
+}
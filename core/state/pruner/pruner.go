@@ -0,0 +1,438 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package pruner implements the removal of stale state data.
+package pruner
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+const (
+	// bloomFilterName is the name of the file the bloom filter of the live state
+	// is persisted into, allowing an interrupted pruning to be resumed.
+	bloomFilterName = "statebloom.bf"
+
+	// logInterval is the time between two progress reports.
+	logInterval = 8 * time.Second
+
+	// DefaultBloomSize is the default number of megabytes allocated to the bloom
+	// filter of the live state.
+	DefaultBloomSize = 1024
+
+	// defaultRetain is the number of recent block states retained on non-Posv
+	// chains, matching the number of tries the blockchain keeps in memory.
+	defaultRetain = 128
+
+	// sweepYieldKeys is the number of database entries scanned by the sweep before
+	// it flushes its deletions and lets the commits of a running node through.
+	sweepYieldKeys = 1024
+
+	// sweepYieldInterval is the maximum time the sweep holds the bloom filter lock,
+	// regardless of the number of entries scanned.
+	sweepYieldInterval = 50 * time.Millisecond
+)
+
+var (
+	// emptyRoot is the known root hash of an empty trie.
+	emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+	// emptyCode is the known hash of the empty EVM bytecode.
+	emptyCode = crypto.Keccak256Hash(nil)
+)
+
+var (
+	// errNoHead is returned if the database doesn't contain a chain head.
+	errNoHead = errors.New("chain head not found")
+
+	// errNoState is returned if none of the retained blocks has its state
+	// available in the database.
+	errNoState = errors.New("no state available in the retention window")
+
+	// ErrInterrupted is returned if the pruning was aborted by the user. It can
+	// be resumed by running the pruner again.
+	ErrInterrupted = errors.New("pruning interrupted")
+)
+
+// Config are the configuration parameters of the state pruner.
+type Config struct {
+	Datadir   string // Directory to persist the bloom filter into
+	BloomSize uint64 // Megabytes of memory allocated to the bloom filter
+	Retain    uint64 // Number of recent blocks whose state is retained
+}
+
+// DefaultRetain returns the number of recent block states retained by default,
+// which is two epochs on Posv chains as required by the checkpoints.
+func DefaultRetain(config *params.ChainConfig) uint64 {
+	if config != nil && config.Posv != nil {
+		return 2 * config.Posv.Epoch
+	}
+	return defaultRetain
+}
+
+// Pruner deletes all the trie nodes from the database which are not reachable
+// from the state of the recent blocks.
+//
+// An offline pruner requires the node to be stopped. An online one operates on
+// the trie database of a running node: the tries kept alive in memory by its
+// reference counting are retained, as is every node it flushes to disk while
+// pruning, so that the state written concurrently is never swept.
+//
+// Pruning is done in two phases: first the live state is marked in a bloom
+// filter which is persisted to disk, after which the database is swept and all
+// unmarked trie nodes are deleted. If the sweep is interrupted, the next run
+// picks up the persisted bloom filter and resumes the deletion.
+//
+// Contract codes are never deleted, as they share the hash keyed namespace with
+// other data and can't be told apart from it reliably.
+type Pruner struct {
+	config Config
+	db     ethdb.Database
+	triedb *trie.Database
+	online bool
+
+	lock sync.Mutex // Serializes the bloom filter access with the trie database commits
+}
+
+// NewPruner creates an offline state pruner operating on the given chain database.
+func NewPruner(db ethdb.Database, config Config) (*Pruner, error) {
+	return newPruner(db, trie.NewDatabase(db), false, config), nil
+}
+
+// NewOnlinePruner creates a state pruner operating on the chain database of a
+// running node, through the trie database the blockchain commits its state with.
+func NewOnlinePruner(db ethdb.Database, triedb *trie.Database, config Config) (*Pruner, error) {
+	return newPruner(db, triedb, true, config), nil
+}
+
+func newPruner(db ethdb.Database, triedb *trie.Database, online bool, config Config) *Pruner {
+	if config.BloomSize == 0 {
+		config.BloomSize = 1
+	}
+	return &Pruner{
+		config: config,
+		db:     db,
+		triedb: triedb,
+		online: online,
+	}
+}
+
+// Prune marks the live state and deletes everything else, stopping early with
+// ErrInterrupted if the given channel is closed.
+func (p *Pruner) Prune(stop <-chan struct{}) error {
+	head := core.GetHeadBlockHash(p.db)
+	if head == (common.Hash{}) {
+		return errNoHead
+	}
+	// Resume from a previous run if it already marked the live state
+	path := filepath.Join(p.config.Datadir, bloomFilterName)
+
+	bloom, err := loadStateBloom(path)
+	switch {
+	case err == nil && bloom.head == head:
+		log.Info("Resuming interrupted state pruning", "head", head, "roots", len(bloom.roots))
+	case err == nil:
+		// The chain progressed since, mark the new states on top of the old ones
+		log.Info("Updating interrupted state pruning", "old", bloom.head, "new", head)
+	case os.IsNotExist(err):
+		bloom = newStateBloom(p.config.BloomSize)
+	default:
+		return err
+	}
+	// Watch the commits of a running node before gathering the states to retain,
+	// so that no state written in between is missed
+	if p.online {
+		p.triedb.SetCommitHook(func(hash common.Hash) {
+			p.add(bloom, hash)
+		})
+		defer p.triedb.SetCommitHook(nil)
+	}
+	if bloom.head != head {
+		roots, err := p.retainedRoots(head)
+		if err != nil {
+			return err
+		}
+		if err := p.mark(bloom, roots, stop); err != nil {
+			return err
+		}
+		if err := p.save(bloom, head, roots, path); err != nil {
+			return err
+		}
+	}
+	if err := p.sweep(bloom, stop); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// retainedRoots gathers the state roots of the recent blocks which are available
+// in the database, newest first, followed by the genesis state.
+func (p *Pruner) retainedRoots(head common.Hash) ([]common.Hash, error) {
+	number := core.GetBlockNumber(p.db, head)
+	if core.GetHeader(p.db, head, number) == nil {
+		return nil, errNoHead
+	}
+	var (
+		roots []common.Hash
+		seen  = make(map[common.Hash]bool)
+	)
+	for n := number; n+p.config.Retain > number || n == number; n-- {
+		if header := core.GetHeader(p.db, core.GetCanonicalHash(p.db, n), n); header != nil && !seen[header.Root] && p.hasState(header.Root) {
+			roots = append(roots, header.Root)
+			seen[header.Root] = true
+		}
+		if n == 0 {
+			break
+		}
+	}
+	if len(roots) == 0 {
+		return nil, errNoState
+	}
+	// Retain the tries of a running node which are still only kept in memory
+	if p.online {
+		for _, root := range p.triedb.References() {
+			if !seen[root] {
+				roots = append(roots, root)
+				seen[root] = true
+			}
+		}
+	}
+	if genesis := core.GetHeader(p.db, core.GetCanonicalHash(p.db, 0), 0); genesis != nil && !seen[genesis.Root] && p.hasState(genesis.Root) {
+		roots = append(roots, genesis.Root)
+	}
+	return roots, nil
+}
+
+// add inserts a live hash into the bloom filter.
+func (p *Pruner) add(bloom *stateBloom, hash common.Hash) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	bloom.add(hash[:])
+}
+
+// save persists the bloom filter along with the chain state it was marked for.
+func (p *Pruner) save(bloom *stateBloom, head common.Hash, roots []common.Hash, path string) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	bloom.head, bloom.roots = head, roots
+	return bloom.save(path)
+}
+
+// hasState checks whether the root node of a state trie is available.
+func (p *Pruner) hasState(root common.Hash) bool {
+	if root == emptyRoot {
+		return true
+	}
+	_, err := p.triedb.Node(root)
+	return err == nil
+}
+
+// mark inserts all the trie nodes and contract codes reachable from the given
+// state roots into the bloom filter. Codes are marked too, so that a live code
+// which happens to look like a trie node is never swept. The first root is iterated in full, every
+// subsequent one only where it differs from the previously marked root.
+func (p *Pruner) mark(bloom *stateBloom, roots []common.Hash, stop <-chan struct{}) error {
+	var (
+		start  = time.Now()
+		logged = time.Now()
+		nodes  uint64
+		base   *trie.Trie
+	)
+	for i, root := range roots {
+		tr, err := trie.New(root, p.triedb)
+		if err == nil {
+			err = p.markTrie(bloom, base, tr, true, &nodes, &logged, stop)
+		}
+		switch {
+		case err == ErrInterrupted:
+			return err
+		case err != nil && i == 0:
+			return err
+		case err != nil:
+			// Older states may be incomplete, marking them partially is harmless
+			log.Warn("Skipping incomplete state", "root", root, "err", err)
+			continue
+		}
+		base = tr
+		log.Debug("Marked live state", "root", root, "nodes", nodes)
+	}
+	log.Info("Marked live state", "roots", len(roots), "nodes", nodes, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// markTrie inserts the nodes of a trie into the bloom filter where they differ
+// from the base trie. For account tries, the storage tries and contract codes
+// of the accounts are marked too.
+func (p *Pruner) markTrie(bloom *stateBloom, base, tr *trie.Trie, accounts bool, nodes *uint64, logged *time.Time, stop <-chan struct{}) error {
+	it := tr.NodeIterator(nil)
+	if base != nil {
+		it, _ = trie.NewDifferenceIterator(base.NodeIterator(nil), it)
+	}
+	for it.Next(true) {
+		if hash := it.Hash(); hash != (common.Hash{}) {
+			p.add(bloom, hash)
+			*nodes++
+		}
+		if accounts && it.Leaf() {
+			var account state.Account
+			if err := rlp.DecodeBytes(it.LeafBlob(), &account); err != nil {
+				return err
+			}
+			if hash := common.BytesToHash(account.CodeHash); hash != emptyCode {
+				p.add(bloom, hash)
+			}
+			if account.Root != emptyRoot {
+				storage, err := trie.New(account.Root, p.triedb)
+				if err != nil {
+					return err
+				}
+				if err := p.markTrie(bloom, p.baseStorage(base, it.LeafKey()), storage, false, nodes, logged, stop); err != nil {
+					return err
+				}
+			}
+		}
+		if time.Since(*logged) > logInterval {
+			select {
+			case <-stop:
+				return ErrInterrupted
+			default:
+			}
+			log.Info("Marking live state", "nodes", *nodes)
+			*logged = time.Now()
+		}
+	}
+	return it.Error()
+}
+
+// baseStorage opens the storage trie of an account in the base state trie, or
+// returns nil if there is no such account or storage.
+func (p *Pruner) baseStorage(base *trie.Trie, key []byte) *trie.Trie {
+	if base == nil {
+		return nil
+	}
+	enc, err := base.TryGet(key)
+	if err != nil || len(enc) == 0 {
+		return nil
+	}
+	var account state.Account
+	if err := rlp.DecodeBytes(enc, &account); err != nil || account.Root == emptyRoot {
+		return nil
+	}
+	storage, err := trie.New(account.Root, p.triedb)
+	if err != nil {
+		return nil
+	}
+	return storage
+}
+
+// sweep iterates over the entire database and deletes all the trie nodes which
+// are not contained in the bloom filter of the live state.
+//
+// The bloom filter is locked until the deletions are written, so a node committed
+// in the meantime is either marked before being checked, or written to disk only
+// after its stale copy got deleted. The lock is released every few thousand keys
+// or milliseconds, however little was deleted, so that the commits of a running
+// node are never stalled for long and an interruption is noticed promptly.
+func (p *Pruner) sweep(bloom *stateBloom, stop <-chan struct{}) error {
+	var (
+		start   = time.Now()
+		logged  = time.Now()
+		yielded = time.Now()
+		batch   = p.db.NewBatch()
+		count   uint64
+		size    common.StorageSize
+		scanned uint64
+	)
+	it := p.db.NewIterator()
+	defer it.Release()
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for it.Next() {
+		scanned++
+
+		// Trie nodes are stored keyed by their own hash, but so are contract codes
+		// and other blobs, only delete entries which are trie nodes
+		key := it.Key()
+		if len(key) == common.HashLength && !bloom.contains(key) {
+			if value := it.Value(); isTrieNode(value) && crypto.Keccak256Hash(value) == common.BytesToHash(key) {
+				batch.Delete(key)
+				count++
+				size += common.StorageSize(len(key) + len(value))
+			}
+		}
+		if batch.ValueSize() >= ethdb.IdealBatchSize || scanned%sweepYieldKeys == 0 || time.Since(yielded) > sweepYieldInterval {
+			if batch.ValueSize() > 0 {
+				if err := batch.Write(); err != nil {
+					return err
+				}
+				batch.Reset()
+			}
+
+			// Let the pending commits of a running node through
+			p.lock.Unlock()
+			p.lock.Lock()
+			yielded = time.Now()
+
+			select {
+			case <-stop:
+				log.Info("State pruning interrupted", "deleted", count, "size", size)
+				return ErrInterrupted
+			default:
+			}
+		}
+		if time.Since(logged) > logInterval {
+			log.Info("Pruning state data", "scanned", scanned, "deleted", count, "size", size, "key", common.ToHex(key), "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Info("Pruned state data", "deleted", count, "size", size, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// isTrieNode reports whether a database value is an encoded trie node, that is
+// an RLP list of either two (short node) or seventeen (full node) items.
+func isTrieNode(blob []byte) bool {
+	elems, rest, err := rlp.SplitList(blob)
+	if err != nil || len(rest) != 0 {
+		return false
+	}
+	n, err := rlp.CountValues(elems)
+	return err == nil && (n == 2 || n == 17)
+}
@@ -0,0 +1,323 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	testKey, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddress  = crypto.PubkeyToAddress(testKey.PublicKey)
+	testContract = common.HexToAddress("0x1000000000000000000000000000000000000001")
+	testGenesis  = &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			testAddress:  {Balance: big.NewInt(1000000000000000000)},
+			testContract: {Balance: new(big.Int), Code: []byte{byte(vm.PUSH1), 0x01, byte(vm.NUMBER), byte(vm.SSTORE)}},
+		},
+	}
+)
+
+// newTestChain creates a database in a temporary directory containing only the
+// genesis, along with a chain on top of it, where every block writes a new
+// storage slot into a contract and funds a new account.
+func newTestChain(t *testing.T, blocks int) (*ethdb.LDBDatabase, []*types.Block, string) {
+	dir, err := ioutil.TempDir("", "pruner-test")
+	if err != nil {
+		t.Fatalf("failed to create temporary datadir: %v", err)
+	}
+	db, err := ethdb.NewLDBDatabase(filepath.Join(dir, "chaindata"), 0, 0)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	genesis := testGenesis.MustCommit(db)
+
+	// Generate the chain in a separate database, so no state is written ahead
+	gendb, _ := ethdb.NewMemDatabase()
+	testGenesis.MustCommit(gendb)
+
+	signer := types.HomesteadSigner{}
+	chain, _ := core.GenerateChain(testGenesis.Config, genesis, ethash.NewFaker(), gendb, blocks, func(i int, block *core.BlockGen) {
+		call, _ := types.SignTx(types.NewTransaction(block.TxNonce(testAddress), testContract, new(big.Int), 100000, new(big.Int), nil), signer, testKey)
+		block.AddTx(call)
+		transfer, _ := types.SignTx(types.NewTransaction(block.TxNonce(testAddress), common.BigToAddress(big.NewInt(int64(i+1))), big.NewInt(1), 21000, new(big.Int), nil), signer, testKey)
+		block.AddTx(transfer)
+	})
+	return db, append([]*types.Block{genesis}, chain...), dir
+}
+
+// newTestBlockChain creates a blockchain on top of the given database and imports
+// the given blocks into it.
+func newTestBlockChain(t *testing.T, db ethdb.Database, cache *core.CacheConfig, blocks []*types.Block) *core.BlockChain {
+	blockchain, err := core.NewBlockChain(db, cache, testGenesis.Config, ethash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	if _, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	return blockchain
+}
+
+// newArchiveChain creates an archive chain in a temporary database, storing the
+// state of every block.
+func newArchiveChain(t *testing.T, blocks int) (*ethdb.LDBDatabase, []*types.Block, string) {
+	db, chain, dir := newTestChain(t, blocks)
+	newTestBlockChain(t, db, &core.CacheConfig{Disabled: true}, chain[1:]).Stop()
+
+	return db, chain, dir
+}
+
+// iterateState walks the entire state of the given root, returning any error.
+func iterateState(db state.Database, root common.Hash) error {
+	statedb, err := state.New(root, db)
+	if err != nil {
+		return err
+	}
+	it := state.NewNodeIterator(statedb)
+	for it.Next() {
+	}
+	return it.Error
+}
+
+// Tests that pruning deletes the stale states while retaining the recent ones,
+// the genesis and any data which isn't a trie node, and that the bloom filter
+// is cleaned up afterwards.
+func TestPruneState(t *testing.T) {
+	db, blocks, dir := newArchiveChain(t, 16)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+
+	// Store some unreferenced blobs keyed by their hash, which aren't trie nodes
+	blobs := [][]byte{
+		{byte(vm.PUSH1), 0x02, byte(vm.NUMBER), byte(vm.SSTORE)},
+		common.HexToAddress("0x2000000000000000000000000000000000000002").Bytes(),
+	}
+	for _, blob := range blobs {
+		db.Put(crypto.Keccak256(blob), blob)
+	}
+	pruner, err := NewPruner(db, Config{Datadir: dir, BloomSize: 1, Retain: 4})
+	if err != nil {
+		t.Fatalf("failed to create pruner: %v", err)
+	}
+	if err := pruner.Prune(nil); err != nil {
+		t.Fatalf("failed to prune state: %v", err)
+	}
+	for i, blob := range blobs {
+		if ok, _ := db.Has(crypto.Keccak256(blob)); !ok {
+			t.Errorf("blob %d: non trie node pruned", i)
+		}
+	}
+	for i := len(blocks) - 4; i < len(blocks); i++ {
+		if err := iterateState(state.NewDatabase(db), blocks[i].Root()); err != nil {
+			t.Errorf("block %d: retained state incomplete: %v", i, err)
+		}
+	}
+	if err := iterateState(state.NewDatabase(db), blocks[0].Root()); err != nil {
+		t.Errorf("genesis state incomplete: %v", err)
+	}
+	for i := 1; i < len(blocks)-4; i++ {
+		if ok, _ := db.Has(blocks[i].Root().Bytes()); ok {
+			t.Errorf("block %d: stale state root not pruned", i)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, bloomFilterName)); !os.IsNotExist(err) {
+		t.Errorf("bloom filter not removed: %v", err)
+	}
+}
+
+// Tests that an interrupted pruning is resumed from the persisted bloom filter,
+// even if the chain head changed in between.
+func TestPruneStateResume(t *testing.T) {
+	db, blocks, dir := newArchiveChain(t, 8)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+
+	pruner, err := NewPruner(db, Config{Datadir: dir, BloomSize: 1, Retain: 2})
+	if err != nil {
+		t.Fatalf("failed to create pruner: %v", err)
+	}
+	// Simulate a run interrupted after marking a stale head
+	bloom := newStateBloom(1)
+	if err := pruner.mark(bloom, []common.Hash{blocks[3].Root()}, nil); err != nil {
+		t.Fatalf("failed to mark state: %v", err)
+	}
+	bloom.head, bloom.roots = blocks[3].Hash(), []common.Hash{blocks[3].Root()}
+	if err := bloom.save(filepath.Join(dir, bloomFilterName)); err != nil {
+		t.Fatalf("failed to save bloom filter: %v", err)
+	}
+	if loaded, err := loadStateBloom(filepath.Join(dir, bloomFilterName)); err != nil {
+		t.Fatalf("failed to load bloom filter: %v", err)
+	} else if loaded.head != bloom.head || len(loaded.bits) != len(bloom.bits) {
+		t.Fatalf("bloom filter mismatch: have %x/%d, want %x/%d", loaded.head, len(loaded.bits), bloom.head, len(bloom.bits))
+	}
+	// Resume the pruning and ensure both the old and new states are retained
+	if err := pruner.Prune(nil); err != nil {
+		t.Fatalf("failed to resume pruning: %v", err)
+	}
+	for _, i := range []int{3, len(blocks) - 2, len(blocks) - 1} {
+		if err := iterateState(state.NewDatabase(db), blocks[i].Root()); err != nil {
+			t.Errorf("block %d: retained state incomplete: %v", i, err)
+		}
+	}
+	if ok, _ := db.Has(blocks[1].Root().Bytes()); ok {
+		t.Errorf("stale state root not pruned")
+	}
+}
+
+// Tests that pruning the state of a running node retains the tries it keeps in
+// memory, as well as the ones it commits while pruning.
+func TestPruneStateOnline(t *testing.T) {
+	db, blocks, dir := newTestChain(t, 16)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+
+	// Store the state of the first blocks on disk and keep the next ones in memory
+	newTestBlockChain(t, db, &core.CacheConfig{Disabled: true}, blocks[1:9]).Stop()
+
+	blockchain := newTestBlockChain(t, db, nil, blocks[9:13])
+	defer blockchain.Stop()
+
+	triedb := blockchain.StateCache().TrieDB()
+	pruner, err := NewOnlinePruner(db, triedb, Config{Datadir: dir, BloomSize: 1, Retain: 1})
+	if err != nil {
+		t.Fatalf("failed to create pruner: %v", err)
+	}
+	// Import and commit more blocks while pruning
+	errc := make(chan error)
+	go func() {
+		errc <- pruner.Prune(nil)
+	}()
+	for _, block := range blocks[13:] {
+		if _, err := blockchain.InsertChain(types.Blocks{block}); err != nil {
+			t.Fatalf("block %d: failed to insert: %v", block.NumberU64(), err)
+		}
+		if err := triedb.Commit(block.Root(), false); err != nil {
+			t.Fatalf("block %d: failed to commit state: %v", block.NumberU64(), err)
+		}
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("failed to prune state: %v", err)
+	}
+	for i := 9; i < len(blocks); i++ {
+		if err := iterateState(blockchain.StateCache(), blocks[i].Root()); err != nil {
+			t.Errorf("block %d: live state incomplete: %v", i, err)
+		}
+	}
+	for i := 13; i < len(blocks); i++ {
+		if err := iterateState(state.NewDatabase(db), blocks[i].Root()); err != nil {
+			t.Errorf("block %d: committed state incomplete: %v", i, err)
+		}
+	}
+	for i := 1; i < 9; i++ {
+		if ok, _ := db.Has(blocks[i].Root().Bytes()); ok {
+			t.Errorf("block %d: stale state root not pruned", i)
+		}
+	}
+}
+
+// Tests that a sweep deleting next to nothing, as a second pruning of the same
+// database does, still lets the commits of a running node through and notices
+// an interruption long before reaching the end of the database.
+func TestPruneStateOnlineYield(t *testing.T) {
+	db, blocks, dir := newTestChain(t, 16)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+
+	newTestBlockChain(t, db, &core.CacheConfig{Disabled: true}, blocks[1:9]).Stop()
+
+	blockchain := newTestBlockChain(t, db, nil, blocks[9:13])
+	defer blockchain.Stop()
+
+	triedb := blockchain.StateCache().TrieDB()
+	pruner, err := NewOnlinePruner(db, triedb, Config{Datadir: dir, BloomSize: 1, Retain: 1})
+	if err != nil {
+		t.Fatalf("failed to create pruner: %v", err)
+	}
+	if err := pruner.Prune(nil); err != nil {
+		t.Fatalf("failed to prune state: %v", err)
+	}
+	// Fill the database with entries the sweep scans but never deletes
+	batch := db.NewBatch()
+	for i := 0; i < 200000; i++ {
+		key := crypto.Keccak256(big.NewInt(int64(i)).Bytes())
+		batch.Put(key, key[:8])
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			batch.Write()
+			batch.Reset()
+		}
+	}
+	batch.Write()
+
+	// Prune again and commit a block once the sweep started
+	var (
+		stop = make(chan struct{})
+		errc = make(chan error, 1)
+	)
+	go func() {
+		errc <- pruner.Prune(stop)
+	}()
+	for {
+		if _, err := os.Stat(filepath.Join(dir, bloomFilterName)); err == nil {
+			break
+		}
+		select {
+		case err := <-errc:
+			t.Fatalf("pruning finished before sweeping: %v", err)
+		case <-time.After(time.Millisecond):
+		}
+	}
+	block := blocks[13]
+	if _, err := blockchain.InsertChain(types.Blocks{block}); err != nil {
+		t.Fatalf("block %d: failed to insert: %v", block.NumberU64(), err)
+	}
+	if err := triedb.Commit(block.Root(), false); err != nil {
+		t.Fatalf("block %d: failed to commit state: %v", block.NumberU64(), err)
+	}
+	select {
+	case err := <-errc:
+		t.Fatalf("commit stalled until the end of the sweep: %v", err)
+	default:
+	}
+	close(stop)
+	if err := <-errc; err != ErrInterrupted {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrInterrupted)
+	}
+	// Resume the pruning and ensure the committed state survived both runs
+	if err := pruner.Prune(nil); err != nil {
+		t.Fatalf("failed to resume pruning: %v", err)
+	}
+	if err := iterateState(state.NewDatabase(db), block.Root()); err != nil {
+		t.Errorf("block %d: committed state incomplete: %v", block.NumberU64(), err)
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+var (
+	// snapshotRootKey tracks the state root the persisted snapshot belongs to.
+	snapshotRootKey = []byte("SnapshotRoot")
+
+	// snapshotGeneratorKey tracks the progress of the snapshot generation. It is
+	// only present while the snapshot is being generated.
+	snapshotGeneratorKey = []byte("SnapshotGenerator")
+
+	// SnapshotAccountPrefix + account hash -> account trie value
+	SnapshotAccountPrefix = []byte("a")
+
+	// SnapshotStoragePrefix + account hash + storage hash -> storage trie value
+	SnapshotStoragePrefix = []byte("o")
+)
+
+const (
+	// accountKeyLength and storageKeyLength are the lengths of the snapshot keys,
+	// used to tell them apart from the trie nodes sharing their first byte.
+	accountKeyLength = 1 + common.HashLength
+	storageKeyLength = 1 + 2*common.HashLength
+)
+
+// accountSnapshotKey = SnapshotAccountPrefix + hash
+func accountSnapshotKey(hash common.Hash) []byte {
+	return append(append([]byte{}, SnapshotAccountPrefix...), hash[:]...)
+}
+
+// storageSnapshotKey = SnapshotStoragePrefix + account hash + storage hash
+func storageSnapshotKey(accountHash, storageHash common.Hash) []byte {
+	key := append(append([]byte{}, SnapshotStoragePrefix...), accountHash[:]...)
+	return append(key, storageHash[:]...)
+}
+
+// storageSnapshotsKey = SnapshotStoragePrefix + account hash
+func storageSnapshotsKey(accountHash common.Hash) []byte {
+	return append(append([]byte{}, SnapshotStoragePrefix...), accountHash[:]...)
+}
+
+// readSnapshotRoot retrieves the root of the persisted snapshot, or an empty
+// hash if there is none.
+func readSnapshotRoot(db ethdb.Database) common.Hash {
+	data, _ := db.Get(snapshotRootKey)
+	if len(data) != common.HashLength {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// writeSnapshotRoot stores the root of the persisted snapshot.
+func writeSnapshotRoot(db ethdb.Putter, root common.Hash) {
+	if err := db.Put(snapshotRootKey, root[:]); err != nil {
+		log.Crit("Failed to store snapshot root", "err", err)
+	}
+}
+
+// deleteSnapshotRoot removes the root of the persisted snapshot, invalidating it.
+func deleteSnapshotRoot(db ethdb.Deleter) {
+	if err := db.Delete(snapshotRootKey); err != nil {
+		log.Crit("Failed to remove snapshot root", "err", err)
+	}
+}
+
+// readSnapshotGenerator retrieves the position the snapshot generation reached,
+// and whether a generation is in progress at all.
+func readSnapshotGenerator(db ethdb.Database) ([]byte, bool) {
+	if ok, _ := db.Has(snapshotGeneratorKey); !ok {
+		return nil, false
+	}
+	data, _ := db.Get(snapshotGeneratorKey)
+	return data, true
+}
+
+// writeSnapshotGenerator stores the position the snapshot generation reached.
+func writeSnapshotGenerator(db ethdb.Putter, marker []byte) {
+	if err := db.Put(snapshotGeneratorKey, marker); err != nil {
+		log.Crit("Failed to store snapshot generator", "err", err)
+	}
+}
+
+// deleteSnapshotGenerator removes the generation progress, marking the snapshot
+// as complete.
+func deleteSnapshotGenerator(db ethdb.Deleter) {
+	if err := db.Delete(snapshotGeneratorKey); err != nil {
+		log.Crit("Failed to remove snapshot generator", "err", err)
+	}
+}
+
+// wipeSnapshot deletes all the account and storage entries of the persisted
+// snapshot. Trie nodes share the first byte with some of the entries, so only the
+// keys with the exact snapshot key lengths are deleted.
+func wipeSnapshot(db ethdb.Database) error {
+	for _, wipe := range []struct {
+		prefix []byte
+		length int
+	}{
+		{SnapshotAccountPrefix, accountKeyLength},
+		{SnapshotStoragePrefix, storageKeyLength},
+	} {
+		batch := db.NewBatch()
+		it := db.NewIteratorWithPrefix(wipe.prefix)
+		for it.Next() {
+			if key := it.Key(); len(key) == wipe.length {
+				batch.Delete(common.CopyBytes(key))
+				if batch.ValueSize() >= ethdb.IdealBatchSize {
+					if err := batch.Write(); err != nil {
+						it.Release()
+						return err
+					}
+					batch.Reset()
+				}
+			}
+		}
+		err := it.Error()
+		it.Release()
+		if err != nil {
+			return err
+		}
+		if err := batch.Write(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// diffLayer represents a collection of modifications made to a state snapshot
+// after running a block on top. It contains one map for the account trie and one
+// map for each modified storage trie.
+//
+// The goal of a diff layer is to act as a journal, tracking recent modifications
+// made to the state, that have not yet graduated into a semi-immutable state.
+type diffLayer struct {
+	parent snapshot    // Parent snapshot modified by this one, never nil
+	root   common.Hash // Root hash to which this snapshot diff belongs to
+	stale  bool        // Signals that the layer became stale (state progressed)
+	memory uint64      // Approximate guess as to how much memory we use
+
+	destructSet map[common.Hash]struct{}               // Keyed markers for deleted (and potentially recreated) accounts
+	accountData map[common.Hash][]byte                 // Keyed accounts for direct retrieval (nil means deleted)
+	storageData map[common.Hash]map[common.Hash][]byte // Keyed storage slots for direct retrieval, one map per account (nil means deleted)
+
+	lock sync.RWMutex
+}
+
+// newDiffLayer creates a new diff on top of an existing snapshot, whether that's
+// a low level persistent database or a hierarchical diff already.
+func newDiffLayer(parent snapshot, root common.Hash, destructs map[common.Hash]struct{}, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) *diffLayer {
+	dl := &diffLayer{
+		parent:      parent,
+		root:        root,
+		destructSet: destructs,
+		accountData: accounts,
+		storageData: storage,
+	}
+	if dl.destructSet == nil {
+		dl.destructSet = make(map[common.Hash]struct{})
+	}
+	if dl.accountData == nil {
+		dl.accountData = make(map[common.Hash][]byte)
+	}
+	if dl.storageData == nil {
+		dl.storageData = make(map[common.Hash]map[common.Hash][]byte)
+	}
+	// Determine memory size and track the dirty writes
+	dl.memory += uint64(common.HashLength * len(dl.destructSet))
+	for _, data := range dl.accountData {
+		dl.memory += uint64(common.HashLength + len(data))
+	}
+	for _, slots := range dl.storageData {
+		for _, data := range slots {
+			dl.memory += uint64(common.HashLength + len(data))
+		}
+		dl.memory += uint64(common.HashLength)
+	}
+	return dl
+}
+
+// Root returns the root hash for which this snapshot was made.
+func (dl *diffLayer) Root() common.Hash {
+	return dl.root
+}
+
+// Parent returns the subsequent layer of a diff layer.
+func (dl *diffLayer) Parent() snapshot {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	return dl.parent
+}
+
+// Stale return whether this layer has become stale (was flattened across) or if
+// it's still live.
+func (dl *diffLayer) Stale() bool {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	return dl.stale
+}
+
+// Account directly retrieves the account RLP associated with a particular hash
+// in the snapshot, walking down the diff layers until the disk layer.
+func (dl *diffLayer) Account(hash common.Hash) ([]byte, error) {
+	var snap snapshot = dl
+	for {
+		diff, ok := snap.(*diffLayer)
+		if !ok {
+			snapshotDirtyAccountMissMeter.Mark(1)
+			return snap.Account(hash)
+		}
+		data, found, err := diff.account(hash)
+		if err != nil || found {
+			if found {
+				snapshotDirtyAccountHitMeter.Mark(1)
+			}
+			return data, err
+		}
+		snap = diff.Parent()
+	}
+}
+
+// account retrieves the account associated with a particular hash from this
+// layer alone, reporting whether the layer contains any information about it.
+func (dl *diffLayer) account(hash common.Hash) ([]byte, bool, error) {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	// If the layer was flattened into, consider it invalid (any live reference to
+	// the original should be marked as unusable).
+	if dl.stale {
+		return nil, false, ErrSnapshotStale
+	}
+	// If the account is known locally, return it
+	if data, ok := dl.accountData[hash]; ok {
+		return data, true, nil
+	}
+	// If the account is known locally, but deleted, return it
+	if _, ok := dl.destructSet[hash]; ok {
+		return nil, true, nil
+	}
+	return nil, false, nil
+}
+
+// Storage directly retrieves the storage data associated with a particular hash,
+// within a particular account, walking down the diff layers until the disk layer.
+func (dl *diffLayer) Storage(accountHash, storageHash common.Hash) ([]byte, error) {
+	var snap snapshot = dl
+	for {
+		diff, ok := snap.(*diffLayer)
+		if !ok {
+			snapshotDirtyStorageMissMeter.Mark(1)
+			return snap.Storage(accountHash, storageHash)
+		}
+		data, found, err := diff.storage(accountHash, storageHash)
+		if err != nil || found {
+			if found {
+				snapshotDirtyStorageHitMeter.Mark(1)
+			}
+			return data, err
+		}
+		snap = diff.Parent()
+	}
+}
+
+// storage retrieves a storage slot from this layer alone, reporting whether the
+// layer contains any information about it.
+func (dl *diffLayer) storage(accountHash, storageHash common.Hash) ([]byte, bool, error) {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	// If the layer was flattened into, consider it invalid (any live reference to
+	// the original should be marked as unusable).
+	if dl.stale {
+		return nil, false, ErrSnapshotStale
+	}
+	// If the account is known locally, try to resolve the slot locally
+	if storage, ok := dl.storageData[accountHash]; ok {
+		if data, ok := storage[storageHash]; ok {
+			return data, true, nil
+		}
+	}
+	// If the account is known locally, but deleted, the slot is gone too
+	if _, ok := dl.destructSet[accountHash]; ok {
+		return nil, true, nil
+	}
+	return nil, false, nil
+}
+
+// Update creates a new layer on top of the existing snapshot diff tree with
+// the specified data items.
+func (dl *diffLayer) Update(blockRoot common.Hash, destructs map[common.Hash]struct{}, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) *diffLayer {
+	return newDiffLayer(dl, blockRoot, destructs, accounts, storage)
+}
+
+// flatten pushes all data from this point downwards, flattening everything into
+// a single diff at the bottom. Since usually the lowermost diff is the largest,
+// the flattening builds up from there in reverse.
+//
+// The layer itself and all the flattened parents are marked stale, the returned
+// layer replaces them all.
+func (dl *diffLayer) flatten() snapshot {
+	// If the parent is not diff, we're the first in line, return unmodified
+	parent, ok := dl.parent.(*diffLayer)
+	if !ok {
+		return dl
+	}
+	// Parent is a diff, flatten it first (note, apart from weird corner cases,
+	// flatten will realistically only ever merge 1 layer, so there's no need to
+	// be smarter about grouping flattens together).
+	parent = parent.flatten().(*diffLayer)
+
+	parent.lock.Lock()
+	defer parent.lock.Unlock()
+
+	// Before actually writing all our data to the parent, first ensure that the
+	// parent hasn't been 'corrupted' by someone else already flattening into it
+	if parent.stale {
+		panic("parent diff layer is stale") // we've flattened into the same parent from two children, boo
+	}
+	parent.stale = true
+
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	if dl.stale {
+		panic(fmt.Sprintf("diff layer %x is stale", dl.root))
+	}
+	dl.stale = true
+
+	// Wipe all the destructed accounts, then overwrite the updated ones blindly
+	for hash := range dl.destructSet {
+		parent.destructSet[hash] = struct{}{}
+		delete(parent.accountData, hash)
+		delete(parent.storageData, hash)
+	}
+	for hash, data := range dl.accountData {
+		parent.accountData[hash] = data
+	}
+	// Overwrite all the updated storage slots (individually)
+	for accountHash, storage := range dl.storageData {
+		// If storage didn't exist (or was deleted) in the parent, overwrite blindly
+		if _, ok := parent.storageData[accountHash]; !ok {
+			parent.storageData[accountHash] = storage
+			continue
+		}
+		// Storage exists in both parent and child, merge the slots
+		comboData := parent.storageData[accountHash]
+		for storageHash, data := range storage {
+			comboData[storageHash] = data
+		}
+	}
+	// Return the combo parent
+	return &diffLayer{
+		parent:      parent.parent,
+		root:        dl.root,
+		destructSet: parent.destructSet,
+		accountData: parent.accountData,
+		storageData: parent.storageData,
+		memory:      parent.memory + dl.memory,
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var (
+	// emptyRoot is the known root hash of an empty trie.
+	emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+)
+
+// account is the consensus representation of an account, as stored in the
+// account trie and in the snapshot. Only the storage root is used here.
+type account struct {
+	Nonce    uint64
+	Balance  *big.Int
+	Root     common.Hash
+	CodeHash []byte
+}
+
+// generateSnapshot regenerates a brand new snapshot based on an existing state
+// database and head block asynchronously. The snapshot is returned immediately
+// and generation is continued in the background until done.
+func generateSnapshot(diskdb ethdb.Database, triedb *trie.Database, cache int, root common.Hash) *diskLayer {
+	// Wipe any previously existing snapshot from the database
+	deleteSnapshotRoot(diskdb)
+	if err := wipeSnapshot(diskdb); err != nil {
+		log.Crit("Failed to wipe state snapshot", "err", err)
+	}
+	// Create a new disk layer with an initialized state marker at zero
+	batch := diskdb.NewBatch()
+	writeSnapshotRoot(batch, root)
+	writeSnapshotGenerator(batch, []byte{})
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to write initialized state marker", "err", err)
+	}
+	base := &diskLayer{
+		diskdb:     diskdb,
+		triedb:     triedb,
+		root:       root,
+		cache:      newCache(cache),
+		genMarker:  []byte{}, // Initialized but empty!
+		genPending: make(chan struct{}),
+		genAbort:   make(chan chan []byte),
+	}
+	go base.generate()
+	return base
+}
+
+// generate is a background thread that iterates over the state and storage tries
+// and constructs a state snapshot. All the entries up to the generation marker
+// are in the snapshot, the ones after it are not, so the generation can resume
+// from the marker if it is interrupted.
+func (dl *diskLayer) generate() {
+	var (
+		start    = time.Now()
+		logged   = time.Now()
+		accounts uint64
+		slots    uint64
+	)
+	dl.lock.RLock()
+	genMarker := dl.genMarker
+	dl.lock.RUnlock()
+
+	// Split the marker into the account to resume from and the storage slot to
+	// resume from within that account
+	accMarker, storeMarker := genMarker, []byte(nil)
+	if len(genMarker) > common.HashLength {
+		accMarker, storeMarker = genMarker[:common.HashLength], genMarker[common.HashLength:]
+	}
+	log.Info("Generating state snapshot", "root", dl.root, "at", common.ToHex(genMarker))
+
+	accTrie, err := trie.New(dl.root, dl.triedb)
+	if err != nil {
+		// The account trie is missing (GC), surf the chain until one becomes available
+		log.Warn("Failed to open state trie for snapshot generation", "root", dl.root, "err", err)
+		dl.waitAbort()
+		return
+	}
+	batch := dl.diskdb.NewBatch()
+
+	// checkAndFlush flushes the batch if it is large enough, persisting the
+	// progress, and reports whether the generation was aborted.
+	checkAndFlush := func(marker []byte) bool {
+		var abort chan []byte
+		select {
+		case abort = <-dl.genAbort:
+		default:
+		}
+		if batch.ValueSize() > ethdb.IdealBatchSize || abort != nil {
+			// Flush out the batch anyway no matter it's empty or not
+			writeSnapshotGenerator(batch, marker)
+			if err := batch.Write(); err != nil {
+				log.Crit("Failed to write snapshot generation progress", "err", err)
+			}
+			batch.Reset()
+
+			dl.lock.Lock()
+			dl.genMarker = marker
+			dl.lock.Unlock()
+		}
+		if abort != nil {
+			log.Info("Aborting state snapshot generation", "root", dl.root, "at", common.ToHex(marker), "accounts", accounts, "slots", slots, "elapsed", common.PrettyDuration(time.Since(start)))
+			abort <- marker
+			return true
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Generating state snapshot", "root", dl.root, "at", common.ToHex(marker), "accounts", accounts, "slots", slots, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+		return false
+	}
+	accIt := trie.NewIterator(accTrie.NodeIterator(accMarker))
+	for accIt.Next() {
+		accountHash := common.BytesToHash(accIt.Key)
+
+		var acc account
+		if err := rlp.DecodeBytes(accIt.Value, &acc); err != nil {
+			log.Crit("Invalid account encountered during snapshot creation", "err", err)
+		}
+		batch.Put(accountSnapshotKey(accountHash), common.CopyBytes(accIt.Value))
+		accounts++
+		snapshotGeneratedAccountMeter.Mark(1)
+
+		// The marker must never move backwards, so if the generation is resumed
+		// within the storage of this account, don't mark the account itself
+		resumed := storeMarker != nil && accountHash == common.BytesToHash(accMarker)
+		if !resumed && checkAndFlush(accountHash[:]) {
+			return
+		}
+		// If the iterated account is a contract, iterate through corresponding contract
+		// storage to generate snapshot entries.
+		if acc.Root != emptyRoot {
+			var start []byte
+			if resumed {
+				start = storeMarker
+			}
+			storeTrie, err := trie.New(acc.Root, dl.triedb)
+			if err != nil {
+				log.Warn("Failed to open storage trie for snapshot generation", "root", acc.Root, "err", err)
+				dl.waitAbort()
+				return
+			}
+			storeIt := trie.NewIterator(storeTrie.NodeIterator(start))
+			for storeIt.Next() {
+				batch.Put(storageSnapshotKey(accountHash, common.BytesToHash(storeIt.Key)), common.CopyBytes(storeIt.Value))
+				slots++
+				snapshotGeneratedStorageMeter.Mark(1)
+
+				if checkAndFlush(append(common.CopyBytes(accountHash[:]), storeIt.Key...)) {
+					return
+				}
+			}
+			if storeIt.Err != nil {
+				log.Warn("Failed to iterate storage trie for snapshot generation", "root", acc.Root, "err", storeIt.Err)
+				dl.waitAbort()
+				return
+			}
+		}
+	}
+	if accIt.Err != nil {
+		log.Warn("Failed to iterate state trie for snapshot generation", "root", dl.root, "err", accIt.Err)
+		dl.waitAbort()
+		return
+	}
+	// Snapshot fully generated, set the marker to nil
+	deleteSnapshotGenerator(batch)
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to flush snapshot generation", "err", err)
+	}
+	log.Info("Generated state snapshot", "root", dl.root, "accounts", accounts, "slots", slots, "elapsed", common.PrettyDuration(time.Since(start)))
+
+	dl.lock.Lock()
+	dl.genMarker = nil
+	close(dl.genPending)
+	dl.lock.Unlock()
+
+	// Someone will be looking for us, wait it out
+	abort := <-dl.genAbort
+	abort <- nil
+}
+
+// waitAbort parks a generator which can't proceed because the trie it iterates
+// is not available (anymore), until it is aborted. The progress persisted so far
+// is retained, so the generation resumes once the disk layer moves to a newer
+// state.
+func (dl *diskLayer) waitAbort() {
+	dl.lock.RLock()
+	marker := dl.genMarker
+	dl.lock.RUnlock()
+
+	abort := <-dl.genAbort
+	abort <- marker
+}
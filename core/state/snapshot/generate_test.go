@@ -0,0 +1,93 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// Tests that a snapshot generated from a state trie contains all the accounts
+// and storage slots of it.
+func TestGeneration(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	triedb := trie.NewDatabase(db)
+
+	// Create a storage trie shared by a few of the accounts
+	stTrie, _ := trie.New(common.Hash{}, triedb)
+	slots := make(map[common.Hash][]byte)
+	for i := byte(1); i <= 10; i++ {
+		key, val := common.BytesToHash([]byte{i}), []byte{i, i}
+		stTrie.Update(key[:], val)
+		slots[key] = val
+	}
+	stRoot, _ := stTrie.Commit(nil)
+
+	accTrie, _ := trie.New(common.Hash{}, triedb)
+	accounts := make(map[common.Hash][]byte)
+	for i := byte(0); i < 20; i++ {
+		acc := &account{Nonce: uint64(i), Balance: big.NewInt(int64(i)), Root: emptyRoot, CodeHash: crypto.Keccak256(nil)}
+		if i%4 == 0 {
+			acc.Root = stRoot
+		}
+		blob, _ := rlp.EncodeToBytes(acc)
+		hash := crypto.Keccak256Hash([]byte{i})
+		accTrie.Update(hash[:], blob)
+		accounts[hash] = blob
+	}
+	root, _ := accTrie.Commit(nil)
+
+	dl := generateSnapshot(db, triedb, 16, root)
+	select {
+	case <-dl.genPending:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("snapshot generation timed out")
+	}
+	for hash, blob := range accounts {
+		data, err := dl.Account(hash)
+		if err != nil || !bytes.Equal(data, blob) {
+			t.Fatalf("account %x mismatch: have %x/%v, want %x", hash, data, err, blob)
+		}
+		var acc account
+		rlp.DecodeBytes(blob, &acc)
+		for key, val := range slots {
+			want := val
+			if acc.Root == emptyRoot {
+				want = nil
+			}
+			if data, err := dl.Storage(hash, key); err != nil || !bytes.Equal(data, want) {
+				t.Fatalf("account %x slot %x mismatch: have %x/%v, want %x", hash, key, data, err, want)
+			}
+		}
+	}
+	if _, generating := readSnapshotGenerator(db); generating {
+		t.Fatalf("generator marker left after generation")
+	}
+	stop := make(chan []byte)
+	dl.genAbort <- stop
+	if marker := <-stop; marker != nil {
+		t.Fatalf("generator reported progress after completion: %x", marker)
+	}
+}
@@ -0,0 +1,517 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package snapshot implements a flat, hash-keyed view of the account and storage
+// state, allowing state reads without traversing the tries.
+//
+// The snapshot consists of a persistent disk layer holding the state of an older
+// block, and a tree of in-memory diff layers on top of it, one for each recent
+// block, so that reads are served for the recent blocks of all the chain forks.
+package snapshot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/trie"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	// aggregatorMemoryLimit is the maximum size of the bottom-most diff layer
+	// that aggregates the writes from above until it's flushed into the disk
+	// layer.
+	aggregatorMemoryLimit = uint64(4 * 1024 * 1024)
+
+	// cacheItemSize is the rough size of a cached disk layer entry, used to turn
+	// the cache allowance into a number of entries.
+	cacheItemSize = 128
+)
+
+var (
+	// ErrSnapshotStale is returned from data accessors if the underlying snapshot
+	// layer had been invalidated due to the chain progressing forward far enough
+	// to not maintain the layer's original state.
+	ErrSnapshotStale = errors.New("snapshot stale")
+
+	// ErrNotCoveredYet is returned from data accessors if the underlying snapshot
+	// is being generated currently and the requested data item is not yet in the
+	// range of accounts covered.
+	ErrNotCoveredYet = errors.New("not covered yet")
+
+	// errSnapshotCycle is returned if a snapshot is attempted to be inserted
+	// that forms a cycle in the snapshot tree.
+	errSnapshotCycle = errors.New("snapshot cycle")
+)
+
+// Snapshot represents the functionality supported by a snapshot storage layer.
+// Accounts and storage slots are keyed by the hash of their address and slot,
+// and returned in the same encoding as they are stored in the tries. A nil blob
+// without an error means the item doesn't exist.
+type Snapshot interface {
+	// Root returns the root hash for which this snapshot was made.
+	Root() common.Hash
+
+	// Account directly retrieves the account RLP associated with a particular
+	// hash in the snapshot.
+	Account(hash common.Hash) ([]byte, error)
+
+	// Storage directly retrieves the storage data associated with a particular
+	// hash, within a particular account.
+	Storage(accountHash, storageHash common.Hash) ([]byte, error)
+}
+
+// snapshot is the internal version of the snapshot data layer that supports
+// some additional methods compared to the public API.
+type snapshot interface {
+	Snapshot
+
+	// Parent returns the subsequent layer of a snapshot, or nil if the base was
+	// reached.
+	Parent() snapshot
+
+	// Update creates a new layer on top of the existing snapshot diff tree with
+	// the specified data items.
+	Update(blockRoot common.Hash, destructs map[common.Hash]struct{}, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) *diffLayer
+
+	// Stale returns whether this layer has become stale (was flattened across) or
+	// if it's still live.
+	Stale() bool
+}
+
+// Tree is an Ethereum state snapshot tree. It consists of one persistent base
+// layer backed by a key-value store, on top of which arbitrarily many in-memory
+// diff layers are topped. The memory diffs can form a tree with branching, but
+// the disk layer is singleton and common to all. If a reorg goes deeper than the
+// disk layer, everything needs to be regenerated.
+type Tree struct {
+	diskdb ethdb.Database           // Persistent database to store the snapshot
+	triedb *trie.Database           // In-memory cache to access the trie through
+	cache  int                      // Megabytes permitted to use for read caches
+	layers map[common.Hash]snapshot // Collection of all known layers
+	lock   sync.RWMutex
+}
+
+// New attempts to load an already existing snapshot from a persistent key-value
+// store, ensuring that the head of the snapshot matches the expected one.
+//
+// If the snapshot is missing, belongs to a different state or is incomplete, it
+// is (re)generated in the background from the state trie. Until the generation
+// catches up, reads not covered yet return ErrNotCoveredYet.
+func New(diskdb ethdb.Database, triedb *trie.Database, cache int, root common.Hash) *Tree {
+	snap := &Tree{
+		diskdb: diskdb,
+		triedb: triedb,
+		cache:  cache,
+		layers: make(map[common.Hash]snapshot),
+	}
+	base := loadSnapshot(diskdb, triedb, cache, root)
+	if base == nil {
+		log.Info("Rebuilding state snapshot", "root", root)
+		base = generateSnapshot(diskdb, triedb, cache, root)
+	}
+	snap.layers[root] = base
+	return snap
+}
+
+// loadSnapshot opens the persisted snapshot if it belongs to the given root,
+// resuming its generation if it was interrupted.
+func loadSnapshot(diskdb ethdb.Database, triedb *trie.Database, cache int, root common.Hash) *diskLayer {
+	if readSnapshotRoot(diskdb) != root {
+		return nil
+	}
+	base := &diskLayer{
+		diskdb: diskdb,
+		triedb: triedb,
+		cache:  newCache(cache),
+		root:   root,
+	}
+	if marker, generating := readSnapshotGenerator(diskdb); generating {
+		log.Info("Resuming state snapshot generation", "root", root, "at", common.ToHex(marker))
+		base.genMarker = marker
+		base.genPending = make(chan struct{})
+		base.genAbort = make(chan chan []byte)
+		go base.generate()
+	} else {
+		log.Info("Loaded state snapshot", "root", root)
+	}
+	return base
+}
+
+// newCache creates a read cache for a disk layer with the given allowance in
+// megabytes.
+func newCache(cache int) *lru.Cache {
+	items := cache * 1024 * 1024 / cacheItemSize
+	if items < 1 {
+		items = 1
+	}
+	lcache, _ := lru.New(items)
+	return lcache
+}
+
+// Snapshot retrieves a snapshot belonging to the given block root, or nil if no
+// snapshot is maintained for that block.
+func (t *Tree) Snapshot(blockRoot common.Hash) Snapshot {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if snap, ok := t.layers[blockRoot]; ok {
+		return snap
+	}
+	return nil
+}
+
+// Update adds a new snapshot into the tree, if that can be linked to an existing
+// old parent. It is disallowed to insert a disk layer (the origin of all).
+func (t *Tree) Update(blockRoot common.Hash, parentRoot common.Hash, destructs map[common.Hash]struct{}, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) error {
+	// Reject noop updates to avoid self-loops in the snapshot tree. This is a
+	// special case that can only happen for Clique networks where empty blocks
+	// don't modify the state (0 block subsidy).
+	if blockRoot == parentRoot {
+		return errSnapshotCycle
+	}
+	// Generate a new snapshot on top of the parent
+	parent := t.Snapshot(parentRoot)
+	if parent == nil {
+		return fmt.Errorf("parent [%#x] snapshot missing", parentRoot)
+	}
+	snap := parent.(snapshot).Update(blockRoot, destructs, accounts, storage)
+
+	// Save the new snapshot for later, unless an identical one is already known
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if _, ok := t.layers[snap.root]; !ok {
+		t.layers[snap.root] = snap
+	}
+	return nil
+}
+
+// Cap traverses downwards the snapshot tree from a head block hash until the
+// number of allowed layers are crossed. All layers beyond the permitted number
+// are flattened downwards.
+func (t *Tree) Cap(root common.Hash, layers int) error {
+	// Retrieve the head snapshot to cap from
+	snap := t.Snapshot(root)
+	if snap == nil {
+		return fmt.Errorf("snapshot [%#x] missing", root)
+	}
+	diff, ok := snap.(*diffLayer)
+	if !ok {
+		// The disk layer has nothing to flatten
+		return nil
+	}
+	// Run the internal capping and discard all stale layers
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if layers == 0 {
+		// Full commit, flatten everything into the disk layer
+		for {
+			if _, ok := diff.parent.(*diffLayer); !ok {
+				break
+			}
+			diff = diff.flatten().(*diffLayer)
+		}
+		diff.lock.RLock()
+		base := diffToDisk(diff)
+		diff.lock.RUnlock()
+
+		// Replace the entire snapshot tree with the flat base
+		t.layers = map[common.Hash]snapshot{base.root: base}
+		return nil
+	}
+	t.cap(diff, layers)
+
+	// Remove any layer that is stale or links into a stale layer
+	children := make(map[common.Hash][]common.Hash)
+	for root, snap := range t.layers {
+		if diff, ok := snap.(*diffLayer); ok {
+			parent := diff.Parent().Root()
+			children[parent] = append(children[parent], root)
+		}
+	}
+	var remove func(root common.Hash)
+	remove = func(root common.Hash) {
+		delete(t.layers, root)
+		for _, child := range children[root] {
+			remove(child)
+		}
+		delete(children, root)
+	}
+	for root, snap := range t.layers {
+		if snap.Stale() {
+			remove(root)
+		}
+	}
+	return nil
+}
+
+// cap traverses downwards the diff tree until the number of allowed layers are
+// crossed. All diffs beyond the permitted number are flattened downwards. If the
+// layer limit is reached, memory cap is also enforced (but not before).
+//
+// The tree lock is assumed to be held.
+func (t *Tree) cap(diff *diffLayer, layers int) {
+	// Dive until we run out of layers or reach the persistent database
+	for ; layers > 1; layers-- {
+		// If we still have diff layers below, continue down
+		if parent, ok := diff.parent.(*diffLayer); ok {
+			diff = parent
+		} else {
+			// Diff stack too shallow, return without modifications
+			return
+		}
+	}
+	// We're out of layers, flatten anything below, stopping if it's the disk or if
+	// the memory limit is not yet exceeded.
+	switch parent := diff.parent.(type) {
+	case *diskLayer:
+		return
+
+	case *diffLayer:
+		// Flatten the parent into the grandparent. The flattening internally obtains a
+		// write lock on grandparent.
+		flattened := parent.flatten().(*diffLayer)
+		t.layers[flattened.root] = flattened
+
+		diff.lock.Lock()
+		defer diff.lock.Unlock()
+
+		diff.parent = flattened
+		if flattened.memory < aggregatorMemoryLimit {
+			// Accumulator layer is smaller than the limit, so we can abort, unless
+			// there's a snapshot being generated currently. In that case, the trie
+			// will move from underneath the generator so we **must** merge all the
+			// partial data down into the snapshot and restart the generation.
+			if !flattened.parent.(*diskLayer).generating() {
+				return
+			}
+		}
+	default:
+		panic(fmt.Sprintf("unknown data layer: %T", parent))
+	}
+	// If the bottom-most layer is larger than our memory cap, persist to disk
+	bottom := diff.parent.(*diffLayer)
+
+	bottom.lock.RLock()
+	base := diffToDisk(bottom)
+	bottom.lock.RUnlock()
+
+	t.layers[base.root] = base
+	diff.parent = base
+}
+
+// diffToDisk merges a bottom-most diff into the persistent disk layer underneath
+// it. The method will panic if called onto a non-bottom-most diff layer.
+func diffToDisk(bottom *diffLayer) *diskLayer {
+	var (
+		base  = bottom.parent.(*diskLayer)
+		batch = base.diskdb.NewBatch()
+	)
+	// If the disk layer is running a snapshot generator, abort it
+	var marker []byte
+	if base.genAbort != nil {
+		abort := make(chan []byte)
+		base.genAbort <- abort
+		marker = <-abort
+	}
+	// Start by temporarily deleting the current snapshot root, so a crash while
+	// the diff is being written leaves the snapshot to be regenerated
+	deleteSnapshotRoot(base.diskdb)
+
+	// Mark the original base as stale as we're going to create a new wrapper
+	base.lock.Lock()
+	if base.stale {
+		panic("parent disk layer is stale") // we've committed into the same base from two children, boo
+	}
+	base.stale = true
+	base.lock.Unlock()
+
+	// Destroy all the destructed accounts from the database
+	for hash := range bottom.destructSet {
+		// Skip any account not covered yet by the snapshot
+		if marker != nil && bytes.Compare(hash[:], marker) > 0 {
+			continue
+		}
+		key := accountSnapshotKey(hash)
+		batch.Delete(key)
+		base.cache.Remove(string(key))
+
+		it := base.diskdb.NewIteratorWithPrefix(storageSnapshotsKey(hash))
+		for it.Next() {
+			if key := it.Key(); len(key) == storageKeyLength {
+				batch.Delete(common.CopyBytes(key))
+				base.cache.Remove(string(key))
+			}
+		}
+		it.Release()
+	}
+	// Push all updated accounts into the database
+	for hash, data := range bottom.accountData {
+		// Skip any account not covered yet by the snapshot
+		if marker != nil && bytes.Compare(hash[:], marker) > 0 {
+			continue
+		}
+		key := accountSnapshotKey(hash)
+		if len(data) > 0 {
+			batch.Put(key, data)
+		} else {
+			batch.Delete(key)
+		}
+		base.cache.Add(string(key), data)
+
+		// Ensure we don't write too much data blindly. It's ok to flush, the root
+		// will go missing in case of a crash and we'll detect and regen the snapshot.
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				log.Crit("Failed to write state changes", "err", err)
+			}
+			batch.Reset()
+		}
+	}
+	// Push all the storage slots into the database
+	for accountHash, storage := range bottom.storageData {
+		// Skip any account not covered yet by the snapshot
+		if marker != nil && bytes.Compare(accountHash[:], marker) > 0 {
+			continue
+		}
+		for storageHash, data := range storage {
+			// Skip any slot not covered yet by the snapshot
+			if marker != nil && bytes.Compare(append(accountHash[:], storageHash[:]...), marker) > 0 {
+				continue
+			}
+			key := storageSnapshotKey(accountHash, storageHash)
+			if len(data) > 0 {
+				batch.Put(key, data)
+			} else {
+				batch.Delete(key)
+			}
+			base.cache.Add(string(key), data)
+		}
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				log.Crit("Failed to write storage deletions", "err", err)
+			}
+			batch.Reset()
+		}
+	}
+	// Update the snapshot block marker and write any remainder data
+	writeSnapshotRoot(batch, bottom.root)
+	if marker != nil {
+		writeSnapshotGenerator(batch, marker)
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to write leftover snapshot", "err", err)
+	}
+	res := &diskLayer{
+		root:   bottom.root,
+		cache:  base.cache,
+		diskdb: base.diskdb,
+		triedb: base.triedb,
+	}
+	// If snapshot generation hasn't finished yet, port over all the starts and
+	// continue where the previous round left off.
+	if marker != nil {
+		res.genMarker = marker
+		res.genPending = make(chan struct{})
+		res.genAbort = make(chan chan []byte)
+		go res.generate()
+	}
+	return res
+}
+
+// Rebuild wipes all available snapshot data from the persistent database and
+// discards all caches and diff layers. Afterwards, it starts a new snapshot
+// generator with the given root hash.
+func (t *Tree) Rebuild(root common.Hash) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.release()
+
+	log.Info("Rebuilding state snapshot", "root", root)
+	t.layers = map[common.Hash]snapshot{
+		root: generateSnapshot(t.diskdb, t.triedb, t.cache, root),
+	}
+}
+
+// Release stops any running snapshot generation and marks all the layers stale,
+// persisting the generation progress so it can be resumed on the next start.
+func (t *Tree) Release() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.release()
+	t.layers = make(map[common.Hash]snapshot)
+}
+
+// release aborts the running generator and marks all the layers stale. The tree
+// lock is assumed to be held.
+func (t *Tree) release() {
+	for _, layer := range t.layers {
+		switch layer := layer.(type) {
+		case *diskLayer:
+			// If the base layer is generating, abort it and save
+			if layer.genAbort != nil {
+				abort := make(chan []byte)
+				layer.genAbort <- abort
+				<-abort
+			}
+			// Layer should be inactive now, mark it as stale
+			layer.lock.Lock()
+			layer.stale = true
+			layer.lock.Unlock()
+
+		case *diffLayer:
+			// If the layer is a simple diff, simply mark as stale
+			layer.lock.Lock()
+			layer.stale = true
+			layer.lock.Unlock()
+
+		default:
+			panic(fmt.Sprintf("unknown layer type: %T", layer))
+		}
+	}
+}
+
+// disklayer is an internal helper function to return the disk layer.
+// The lock of snapTree is assumed to be held already.
+func (t *Tree) disklayer() *diskLayer {
+	var snap snapshot
+	for _, s := range t.layers {
+		snap = s
+		break
+	}
+	if snap == nil {
+		return nil
+	}
+	for {
+		switch layer := snap.(type) {
+		case *diskLayer:
+			return layer
+		case *diffLayer:
+			snap = layer.Parent()
+		default:
+			panic(fmt.Sprintf("%T: undefined layer", snap))
+		}
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// newTestTree creates a snapshot tree with a fully generated, empty disk layer
+// at the given root.
+func newTestTree(root common.Hash) (*Tree, ethdb.Database) {
+	db, _ := ethdb.NewMemDatabase()
+	writeSnapshotRoot(db, root)
+
+	return New(db, trie.NewDatabase(db), 16, root), db
+}
+
+// Tests that lookups walk down the diff layers, honouring account destructions.
+func TestDiffLayerLookups(t *testing.T) {
+	var (
+		base  = common.HexToHash("0x01")
+		acc   = common.HexToHash("0xa1")
+		other = common.HexToHash("0xa2")
+		slot  = common.HexToHash("0xb1")
+	)
+	tree, db := newTestTree(base)
+	db.Put(accountSnapshotKey(acc), []byte{0x00})
+	db.Put(storageSnapshotKey(acc, slot), []byte{0x00})
+	db.Put(accountSnapshotKey(other), []byte{0x0f})
+
+	// Modify the account, then delete it, then recreate it without storage
+	if err := tree.Update(common.HexToHash("0x02"), base, nil, map[common.Hash][]byte{acc: {0x01}}, map[common.Hash]map[common.Hash][]byte{acc: {slot: {0x01}}}); err != nil {
+		t.Fatalf("failed to create diff layer: %v", err)
+	}
+	if err := tree.Update(common.HexToHash("0x03"), common.HexToHash("0x02"), map[common.Hash]struct{}{acc: {}}, nil, nil); err != nil {
+		t.Fatalf("failed to create diff layer: %v", err)
+	}
+	if err := tree.Update(common.HexToHash("0x04"), common.HexToHash("0x03"), nil, map[common.Hash][]byte{acc: {0x04}}, nil); err != nil {
+		t.Fatalf("failed to create diff layer: %v", err)
+	}
+	tests := []struct {
+		root    common.Hash
+		account []byte
+		storage []byte
+	}{
+		{base, []byte{0x00}, []byte{0x00}},
+		{common.HexToHash("0x02"), []byte{0x01}, []byte{0x01}},
+		{common.HexToHash("0x03"), nil, nil},
+		{common.HexToHash("0x04"), []byte{0x04}, nil},
+	}
+	for i, tt := range tests {
+		snap := tree.Snapshot(tt.root)
+		if snap == nil {
+			t.Fatalf("test %d: snapshot missing", i)
+		}
+		if data, err := snap.Account(acc); err != nil || !bytes.Equal(data, tt.account) {
+			t.Errorf("test %d: account mismatch: have %x/%v, want %x", i, data, err, tt.account)
+		}
+		if data, err := snap.Storage(acc, slot); err != nil || !bytes.Equal(data, tt.storage) {
+			t.Errorf("test %d: storage mismatch: have %x/%v, want %x", i, data, err, tt.storage)
+		}
+		if data, err := snap.Account(other); err != nil || !bytes.Equal(data, []byte{0x0f}) {
+			t.Errorf("test %d: untouched account mismatch: have %x/%v, want %x", i, data, err, []byte{0x0f})
+		}
+	}
+	if err := tree.Update(common.HexToHash("0x04"), common.HexToHash("0x04"), nil, nil, nil); err != errSnapshotCycle {
+		t.Errorf("self referencing update error mismatch: have %v, want %v", err, errSnapshotCycle)
+	}
+}
+
+// Tests that capping the tree flattens the layers beyond the limit, marking the
+// flattened ones stale and dropping them from the tree.
+func TestTreeCap(t *testing.T) {
+	var (
+		base = common.HexToHash("0x01")
+		acc  = common.HexToHash("0xa1")
+	)
+	tree, _ := newTestTree(base)
+
+	parent := base
+	for i := byte(2); i <= 4; i++ {
+		root := common.BytesToHash([]byte{i})
+		if err := tree.Update(root, parent, nil, map[common.Hash][]byte{acc: {i}}, nil); err != nil {
+			t.Fatalf("failed to create diff layer %d: %v", i, err)
+		}
+		parent = root
+	}
+	// Create a sibling of a flattened diff, which must go away with its parent
+	if err := tree.Update(common.HexToHash("0x13"), common.HexToHash("0x02"), nil, map[common.Hash][]byte{acc: {0x13}}, nil); err != nil {
+		t.Fatalf("failed to create sibling layer: %v", err)
+	}
+	bottom := tree.Snapshot(common.HexToHash("0x02"))
+	if n := len(tree.layers); n != 5 {
+		t.Fatalf("layer count mismatch: have %d, want %d", n, 5)
+	}
+	if err := tree.Cap(common.HexToHash("0x04"), 1); err != nil {
+		t.Fatalf("failed to cap tree: %v", err)
+	}
+	if n := len(tree.layers); n != 3 {
+		t.Errorf("layer count mismatch after cap: have %d, want %d", n, 3)
+	}
+	for _, root := range []common.Hash{common.HexToHash("0x02"), common.HexToHash("0x13")} {
+		if tree.Snapshot(root) != nil {
+			t.Errorf("layer %x still present after cap", root)
+		}
+	}
+	if _, err := bottom.Account(acc); err != ErrSnapshotStale {
+		t.Errorf("flattened layer error mismatch: have %v, want %v", err, ErrSnapshotStale)
+	}
+	if data, err := tree.Snapshot(common.HexToHash("0x03")).Account(acc); err != nil || !bytes.Equal(data, []byte{0x03}) {
+		t.Errorf("flattened account mismatch: have %x/%v, want %x", data, err, []byte{0x03})
+	}
+	if data, err := tree.Snapshot(common.HexToHash("0x04")).Account(acc); err != nil || !bytes.Equal(data, []byte{0x04}) {
+		t.Errorf("head account mismatch: have %x/%v, want %x", data, err, []byte{0x04})
+	}
+}
+
+// Tests that fully capping the tree persists the state into the database, from
+// where a new tree can load it.
+func TestTreePersist(t *testing.T) {
+	var (
+		base = common.HexToHash("0x01")
+		head = common.HexToHash("0x03")
+		acc  = common.HexToHash("0xa1")
+		slot = common.HexToHash("0xb1")
+	)
+	tree, db := newTestTree(base)
+	db.Put(accountSnapshotKey(acc), []byte{0x00})
+	db.Put(storageSnapshotKey(acc, slot), []byte{0x00})
+
+	if err := tree.Update(common.HexToHash("0x02"), base, map[common.Hash]struct{}{acc: {}}, nil, nil); err != nil {
+		t.Fatalf("failed to create diff layer: %v", err)
+	}
+	if err := tree.Update(head, common.HexToHash("0x02"), nil, map[common.Hash][]byte{acc: {0x03}}, nil); err != nil {
+		t.Fatalf("failed to create diff layer: %v", err)
+	}
+	if err := tree.Cap(head, 0); err != nil {
+		t.Fatalf("failed to persist tree: %v", err)
+	}
+	tree.Release()
+
+	if root := readSnapshotRoot(db); root != head {
+		t.Fatalf("persisted root mismatch: have %x, want %x", root, head)
+	}
+	if _, generating := readSnapshotGenerator(db); generating {
+		t.Fatalf("persisted snapshot marked as generating")
+	}
+	snap := New(db, trie.NewDatabase(db), 16, head).Snapshot(head)
+	if data, err := snap.Account(acc); err != nil || !bytes.Equal(data, []byte{0x03}) {
+		t.Errorf("account mismatch: have %x/%v, want %x", data, err, []byte{0x03})
+	}
+	if data, err := snap.Storage(acc, slot); err != nil || data != nil {
+		t.Errorf("destructed storage mismatch: have %x/%v, want nil", data, err)
+	}
+}
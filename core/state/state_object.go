@@ -79,11 +79,20 @@ type stateObject struct {
 
 	cachedStorage Storage // Storage entry cache to avoid duplicate reads
 	dirtyStorage  Storage // Storage entries that need to be flushed to disk
+	originStorage Storage // Committed values of dirty storage entries, read for net gas metering
+	prevStorage   Storage // Values of the entries written since the last commit, before their first write
+	fakeStorage   Storage // Storage replacing the persisted one, set to override the state of simulations
+
+	// Snapshot tracking. The storage can only be read from the snapshot as long
+	// as the storage trie is the one the account was loaded with.
+	originRoot  common.Hash            // Storage root the account was loaded with
+	snapStorage map[common.Hash][]byte // Storage entries written into the trie, keyed by slot hash
 
 	// Cache flags.
 	// When an object is marked suicided it will be delete from the trie
 	// during the "update" phase of the state transition.
 	dirtyCode bool // true if the code was updated
+	created   bool // true if the object was (re)created since the last commit
 	suicided  bool
 	touched   bool
 	deleted   bool
@@ -117,8 +126,10 @@ func newObject(db *StateDB, address common.Address, data Account, onDirty func(a
 		address:       address,
 		addrHash:      crypto.Keccak256Hash(address[:]),
 		data:          data,
+		originRoot:    data.Root,
 		cachedStorage: make(Storage),
 		dirtyStorage:  make(Storage),
+		originStorage: make(Storage),
 		onDirty:       onDirty,
 	}
 }
@@ -170,15 +181,58 @@ func (c *stateObject) getTrie(db Database) Trie {
 
 // GetState returns a value in account storage.
 func (self *stateObject) GetState(db Database, key common.Hash) common.Hash {
+	// If the storage is overridden, it's the only one to look at
+	if self.fakeStorage != nil {
+		return self.fakeStorage[key]
+	}
 	value, exists := self.cachedStorage[key]
 	if exists {
 		return value
 	}
-	// Load from DB in case it is missing.
-	enc, err := self.getTrie(db).TryGet(key[:])
-	if err != nil {
-		self.setError(err)
-		return common.Hash{}
+	value = self.loadState(db, key)
+	if (value != common.Hash{}) {
+		self.cachedStorage[key] = value
+	}
+	return value
+}
+
+// GetCommittedState returns a value in account storage as it was at the end of
+// the previous transaction, ignoring the changes made since.
+func (self *stateObject) GetCommittedState(db Database, key common.Hash) common.Hash {
+	if self.fakeStorage != nil {
+		return self.fakeStorage[key]
+	}
+	if _, dirty := self.dirtyStorage[key]; !dirty {
+		return self.GetState(db, key)
+	}
+	value, exists := self.originStorage[key]
+	if exists {
+		return value
+	}
+	value = self.loadState(db, key)
+	self.originStorage[key] = value
+	return value
+}
+
+// loadState reads a value of the committed account storage from the database.
+func (self *stateObject) loadState(db Database, key common.Hash) common.Hash {
+	// Load from the snapshot if the storage is unchanged since the account was
+	// loaded, falling back to the trie if the snapshot can't serve the request.
+	var (
+		value    common.Hash
+		enc      []byte
+		err      error
+		readable = self.db.snap != nil && self.originRoot != (common.Hash{}) && self.data.Root == self.originRoot
+	)
+	if readable {
+		enc, err = self.db.snap.Storage(self.addrHash, crypto.Keccak256Hash(key[:]))
+	}
+	if !readable || err != nil {
+		enc, err = self.getTrie(db).TryGet(key[:])
+		if err != nil {
+			self.setError(err)
+			return common.Hash{}
+		}
 	}
 	if len(enc) > 0 {
 		_, content, _, err := rlp.Split(enc)
@@ -187,23 +241,41 @@ func (self *stateObject) GetState(db Database, key common.Hash) common.Hash {
 		}
 		value.SetBytes(content)
 	}
-	if (value != common.Hash{}) {
-		self.cachedStorage[key] = value
-	}
 	return value
 }
 
 // SetState updates a value in account storage.
 func (self *stateObject) SetState(db Database, key, value common.Hash) {
+	prev := self.GetState(db, key)
 	self.db.journal = append(self.db.journal, storageChange{
 		account:  &self.address,
 		key:      key,
-		prevalue: self.GetState(db, key),
+		prevalue: prev,
 	})
+	// Remember the value before the first write for the state diff of the commit
+	if self.prevStorage == nil {
+		self.prevStorage = make(Storage)
+	}
+	if _, ok := self.prevStorage[key]; !ok {
+		self.prevStorage[key] = prev
+	}
 	self.setState(key, value)
 }
 
+// SetStorage replaces the entire storage of the account with the given one. The
+// replacement is only kept in memory and never written to the database.
+func (self *stateObject) SetStorage(storage map[common.Hash]common.Hash) {
+	self.fakeStorage = make(Storage, len(storage))
+	for key, value := range storage {
+		self.fakeStorage[key] = value
+	}
+}
+
 func (self *stateObject) setState(key, value common.Hash) {
+	if self.fakeStorage != nil {
+		self.fakeStorage[key] = value
+		return
+	}
 	self.cachedStorage[key] = value
 	self.dirtyStorage[key] = value
 
@@ -218,13 +290,23 @@ func (self *stateObject) updateTrie(db Database) Trie {
 	tr := self.getTrie(db)
 	for key, value := range self.dirtyStorage {
 		delete(self.dirtyStorage, key)
+		delete(self.originStorage, key)
+
+		var v []byte
 		if (value == common.Hash{}) {
 			self.setError(tr.TryDelete(key[:]))
-			continue
+		} else {
+			// Encoding []byte cannot fail, ok to ignore the error.
+			v, _ = rlp.EncodeToBytes(bytes.TrimLeft(value[:], "\x00"))
+			self.setError(tr.TryUpdate(key[:], v))
+		}
+		// Track the slot for the snapshot diff of the block
+		if self.db.snap != nil {
+			if self.snapStorage == nil {
+				self.snapStorage = make(map[common.Hash][]byte)
+			}
+			self.snapStorage[crypto.Keccak256Hash(key[:])] = v
 		}
-		// Encoding []byte cannot fail, ok to ignore the error.
-		v, _ := rlp.EncodeToBytes(bytes.TrimLeft(value[:], "\x00"))
-		self.setError(tr.TryUpdate(key[:], v))
 	}
 	return tr
 }
@@ -298,8 +380,23 @@ func (self *stateObject) deepCopy(db *StateDB, onDirty func(addr common.Address)
 		stateObject.trie = db.db.CopyTrie(self.trie)
 	}
 	stateObject.code = self.code
+	stateObject.originRoot = self.originRoot
+	if self.snapStorage != nil {
+		stateObject.snapStorage = make(map[common.Hash][]byte, len(self.snapStorage))
+		for key, data := range self.snapStorage {
+			stateObject.snapStorage[key] = data
+		}
+	}
 	stateObject.dirtyStorage = self.dirtyStorage.Copy()
 	stateObject.cachedStorage = self.dirtyStorage.Copy()
+	stateObject.originStorage = self.originStorage.Copy()
+	if self.prevStorage != nil {
+		stateObject.prevStorage = self.prevStorage.Copy()
+	}
+	if self.fakeStorage != nil {
+		stateObject.fakeStorage = self.fakeStorage.Copy()
+	}
+	stateObject.created = self.created
 	stateObject.suicided = self.suicided
 	stateObject.dirtyCode = self.dirtyCode
 	stateObject.deleted = self.deleted
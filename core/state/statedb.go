@@ -18,12 +18,15 @@
 package state
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"math/big"
 	"sort"
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
@@ -53,6 +56,19 @@ type StateDB struct {
 	db   Database
 	trie Trie
 
+	// Root of the state as of the last commit, the origin of the state diffs.
+	originalRoot common.Hash
+
+	// Flat snapshot of the state the StateDB was opened at, if available, along
+	// with the changes to apply to the snapshot tree. The changes are retained
+	// on commit until they are applied by UpdateSnapshot.
+	snaps         *snapshot.Tree
+	snap          snapshot.Snapshot
+	snapDestructs map[common.Hash]struct{}
+	snapAccounts  map[common.Hash][]byte
+	snapStorage   map[common.Hash]map[common.Hash][]byte
+	snapDiff      *snapshotDiff
+
 	// This map holds 'live' objects, which will get modified while processing a state transition.
 	stateObjects      map[common.Address]*stateObject
 	stateObjectsDirty map[common.Address]struct{}
@@ -85,18 +101,43 @@ type StateDB struct {
 
 // Create a new state from a given trie.
 func New(root common.Hash, db Database) (*StateDB, error) {
+	return NewWithSnapshot(root, db, nil)
+}
+
+// NewWithSnapshot creates a new state from a given trie, reading the accounts and
+// storage slots from the flat snapshot of the state if the snapshot tree has one
+// for the root, and updating the tree with the changes on commit.
+func NewWithSnapshot(root common.Hash, db Database, snaps *snapshot.Tree) (*StateDB, error) {
 	tr, err := db.OpenTrie(root)
 	if err != nil {
 		return nil, err
 	}
-	return &StateDB{
+	sdb := &StateDB{
 		db:                db,
 		trie:              tr,
+		originalRoot:      root,
+		snaps:             snaps,
 		stateObjects:      make(map[common.Address]*stateObject),
 		stateObjectsDirty: make(map[common.Address]struct{}),
 		logs:              make(map[common.Hash][]*types.Log),
 		preimages:         make(map[common.Hash][]byte),
-	}, nil
+	}
+	sdb.openSnapshot(root)
+	return sdb, nil
+}
+
+// openSnapshot picks the snapshot of the given root from the snapshot tree, if
+// there is one, and resets the changes tracked for it.
+func (self *StateDB) openSnapshot(root common.Hash) {
+	self.snap, self.snapDestructs, self.snapAccounts, self.snapStorage = nil, nil, nil, nil
+	if self.snaps == nil {
+		return
+	}
+	if self.snap = self.snaps.Snapshot(root); self.snap != nil {
+		self.snapDestructs = make(map[common.Hash]struct{})
+		self.snapAccounts = make(map[common.Hash][]byte)
+		self.snapStorage = make(map[common.Hash]map[common.Hash][]byte)
+	}
 }
 
 // setError remembers the first non-nil error it is called with.
@@ -118,6 +159,8 @@ func (self *StateDB) Reset(root common.Hash) error {
 		return err
 	}
 	self.trie = tr
+	self.originalRoot = root
+	self.openSnapshot(root)
 	self.stateObjects = make(map[common.Address]*stateObject)
 	self.stateObjectsDirty = make(map[common.Address]struct{})
 	self.thash = common.Hash{}
@@ -173,6 +216,16 @@ func (self *StateDB) AddRefund(gas uint64) {
 	self.refund += gas
 }
 
+// SubRefund removes gas from the refund counter.
+// This method will panic if the refund counter goes below zero
+func (self *StateDB) SubRefund(gas uint64) {
+	self.journal = append(self.journal, refundChange{prev: self.refund})
+	if gas > self.refund {
+		panic("Refund counter below zero")
+	}
+	self.refund -= gas
+}
+
 // Exist reports whether the given account address exists in the state.
 // Notably this also returns true for suicided accounts.
 func (self *StateDB) Exist(addr common.Address) bool {
@@ -243,6 +296,16 @@ func (self *StateDB) GetState(addr common.Address, bhash common.Hash) common.Has
 	return common.Hash{}
 }
 
+// GetCommittedState retrieves a value from the given account's storage as it was
+// at the end of the previous transaction.
+func (self *StateDB) GetCommittedState(addr common.Address, hash common.Hash) common.Hash {
+	stateObject := self.getStateObject(addr)
+	if stateObject != nil {
+		return stateObject.GetCommittedState(self.db, hash)
+	}
+	return common.Hash{}
+}
+
 // Database retrieves the low level database supporting the lower level trie ops.
 func (self *StateDB) Database() Database {
 	return self.db
@@ -259,6 +322,34 @@ func (self *StateDB) StorageTrie(addr common.Address) Trie {
 	return cpy.updateTrie(self.db)
 }
 
+// GetProof returns the Merkle proof of an account in the state trie, ordered
+// from the root node to the account leaf.
+func (self *StateDB) GetProof(addr common.Address) ([][]byte, error) {
+	var proof proofList
+	err := self.trie.Prove(crypto.Keccak256(addr.Bytes()), 0, &proof)
+	return [][]byte(proof), err
+}
+
+// GetStorageProof returns the Merkle proof of a storage slot in the storage trie
+// of an account, ordered from the root node to the slot leaf.
+func (self *StateDB) GetStorageProof(addr common.Address, key common.Hash) ([][]byte, error) {
+	var proof proofList
+	trie := self.StorageTrie(addr)
+	if trie == nil {
+		return proof, errors.New("storage trie for requested address does not exist")
+	}
+	err := trie.Prove(crypto.Keccak256(key.Bytes()), 0, &proof)
+	return [][]byte(proof), err
+}
+
+// proofList collects the nodes of a Merkle proof in insertion order.
+type proofList [][]byte
+
+func (n *proofList) Put(key []byte, value []byte) error {
+	*n = append(*n, value)
+	return nil
+}
+
 func (self *StateDB) HasSuicided(addr common.Address) bool {
 	stateObject := self.getStateObject(addr)
 	if stateObject != nil {
@@ -315,6 +406,16 @@ func (self *StateDB) SetState(addr common.Address, key, value common.Hash) {
 	}
 }
 
+// SetStorage replaces the entire storage of the account with the given one,
+// leaving the account itself untouched. The replacement is never written to the
+// database, it's meant to override the state of simulated calls.
+func (self *StateDB) SetStorage(addr common.Address, storage map[common.Hash]common.Hash) {
+	stateObject := self.GetOrNewStateObject(addr)
+	if stateObject != nil {
+		stateObject.SetStorage(storage)
+	}
+}
+
 // Suicide marks the given account as suicided.
 // This clears the account balance.
 //
@@ -348,6 +449,11 @@ func (self *StateDB) updateStateObject(stateObject *stateObject) {
 		panic(fmt.Errorf("can't encode object at %x: %v", addr[:], err))
 	}
 	self.setError(self.trie.TryUpdate(addr[:], data))
+
+	// Track the account for the snapshot diff of the block
+	if self.snap != nil {
+		self.snapAccounts[stateObject.addrHash] = data
+	}
 }
 
 // deleteStateObject removes the given object from the state trie.
@@ -355,6 +461,13 @@ func (self *StateDB) deleteStateObject(stateObject *stateObject) {
 	stateObject.deleted = true
 	addr := stateObject.Address()
 	self.setError(self.trie.TryDelete(addr[:]))
+
+	// Track the deletion, along with the storage, for the snapshot diff of the block
+	if self.snap != nil {
+		self.snapDestructs[stateObject.addrHash] = struct{}{}
+		delete(self.snapAccounts, stateObject.addrHash)
+		delete(self.snapStorage, stateObject.addrHash)
+	}
 }
 
 // DeleteAddress removes the address from the state trie.
@@ -375,8 +488,18 @@ func (self *StateDB) getStateObject(addr common.Address) (stateObject *stateObje
 		return obj
 	}
 
-	// Load the object from the database.
-	enc, err := self.trie.TryGet(addr[:])
+	// Load the object from the snapshot if available, falling back to the trie
+	// if the snapshot can't serve the request.
+	var (
+		enc []byte
+		err error
+	)
+	if self.snap != nil {
+		enc, err = self.snap.Account(crypto.Keccak256Hash(addr[:]))
+	}
+	if self.snap == nil || err != nil {
+		enc, err = self.trie.TryGet(addr[:])
+	}
 	if len(enc) == 0 {
 		self.setError(err)
 		return nil
@@ -415,12 +538,22 @@ func (self *StateDB) MarkStateObjectDirty(addr common.Address) {
 // the given address, it is overwritten and returned as the second return value.
 func (self *StateDB) createObject(addr common.Address) (newobj, prev *stateObject) {
 	prev = self.getStateObject(addr)
+
+	// An overwritten account loses its storage, which the snapshot must drop too
+	var prevdestruct bool
+	if self.snap != nil && prev != nil {
+		_, prevdestruct = self.snapDestructs[prev.addrHash]
+		if !prevdestruct {
+			self.snapDestructs[prev.addrHash] = struct{}{}
+		}
+	}
 	newobj = newObject(self, addr, Account{}, self.MarkStateObjectDirty)
 	newobj.setNonce(0) // sets the object to dirty
+	newobj.created = true
 	if prev == nil {
 		self.journal = append(self.journal, createObjectChange{account: &addr})
 	} else {
-		self.journal = append(self.journal, resetObjectChange{prev: prev})
+		self.journal = append(self.journal, resetObjectChange{prev: prev, prevdestruct: prevdestruct})
 	}
 	self.setStateObject(newobj)
 	return newobj, prev
@@ -474,6 +607,9 @@ func (self *StateDB) Copy() *StateDB {
 	state := &StateDB{
 		db:                self.db,
 		trie:              self.db.CopyTrie(self.trie),
+		originalRoot:      self.originalRoot,
+		snaps:             self.snaps,
+		snap:              self.snap,
 		stateObjects:      make(map[common.Address]*stateObject, len(self.stateObjectsDirty)),
 		stateObjectsDirty: make(map[common.Address]struct{}, len(self.stateObjectsDirty)),
 		refund:            self.refund,
@@ -493,6 +629,25 @@ func (self *StateDB) Copy() *StateDB {
 	for hash, preimage := range self.preimages {
 		state.preimages[hash] = preimage
 	}
+	if self.snap != nil {
+		// The snapshot itself is immutable, only the tracked changes are copied.
+		// The blobs are never modified in place, so they can be shared.
+		state.snapDestructs = make(map[common.Hash]struct{}, len(self.snapDestructs))
+		for hash := range self.snapDestructs {
+			state.snapDestructs[hash] = struct{}{}
+		}
+		state.snapAccounts = make(map[common.Hash][]byte, len(self.snapAccounts))
+		for hash, data := range self.snapAccounts {
+			state.snapAccounts[hash] = data
+		}
+		state.snapStorage = make(map[common.Hash]map[common.Hash][]byte, len(self.snapStorage))
+		for hash, storage := range self.snapStorage {
+			state.snapStorage[hash] = make(map[common.Hash][]byte, len(storage))
+			for key, data := range storage {
+				state.snapStorage[hash][key] = data
+			}
+		}
+	}
 	return state
 }
 
@@ -530,6 +685,19 @@ func (self *StateDB) GetRefund() uint64 {
 	return self.refund
 }
 
+// DirtyAccounts returns the sorted addresses of the accounts modified since the
+// state was last committed.
+func (s *StateDB) DirtyAccounts() []common.Address {
+	addrs := make([]common.Address, 0, len(s.stateObjectsDirty))
+	for addr := range s.stateObjectsDirty {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i][:], addrs[j][:]) < 0
+	})
+	return addrs
+}
+
 // Finalise finalises the state by removing the self destructed objects
 // and clears the journal as well as the refunds.
 func (s *StateDB) Finalise(deleteEmptyObjects bool) {
@@ -591,8 +759,29 @@ func (s *StateDB) clearJournalAndRefund() {
 
 // Commit writes the state to the underlying in-memory trie database.
 func (s *StateDB) Commit(deleteEmptyObjects bool) (root common.Hash, err error) {
+	root, _, err = s.commit(deleteEmptyObjects, false)
+	return root, err
+}
+
+// CommitWithDiff writes the state to the underlying in-memory trie database, like
+// Commit, and additionally returns the changes made to the state since it was
+// opened or last committed.
+func (s *StateDB) CommitWithDiff(deleteEmptyObjects bool) (common.Hash, *StateDiff, error) {
+	return s.commit(deleteEmptyObjects, true)
+}
+
+func (s *StateDB) commit(deleteEmptyObjects bool, withDiff bool) (root common.Hash, diff *StateDiff, err error) {
 	defer s.clearJournalAndRefund()
 
+	// Gather the changed accounts before the dirty markers are cleared
+	var changed []common.Address
+	if withDiff {
+		for addr, stateObject := range s.stateObjects {
+			if _, isDirty := s.stateObjectsDirty[addr]; isDirty || stateObject.suicided {
+				changed = append(changed, addr)
+			}
+		}
+	}
 	// Commit objects to the trie.
 	for addr, stateObject := range s.stateObjects {
 		_, isDirty := s.stateObjectsDirty[addr]
@@ -609,7 +798,7 @@ func (s *StateDB) Commit(deleteEmptyObjects bool) (root common.Hash, err error)
 			}
 			// Write any storage changes in the state object to its storage trie.
 			if err := stateObject.CommitTrie(s.db); err != nil {
-				return common.Hash{}, err
+				return common.Hash{}, nil, err
 			}
 			// Update the object in the main account trie.
 			s.updateStateObject(stateObject)
@@ -632,5 +821,61 @@ func (s *StateDB) Commit(deleteEmptyObjects bool) (root common.Hash, err error)
 		return nil
 	})
 	log.Debug("Trie cache stats after commit", "misses", trie.CacheMisses(), "unloads", trie.CacheUnloads())
-	return root, err
+
+	// Compute the changes since the previous commit, which becomes this one
+	if err == nil && withDiff {
+		diff, err = s.stateDiff(changed)
+	}
+	if err == nil {
+		for _, stateObject := range s.stateObjects {
+			stateObject.created = false
+			stateObject.prevStorage = nil
+		}
+		s.originalRoot = root
+	}
+
+	// If snapshotting is enabled, retain the changes for the snapshot tree
+	if err == nil && s.snap != nil {
+		// Only update if there's a state transition (skip empty blocks)
+		if parent := s.snap.Root(); parent != root {
+			for _, stateObject := range s.stateObjects {
+				if !stateObject.deleted && len(stateObject.snapStorage) > 0 {
+					s.snapStorage[stateObject.addrHash] = stateObject.snapStorage
+				}
+				stateObject.snapStorage = nil
+			}
+			s.snapDiff = &snapshotDiff{
+				root:      root,
+				parent:    parent,
+				destructs: s.snapDestructs,
+				accounts:  s.snapAccounts,
+				storage:   s.snapStorage,
+			}
+		}
+		s.snap, s.snapDestructs, s.snapAccounts, s.snapStorage = nil, nil, nil, nil
+	}
+	return root, diff, err
+}
+
+// snapshotDiff is the flat state change of a commit, pending to be applied to
+// the snapshot tree.
+type snapshotDiff struct {
+	root      common.Hash
+	parent    common.Hash
+	destructs map[common.Hash]struct{}
+	accounts  map[common.Hash][]byte
+	storage   map[common.Hash]map[common.Hash][]byte
+}
+
+// UpdateSnapshot applies the changes of the last commit to the snapshot tree as
+// a new diff layer on top of the state the StateDB was opened at. It is a no-op
+// if the StateDB has no snapshot or the commit didn't change the state. The tree
+// is never capped, that is left to the owner of the tree.
+func (s *StateDB) UpdateSnapshot() error {
+	diff := s.snapDiff
+	if diff == nil {
+		return nil
+	}
+	s.snapDiff = nil
+	return s.snaps.Update(diff.root, diff.parent, diff.destructs, diff.accounts, diff.storage)
 }
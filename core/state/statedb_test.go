@@ -19,6 +19,7 @@ package state
 import (
 	"bytes"
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"math"
 	"math/big"
@@ -27,12 +28,17 @@ import (
 	"strings"
 	"testing"
 	"testing/quick"
+	"time"
 
 	check "gopkg.in/check.v1"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
 )
 
 // Tests that updating a state trie does not leak any database writes prior to
@@ -421,3 +427,319 @@ func (s *StateSuite) TestTouchDelete(c *check.C) {
 		c.Fatal("expected no dirty state object")
 	}
 }
+
+// proofDatabase indexes the nodes of a Merkle proof by their hash.
+func proofDatabase(proof [][]byte) *ethdb.MemDatabase {
+	db, _ := ethdb.NewMemDatabase()
+	for _, node := range proof {
+		db.Put(crypto.Keccak256(node), node)
+	}
+	return db
+}
+
+// Tests that account and storage proofs verify against the state and storage
+// roots, both for existing and missing entries.
+func TestStateProofs(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	state, _ := New(common.Hash{}, NewDatabase(db))
+
+	addr := common.HexToAddress("0x1000000000000000000000000000000000000001")
+	state.SetBalance(addr, big.NewInt(42))
+	state.SetNonce(addr, 7)
+	state.SetState(addr, common.HexToHash("0x01"), common.HexToHash("0xff"))
+	for i := byte(0); i < 16; i++ {
+		state.SetState(common.BytesToAddress([]byte{i}), common.HexToHash("0x02"), common.HexToHash("0x03"))
+	}
+	root, _ := state.Commit(false)
+	state, _ = New(root, state.Database())
+
+	// Verify the account proof and the account it proves
+	proof, err := state.GetProof(addr)
+	if err != nil {
+		t.Fatalf("failed to create account proof: %v", err)
+	}
+	blob, err, _ := trie.VerifyProof(root, crypto.Keccak256(addr.Bytes()), proofDatabase(proof))
+	if err != nil {
+		t.Fatalf("failed to verify account proof: %v", err)
+	}
+	var account Account
+	if err := rlp.DecodeBytes(blob, &account); err != nil {
+		t.Fatalf("failed to decode proven account: %v", err)
+	}
+	if account.Nonce != 7 || account.Balance.Cmp(big.NewInt(42)) != 0 {
+		t.Fatalf("proven account mismatch: have %d/%v, want 7/42", account.Nonce, account.Balance)
+	}
+	// Verify proofs of an existing and a missing storage slot
+	for _, key := range []common.Hash{common.HexToHash("0x01"), common.HexToHash("0x05")} {
+		proof, err := state.GetStorageProof(addr, key)
+		if err != nil {
+			t.Fatalf("slot %x: failed to create storage proof: %v", key, err)
+		}
+		blob, err, _ := trie.VerifyProof(account.Root, crypto.Keccak256(key.Bytes()), proofDatabase(proof))
+		if err != nil {
+			t.Fatalf("slot %x: failed to verify storage proof: %v", key, err)
+		}
+		var value []byte
+		if len(blob) > 0 {
+			rlp.DecodeBytes(blob, &value)
+		}
+		if have, want := common.BytesToHash(value), state.GetState(addr, key); have != want {
+			t.Errorf("slot %x: proven value mismatch: have %x, want %x", key, have, want)
+		}
+	}
+	// Missing accounts can be proven absent, but have no storage to prove
+	missing := common.HexToAddress("0x2000000000000000000000000000000000000002")
+	proof, err = state.GetProof(missing)
+	if err != nil {
+		t.Fatalf("failed to create absence proof: %v", err)
+	}
+	if blob, err, _ := trie.VerifyProof(root, crypto.Keccak256(missing.Bytes()), proofDatabase(proof)); err != nil || blob != nil {
+		t.Fatalf("absence proof mismatch: have %x/%v, want nil", blob, err)
+	}
+	if _, err := state.GetStorageProof(missing, common.Hash{}); err == nil {
+		t.Fatalf("storage proof created for missing account")
+	}
+}
+
+// Tests that the states read through the snapshot tree match the ones read from
+// the tries, across account modifications, deletions and resurrections.
+func TestSnapshotReads(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	sdb := NewDatabase(db)
+
+	addrs := make([]common.Address, 8)
+	for i := range addrs {
+		addrs[i] = common.BytesToAddress([]byte{byte(i + 1)})
+	}
+	keys := []common.Hash{common.HexToHash("0x01"), common.HexToHash("0x02"), common.HexToHash("0x03")}
+
+	state, _ := New(common.Hash{}, sdb)
+	for i, addr := range addrs {
+		state.SetBalance(addr, big.NewInt(int64(i+1)))
+		state.SetState(addr, keys[0], common.BytesToHash([]byte{byte(i + 1)}))
+		state.SetState(addr, keys[1], common.BytesToHash([]byte{byte(i + 1), 1}))
+	}
+	root, _ := state.Commit(false)
+	snaps := snapshot.New(db, sdb.TrieDB(), 16, root)
+
+	// Wait for the snapshot generation, otherwise the reads fall back to the trie
+	for i := 0; ; i++ {
+		if _, err := snaps.Snapshot(root).Account(common.BytesToHash(bytes.Repeat([]byte{0xff}, common.HashLength))); err == nil {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("snapshot generation timed out")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Apply a few blocks on top through the snapshot, modifying the storage,
+	// deleting accounts and resurrecting them with fresh storage
+	blocks := []func(state *StateDB){
+		func(state *StateDB) {
+			state.SetState(addrs[0], keys[0], common.Hash{})
+			state.SetState(addrs[1], keys[2], common.HexToHash("0xff"))
+			state.Suicide(addrs[2])
+			state.Suicide(addrs[3])
+		},
+		func(state *StateDB) {
+			state.CreateAccount(addrs[2])
+			state.SetState(addrs[2], keys[2], common.HexToHash("0xfe"))
+			state.AddBalance(addrs[4], big.NewInt(100))
+			state.CreateAccount(addrs[5])
+			state.SetState(addrs[5], keys[1], common.HexToHash("0xfd"))
+		},
+	}
+	for i, block := range blocks {
+		state, err := NewWithSnapshot(root, sdb, snaps)
+		if err != nil {
+			t.Fatalf("block %d: failed to open state: %v", i, err)
+		}
+		block(state)
+		if root, err = state.Commit(true); err != nil {
+			t.Fatalf("block %d: failed to commit state: %v", i, err)
+		}
+		if snaps.Snapshot(root) != nil {
+			t.Fatalf("block %d: snapshot updated before being requested", i)
+		}
+		if err := state.UpdateSnapshot(); err != nil {
+			t.Fatalf("block %d: failed to update snapshot: %v", i, err)
+		}
+		if snaps.Snapshot(root) == nil {
+			t.Fatalf("block %d: snapshot missing for root %x", i, root)
+		}
+		snapState, _ := NewWithSnapshot(root, sdb, snaps)
+		trieState, _ := New(root, sdb)
+		for _, addr := range addrs {
+			if have, want := snapState.Exist(addr), trieState.Exist(addr); have != want {
+				t.Errorf("block %d: account %x existence mismatch: have %v, want %v", i, addr, have, want)
+			}
+			if have, want := snapState.GetBalance(addr), trieState.GetBalance(addr); have.Cmp(want) != 0 {
+				t.Errorf("block %d: account %x balance mismatch: have %v, want %v", i, addr, have, want)
+			}
+			for _, key := range keys {
+				if have, want := snapState.GetState(addr, key), trieState.GetState(addr, key); have != want {
+					t.Errorf("block %d: account %x slot %x mismatch: have %x, want %x", i, addr, key, have, want)
+				}
+			}
+		}
+	}
+}
+
+// Tests that committing with a diff reports the changes made to the accounts and
+// their storage since the previous commit.
+func TestStateDiff(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	sdb := NewDatabase(db)
+
+	var (
+		modified  = common.HexToAddress("0x01")
+		destroyed = common.HexToAddress("0x02")
+		recreated = common.HexToAddress("0x03")
+		created   = common.HexToAddress("0x04")
+		untouched = common.HexToAddress("0x05")
+		k1, k2    = common.HexToHash("0x01"), common.HexToHash("0x02")
+	)
+	state, _ := New(common.Hash{}, sdb)
+	state.SetBalance(modified, big.NewInt(1))
+	state.SetState(modified, k1, common.HexToHash("0x11"))
+	state.SetState(modified, k2, common.HexToHash("0x12"))
+	state.SetBalance(destroyed, big.NewInt(2))
+	state.SetCode(recreated, []byte{0x60})
+	state.SetState(recreated, k1, common.HexToHash("0x31"))
+	state.SetBalance(untouched, big.NewInt(5))
+	root, _ := state.Commit(false)
+
+	state, _ = New(root, sdb)
+	state.AddBalance(modified, big.NewInt(10))
+	state.SetState(modified, k1, common.HexToHash("0x21"))
+	state.SetState(modified, k2, common.Hash{})
+	state.Suicide(destroyed)
+	state.CreateAccount(recreated)
+	state.SetBalance(recreated, big.NewInt(3))
+	state.SetState(recreated, k2, common.HexToHash("0x32"))
+	state.SetNonce(created, 1)
+	state.GetBalance(untouched)
+	state.AddBalance(untouched, new(big.Int))
+
+	_, diff, err := state.CommitWithDiff(true)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	want := []*AccountDiff{
+		{
+			Address: modified, Existed: true, Exists: true,
+			BalanceFrom: big.NewInt(1), BalanceTo: big.NewInt(11),
+			CodeHashFrom: emptyCode, CodeHashTo: emptyCode,
+			Storage: []*StorageDiff{
+				{Key: k1, From: common.HexToHash("0x11"), To: common.HexToHash("0x21")},
+				{Key: k2, From: common.HexToHash("0x12"), To: common.Hash{}},
+			},
+		},
+		{
+			Address: destroyed, Existed: true, Destructed: true,
+			BalanceFrom: big.NewInt(2), BalanceTo: new(big.Int),
+			CodeHashFrom: emptyCode, CodeHashTo: emptyCode,
+		},
+		{
+			Address: recreated, Existed: true, Exists: true, Destructed: true,
+			BalanceFrom: new(big.Int), BalanceTo: big.NewInt(3),
+			CodeHashFrom: crypto.Keccak256Hash([]byte{0x60}), CodeHashTo: emptyCode,
+			Storage: []*StorageDiff{
+				{Key: k2, From: common.Hash{}, To: common.HexToHash("0x32")},
+			},
+		},
+		{
+			Address: created, Exists: true,
+			BalanceFrom: new(big.Int), BalanceTo: new(big.Int), NonceTo: 1,
+			CodeHashFrom: emptyCode, CodeHashTo: emptyCode,
+		},
+	}
+	have, _ := json.MarshalIndent(diff.Accounts, "", "  ")
+	expect, _ := json.MarshalIndent(want, "", "  ")
+	if !bytes.Equal(have, expect) {
+		t.Fatalf("state diff mismatch:\nhave %s\nwant %s", have, expect)
+	}
+}
+
+// Tests that the changes made since the last finalisation are rebuilt from the
+// journal without committing, the ones of previous transactions being left out,
+// and that overriding the storage of an account keeps the account itself.
+func TestFinaliseDiff(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	sdb := NewDatabase(db)
+
+	var (
+		modified   = common.HexToAddress("0x01")
+		destroyed  = common.HexToAddress("0x02")
+		overridden = common.HexToAddress("0x03")
+		created    = common.HexToAddress("0x04")
+		k1, k2     = common.HexToHash("0x01"), common.HexToHash("0x02")
+	)
+	state, _ := New(common.Hash{}, sdb)
+	state.SetBalance(modified, big.NewInt(1))
+	state.SetState(modified, k1, common.HexToHash("0x11"))
+	state.SetBalance(destroyed, big.NewInt(2))
+	state.SetCode(overridden, []byte{0x60})
+	state.SetState(overridden, k1, common.HexToHash("0x31"))
+	root, _ := state.Commit(false)
+
+	// Change the state in a first transaction, which must not be reported
+	state, _ = New(root, sdb)
+	state.AddBalance(modified, big.NewInt(4))
+	state.SetState(modified, k1, common.HexToHash("0x21"))
+	state.SetStorage(overridden, map[common.Hash]common.Hash{k2: common.HexToHash("0x32")})
+	state.Finalise(true)
+
+	if value := state.GetState(overridden, k1); value != (common.Hash{}) {
+		t.Errorf("overridden storage slot not cleared: have %x", value)
+	}
+	if code := state.GetCode(overridden); !bytes.Equal(code, []byte{0x60}) {
+		t.Errorf("overridden code mismatch: have %x, want %x", code, []byte{0x60})
+	}
+	state.AddBalance(modified, big.NewInt(10))
+	state.AddBalance(modified, big.NewInt(20))
+	state.SetState(modified, k2, common.HexToHash("0x22"))
+	state.Suicide(destroyed)
+	state.SetState(overridden, k2, common.HexToHash("0x33"))
+	state.SetNonce(created, 1)
+
+	// Reverted changes must not be reported
+	snapshot := state.Snapshot()
+	state.SetState(modified, k1, common.HexToHash("0x99"))
+	state.RevertToSnapshot(snapshot)
+
+	want := []*AccountDiff{
+		{
+			Address: modified, Existed: true, Exists: true,
+			BalanceFrom: big.NewInt(5), BalanceTo: big.NewInt(35),
+			CodeHashFrom: emptyCode, CodeHashTo: emptyCode,
+			Storage: []*StorageDiff{
+				{Key: k2, From: common.Hash{}, To: common.HexToHash("0x22")},
+			},
+		},
+		{
+			Address: destroyed, Existed: true, Destructed: true,
+			BalanceFrom: big.NewInt(2), BalanceTo: new(big.Int),
+			CodeHashFrom: emptyCode, CodeHashTo: emptyCode,
+		},
+		{
+			Address: overridden, Existed: true, Exists: true,
+			BalanceFrom: new(big.Int), BalanceTo: new(big.Int),
+			CodeHashFrom: crypto.Keccak256Hash([]byte{0x60}), CodeHashTo: crypto.Keccak256Hash([]byte{0x60}),
+			Storage: []*StorageDiff{
+				{Key: k2, From: common.HexToHash("0x32"), To: common.HexToHash("0x33")},
+			},
+		},
+		{
+			Address: created, Exists: true,
+			BalanceFrom: new(big.Int), BalanceTo: new(big.Int), NonceTo: 1,
+			CodeHashFrom: emptyCode, CodeHashTo: emptyCode,
+		},
+	}
+	have, _ := json.MarshalIndent(state.FinaliseDiff(true).Accounts, "", "  ")
+	expect, _ := json.MarshalIndent(want, "", "  ")
+	if !bytes.Equal(have, expect) {
+		t.Fatalf("state diff mismatch:\nhave %s\nwant %s", have, expect)
+	}
+}
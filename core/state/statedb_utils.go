@@ -35,6 +35,42 @@ func GetSigners(statedb *StateDB, block *types.Block) []common.Address {
 	return rets
 }
 
+// ClearBlockSigners deletes the sign records of a block number from the storage
+// of the BlockSigners contract, both the signed block hashes and the signers of
+// each of them. It returns the number of storage slots cleared.
+func ClearBlockSigners(statedb *StateDB, number uint64) int {
+	var (
+		addr     = common.HexToAddress(common.BlockSigners)
+		cleared  = 0
+		hashSlot = common.BigToHash(GetLocMappingAtKey(common.BigToHash(new(big.Int).SetUint64(number)), slotBlockSignerMapping["blocks"]))
+	)
+	clear := func(key common.Hash) {
+		if statedb.GetState(addr, key) != (common.Hash{}) {
+			statedb.SetState(addr, key, common.Hash{})
+			cleared++
+		}
+	}
+	// Every sign transaction pushes the block hash again, clear each only once
+	hashes := statedb.GetState(addr, hashSlot).Big().Uint64()
+	seen := make(map[common.Hash]bool)
+	for i := uint64(0); i < hashes; i++ {
+		key := GetLocDynamicArrAtElement(hashSlot, i, 1)
+		if hash := statedb.GetState(addr, key); !seen[hash] {
+			seen[hash] = true
+
+			signerSlot := common.BigToHash(GetLocMappingAtKey(hash, slotBlockSignerMapping["blockSigners"]))
+			signers := statedb.GetState(addr, signerSlot).Big().Uint64()
+			for j := uint64(0); j < signers; j++ {
+				clear(GetLocDynamicArrAtElement(signerSlot, j, 1))
+			}
+			clear(signerSlot)
+		}
+		clear(key)
+	}
+	clear(hashSlot)
+	return cleared
+}
+
 var (
 	slotRandomizeMapping = map[string]uint64{
 		"randomSecret":  0,
@@ -108,12 +144,18 @@ func GetCandidateOwner(statedb *StateDB, candidate common.Address) common.Addres
 }
 
 func GetCandidateCap(statedb *StateDB, candidate common.Address) *big.Int {
+	ret := statedb.GetState(common.HexToAddress(common.MasternodeVotingSMC), CandidateCapSlot(candidate))
+	return ret.Big()
+}
+
+// CandidateCapSlot returns the storage slot of the validator contract holding the
+// cap of a candidate.
+func CandidateCapSlot(candidate common.Address) common.Hash {
 	slot := slotValidatorMapping["validatorsState"]
 	// validatorsState[_candidate].cap;
 	locValidatorsState := GetLocMappingAtKey(candidate.Hash(), slot)
 	locCandidateCap := locValidatorsState.Add(locValidatorsState, new(big.Int).SetUint64(uint64(1)))
-	ret := statedb.GetState(common.HexToAddress(common.MasternodeVotingSMC), common.BigToHash(locCandidateCap))
-	return ret.Big()
+	return common.BigToHash(locCandidateCap)
 }
 
 func GetVoters(statedb *StateDB, candidate common.Address) []common.Address {
@@ -136,10 +178,17 @@ func GetVoters(statedb *StateDB, candidate common.Address) []common.Address {
 }
 
 func GetVoterCap(statedb *StateDB, candidate, voter common.Address) *big.Int {
+	ret := statedb.GetState(common.HexToAddress(common.MasternodeVotingSMC), VoterCapSlot(candidate, voter))
+	return ret.Big()
+}
+
+// VoterCapSlot returns the storage slot of the validator contract holding the cap
+// a voter staked on a candidate.
+func VoterCapSlot(candidate, voter common.Address) common.Hash {
 	slot := slotValidatorMapping["validatorsState"]
+	// validatorsState[_candidate].voters[_voter];
 	locValidatorsState := GetLocMappingAtKey(candidate.Hash(), slot)
 	locCandidateVoters := locValidatorsState.Add(locValidatorsState, new(big.Int).SetUint64(uint64(2)))
 	retByte := crypto.Keccak256(voter.Hash().Bytes(), common.BigToHash(locCandidateVoters).Bytes())
-	ret := statedb.GetState(common.HexToAddress(common.MasternodeVotingSMC), common.BytesToHash(retByte))
-	return ret.Big()
+	return common.BytesToHash(retByte)
 }
@@ -0,0 +1,76 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// signBlock records a sign transaction in the BlockSigners contract storage the
+// same way the contract's sign method does.
+func signBlock(statedb *StateDB, number uint64, hash common.Hash, signer common.Address) {
+	addr := common.HexToAddress(common.BlockSigners)
+	push := func(slot common.Hash, value common.Hash) {
+		length := statedb.GetState(addr, slot).Big().Uint64()
+		statedb.SetState(addr, GetLocDynamicArrAtElement(slot, length, 1), value)
+		statedb.SetState(addr, slot, common.BigToHash(new(big.Int).SetUint64(length+1)))
+	}
+	push(common.BigToHash(GetLocMappingAtKey(common.BigToHash(new(big.Int).SetUint64(number)), slotBlockSignerMapping["blocks"])), hash)
+	push(common.BigToHash(GetLocMappingAtKey(hash, slotBlockSignerMapping["blockSigners"])), signer.Hash())
+}
+
+// Tests that clearing the sign records of a block number removes the signers
+// of all its signed hashes, while leaving other blocks untouched.
+func TestClearBlockSigners(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := New(common.Hash{}, NewDatabase(db))
+
+	var (
+		block  = types.NewBlockWithHeader(&types.Header{Number: big.NewInt(10)})
+		fork   = types.NewBlockWithHeader(&types.Header{Number: big.NewInt(10), Extra: []byte{0x01}})
+		other  = types.NewBlockWithHeader(&types.Header{Number: big.NewInt(11)})
+		alice  = common.HexToAddress("0x0000000000000000000000000000000000000a11")
+		bob    = common.HexToAddress("0x0000000000000000000000000000000000000b0b")
+		signer = []common.Address{alice, bob}
+	)
+	for _, addr := range signer {
+		signBlock(statedb, 10, block.Hash(), addr)
+		signBlock(statedb, 11, other.Hash(), addr)
+	}
+	signBlock(statedb, 10, fork.Hash(), alice)
+
+	// 3 block hashes and the length, 2+1 signers and the lengths
+	if cleared := ClearBlockSigners(statedb, 10); cleared != 9 {
+		t.Errorf("cleared slots mismatch: have %d, want %d", cleared, 9)
+	}
+	for _, b := range []*types.Block{block, fork} {
+		if signers := GetSigners(statedb, b); len(signers) != 0 {
+			t.Errorf("block %x: signers not cleared: %v", b.Hash(), signers)
+		}
+	}
+	if signers := GetSigners(statedb, other); len(signers) != 2 {
+		t.Errorf("unrelated signers cleared: have %v, want %v", signers, signer)
+	}
+	if cleared := ClearBlockSigners(statedb, 10); cleared != 0 {
+		t.Errorf("cleared slots on second run: have %d, want 0", cleared)
+	}
+}
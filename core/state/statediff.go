@@ -0,0 +1,267 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// StateDiff is the set of account and storage changes made to the state between
+// two commits, typically by a block.
+type StateDiff struct {
+	Accounts []*AccountDiff // Changed accounts, sorted by address
+}
+
+// AccountDiff is the change of a single account, with the values of the fields
+// before and after the change.
+//
+// The storage of a destructed account is wiped. Only the slots accessed after the
+// account was recreated are listed (with their original values), all the others
+// are implicitly cleared.
+type AccountDiff struct {
+	Address    common.Address
+	Existed    bool // Whether the account existed before the change
+	Exists     bool // Whether the account exists after the change
+	Destructed bool // Whether the account was deleted (and potentially recreated)
+
+	BalanceFrom  *big.Int
+	BalanceTo    *big.Int
+	NonceFrom    uint64
+	NonceTo      uint64
+	CodeHashFrom common.Hash
+	CodeHashTo   common.Hash
+	Code         []byte         // New code of the account, only set if the code changed
+	Storage      []*StorageDiff // Changed storage slots, sorted by key
+}
+
+// StorageDiff is the change of a single storage slot.
+type StorageDiff struct {
+	Key  common.Hash
+	From common.Hash
+	To   common.Hash
+}
+
+// stateDiff computes the changes made to the given accounts since the state was
+// last committed, comparing them with the values in the original state trie. It
+// must be called after the changes are written into the state objects.
+func (s *StateDB) stateDiff(addrs []common.Address) (*StateDiff, error) {
+	origin, err := s.db.OpenTrie(s.originalRoot)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i][:], addrs[j][:]) < 0
+	})
+
+	diff := new(StateDiff)
+	for _, addr := range addrs {
+		obj := s.stateObjects[addr]
+
+		// Retrieve the account as it was before the changes
+		var prev Account
+		enc, err := origin.TryGet(addr[:])
+		if err != nil {
+			return nil, err
+		}
+		if len(enc) > 0 {
+			if err := rlp.DecodeBytes(enc, &prev); err != nil {
+				return nil, err
+			}
+		}
+		account := &AccountDiff{
+			Address:      addr,
+			Existed:      len(enc) > 0,
+			Exists:       !obj.deleted,
+			BalanceFrom:  new(big.Int),
+			BalanceTo:    new(big.Int),
+			CodeHashFrom: emptyCode,
+			CodeHashTo:   emptyCode,
+		}
+		account.Destructed = account.Existed && (obj.deleted || obj.created)
+		if account.Existed {
+			account.BalanceFrom, account.NonceFrom = prev.Balance, prev.Nonce
+			account.CodeHashFrom = common.BytesToHash(prev.CodeHash)
+		}
+		if account.Exists {
+			account.BalanceTo, account.NonceTo = new(big.Int).Set(obj.data.Balance), obj.data.Nonce
+			account.CodeHashTo = common.BytesToHash(obj.data.CodeHash)
+		}
+		if account.CodeHashFrom != account.CodeHashTo {
+			account.Code = common.CopyBytes(obj.code)
+		}
+		// Compare the written storage slots with the original ones, the storage
+		// of deleted accounts being all cleared. The original values are tracked
+		// on write, only recreated accounts need them read from the old trie.
+		if account.Exists && len(obj.prevStorage) > 0 {
+			var storage Trie
+			if account.Destructed && prev.Root != emptyState {
+				if storage, err = s.db.OpenStorageTrie(obj.addrHash, prev.Root); err != nil {
+					return nil, err
+				}
+			}
+			for key, original := range obj.prevStorage {
+				if account.Destructed {
+					original = common.Hash{}
+				}
+				if storage != nil {
+					enc, err := storage.TryGet(key[:])
+					if err != nil {
+						return nil, err
+					}
+					if len(enc) > 0 {
+						_, content, _, err := rlp.Split(enc)
+						if err != nil {
+							return nil, err
+						}
+						original.SetBytes(content)
+					}
+				}
+				if value := obj.GetState(s.db, key); original != value {
+					account.Storage = append(account.Storage, &StorageDiff{Key: key, From: original, To: value})
+				}
+			}
+			sort.Slice(account.Storage, func(i, j int) bool {
+				return bytes.Compare(account.Storage[i].Key[:], account.Storage[j].Key[:]) < 0
+			})
+		}
+		if account.changed() {
+			diff.Accounts = append(diff.Accounts, account)
+		}
+	}
+	return diff, nil
+}
+
+// journalOrigin is the original value of an account changed since the last
+// finalisation, rebuilt by undoing the journal.
+type journalOrigin struct {
+	existed  bool
+	reset    bool // Whether the account was recreated, wiping its storage
+	balance  *big.Int
+	nonce    uint64
+	codeHash common.Hash
+	storage  Storage
+}
+
+// FinaliseDiff finalises the state like Finalise, returning the changes made to
+// it since the previous finalisation. The changes are rebuilt from the journal,
+// so unlike the diffs of a commit they're available without writing anything to
+// the database, as needed by simulations.
+func (s *StateDB) FinaliseDiff(deleteEmptyObjects bool) *StateDiff {
+	// Undo the journal on the current values of the changed accounts
+	origins := make(map[common.Address]*journalOrigin)
+	origin := func(addr common.Address) *journalOrigin {
+		if account, ok := origins[addr]; ok {
+			return account
+		}
+		obj := s.stateObjects[addr]
+		account := &journalOrigin{
+			existed:  true,
+			balance:  obj.Balance(),
+			nonce:    obj.Nonce(),
+			codeHash: common.BytesToHash(obj.CodeHash()),
+			storage:  make(Storage),
+		}
+		origins[addr] = account
+		return account
+	}
+	for i := len(s.journal) - 1; i >= 0; i-- {
+		switch entry := s.journal[i].(type) {
+		case createObjectChange:
+			account := origin(*entry.account)
+			account.existed = false
+			for key := range account.storage {
+				account.storage[key] = common.Hash{}
+			}
+		case resetObjectChange:
+			account := origin(entry.prev.address)
+			account.reset = true
+			account.balance, account.nonce = entry.prev.Balance(), entry.prev.Nonce()
+			account.codeHash = common.BytesToHash(entry.prev.CodeHash())
+			for key := range account.storage {
+				account.storage[key] = entry.prev.GetState(s.db, key)
+			}
+		case suicideChange:
+			origin(*entry.account).balance = entry.prevbalance
+		case balanceChange:
+			origin(*entry.account).balance = entry.prev
+		case nonceChange:
+			origin(*entry.account).nonce = entry.prev
+		case codeChange:
+			origin(*entry.account).codeHash = common.BytesToHash(entry.prevhash)
+		case storageChange:
+			origin(*entry.account).storage[entry.key] = entry.prevalue
+		case touchChange:
+			if *entry.account != ripemd {
+				origin(*entry.account)
+			}
+		}
+	}
+	// Compare the original values with the current ones before finalising
+	diff := new(StateDiff)
+	for addr, origin := range origins {
+		obj := s.stateObjects[addr]
+		account := &AccountDiff{
+			Address:      addr,
+			Existed:      origin.existed,
+			Exists:       !obj.suicided && !(deleteEmptyObjects && obj.empty()),
+			BalanceFrom:  new(big.Int),
+			BalanceTo:    new(big.Int),
+			CodeHashFrom: emptyCode,
+			CodeHashTo:   emptyCode,
+		}
+		account.Destructed = account.Existed && (!account.Exists || origin.reset)
+		if account.Existed {
+			account.BalanceFrom, account.NonceFrom = new(big.Int).Set(origin.balance), origin.nonce
+			account.CodeHashFrom = origin.codeHash
+		}
+		if account.Exists {
+			account.BalanceTo, account.NonceTo = new(big.Int).Set(obj.Balance()), obj.Nonce()
+			account.CodeHashTo = common.BytesToHash(obj.CodeHash())
+
+			for key, original := range origin.storage {
+				if value := obj.GetState(s.db, key); value != original {
+					account.Storage = append(account.Storage, &StorageDiff{Key: key, From: original, To: value})
+				}
+			}
+			sort.Slice(account.Storage, func(i, j int) bool {
+				return bytes.Compare(account.Storage[i].Key[:], account.Storage[j].Key[:]) < 0
+			})
+		}
+		if account.CodeHashFrom != account.CodeHashTo {
+			account.Code = common.CopyBytes(obj.Code(s.db))
+		}
+		if account.changed() {
+			diff.Accounts = append(diff.Accounts, account)
+		}
+	}
+	sort.Slice(diff.Accounts, func(i, j int) bool {
+		return bytes.Compare(diff.Accounts[i].Address[:], diff.Accounts[j].Address[:]) < 0
+	})
+	s.Finalise(deleteEmptyObjects)
+	return diff
+}
+
+// changed reports whether the account differs in any way from its original.
+func (a *AccountDiff) changed() bool {
+	return a.Existed != a.Exists || a.Destructed || len(a.Storage) > 0 ||
+		a.BalanceFrom.Cmp(a.BalanceTo) != 0 || a.NonceFrom != a.NonceTo || a.CodeHashFrom != a.CodeHashTo
+}
@@ -28,8 +28,10 @@ import (
 	"math/big"
 )
 import (
+	"bytes"
 	"fmt"
 	"runtime"
+	"sort"
 	"sync"
 )
 
@@ -106,12 +108,70 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 			totalFeeUsed = totalFeeUsed + gas
 		}
 	}
-	state.UpdateTRC21Fee(statedb, balanceUpdated, totalFeeUsed)
+	updateTRC21Fee(statedb, balanceUpdated, totalFeeUsed, cfg)
 	// Finalize the block, applying any consensus engine specific extras (e.g. block rewards)
-	p.engine.Finalize(p.bc, header, statedb, block.Transactions(), block.Uncles(), receipts)
+	p.finalize(header, statedb, block, receipts, cfg)
 	return receipts, allLogs, *usedGas, nil
 }
 
+// updateTRC21Fee settles the fees paid by the TRC21 token issuers for the
+// transactions of the block. If the tracer of cfg is a structured one, the
+// updated fee capacities and the balance taken from the issuer contract are
+// reported to it.
+//
+// Unlike the transactions, the block level changes are traced even if cfg.Debug
+// is unset, so they can be traced without tracing the whole block.
+func updateTRC21Fee(statedb *state.StateDB, balanceUpdated map[common.Address]*big.Int, totalFeeUsed uint64, cfg vm.Config) {
+	tracer, ok := cfg.Tracer.(vm.StructuredTracer)
+	if !ok || len(balanceUpdated) == 0 {
+		state.UpdateTRC21Fee(statedb, balanceUpdated, totalFeeUsed)
+		return
+	}
+	tokens := make([]common.Address, 0, len(balanceUpdated))
+	for token := range balanceUpdated {
+		tokens = append(tokens, token)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return bytes.Compare(tokens[i][:], tokens[j][:]) < 0
+	})
+	var (
+		slots = make([]common.Hash, len(tokens))
+		prevs = make([]common.Hash, len(tokens))
+		prev  = statedb.GetBalance(common.TRC21IssuerSMC)
+	)
+	for i, token := range tokens {
+		slots[i] = common.BigToHash(state.GetLocMappingAtKey(token.Hash(), state.SlotTRC21Issuer["tokensState"]))
+		prevs[i] = statedb.GetState(common.TRC21IssuerSMC, slots[i])
+	}
+	state.UpdateTRC21Fee(statedb, balanceUpdated, totalFeeUsed)
+
+	for i, slot := range slots {
+		tracer.CaptureStorageChange(common.TRC21IssuerSMC, slot, prevs[i], statedb.GetState(common.TRC21IssuerSMC, slot))
+	}
+	if next := statedb.GetBalance(common.TRC21IssuerSMC); prev.Cmp(next) != 0 {
+		tracer.CaptureBalanceChange(common.TRC21IssuerSMC, prev, next, vm.BalanceChangeTRC21Fee)
+	}
+}
+
+// finalize finalizes the block with the consensus engine. If the tracer of cfg
+// is a structured one, the balance changes made by the engine are reported to it
+// as rewards, even if cfg.Debug is unset.
+func (p *StateProcessor) finalize(header *types.Header, statedb *state.StateDB, block *types.Block, receipts types.Receipts, cfg vm.Config) {
+	tracer, ok := cfg.Tracer.(vm.StructuredTracer)
+	if !ok {
+		p.engine.Finalize(p.bc, header, statedb, block.Transactions(), block.Uncles(), receipts)
+		return
+	}
+	prev := statedb.Copy()
+	p.engine.Finalize(p.bc, header, statedb, block.Transactions(), block.Uncles(), receipts)
+
+	for _, addr := range statedb.DirtyAccounts() {
+		if before, after := prev.GetBalance(addr), statedb.GetBalance(addr); before.Cmp(after) != 0 {
+			tracer.CaptureBalanceChange(addr, before, after, vm.BalanceChangeReward)
+		}
+	}
+}
+
 func (p *StateProcessor) ProcessBlockNoValidator(cBlock *CalculatedBlock, statedb *state.StateDB, cfg vm.Config, balanceFee map[common.Address]*big.Int) (types.Receipts, []*types.Log, uint64, error) {
 	block := cBlock.block
 	var (
@@ -168,9 +228,9 @@ func (p *StateProcessor) ProcessBlockNoValidator(cBlock *CalculatedBlock, stated
 			totalFeeUsed = totalFeeUsed + gas
 		}
 	}
-	state.UpdateTRC21Fee(statedb, balanceUpdated, totalFeeUsed)
+	updateTRC21Fee(statedb, balanceUpdated, totalFeeUsed, cfg)
 	// Finalize the block, applying any consensus engine specific extras (e.g. block rewards)
-	p.engine.Finalize(p.bc, header, statedb, block.Transactions(), block.Uncles(), receipts)
+	p.finalize(header, statedb, block, receipts, cfg)
 	return receipts, allLogs, *usedGas, nil
 }
 
@@ -0,0 +1,77 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// changeTracer is a StructuredTracer recording the balance and storage changes
+// it is notified of.
+type changeTracer struct {
+	*vm.StructLogger
+	changes []string
+}
+
+func (t *changeTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+}
+func (t *changeTracer) CaptureExit(output []byte, gasUsed uint64, err error) {}
+func (t *changeTracer) CaptureBalanceChange(addr common.Address, prev, next *big.Int, reason vm.BalanceChangeReason) {
+	t.changes = append(t.changes, fmt.Sprintf("balance %x %v -> %v %v", addr, prev, next, reason))
+}
+func (t *changeTracer) CaptureStorageChange(addr common.Address, slot, prev, next common.Hash) {
+	t.changes = append(t.changes, fmt.Sprintf("storage %x %x %v -> %v", addr, slot, prev.Big(), next.Big()))
+}
+
+// Tests that the settlement of the TRC21 fees of a block is reported to a
+// structured tracer even if the transactions are not traced.
+func TestUpdateTRC21FeeTracing(t *testing.T) {
+	var (
+		db, _      = ethdb.NewMemDatabase()
+		statedb, _ = state.New(common.Hash{}, state.NewDatabase(db))
+		tracer     = &changeTracer{StructLogger: vm.NewStructLogger(nil)}
+		tokens     = []common.Address{common.HexToAddress("0x02"), common.HexToAddress("0x01")}
+		slots      = make([]common.Hash, len(tokens))
+	)
+	statedb.SetBalance(common.TRC21IssuerSMC, big.NewInt(1000))
+	for i, token := range tokens {
+		slots[i] = common.BigToHash(state.GetLocMappingAtKey(token.Hash(), state.SlotTRC21Issuer["tokensState"]))
+		statedb.SetState(common.TRC21IssuerSMC, slots[i], common.BigToHash(big.NewInt(500)))
+	}
+	updated := map[common.Address]*big.Int{
+		tokens[0]: big.NewInt(480),
+		tokens[1]: big.NewInt(460),
+	}
+	updateTRC21Fee(statedb, updated, 60, vm.Config{Tracer: tracer})
+
+	want := []string{
+		fmt.Sprintf("storage %x %x 500 -> 460", common.TRC21IssuerSMC, slots[1]),
+		fmt.Sprintf("storage %x %x 500 -> 480", common.TRC21IssuerSMC, slots[0]),
+		fmt.Sprintf("balance %x 1000 -> 940 trc21Fee", common.TRC21IssuerSMC),
+	}
+	if !reflect.DeepEqual(tracer.changes, want) {
+		t.Errorf("change mismatch:\nhave %q\nwant %q", tracer.changes, want)
+	}
+}
@@ -191,11 +191,37 @@ func (st *StateTransition) buyGas() error {
 
 	st.initialGas = st.msg.Gas()
 	if balanceTokenFee == nil {
-		state.SubBalance(from.Address(), mgval)
+		st.subBalance(from.Address(), mgval, vm.BalanceChangeGasBuy)
 	}
 	return nil
 }
 
+// addBalance credits the account with amount, reporting the change to the
+// structured tracer of the EVM if there's one.
+func (st *StateTransition) addBalance(addr common.Address, amount *big.Int, reason vm.BalanceChangeReason) {
+	tracer := st.evm.StructuredTracer()
+	if tracer == nil || amount.Sign() == 0 {
+		st.state.AddBalance(addr, amount)
+		return
+	}
+	prev := st.state.GetBalance(addr)
+	st.state.AddBalance(addr, amount)
+	tracer.CaptureBalanceChange(addr, prev, st.state.GetBalance(addr), reason)
+}
+
+// subBalance debits the account with amount, reporting the change to the
+// structured tracer of the EVM if there's one.
+func (st *StateTransition) subBalance(addr common.Address, amount *big.Int, reason vm.BalanceChangeReason) {
+	tracer := st.evm.StructuredTracer()
+	if tracer == nil || amount.Sign() == 0 {
+		st.state.SubBalance(addr, amount)
+		return
+	}
+	prev := st.state.GetBalance(addr)
+	st.state.SubBalance(addr, amount)
+	tracer.CaptureBalanceChange(addr, prev, st.state.GetBalance(addr), reason)
+}
+
 func (st *StateTransition) preCheck() error {
 	msg := st.msg
 	sender := st.from()
@@ -265,7 +291,13 @@ func (st *StateTransition) TransitionDb() (ret []byte, usedGas uint64, failed bo
 		}
 	}
 	st.refundGas()
-	st.state.AddBalance(st.evm.Coinbase, new(big.Int).Mul(new(big.Int).SetUint64(st.gasUsed()), st.gasPrice))
+
+	// The fee of a TRC21 transaction is paid by the token issuer rather than the sender
+	reason := vm.BalanceChangeGasFee
+	if st.balanceTokenFee() != nil {
+		reason = vm.BalanceChangeTRC21Fee
+	}
+	st.addBalance(st.evm.Coinbase, new(big.Int).Mul(new(big.Int).SetUint64(st.gasUsed()), st.gasPrice), reason)
 
 	return ret, st.gasUsed(), vmerr != nil, err
 }
@@ -283,7 +315,7 @@ func (st *StateTransition) refundGas() {
 		from := st.from()
 		// Return ETH for remaining gas, exchanged at the original rate.
 		remaining := new(big.Int).Mul(new(big.Int).SetUint64(st.gas), st.gasPrice)
-		st.state.AddBalance(from.Address(), remaining)
+		st.addBalance(from.Address(), remaining, vm.BalanceChangeGasRefund)
 	}
 	// Also return remaining gas to the block gas counter so it is
 	// available for the next transaction.
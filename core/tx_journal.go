@@ -17,9 +17,12 @@
 package core
 
 import (
+	"container/heap"
 	"errors"
 	"io"
 	"os"
+	"sort"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -161,3 +164,234 @@ func (journal *txJournal) close() error {
 	}
 	return err
 }
+
+// remoteJournalBatch is the number of remote transactions injected into the pool
+// at once when loading the remote journal.
+const remoteJournalBatch = 1024
+
+// journalEntry is a remote transaction stored in the journal together with the
+// time it was first seen, allowing stale ones to be dropped on load.
+type journalEntry struct {
+	Tx   *types.Transaction
+	Time uint64 // Unix timestamp the transaction was first journaled at
+}
+
+// remoteTxJournal is a bounded, rotating log of transactions received from the
+// network with the aim of allowing gateway nodes to retain their pools across
+// restarts.
+type remoteTxJournal struct {
+	path     string                 // Filesystem path to store the transactions at
+	limit    uint64                 // Maximum number of transactions kept in the journal
+	lifetime time.Duration          // Maximum age of a transaction to be reloaded
+	writer   io.WriteCloser         // Output stream to write new transactions into
+	written  uint64                 // Number of transactions in the live journal
+	seen     map[common.Hash]uint64 // First seen timestamps, retained across rotations
+}
+
+// newRemoteTxJournal creates a new remote transaction journal bounded to the
+// given number of transactions and maximum age.
+func newRemoteTxJournal(path string, limit uint64, lifetime time.Duration) *remoteTxJournal {
+	return &remoteTxJournal{
+		path:     path,
+		limit:    limit,
+		lifetime: lifetime,
+		seen:     make(map[common.Hash]uint64),
+	}
+}
+
+// load parses a remote transaction journal dump from disk, loading its still
+// fresh contents into the specified pool. A truncated trailing entry, left by a
+// crash in the middle of a write, is silently discarded.
+func (journal *remoteTxJournal) load(add func([]*types.Transaction) []error) error {
+	// Skip the parsing if the journal file doesn't exist at all
+	if _, err := os.Stat(journal.path); os.IsNotExist(err) {
+		return nil
+	}
+	// Open the journal for loading any past transactions
+	input, err := os.Open(journal.path)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	// Temporarily discard any journal additions (don't double add on load)
+	journal.writer = new(devNull)
+	defer func() { journal.writer = nil }()
+
+	// Inject all fresh transactions from the journal into the pool in batches
+	var (
+		stream = rlp.NewStream(input, 0)
+		now    = time.Now()
+		batch  = make([]*types.Transaction, 0, remoteJournalBatch)
+
+		total, stale, dropped int
+		failure               error
+	)
+	flush := func() {
+		for _, err := range add(batch) {
+			if err != nil {
+				log.Debug("Failed to add journaled remote transaction", "err", err)
+				dropped++
+			}
+		}
+		batch = batch[:0]
+	}
+	for {
+		// Parse the next entry and terminate on error
+		entry := new(journalEntry)
+		if err = stream.Decode(entry); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				log.Warn("Discarded truncated remote transaction journal entry")
+			} else if err != io.EOF {
+				failure = err
+			}
+			break
+		}
+		total++
+		if journal.lifetime > 0 && now.Sub(time.Unix(int64(entry.Time), 0)) > journal.lifetime {
+			stale++
+			continue
+		}
+		journal.seen[entry.Tx.Hash()] = entry.Time
+
+		if batch = append(batch, entry.Tx); len(batch) == remoteJournalBatch {
+			flush()
+		}
+	}
+	flush()
+	log.Info("Loaded remote transaction journal", "transactions", total, "stale", stale, "dropped", dropped)
+
+	return failure
+}
+
+// insert adds the specified transaction to the remote disk journal, unless the
+// journal already reached its size limit.
+func (journal *remoteTxJournal) insert(tx *types.Transaction) error {
+	if journal.writer == nil {
+		return errNoActiveJournal
+	}
+	if journal.written >= journal.limit {
+		return nil
+	}
+	hash := tx.Hash()
+	if _, ok := journal.seen[hash]; !ok {
+		journal.seen[hash] = uint64(time.Now().Unix())
+	}
+	if err := rlp.Encode(journal.writer, &journalEntry{Tx: tx, Time: journal.seen[hash]}); err != nil {
+		return err
+	}
+	journal.written++
+	return nil
+}
+
+// rotate regenerates the remote transaction journal based on the current
+// contents of the transaction pool, retaining at most the configured number of
+// transactions. The highest priced ones are retained, the most recently seen
+// first among equal prices, without leaving nonce gaps within an account. Only
+// the first seen timestamps of the retained transactions are kept.
+func (journal *remoteTxJournal) rotate(all map[common.Address]types.Transactions) error {
+	// Close the current journal (if any is open)
+	if journal.writer != nil {
+		if err := journal.writer.Close(); err != nil {
+			return err
+		}
+		journal.writer = nil
+	}
+	// Generate a new journal with the contents of the current pool
+	replacement, err := os.OpenFile(journal.path+".new", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	var (
+		now  = uint64(time.Now().Unix())
+		seen = make(map[common.Hash]uint64)
+	)
+	heads := &journalHeads{seen: journal.seen}
+	for _, txs := range all {
+		if len(txs) > 0 {
+			sort.Sort(types.TxByNonce(txs))
+			heads.lists = append(heads.lists, txs)
+		}
+	}
+	heap.Init(heads)
+
+	journaled := uint64(0)
+	for journaled < journal.limit && heads.Len() > 0 {
+		// Journal the best account head and advance that account
+		tx := heads.lists[0][0]
+		if heads.lists[0] = heads.lists[0][1:]; len(heads.lists[0]) > 0 {
+			heap.Fix(heads, 0)
+		} else {
+			heap.Pop(heads)
+		}
+		hash := tx.Hash()
+		if seen[hash] = journal.seen[hash]; seen[hash] == 0 {
+			seen[hash] = now
+		}
+		if err = rlp.Encode(replacement, &journalEntry{Tx: tx, Time: seen[hash]}); err != nil {
+			replacement.Close()
+			return err
+		}
+		journaled++
+	}
+	// Make sure the new journal hit the disk before replacing the old one
+	if err = replacement.Sync(); err != nil {
+		replacement.Close()
+		return err
+	}
+	replacement.Close()
+
+	// Replace the live journal with the newly generated one
+	if err = os.Rename(journal.path+".new", journal.path); err != nil {
+		return err
+	}
+	sink, err := os.OpenFile(journal.path, os.O_WRONLY|os.O_APPEND, 0755)
+	if err != nil {
+		return err
+	}
+	journal.writer, journal.written, journal.seen = sink, journaled, seen
+	log.Info("Regenerated remote transaction journal", "transactions", journaled, "accounts", len(all))
+
+	return nil
+}
+
+// close flushes the remote transaction journal contents to disk and closes the
+// file.
+func (journal *remoteTxJournal) close() error {
+	var err error
+
+	if journal.writer != nil {
+		err = journal.writer.Close()
+		journal.writer = nil
+	}
+	return err
+}
+
+// journalHeads is a heap of nonce sorted account transaction lists, ordered by
+// the gas price of their lowest nonce transaction and, among equal prices, by
+// the time it was first seen, most recent first.
+type journalHeads struct {
+	lists []types.Transactions
+	seen  map[common.Hash]uint64
+}
+
+func (h journalHeads) Len() int { return len(h.lists) }
+func (h journalHeads) Less(i, j int) bool {
+	if cmp := h.lists[i][0].GasPrice().Cmp(h.lists[j][0].GasPrice()); cmp != 0 {
+		return cmp > 0
+	}
+	return h.seen[h.lists[i][0].Hash()] > h.seen[h.lists[j][0].Hash()]
+}
+func (h journalHeads) Swap(i, j int) { h.lists[i], h.lists[j] = h.lists[j], h.lists[i] }
+
+func (h *journalHeads) Push(x interface{}) {
+	h.lists = append(h.lists, x.(types.Transactions))
+}
+
+func (h *journalHeads) Pop() interface{} {
+	old := h.lists
+	n := len(old)
+	x := old[n-1]
+	h.lists = old[0 : n-1]
+	return x
+}
@@ -0,0 +1,292 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	// ErrPolicyRateLimit is returned if a sender submitted more transactions than
+	// the rate limit policy allows within its interval.
+	ErrPolicyRateLimit = errors.New("sender exceeded transaction rate limit")
+
+	// ErrPolicyDestination is returned if a transaction calls into or creates a
+	// contract that is not in the allowlist of the pool.
+	ErrPolicyDestination = errors.New("destination not in allowlist")
+
+	// ErrPolicyUnderpriced is returned if a transaction's gas price is below the
+	// minimum configured for its destination.
+	ErrPolicyUnderpriced = errors.New("transaction underpriced for destination")
+
+	// ErrPolicySelector is returned if a transaction invokes a method selector
+	// rejected by the pool.
+	ErrPolicySelector = errors.New("method selector rejected")
+)
+
+// TxPoolPolicy is a local admission rule consulted by the transaction pool after
+// a transaction passed all consensus and DOS protection checks.
+//
+// Note, policies are invoked with the pool lock held and must not call back into
+// the pool.
+type TxPoolPolicy interface {
+	// Name returns a short identifier of the policy, used for logging.
+	Name() string
+
+	// Validate checks whether a transaction originating from the given sender
+	// may enter the pool, returning a non-nil error if it must be rejected.
+	Validate(tx *types.Transaction, from common.Address, local bool) error
+}
+
+// TxPoolPolicyTracker is an admission policy keeping track of the transactions it
+// validated, which is notified once they actually entered the pool.
+type TxPoolPolicyTracker interface {
+	TxPoolPolicy
+
+	// Admitted records that a transaction validated by the policy was pooled.
+	Admitted(tx *types.Transaction, from common.Address, local bool)
+}
+
+// TxPoolPolicyConfig are the configuration parameters of the built-in admission
+// policies of the transaction pool. Zero values disable the respective policy.
+type TxPoolPolicyConfig struct {
+	RateLimit    uint64        `json:"rateLimit"`    // Maximum number of remote transactions accepted per sender within RateInterval
+	RateInterval time.Duration `json:"rateInterval"` // Time window over which the rate limit is enforced, a duration string or seconds in JSON
+
+	Allowlist []common.Address `json:"allowlist"` // Contracts permitted to be called, creation is rejected if set
+
+	MinGasPrices map[common.Address]uint64 `json:"minGasPrices"` // Minimum gas price enforced per destination
+
+	RejectSelectors []hexutil.Bytes `json:"rejectSelectors"` // Method selectors (4 bytes) rejected by the pool
+}
+
+// MarshalJSON encodes the policy configuration, representing the rate interval as
+// a human readable duration string.
+func (c TxPoolPolicyConfig) MarshalJSON() ([]byte, error) {
+	type policyConfig TxPoolPolicyConfig
+	enc := struct {
+		policyConfig
+		RateInterval string `json:"rateInterval"`
+	}{policyConfig(c), c.RateInterval.String()}
+	return json.Marshal(&enc)
+}
+
+// UnmarshalJSON decodes the policy configuration, accepting the rate interval
+// either as a duration string (e.g. "1m30s") or as a number of seconds.
+func (c *TxPoolPolicyConfig) UnmarshalJSON(input []byte) error {
+	type policyConfig TxPoolPolicyConfig
+	var dec struct {
+		*policyConfig
+		RateInterval json.RawMessage `json:"rateInterval"`
+	}
+	dec.policyConfig = (*policyConfig)(c)
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	c.RateInterval = 0
+	if len(dec.RateInterval) == 0 || string(dec.RateInterval) == "null" {
+		return nil
+	}
+	var str string
+	if err := json.Unmarshal(dec.RateInterval, &str); err == nil {
+		interval, err := time.ParseDuration(str)
+		if err != nil {
+			return fmt.Errorf("invalid rate interval %q: %v", str, err)
+		}
+		c.RateInterval = interval
+		return nil
+	}
+	var secs uint64
+	if err := json.Unmarshal(dec.RateInterval, &secs); err != nil {
+		return fmt.Errorf("invalid rate interval %s: want duration string or seconds", dec.RateInterval)
+	}
+	c.RateInterval = time.Duration(secs) * time.Second
+	return nil
+}
+
+// newTxPoolPolicies assembles the chain of built-in policies enabled by the given
+// configuration. The code lookup is used to tell contracts from plain accounts.
+func newTxPoolPolicies(config TxPoolPolicyConfig, hasCode func(common.Address) bool) []TxPoolPolicy {
+	var policies []TxPoolPolicy
+
+	if config.RateLimit > 0 && config.RateInterval > 0 {
+		policies = append(policies, newRateLimitPolicy(config.RateLimit, config.RateInterval))
+	}
+	if len(config.Allowlist) > 0 {
+		policies = append(policies, newAllowlistPolicy(config.Allowlist, hasCode))
+	}
+	if len(config.MinGasPrices) > 0 {
+		policies = append(policies, newGasPricePolicy(config.MinGasPrices))
+	}
+	if len(config.RejectSelectors) > 0 {
+		policies = append(policies, newSelectorPolicy(config.RejectSelectors))
+	}
+	return policies
+}
+
+// rateLimitPolicy limits the number of remote transactions a single sender may
+// submit within a fixed time window.
+type rateLimitPolicy struct {
+	limit    uint64
+	interval time.Duration
+
+	windows map[common.Address]*rateWindow // Currently open window of each sender
+	swept   time.Time                      // Last time expired windows were dropped
+}
+
+// rateWindow tracks the number of transactions a sender submitted since start.
+type rateWindow struct {
+	start time.Time
+	count uint64
+}
+
+func newRateLimitPolicy(limit uint64, interval time.Duration) *rateLimitPolicy {
+	return &rateLimitPolicy{
+		limit:    limit,
+		interval: interval,
+		windows:  make(map[common.Address]*rateWindow),
+		swept:    time.Now(),
+	}
+}
+
+func (p *rateLimitPolicy) Name() string { return "ratelimit" }
+
+func (p *rateLimitPolicy) Validate(tx *types.Transaction, from common.Address, local bool) error {
+	if local {
+		return nil
+	}
+	if window := p.windows[from]; window != nil && time.Since(window.start) <= p.interval && window.count >= p.limit {
+		return ErrPolicyRateLimit
+	}
+	return nil
+}
+
+// Admitted counts a pooled transaction against the window of its sender, so that
+// transactions rejected by the pool don't use up the rate limit.
+func (p *rateLimitPolicy) Admitted(tx *types.Transaction, from common.Address, local bool) {
+	if local {
+		return
+	}
+	now := time.Now()
+
+	// Drop expired windows every now and then to keep memory bounded
+	if now.Sub(p.swept) > p.interval {
+		for addr, window := range p.windows {
+			if now.Sub(window.start) > p.interval {
+				delete(p.windows, addr)
+			}
+		}
+		p.swept = now
+	}
+	window := p.windows[from]
+	if window == nil || now.Sub(window.start) > p.interval {
+		window = &rateWindow{start: now}
+		p.windows[from] = window
+	}
+	window.count++
+}
+
+// allowlistPolicy restricts contract interactions to a fixed set of destinations.
+// Plain value transfers without call data are permitted to accounts holding no
+// code, as a contract's fallback function runs even without call data.
+type allowlistPolicy struct {
+	allowed map[common.Address]struct{}
+	hasCode func(common.Address) bool // Reports whether an account is a contract
+}
+
+func newAllowlistPolicy(addrs []common.Address, hasCode func(common.Address) bool) *allowlistPolicy {
+	allowed := make(map[common.Address]struct{}, len(addrs))
+	for _, addr := range addrs {
+		allowed[addr] = struct{}{}
+	}
+	return &allowlistPolicy{allowed: allowed, hasCode: hasCode}
+}
+
+func (p *allowlistPolicy) Name() string { return "allowlist" }
+
+func (p *allowlistPolicy) Validate(tx *types.Transaction, from common.Address, local bool) error {
+	if tx.To() == nil {
+		return ErrPolicyDestination
+	}
+	if _, ok := p.allowed[*tx.To()]; ok {
+		return nil
+	}
+	if len(tx.Data()) == 0 && !p.hasCode(*tx.To()) {
+		return nil
+	}
+	return ErrPolicyDestination
+}
+
+// gasPricePolicy enforces a minimum gas price for transactions sent to specific
+// destinations, on top of the pool wide price limit.
+type gasPricePolicy struct {
+	prices map[common.Address]*big.Int
+}
+
+func newGasPricePolicy(prices map[common.Address]uint64) *gasPricePolicy {
+	policy := &gasPricePolicy{prices: make(map[common.Address]*big.Int, len(prices))}
+	for addr, price := range prices {
+		policy.prices[addr] = new(big.Int).SetUint64(price)
+	}
+	return policy
+}
+
+func (p *gasPricePolicy) Name() string { return "gasprice" }
+
+func (p *gasPricePolicy) Validate(tx *types.Transaction, from common.Address, local bool) error {
+	if tx.To() == nil {
+		return nil
+	}
+	if price, ok := p.prices[*tx.To()]; ok && tx.GasPrice().Cmp(price) < 0 {
+		return ErrPolicyUnderpriced
+	}
+	return nil
+}
+
+// selectorPolicy rejects transactions invoking any of a set of method selectors.
+type selectorPolicy struct {
+	selectors [][]byte
+}
+
+func newSelectorPolicy(selectors []hexutil.Bytes) *selectorPolicy {
+	policy := new(selectorPolicy)
+	for _, selector := range selectors {
+		policy.selectors = append(policy.selectors, common.CopyBytes(selector))
+	}
+	return policy
+}
+
+func (p *selectorPolicy) Name() string { return "selector" }
+
+func (p *selectorPolicy) Validate(tx *types.Transaction, from common.Address, local bool) error {
+	data := tx.Data()
+	for _, selector := range p.selectors {
+		if len(selector) > 0 && bytes.HasPrefix(data, selector) {
+			return ErrPolicySelector
+		}
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func callTransaction(nonce uint64, to common.Address, gasprice *big.Int, data []byte, key *ecdsa.PrivateKey) *types.Transaction {
+	tx, _ := types.SignTx(types.NewTransaction(nonce, to, big.NewInt(0), 100000, gasprice, data), types.HomesteadSigner{}, key)
+	return tx
+}
+
+// Tests that the built-in admission policies reject the transactions they are
+// configured for and that they can be reloaded at runtime.
+func TestTransactionPolicies(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000000))
+
+	var (
+		price    = big.NewInt(common.DefaultMinGasPrice)
+		allowed  = common.HexToAddress("0x0000000000000000000000000000000000000001")
+		premium  = common.HexToAddress("0x0000000000000000000000000000000000000002")
+		contract = common.HexToAddress("0x0000000000000000000000000000000000000003")
+		selector = hexutil.Bytes{0xde, 0xad, 0xbe, 0xef}
+	)
+	pool.currentState.SetCode(contract, []byte{0x00})
+
+	pool.SetPolicy(TxPoolPolicyConfig{
+		Allowlist:       []common.Address{allowed, premium},
+		MinGasPrices:    map[common.Address]uint64{premium: 2 * common.DefaultMinGasPrice},
+		RejectSelectors: []hexutil.Bytes{selector},
+	})
+	tests := []struct {
+		tx  *types.Transaction
+		err error
+	}{
+		{callTransaction(0, common.Address{}, price, []byte{0x01, 0x02, 0x03, 0x04}, key), ErrPolicyDestination},
+		{callTransaction(0, premium, price, []byte{0x01, 0x02, 0x03, 0x04}, key), ErrPolicyUnderpriced},
+		{callTransaction(0, allowed, price, append(selector, 0x00), key), ErrPolicySelector},
+		{callTransaction(0, contract, price, nil, key), ErrPolicyDestination},
+		{callTransaction(0, common.Address{}, price, nil, key), nil},
+		{callTransaction(1, allowed, price, []byte{0x01, 0x02, 0x03, 0x04}, key), nil},
+	}
+	for i, tt := range tests {
+		if err := pool.AddRemote(tt.tx); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	// Reload the policies and ensure previously rejected transactions pass
+	pool.SetPolicy(TxPoolPolicyConfig{})
+	if err := pool.AddRemote(callTransaction(2, common.Address{}, price, []byte{0x01, 0x02, 0x03, 0x04}, key)); err != nil {
+		t.Errorf("transaction rejected after policy reload: %v", err)
+	}
+}
+
+// Tests that the rate limit policy caps the admitted remote transactions per
+// sender, while local ones are exempt.
+func TestTransactionRateLimitPolicy(t *testing.T) {
+	t.Parallel()
+
+	policy := newRateLimitPolicy(2, time.Hour)
+
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	tx := callTransaction(0, common.Address{}, big.NewInt(1), nil, key)
+
+	for i := 0; i < 2; i++ {
+		if err := policy.Validate(tx, from, false); err != nil {
+			t.Fatalf("transaction %d: rejected within limit: %v", i, err)
+		}
+		policy.Admitted(tx, from, false)
+	}
+	if err := policy.Validate(tx, from, false); err != ErrPolicyRateLimit {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrPolicyRateLimit)
+	}
+	if err := policy.Validate(tx, from, true); err != nil {
+		t.Fatalf("local transaction rejected: %v", err)
+	}
+	// Expire the window and ensure the sender is accepted again
+	policy.windows[from].start = time.Now().Add(-2 * time.Hour)
+	if err := policy.Validate(tx, from, false); err != nil {
+		t.Fatalf("transaction rejected after window expiry: %v", err)
+	}
+}
+
+// Tests that the rate interval of the policy configuration can be given either as
+// a duration string or as a number of seconds.
+func TestTransactionPolicyConfigJSON(t *testing.T) {
+	tests := []struct {
+		input    string
+		interval time.Duration
+		fail     bool
+	}{
+		{`{"rateLimit": 10, "rateInterval": "1m30s"}`, 90 * time.Second, false},
+		{`{"rateLimit": 10, "rateInterval": 60}`, time.Minute, false},
+		{`{"rateLimit": 10}`, 0, false},
+		{`{"rateLimit": 10, "rateInterval": "1 minute"}`, 0, true},
+		{`{"rateLimit": 10, "rateInterval": -1}`, 0, true},
+	}
+	for i, tt := range tests {
+		var config TxPoolPolicyConfig
+		err := json.Unmarshal([]byte(tt.input), &config)
+		if (err != nil) != tt.fail {
+			t.Errorf("test %d: error mismatch: have %v, want failure %v", i, err, tt.fail)
+			continue
+		}
+		if tt.fail {
+			continue
+		}
+		if config.RateLimit != 10 || config.RateInterval != tt.interval {
+			t.Errorf("test %d: config mismatch: have %d/%v, want %d/%v", i, config.RateLimit, config.RateInterval, 10, tt.interval)
+		}
+	}
+	// Ensure the encoded configuration decodes into the same one
+	config := TxPoolPolicyConfig{RateLimit: 5, RateInterval: 2 * time.Minute}
+	blob, err := json.Marshal(config)
+	if err != nil {
+		t.Fatalf("failed to encode config: %v", err)
+	}
+	var decoded TxPoolPolicyConfig
+	if err := json.Unmarshal(blob, &decoded); err != nil {
+		t.Fatalf("failed to decode config %s: %v", blob, err)
+	}
+	if decoded.RateLimit != config.RateLimit || decoded.RateInterval != config.RateInterval {
+		t.Fatalf("config mismatch after round trip: have %+v, want %+v", decoded, config)
+	}
+}
+
+// Tests that only the special transactions of masternodes bypass the admission
+// policies, the ones of any other account being rate limited as usual.
+func TestTransactionPolicySpecialTransactions(t *testing.T) {
+	t.Parallel()
+
+	pool, stranger := setupTxPool()
+	defer pool.Stop()
+
+	masternode, _ := crypto.GenerateKey()
+	pool.Signers = func() (map[common.Address]struct{}, error) {
+		return map[common.Address]struct{}{crypto.PubkeyToAddress(masternode.PublicKey): {}}, nil
+	}
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(stranger.PublicKey), big.NewInt(1000000000))
+	pool.SetPolicy(TxPoolPolicyConfig{RateLimit: 1, RateInterval: time.Hour})
+
+	signers := common.HexToAddress(common.BlockSigners)
+	for i := uint64(0); i < 2; i++ {
+		if err := pool.AddRemote(callTransaction(i, signers, new(big.Int), nil, masternode)); err != nil {
+			t.Fatalf("masternode special transaction %d: failed to add: %v", i, err)
+		}
+	}
+	if err := pool.AddRemote(callTransaction(0, signers, big.NewInt(1), nil, stranger)); err != nil {
+		t.Fatalf("stranger special transaction: failed to add: %v", err)
+	}
+	if err := pool.AddRemote(callTransaction(1, signers, big.NewInt(1), nil, stranger)); err != ErrPolicyRateLimit {
+		t.Fatalf("stranger special transaction over rate limit error mismatch: have %v, want %v", err, ErrPolicyRateLimit)
+	}
+}
+
+// testPolicy is a custom admission policy rejecting every transaction.
+type testPolicy struct{}
+
+var errTestPolicy = errors.New("rejected by test policy")
+
+func (testPolicy) Name() string { return "test" }
+
+func (testPolicy) Validate(tx *types.Transaction, from common.Address, local bool) error {
+	return errTestPolicy
+}
+
+// Tests that programmatically registered policies are consulted and survive a
+// reload of the configured ones.
+func TestTransactionCustomPolicy(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000000))
+	pool.AddPolicy(testPolicy{})
+	pool.SetPolicy(TxPoolPolicyConfig{RateLimit: 10, RateInterval: time.Minute})
+
+	tx := callTransaction(0, common.Address{}, big.NewInt(common.DefaultMinGasPrice), nil, key)
+	if err := pool.AddRemote(tx); err != errTestPolicy {
+		t.Fatalf("error mismatch: have %v, want %v", err, errTestPolicy)
+	}
+}
@@ -40,6 +40,9 @@ const (
 	chainHeadChanSize = 10
 	// rmTxChanSize is the size of channel listening to RemovedTransactionEvent.
 	rmTxChanSize = 10
+	// dropChanSize is the number of drop events buffered for delivery to the
+	// subscribers, any further ones being discarded.
+	dropChanSize = 1024
 )
 
 var (
@@ -85,6 +88,10 @@ var (
 
 	ErrDuplicateSpecialTransaction = errors.New("duplicate a special transaction")
 
+	// ErrSpecialTxQuota is returned if a masternode already holds the maximum
+	// number of special transactions permitted in the pool.
+	ErrSpecialTxQuota = errors.New("special transaction quota exceeded")
+
 	ErrMinDeploySMC = errors.New("smart contract creation cost is under allowance")
 )
 
@@ -106,9 +113,16 @@ var (
 	queuedRateLimitCounter = metrics.NewRegisteredCounter("txpool/queued/ratelimit", nil) // Dropped due to rate limiting
 	queuedNofundsCounter   = metrics.NewRegisteredCounter("txpool/queued/nofunds", nil)   // Dropped due to out-of-funds
 
+	// Metrics for the special transaction lane
+	specialAdmitCounter     = metrics.NewRegisteredCounter("txpool/special/admit", nil)
+	specialRateLimitCounter = metrics.NewRegisteredCounter("txpool/special/ratelimit", nil) // Rejected due to the per-masternode quota
+	specialEvictCounter     = metrics.NewRegisteredCounter("txpool/special/evict", nil)     // Dropped due to lane overflow or masternode rotation
+	specialGauge            = metrics.NewRegisteredGauge("txpool/special/size", nil)
+
 	// General tx metrics
 	invalidTxCounter     = metrics.NewRegisteredCounter("txpool/invalid", nil)
 	underpricedTxCounter = metrics.NewRegisteredCounter("txpool/underpriced", nil)
+	dropEventLossCounter = metrics.NewRegisteredCounter("txpool/dropevent/loss", nil) // Drop events discarded due to slow subscribers
 )
 
 // TxStatus is the current status of a transaction as seen by the pool.
@@ -137,6 +151,10 @@ type TxPoolConfig struct {
 	Journal   string        // Journal of local transactions to survive node restarts
 	Rejournal time.Duration // Time interval to regenerate the local transaction journal
 
+	RemoteJournal         string        // Journal of remote transactions to survive node restarts (disabled if empty)
+	RemoteJournalSize     uint64        // Maximum number of remote transactions kept in the journal
+	RemoteJournalLifetime time.Duration // Maximum age of remote transactions reloaded from the journal
+
 	PriceLimit uint64 // Minimum gas price to enforce for acceptance into the pool
 	PriceBump  uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
 
@@ -146,6 +164,15 @@ type TxPoolConfig struct {
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 
 	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
+
+	NonceThreshold uint64 // Maximum gap permitted between an account's pending nonce and a new transaction
+
+	SpecialSlots       uint64 // Maximum number of special transaction slots permitted per masternode
+	SpecialGlobalSlots uint64 // Maximum number of special transaction slots for all masternodes
+
+	Policy TxPoolPolicyConfig // Local admission policies enforced on top of the validation rules
+
+	TrackLifetime time.Duration // Amount of time dropped transactions are remembered for (0 = disabled)
 }
 
 // DefaultTxPoolConfig contains the default configurations for the transaction
@@ -154,6 +181,9 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	Journal:   "transactions.rlp",
 	Rejournal: time.Hour,
 
+	RemoteJournalSize:     4096,
+	RemoteJournalLifetime: 3 * time.Hour,
+
 	PriceLimit: 1,
 	PriceBump:  10,
 
@@ -163,6 +193,13 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	GlobalQueue:  1024,
 
 	Lifetime: 3 * time.Hour,
+
+	NonceThreshold: common.LimitThresholdNonceInQueue,
+
+	SpecialSlots:       16,
+	SpecialGlobalSlots: 2048,
+
+	TrackLifetime: time.Hour,
 }
 
 // sanitize checks the provided user configurations and changes anything that's
@@ -173,6 +210,10 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool journal time", "provided", conf.Rejournal, "updated", time.Second)
 		conf.Rejournal = time.Second
 	}
+	if conf.RemoteJournal != "" && conf.RemoteJournalSize < 1 {
+		log.Warn("Sanitizing invalid txpool remote journal size", "provided", conf.RemoteJournalSize, "updated", DefaultTxPoolConfig.RemoteJournalSize)
+		conf.RemoteJournalSize = DefaultTxPoolConfig.RemoteJournalSize
+	}
 	if conf.PriceLimit < 1 {
 		log.Warn("Sanitizing invalid txpool price limit", "provided", conf.PriceLimit, "updated", DefaultTxPoolConfig.PriceLimit)
 		conf.PriceLimit = DefaultTxPoolConfig.PriceLimit
@@ -181,6 +222,18 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool price bump", "provided", conf.PriceBump, "updated", DefaultTxPoolConfig.PriceBump)
 		conf.PriceBump = DefaultTxPoolConfig.PriceBump
 	}
+	if conf.NonceThreshold < 1 {
+		log.Warn("Sanitizing invalid txpool nonce threshold", "provided", conf.NonceThreshold, "updated", DefaultTxPoolConfig.NonceThreshold)
+		conf.NonceThreshold = DefaultTxPoolConfig.NonceThreshold
+	}
+	if conf.SpecialSlots < 1 {
+		log.Warn("Sanitizing invalid txpool special slots", "provided", conf.SpecialSlots, "updated", DefaultTxPoolConfig.SpecialSlots)
+		conf.SpecialSlots = DefaultTxPoolConfig.SpecialSlots
+	}
+	if conf.SpecialGlobalSlots < conf.SpecialSlots {
+		log.Warn("Sanitizing invalid txpool special global slots", "provided", conf.SpecialGlobalSlots, "updated", conf.SpecialSlots)
+		conf.SpecialGlobalSlots = conf.SpecialSlots
+	}
 	return conf
 }
 
@@ -197,6 +250,9 @@ type TxPool struct {
 	chain        blockChain
 	gasPrice     *big.Int
 	txFeed       event.Feed
+	dropFeed     event.Feed
+	dropCh       chan TxDropEvent
+	dropQuit     chan struct{}
 	scope        event.SubscriptionScope
 	chainHeadCh  chan ChainHeadEvent
 	chainHeadSub event.Subscription
@@ -207,8 +263,10 @@ type TxPool struct {
 	pendingState  *state.ManagedState // Pending state tracking virtual nonces
 	currentMaxGas uint64              // Current gas limit for transaction caps
 
-	locals  *accountSet // Set of local transaction to exempt from eviction rules
-	journal *txJournal  // Journal of local transaction to back up to disk
+	locals  *accountSet      // Set of local transaction to exempt from eviction rules
+	journal *txJournal       // Journal of local transaction to back up to disk
+	remotes *remoteTxJournal // Journal of remote transactions to back up to disk
+	tracker *txTracker       // Recently dropped transactions and their reasons
 
 	pending map[common.Address]*txList         // All currently processable transactions
 	queue   map[common.Address]*txList         // Queued but non-processable transactions
@@ -218,9 +276,18 @@ type TxPool struct {
 
 	wg sync.WaitGroup // for shutdown sync
 
+	policies []TxPoolPolicy // Admission policies built from the configuration
+	custom   []TxPoolPolicy // Admission policies registered programmatically
+
 	homestead        bool
-	IsSigner         func(address common.Address) bool
+	Signers          func() (map[common.Address]struct{}, error) // Masternodes of the current epoch, allowed to send special transactions
 	trc21FeeCapacity map[common.Address]*big.Int
+
+	signers       map[common.Address]struct{}    // Masternode set cached for the current head
+	signersErr    error                          // Error retrieving the masternode set of the current head
+	signersLoaded bool                           // Whether the masternode set was retrieved for the current head
+	specials      map[common.Hash]common.Address // Special transactions admitted into the lane, indexed by hash
+	specialCounts map[common.Address]int         // Number of special transactions in the lane per masternode
 }
 
 // NewTxPool creates a new transaction pool to gather, sort and filter inbound
@@ -240,30 +307,57 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		beats:            make(map[common.Address]time.Time),
 		all:              make(map[common.Hash]*types.Transaction),
 		chainHeadCh:      make(chan ChainHeadEvent, chainHeadChanSize),
+		dropCh:           make(chan TxDropEvent, dropChanSize),
+		dropQuit:         make(chan struct{}),
 		gasPrice:         new(big.Int).SetUint64(config.PriceLimit),
 		trc21FeeCapacity: map[common.Address]*big.Int{},
+		specials:         make(map[common.Hash]common.Address),
+		specialCounts:    make(map[common.Address]int),
 	}
 	pool.locals = newAccountSet(pool.signer)
 	pool.priced = newTxPricedList(&pool.all)
+	pool.policies = newTxPoolPolicies(config.Policy, pool.hasCode)
+	if config.TrackLifetime > 0 {
+		pool.tracker = newTxTracker(config.TrackLifetime)
+	}
 	pool.reset(nil, chain.CurrentBlock().Header())
 
 	// If local transactions and journaling is enabled, load from disk
 	if !config.NoLocals && config.Journal != "" {
 		pool.journal = newTxJournal(config.Journal)
 
-		if err := pool.journal.load(pool.AddLocal); err != nil {
+		// Journaled transactions were admitted once already, skip the policies
+		err := pool.journal.load(func(tx *types.Transaction) error {
+			return pool.addTx(tx, !pool.config.NoLocals, true)
+		})
+		if err != nil {
 			log.Warn("Failed to load transaction journal", "err", err)
 		}
 		if err := pool.journal.rotate(pool.local()); err != nil {
 			log.Warn("Failed to rotate transaction journal", "err", err)
 		}
 	}
+	// If remote transaction journaling is enabled, load the fresh ones from disk
+	if config.RemoteJournal != "" {
+		pool.remotes = newRemoteTxJournal(config.RemoteJournal, config.RemoteJournalSize, config.RemoteJournalLifetime)
+
+		err := pool.remotes.load(func(txs []*types.Transaction) []error {
+			return pool.addTxs(txs, false, true)
+		})
+		if err != nil {
+			log.Warn("Failed to load remote transaction journal", "err", err)
+		}
+		if err := pool.remotes.rotate(pool.remote()); err != nil {
+			log.Warn("Failed to rotate remote transaction journal", "err", err)
+		}
+	}
 	// Subscribe events from blockchain
 	pool.chainHeadSub = pool.chain.SubscribeChainHeadEvent(pool.chainHeadCh)
 
-	// Start the event loop and return
-	pool.wg.Add(1)
+	// Start the event loops and return
+	pool.wg.Add(2)
 	go pool.loop()
+	go pool.dropLoop()
 
 	return pool
 }
@@ -331,13 +425,13 @@ func (pool *TxPool) loop() {
 				// Any non-locals old enough should be removed
 				if time.Since(pool.beats[addr]) > pool.config.Lifetime {
 					for _, tx := range pool.queue[addr].Flatten() {
-						pool.removeTx(tx.Hash())
+						pool.removeTx(tx.Hash(), TxDropExpired)
 					}
 				}
 			}
 			pool.mu.Unlock()
 
-			// Handle local transaction journal rotation
+			// Handle local and remote transaction journal rotation
 		case <-journal.C:
 			if pool.journal != nil {
 				pool.mu.Lock()
@@ -346,6 +440,13 @@ func (pool *TxPool) loop() {
 				}
 				pool.mu.Unlock()
 			}
+			if pool.remotes != nil {
+				pool.mu.Lock()
+				if err := pool.remotes.rotate(pool.remote()); err != nil {
+					log.Warn("Failed to rotate remote tx journal", "err", err)
+				}
+				pool.mu.Unlock()
+			}
 		}
 	}
 }
@@ -423,9 +524,12 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	pool.pendingState = state.ManageState(statedb)
 	pool.currentMaxGas = newHead.GasLimit
 
+	// The masternode set may have rotated, retrieve it again on first use
+	pool.signers, pool.signersErr, pool.signersLoaded = nil, nil, false
+
 	// Inject any transactions discarded due to reorgs
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
-	pool.addTxsLocked(reinject, false)
+	pool.addTxsLocked(reinject, false, true)
 
 	// validate the pool of pending transactions, this will remove
 	// any transactions that have been included in the block or
@@ -433,6 +537,9 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	// higher gas price)
 	pool.demoteUnexecutables()
 
+	// Drop the special transactions of accounts rotated out of the masternode set
+	pool.demoteSpecials()
+
 	// Update all accounts to the latest known pending nonce
 	for addr, list := range pool.pending {
 		txs := list.Flatten() // Heavy but will be cached and is needed by the miner anyway
@@ -450,11 +557,15 @@ func (pool *TxPool) Stop() {
 
 	// Unsubscribe subscriptions registered from blockchain
 	pool.chainHeadSub.Unsubscribe()
+	close(pool.dropQuit)
 	pool.wg.Wait()
 
 	if pool.journal != nil {
 		pool.journal.close()
 	}
+	if pool.remotes != nil {
+		pool.remotes.close()
+	}
 	log.Info("Transaction pool stopped")
 }
 
@@ -464,6 +575,12 @@ func (pool *TxPool) SubscribeTxPreEvent(ch chan<- TxPreEvent) event.Subscription
 	return pool.scope.Track(pool.txFeed.Subscribe(ch))
 }
 
+// SubscribeTxDropEvent registers a subscription of TxDropEvent and starts sending
+// event to the given channel.
+func (pool *TxPool) SubscribeTxDropEvent(ch chan<- TxDropEvent) event.Subscription {
+	return pool.scope.Track(pool.dropFeed.Subscribe(ch))
+}
+
 // GasPrice returns the current gas price enforced by the transaction pool.
 func (pool *TxPool) GasPrice() *big.Int {
 	pool.mu.RLock()
@@ -480,11 +597,43 @@ func (pool *TxPool) SetGasPrice(price *big.Int) {
 
 	pool.gasPrice = price
 	for _, tx := range pool.priced.Cap(price, pool.locals) {
-		pool.removeTx(tx.Hash())
+		pool.removeTx(tx.Hash(), TxDropUnderpriced)
 	}
 	log.Info("Transaction pool price threshold updated", "price", price)
 }
 
+// Policy returns the configuration of the built-in admission policies currently
+// enforced by the transaction pool.
+func (pool *TxPool) Policy() TxPoolPolicyConfig {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	return pool.config.Policy
+}
+
+// SetPolicy replaces the built-in admission policies of the transaction pool,
+// keeping any programmatically registered ones. Transactions already in the pool
+// are not affected.
+func (pool *TxPool) SetPolicy(config TxPoolPolicyConfig) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.config.Policy = config
+	pool.policies = append(newTxPoolPolicies(config, pool.hasCode), pool.custom...)
+
+	log.Info("Transaction pool policies updated", "count", len(pool.policies))
+}
+
+// AddPolicy registers an additional admission policy, consulted after the ones
+// built from the configuration.
+func (pool *TxPool) AddPolicy(policy TxPoolPolicy) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.custom = append(pool.custom, policy)
+	pool.policies = append(pool.policies, policy)
+}
+
 // State returns the virtual managed state of the transaction pool.
 func (pool *TxPool) State() *state.ManagedState {
 	pool.mu.RLock()
@@ -563,6 +712,24 @@ func (pool *TxPool) local() map[common.Address]types.Transactions {
 	return txs
 }
 
+// remote retrieves all currently known remote transactions, grouped by origin
+// account and sorted by nonce, pending ones first. The returned transaction set
+// is a copy and can be freely modified by calling code.
+func (pool *TxPool) remote() map[common.Address]types.Transactions {
+	txs := make(map[common.Address]types.Transactions)
+	for addr, pending := range pool.pending {
+		if !pool.locals.contains(addr) {
+			txs[addr] = append(txs[addr], pending.Flatten()...)
+		}
+	}
+	for addr, queued := range pool.queue {
+		if !pool.locals.contains(addr) {
+			txs[addr] = append(txs[addr], queued.Flatten()...)
+		}
+	}
+	return txs
+}
+
 func (pool *TxPool) GetSender(tx *types.Transaction) (common.Address, error) {
 	from, err := types.Sender(pool.signer, tx)
 	if err != nil {
@@ -601,10 +768,12 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if err != nil {
 		return ErrInvalidSender
 	}
-	// Drop non-local transactions under our own minimal accepted gas price
+	// Drop non-local transactions under our own minimal accepted gas price. Only
+	// the special transactions of masternodes are exempt, the ones of any other
+	// account are priced as usual and kept out of the special lane.
 	local = local || pool.locals.contains(from) // account may be local even if the transaction arrived from the network
 	if !local && pool.gasPrice.Cmp(tx.GasPrice()) > 0 {
-		if !tx.IsSpecialTransaction() || (pool.IsSigner != nil && !pool.IsSigner(from)) {
+		if !tx.IsSpecialTransaction() || (pool.Signers != nil && !pool.isSigner(from)) {
 			return ErrUnderpriced
 		}
 	}
@@ -612,7 +781,7 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if pool.currentState.GetNonce(from) > tx.Nonce() {
 		return ErrNonceTooLow
 	}
-	if pool.pendingState.GetNonce(from)+common.LimitThresholdNonceInQueue < tx.Nonce() {
+	if pool.pendingState.GetNonce(from)+pool.config.NonceThreshold < tx.Nonce() {
 		return ErrNonceTooHigh
 	}
 	// Transactor should have enough funds to cover the costs
@@ -659,10 +828,38 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if tx.To() == nil && (tx.Cost().Cmp(minGasDeploySMC) < 0 || tx.GasPrice().Cmp(new(big.Int).SetUint64(10000*params.Shannon)) < 0) {
 		return ErrMinDeploySMC
 	}
+	return nil
+}
+
+// hasCode reports whether an account holds contract code in the current state.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) hasCode(addr common.Address) bool {
+	return pool.currentState.GetCodeSize(addr) > 0
+}
 
+// validatePolicies checks a transaction against the locally configured admission
+// policies, after it passed all the validation rules.
+func (pool *TxPool) validatePolicies(tx *types.Transaction, from common.Address, local bool) error {
+	for _, policy := range pool.policies {
+		if err := policy.Validate(tx, from, local); err != nil {
+			log.Trace("Transaction rejected by pool policy", "hash", tx.Hash(), "policy", policy.Name(), "err", err)
+			return err
+		}
+	}
 	return nil
 }
 
+// admitPolicies notifies the admission policies tracking the pooled transactions
+// that a transaction validated by them entered the pool.
+func (pool *TxPool) admitPolicies(tx *types.Transaction, from common.Address, local bool) {
+	for _, policy := range pool.policies {
+		if tracker, ok := policy.(TxPoolPolicyTracker); ok {
+			tracker.Admitted(tx, from, local)
+		}
+	}
+}
+
 // add validates a transaction and inserts it into the non-executable queue for
 // later pending promotion and execution. If the transaction is a replacement for
 // an already pending or queued one, it overwrites the previous and returns this
@@ -671,7 +868,10 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 // If a newly added transaction is marked as local, its sending account will be
 // whitelisted, preventing any associated transaction from being dropped out of
 // the pool due to pricing constraints.
-func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
+//
+// Restored transactions, which were admitted once already and are reinjected
+// after a reorg or reloaded from a journal, are exempt from the admission policies.
+func (pool *TxPool) add(tx *types.Transaction, local bool, restore bool) (bool, error) {
 	// If the transaction is already known, discard it
 	hash := tx.Hash()
 	if pool.all[hash] != nil {
@@ -686,11 +886,31 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 		return false, err
 	}
 	from, _ := types.Sender(pool.signer, tx) // already validated
-	if tx.IsSpecialTransaction() && pool.IsSigner != nil && pool.IsSigner(from) && pool.pendingState.GetNonce(from) == tx.Nonce() {
-		return pool.promoteSpecialTx(from, tx)
+
+	// Special transactions of masternodes go into their own lane, bounded by the
+	// per-masternode quota instead of the global pricing rules
+	special := tx.IsSpecialTransaction() && pool.Signers != nil && pool.isSigner(from)
+
+	// Enforce the locally configured admission policies last, except on the lane
+	enforce := !restore && !special
+	if enforce {
+		if err := pool.validatePolicies(tx, from, local || pool.locals.contains(from)); err != nil {
+			invalidTxCounter.Inc(1)
+			return false, err
+		}
+	}
+	if special {
+		if uint64(pool.specialCount(from, tx.Nonce())) >= pool.config.SpecialSlots {
+			log.Trace("Discarding special transaction over quota", "hash", hash, "from", from)
+			specialRateLimitCounter.Inc(1)
+			return false, ErrSpecialTxQuota
+		}
+		if pool.pendingState.GetNonce(from) == tx.Nonce() {
+			return pool.promoteSpecialTx(from, tx)
+		}
 	}
 	// If the transaction pool is full, discard underpriced transactions
-	if uint64(len(pool.all)) >= pool.config.GlobalSlots+pool.config.GlobalQueue {
+	if !special && uint64(len(pool.all)) >= pool.config.GlobalSlots+pool.config.GlobalQueue {
 		log.Debug("Add transaction to pool full", "hash", hash, "nonce", tx.Nonce())
 		// If the new transaction is underpriced, don't accept it
 		if pool.priced.Underpriced(tx, pool.locals) {
@@ -703,7 +923,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 		for _, tx := range drop {
 			log.Trace("Discarding freshly underpriced transaction", "hash", tx.Hash(), "price", tx.GasPrice())
 			underpricedTxCounter.Inc(1)
-			pool.removeTx(tx.Hash())
+			pool.removeTx(tx.Hash(), TxDropUnderpriced)
 		}
 	}
 	// If the transaction is replacing an already pending one, do directly
@@ -717,11 +937,19 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 		// New transaction is better, replace old one
 		if old != nil {
 			delete(pool.all, old.Hash())
+			pool.untrackSpecial(old.Hash())
 			pool.priced.Removed()
+			pool.trackReplace(old, tx)
 			pendingReplaceCounter.Inc(1)
 		}
 		pool.all[tx.Hash()] = tx
 		pool.priced.Put(tx)
+		if special {
+			pool.trackSpecial(hash, from)
+		}
+		if enforce {
+			pool.admitPolicies(tx, from, local || pool.locals.contains(from))
+		}
 		pool.journalTx(from, tx)
 
 		log.Trace("Pooled new executable transaction", "hash", hash, "from", from, "to", tx.To())
@@ -736,6 +964,13 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 	if err != nil {
 		return false, err
 	}
+	if special {
+		pool.trackSpecial(hash, from)
+		specialAdmitCounter.Inc(1)
+	}
+	if enforce {
+		pool.admitPolicies(tx, from, local || pool.locals.contains(from))
+	}
 	// Mark local addresses and journal local transactions
 	if local {
 		pool.locals.add(from)
@@ -764,7 +999,9 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction) (bool, er
 	// Discard any previous transaction and mark this
 	if old != nil {
 		delete(pool.all, old.Hash())
+		pool.untrackSpecial(old.Hash())
 		pool.priced.Removed()
+		pool.trackReplace(old, tx)
 		queuedReplaceCounter.Inc(1)
 	}
 	pool.all[hash] = tx
@@ -773,14 +1010,23 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction) (bool, er
 }
 
 // journalTx adds the specified transaction to the local disk journal if it is
-// deemed to have been sent from a local account.
+// deemed to have been sent from a local account, or to the remote one otherwise.
 func (pool *TxPool) journalTx(from common.Address, tx *types.Transaction) {
-	// Only journal if it's enabled and the transaction is local
-	if pool.journal == nil || !pool.locals.contains(from) {
+	if pool.locals.contains(from) {
+		// Only journal if it's enabled and the transaction is local
+		if pool.journal == nil {
+			return
+		}
+		if err := pool.journal.insert(tx); err != nil {
+			log.Warn("Failed to journal local transaction", "err", err)
+		}
+		return
+	}
+	if pool.remotes == nil {
 		return
 	}
-	if err := pool.journal.insert(tx); err != nil {
-		log.Warn("Failed to journal local transaction", "err", err)
+	if err := pool.remotes.insert(tx); err != nil {
+		log.Warn("Failed to journal remote transaction", "err", err)
 	}
 }
 
@@ -798,7 +1044,9 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 	if !inserted {
 		// An older transaction was better, discard this
 		delete(pool.all, hash)
+		pool.untrackSpecial(hash)
 		pool.priced.Removed()
+		pool.trackDrop(tx, TxDropUnderpriced)
 
 		pendingDiscardCounter.Inc(1)
 		return
@@ -806,7 +1054,9 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 	// Otherwise discard any previous transaction and mark this
 	if old != nil {
 		delete(pool.all, old.Hash())
+		pool.untrackSpecial(old.Hash())
 		pool.priced.Removed()
+		pool.trackReplace(old, tx)
 
 		pendingReplaceCounter.Inc(1)
 	}
@@ -835,7 +1085,9 @@ func (pool *TxPool) promoteSpecialTx(addr common.Address, tx *types.Transaction)
 	// Otherwise discard any previous transaction and mark this
 	if old != nil {
 		delete(pool.all, old.Hash())
+		pool.untrackSpecial(old.Hash())
 		pool.priced.Removed()
+		pool.trackReplace(old, tx)
 		pendingReplaceCounter.Inc(1)
 	}
 	list.txs.Put(tx)
@@ -849,51 +1101,206 @@ func (pool *TxPool) promoteSpecialTx(addr common.Address, tx *types.Transaction)
 	if pool.all[tx.Hash()] == nil {
 		pool.all[tx.Hash()] = tx
 	}
+	pool.trackSpecial(tx.Hash(), addr)
+
 	// Set the potentially new pending nonce and notify any subsystems of the new tx
 	pool.beats[addr] = time.Now()
 	pool.pendingState.SetNonce(addr, tx.Nonce()+1)
+	specialAdmitCounter.Inc(1)
+
 	go pool.txFeed.Send(TxPreEvent{tx})
-	return true, nil
+
+	// Not reported as a replacement, so the caller still enforces the lane limits
+	return false, nil
+}
+
+// specialCount returns the number of special transactions an account has in the
+// lane, both pending and queued. The transaction at the ignored nonce is skipped
+// as a new arrival would replace it rather than take up another slot.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) specialCount(addr common.Address, ignore uint64) int {
+	count := pool.specialCounts[addr]
+	for _, list := range []*txList{pool.pending[addr], pool.queue[addr]} {
+		if list == nil {
+			continue
+		}
+		if tx := list.txs.Get(ignore); tx != nil {
+			if _, ok := pool.specials[tx.Hash()]; ok {
+				count--
+			}
+		}
+	}
+	return count
+}
+
+// trackSpecial adds a transaction admitted into the special lane to the index.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) trackSpecial(hash common.Hash, addr common.Address) {
+	if _, ok := pool.specials[hash]; ok {
+		return
+	}
+	pool.specials[hash] = addr
+	pool.specialCounts[addr]++
+}
+
+// untrackSpecial removes a transaction leaving the pool from the special lane
+// index, if it was admitted into it.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) untrackSpecial(hash common.Hash) {
+	addr, ok := pool.specials[hash]
+	if !ok {
+		return
+	}
+	delete(pool.specials, hash)
+	if pool.specialCounts[addr]--; pool.specialCounts[addr] <= 0 {
+		delete(pool.specialCounts, addr)
+	}
+}
+
+// specialTxs gathers the special transactions of every account in the lane, both
+// pending and queued.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) specialTxs() map[common.Address]types.Transactions {
+	specials := make(map[common.Address]types.Transactions, len(pool.specialCounts))
+	for hash, addr := range pool.specials {
+		if tx := pool.all[hash]; tx != nil {
+			specials[addr] = append(specials[addr], tx)
+		}
+	}
+	return specials
+}
+
+// isSigner reports whether an account is a masternode of the current epoch. The
+// masternode set is only retrieved once per chain head.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) isSigner(addr common.Address) bool {
+	pool.loadSigners()
+	_, ok := pool.signers[addr]
+	return ok
+}
+
+// loadSigners retrieves the masternode set of the current head if it was not yet
+// retrieved since the last reset.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) loadSigners() {
+	if pool.signersLoaded || pool.Signers == nil {
+		return
+	}
+	pool.signers, pool.signersErr = pool.Signers()
+	if pool.signersErr != nil {
+		log.Warn("Failed to retrieve masternode set", "err", pool.signersErr)
+	}
+	pool.signersLoaded = true
+}
+
+// truncateSpecials drops special transactions once the special lane grows above
+// its global allowance, always evicting the highest nonce of the masternode that
+// currently holds the most slots.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) truncateSpecials() {
+	specials := pool.specialTxs()
+
+	total := 0
+	for _, txs := range specials {
+		total += len(txs)
+	}
+	if uint64(total) > pool.config.SpecialGlobalSlots {
+		// Assemble an eviction order penalizing the largest lane holders first
+		spammers := prque.New()
+		for addr, txs := range specials {
+			sort.Sort(types.TxByNonce(txs))
+			spammers.Push(addr, float32(len(txs)))
+		}
+		for uint64(total) > pool.config.SpecialGlobalSlots && !spammers.Empty() {
+			offender, _ := spammers.Pop()
+			addr := offender.(common.Address)
+
+			txs := specials[addr]
+			hash := txs[len(txs)-1].Hash()
+			pool.removeTx(hash, TxDropSpecialEvicted)
+			log.Trace("Removed lane-exceeding special transaction", "hash", hash, "from", addr)
+
+			if specials[addr] = txs[:len(txs)-1]; len(specials[addr]) > 0 {
+				spammers.Push(addr, float32(len(specials[addr])))
+			}
+			specialEvictCounter.Inc(1)
+			total--
+		}
+	}
+	specialGauge.Update(int64(total))
+}
+
+// demoteSpecials removes all special transactions of accounts which are no longer
+// masternodes of the current epoch. If the masternode set cannot be retrieved,
+// the transactions are kept until it can be.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) demoteSpecials() {
+	if pool.Signers == nil || len(pool.specials) == 0 {
+		return
+	}
+	if pool.loadSigners(); pool.signersErr != nil {
+		return
+	}
+	for addr, txs := range pool.specialTxs() {
+		if _, ok := pool.signers[addr]; ok {
+			continue
+		}
+		for _, tx := range txs {
+			hash := tx.Hash()
+			log.Trace("Removed special transaction of retired masternode", "hash", hash, "from", addr)
+			pool.removeTx(hash, TxDropSpecialEvicted)
+			specialEvictCounter.Inc(1)
+		}
+	}
 }
 
 // AddLocal enqueues a single transaction into the pool if it is valid, marking
 // the sender as a local one in the mean time, ensuring it goes around the local
 // pricing constraints.
 func (pool *TxPool) AddLocal(tx *types.Transaction) error {
-	return pool.addTx(tx, !pool.config.NoLocals)
+	return pool.addTx(tx, !pool.config.NoLocals, false)
 }
 
 // AddRemote enqueues a single transaction into the pool if it is valid. If the
 // sender is not among the locally tracked ones, full pricing constraints will
 // apply.
 func (pool *TxPool) AddRemote(tx *types.Transaction) error {
-	return pool.addTx(tx, false)
+	return pool.addTx(tx, false, false)
 }
 
 // AddLocals enqueues a batch of transactions into the pool if they are valid,
 // marking the senders as a local ones in the mean time, ensuring they go around
 // the local pricing constraints.
 func (pool *TxPool) AddLocals(txs []*types.Transaction) []error {
-	return pool.addTxs(txs, !pool.config.NoLocals)
+	return pool.addTxs(txs, !pool.config.NoLocals, false)
 }
 
 // AddRemotes enqueues a batch of transactions into the pool if they are valid.
 // If the senders are not among the locally tracked ones, full pricing constraints
 // will apply.
 func (pool *TxPool) AddRemotes(txs []*types.Transaction) []error {
-	return pool.addTxs(txs, false)
+	return pool.addTxs(txs, false, false)
 }
 
 // addTx enqueues a single transaction into the pool if it is valid.
-func (pool *TxPool) addTx(tx *types.Transaction, local bool) error {
+func (pool *TxPool) addTx(tx *types.Transaction, local bool, restore bool) error {
 	tx.CacheHash()
 	types.CacheSigner(pool.signer, tx)
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
 	// Try to inject the transaction and update any state
-	replace, err := pool.add(tx, local)
+	replace, err := pool.add(tx, local, restore)
 	if err != nil {
+		pool.trackReject(tx, err)
 		return err
 	}
 	// If we added a new transaction, run promotion checks and return
@@ -905,27 +1312,29 @@ func (pool *TxPool) addTx(tx *types.Transaction, local bool) error {
 }
 
 // addTxs attempts to queue a batch of transactions if they are valid.
-func (pool *TxPool) addTxs(txs []*types.Transaction, local bool) []error {
+func (pool *TxPool) addTxs(txs []*types.Transaction, local bool, restore bool) []error {
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
-	return pool.addTxsLocked(txs, local)
+	return pool.addTxsLocked(txs, local, restore)
 }
 
 // addTxsLocked attempts to queue a batch of transactions if they are valid,
 // whilst assuming the transaction pool lock is already held.
-func (pool *TxPool) addTxsLocked(txs []*types.Transaction, local bool) []error {
+func (pool *TxPool) addTxsLocked(txs []*types.Transaction, local bool, restore bool) []error {
 	// Add the batch of transaction, tracking the accepted ones
 	dirty := make(map[common.Address]struct{})
 	errs := make([]error, len(txs))
 
 	for i, tx := range txs {
 		var replace bool
-		if replace, errs[i] = pool.add(tx, local); errs[i] == nil {
+		if replace, errs[i] = pool.add(tx, local, restore); errs[i] == nil {
 			if !replace {
 				from, _ := types.Sender(pool.signer, tx) // already validated
 				dirty[from] = struct{}{}
 			}
+		} else {
+			pool.trackReject(tx, errs[i])
 		}
 	}
 	// Only reprocess the internal state if something was actually added
@@ -959,6 +1368,26 @@ func (pool *TxPool) Status(hashes []common.Hash) []TxStatus {
 	return status
 }
 
+// Lifecycle returns the status of a transaction as seen by the pool. If it is no
+// longer pooled, the reason it was dropped for is also returned, as long as the
+// drop is still remembered.
+func (pool *TxPool) Lifecycle(hash common.Hash) (TxStatus, *TxDrop) {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	if tx := pool.all[hash]; tx != nil {
+		from, _ := types.Sender(pool.signer, tx) // already validated
+		if pool.pending[from] != nil && pool.pending[from].txs.items[tx.Nonce()] != nil {
+			return TxStatusPending, nil
+		}
+		return TxStatusQueued, nil
+	}
+	if pool.tracker == nil {
+		return TxStatusUnknown, nil
+	}
+	return TxStatusUnknown, pool.tracker.get(hash)
+}
+
 // Get returns a transaction if it is contained in the pool
 // and nil otherwise.
 func (pool *TxPool) Get(hash common.Hash) *types.Transaction {
@@ -970,7 +1399,7 @@ func (pool *TxPool) Get(hash common.Hash) *types.Transaction {
 
 // removeTx removes a single transaction from the queue, moving all subsequent
 // transactions back to the future queue.
-func (pool *TxPool) removeTx(hash common.Hash) {
+func (pool *TxPool) removeTx(hash common.Hash, reason TxDropReason) {
 	// Fetch the transaction we wish to delete
 	tx, ok := pool.all[hash]
 	if !ok {
@@ -980,7 +1409,9 @@ func (pool *TxPool) removeTx(hash common.Hash) {
 
 	// Remove it from the list of known transactions
 	delete(pool.all, hash)
+	pool.untrackSpecial(hash)
 	pool.priced.Removed()
+	pool.trackDrop(tx, reason)
 
 	// Remove the transaction from the pending lists and reset the account nonce
 	if pending := pool.pending[addr]; pending != nil {
@@ -1035,6 +1466,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			hash := tx.Hash()
 			log.Trace("Removed old queued transaction", "hash", hash)
 			delete(pool.all, hash)
+			pool.untrackSpecial(hash)
 			pool.priced.Removed()
 		}
 		// Drop all transactions that are too costly (low balance or out of gas)
@@ -1043,7 +1475,9 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			hash := tx.Hash()
 			log.Trace("Removed unpayable queued transaction", "hash", hash)
 			delete(pool.all, hash)
+			pool.untrackSpecial(hash)
 			pool.priced.Removed()
+			pool.trackDrop(tx, pool.unpayableReason(tx))
 			queuedNofundsCounter.Inc(1)
 		}
 		// Gather all executable transactions and promote them
@@ -1057,7 +1491,9 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			for _, tx := range list.Cap(int(pool.config.AccountQueue)) {
 				hash := tx.Hash()
 				delete(pool.all, hash)
+				pool.untrackSpecial(hash)
 				pool.priced.Removed()
+				pool.trackDrop(tx, TxDropRateLimit)
 				queuedRateLimitCounter.Inc(1)
 				log.Trace("Removed cap-exceeding queued transaction", "hash", hash)
 			}
@@ -1102,7 +1538,9 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 							// Drop the transaction from the global pools too
 							hash := tx.Hash()
 							delete(pool.all, hash)
+							pool.untrackSpecial(hash)
 							pool.priced.Removed()
+							pool.trackDrop(tx, TxDropRateLimit)
 
 							// Update the account nonce to the dropped transaction
 							if nonce := tx.Nonce(); pool.pendingState.GetNonce(offenders[i]) > nonce {
@@ -1124,7 +1562,9 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 						// Drop the transaction from the global pools too
 						hash := tx.Hash()
 						delete(pool.all, hash)
+						pool.untrackSpecial(hash)
 						pool.priced.Removed()
+						pool.trackDrop(tx, TxDropRateLimit)
 
 						// Update the account nonce to the dropped transaction
 						if nonce := tx.Nonce(); pool.pendingState.GetNonce(addr) > nonce {
@@ -1163,7 +1603,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			// Drop all transactions if they are less than the overflow
 			if size := uint64(list.Len()); size <= drop {
 				for _, tx := range list.Flatten() {
-					pool.removeTx(tx.Hash())
+					pool.removeTx(tx.Hash(), TxDropRateLimit)
 				}
 				drop -= size
 				queuedRateLimitCounter.Inc(int64(size))
@@ -1172,12 +1612,14 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			// Otherwise drop only last few transactions
 			txs := list.Flatten()
 			for i := len(txs) - 1; i >= 0 && drop > 0; i-- {
-				pool.removeTx(txs[i].Hash())
+				pool.removeTx(txs[i].Hash(), TxDropRateLimit)
 				drop--
 				queuedRateLimitCounter.Inc(1)
 			}
 		}
 	}
+	// Keep the special transaction lane within its global allowance
+	pool.truncateSpecials()
 }
 
 // demoteUnexecutables removes invalid and processed transactions from the pools
@@ -1193,6 +1635,7 @@ func (pool *TxPool) demoteUnexecutables() {
 			hash := tx.Hash()
 			log.Trace("Removed old pending transaction", "hash", hash)
 			delete(pool.all, hash)
+			pool.untrackSpecial(hash)
 			pool.priced.Removed()
 		}
 		// Drop all transactions that are too costly (low balance or out of gas), and queue any invalids back for later
@@ -1201,7 +1644,9 @@ func (pool *TxPool) demoteUnexecutables() {
 			hash := tx.Hash()
 			log.Trace("Removed unpayable pending transaction", "hash", hash)
 			delete(pool.all, hash)
+			pool.untrackSpecial(hash)
 			pool.priced.Removed()
+			pool.trackDrop(tx, pool.unpayableReason(tx))
 			pendingNofundsCounter.Inc(1)
 		}
 		for _, tx := range invalids {
@@ -1225,6 +1670,98 @@ func (pool *TxPool) demoteUnexecutables() {
 	}
 }
 
+// trackDrop remembers a transaction dropped out of the pool along with the reason
+// and notifies any subscribers.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) trackDrop(tx *types.Transaction, reason TxDropReason) {
+	pool.track(tx, &TxDrop{Hash: tx.Hash(), Reason: reason, Time: time.Now()})
+}
+
+// trackReplace remembers a transaction replaced by another one with the same
+// nonce and notifies any subscribers.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) trackReplace(old, tx *types.Transaction) {
+	pool.track(old, &TxDrop{Hash: old.Hash(), Reason: TxDropReplaced, Replacement: tx.Hash(), Time: time.Now()})
+}
+
+// trackReject remembers a transaction rejected on admission along with the
+// validation error and notifies any subscribers. Already known transactions are
+// ignored as they are still pooled.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) trackReject(tx *types.Transaction, err error) {
+	hash := tx.Hash()
+	if pool.all[hash] != nil {
+		return
+	}
+	var reason TxDropReason
+	switch err {
+	case ErrUnderpriced, ErrReplaceUnderpriced, ErrZeroGasPrice, ErrUnderMinGasPrice, ErrPolicyUnderpriced:
+		reason = TxDropUnderpriced
+	case ErrNonceTooLow:
+		reason = TxDropNonceTooLow
+	case ErrNonceTooHigh:
+		reason = TxDropNonceGap
+	case ErrGasLimit:
+		reason = TxDropGasLimit
+	case ErrInsufficientFunds:
+		reason = pool.unpayableReason(tx)
+	case ErrSpecialTxQuota, ErrPolicyRateLimit:
+		reason = TxDropRateLimit
+	default:
+		reason = TxDropInvalid
+		if (tx.From() != nil && common.Blacklist[*tx.From()]) || (tx.To() != nil && common.Blacklist[*tx.To()]) {
+			reason = TxDropBlacklisted
+		}
+	}
+	pool.track(tx, &TxDrop{Hash: hash, Reason: reason, Error: err.Error(), Time: time.Now()})
+}
+
+// unpayableReason returns the reason an unpayable transaction is dropped for,
+// distinguishing sponsored TRC21 transactions from plain ones.
+func (pool *TxPool) unpayableReason(tx *types.Transaction) TxDropReason {
+	if tx.Gas() > pool.currentMaxGas {
+		return TxDropGasLimit
+	}
+	if tx.To() != nil {
+		if _, ok := pool.trc21FeeCapacity[*tx.To()]; ok {
+			return TxDropTRC21Capacity
+		}
+	}
+	return TxDropNoFunds
+}
+
+// track records a drop in the tracker (if enabled) and posts it to subscribers.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) track(tx *types.Transaction, drop *TxDrop) {
+	if pool.tracker != nil {
+		pool.tracker.add(drop)
+	}
+	select {
+	case pool.dropCh <- TxDropEvent{Tx: tx, Drop: drop}:
+	default:
+		dropEventLossCounter.Inc(1)
+	}
+}
+
+// dropLoop delivers the drop events to the subscribers from a single goroutine,
+// so a flood of rejected transactions cannot pile up blocked senders.
+func (pool *TxPool) dropLoop() {
+	defer pool.wg.Done()
+
+	for {
+		select {
+		case ev := <-pool.dropCh:
+			pool.dropFeed.Send(ev)
+		case <-pool.dropQuit:
+			return
+		}
+	}
+}
+
 // addressByHeartbeat is an account address tagged with its last activity timestamp.
 type addressByHeartbeat struct {
 	address   common.Address
@@ -18,11 +18,13 @@ package core
 
 import (
 	"crypto/ecdsa"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"math/big"
 	"math/rand"
 	"os"
+	"runtime"
 	"testing"
 	"time"
 
@@ -33,6 +35,7 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 )
 
 // testTxPoolConfig is a transaction pool configuration without stateful disk
@@ -347,14 +350,14 @@ func TestTransactionChainFork(t *testing.T) {
 	resetState()
 
 	tx := transaction(0, 100000, key)
-	if _, err := pool.add(tx, false); err != nil {
+	if _, err := pool.add(tx, false, false); err != nil {
 		t.Error("didn't expect error", err)
 	}
-	pool.removeTx(tx.Hash())
+	pool.removeTx(tx.Hash(), TxDropInvalid)
 
 	// reset the pool's internal state
 	resetState()
-	if _, err := pool.add(tx, false); err != nil {
+	if _, err := pool.add(tx, false, false); err != nil {
 		t.Error("didn't expect error", err)
 	}
 }
@@ -382,10 +385,10 @@ func TestTransactionDoubleNonce(t *testing.T) {
 	tx3, _ := types.SignTx(types.NewTransaction(0, common.Address{}, big.NewInt(100), 1000000, big.NewInt(1), nil), signer, key)
 
 	// Add the first two transaction, ensure higher priced stays only
-	if replace, err := pool.add(tx1, false); err != nil || replace {
+	if replace, err := pool.add(tx1, false, false); err != nil || replace {
 		t.Errorf("first transaction insert failed (%v) or reported replacement (%v)", err, replace)
 	}
-	if replace, err := pool.add(tx2, false); err != nil || !replace {
+	if replace, err := pool.add(tx2, false, false); err != nil || !replace {
 		t.Errorf("second transaction insert failed (%v) or not reported replacement (%v)", err, replace)
 	}
 	pool.promoteExecutables([]common.Address{addr})
@@ -396,7 +399,7 @@ func TestTransactionDoubleNonce(t *testing.T) {
 		t.Errorf("transaction mismatch: have %x, want %x", tx.Hash(), tx2.Hash())
 	}
 	// Add the third transaction and ensure it's not saved (smaller price)
-	pool.add(tx3, false)
+	pool.add(tx3, false, false)
 	pool.promoteExecutables([]common.Address{addr})
 	if pool.pending[addr].Len() != 1 {
 		t.Error("expected 1 pending transactions, got", pool.pending[addr].Len())
@@ -419,7 +422,7 @@ func TestTransactionMissingNonce(t *testing.T) {
 	addr := crypto.PubkeyToAddress(key.PublicKey)
 	pool.currentState.AddBalance(addr, big.NewInt(100000000000000))
 	tx := transaction(1, 100000, key)
-	if _, err := pool.add(tx, false); err != nil {
+	if _, err := pool.add(tx, false, false); err != nil {
 		t.Error("didn't expect error", err)
 	}
 	if len(pool.pending) != 0 {
@@ -1627,6 +1630,179 @@ func testTransactionJournaling(t *testing.T, nolocals bool) {
 	pool.Stop()
 }
 
+// Tests that remote transactions are persisted into the remote journal up to its
+// size limit and revalidated against the new head state when reloaded.
+func TestTransactionRemoteJournaling(t *testing.T) {
+	t.Parallel()
+
+	// Create a temporary file for the journal
+	file, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary journal: %v", err)
+	}
+	journal := file.Name()
+	defer os.Remove(journal)
+
+	// Clean up the temporary file, we only need the path for now
+	file.Close()
+	os.Remove(journal)
+
+	// Create the original pool to inject transaction into the journal
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.RemoteJournal = journal
+	config.RemoteJournalSize = 2
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+
+	remote, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(remote.PublicKey), big.NewInt(1000000000000))
+
+	// Add three remote transactions, only the first two fitting into the journal
+	price := big.NewInt(common.DefaultMinGasPrice)
+	for i := uint64(0); i < 3; i++ {
+		if err := pool.AddRemote(pricedTransaction(i, 100000, price, remote)); err != nil {
+			t.Fatalf("failed to add remote transaction %d: %v", i, err)
+		}
+	}
+	if pending, _ := pool.Stats(); pending != 3 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 3)
+	}
+	// Terminate the old pool, bump the nonce, create a new pool and ensure only the
+	// journaled and still valid transaction survives
+	pool.Stop()
+	statedb.SetNonce(crypto.PubkeyToAddress(remote.PublicKey), 1)
+	blockchain = &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	pool = NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	pending, queued := pool.Stats()
+	if pending != 1 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 1)
+	}
+	if queued != 0 {
+		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 0)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that the remote journal drops expired entries and tolerates a truncated
+// trailing entry left behind by a crash.
+func TestRemoteJournalRecovery(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	path := dir + "/remotes.rlp"
+	journal := newRemoteTxJournal(path, 16, time.Hour)
+
+	// Journal a fresh and an expired transaction, followed by a broken write
+	fresh := pricedTransaction(0, 100000, big.NewInt(1), key)
+	expired := pricedTransaction(1, 100000, big.NewInt(1), key)
+
+	journal.seen[expired.Hash()] = uint64(time.Now().Add(-2 * time.Hour).Unix())
+	if err := journal.rotate(map[common.Address]types.Transactions{addr: {fresh, expired}}); err != nil {
+		t.Fatalf("failed to rotate journal: %v", err)
+	}
+	journal.close()
+
+	output, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0755)
+	if err != nil {
+		t.Fatalf("failed to open journal: %v", err)
+	}
+	blob, _ := rlp.EncodeToBytes(&journalEntry{Tx: pricedTransaction(2, 100000, big.NewInt(1), key)})
+	output.Write(blob[:len(blob)/2])
+	output.Close()
+
+	// Reload the journal and ensure only the fresh transaction is injected
+	var loaded []*types.Transaction
+	journal = newRemoteTxJournal(path, 16, time.Hour)
+	err = journal.load(func(txs []*types.Transaction) []error {
+		loaded = append(loaded, txs...)
+		return make([]error, len(txs))
+	})
+	if err != nil {
+		t.Fatalf("failed to load journal: %v", err)
+	}
+	if len(loaded) != 1 || loaded[0].Hash() != fresh.Hash() {
+		t.Fatalf("loaded transactions mismatch: have %v, want [%x]", loaded, fresh.Hash())
+	}
+}
+
+// Tests that rotating an oversized remote journal retains the highest priced
+// transactions without leaving nonce gaps, and forgets the dropped ones.
+func TestRemoteJournalRotation(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	cheap, _ := crypto.GenerateKey()
+	pricey, _ := crypto.GenerateKey()
+
+	journal := newRemoteTxJournal(dir+"/remotes.rlp", 3, time.Hour)
+
+	// The pricey account's second transaction outbids every cheap one, but is
+	// only reachable after its underpriced first one
+	all := map[common.Address]types.Transactions{
+		crypto.PubkeyToAddress(cheap.PublicKey): {
+			pricedTransaction(1, 100000, big.NewInt(3), cheap),
+			pricedTransaction(0, 100000, big.NewInt(3), cheap),
+			pricedTransaction(2, 100000, big.NewInt(3), cheap),
+		},
+		crypto.PubkeyToAddress(pricey.PublicKey): {
+			pricedTransaction(0, 100000, big.NewInt(2), pricey),
+			pricedTransaction(1, 100000, big.NewInt(5), pricey),
+		},
+	}
+	if err := journal.rotate(all); err != nil {
+		t.Fatalf("failed to rotate journal: %v", err)
+	}
+	journal.close()
+
+	want := []*types.Transaction{
+		pricedTransaction(0, 100000, big.NewInt(3), cheap),
+		pricedTransaction(1, 100000, big.NewInt(3), cheap),
+		pricedTransaction(2, 100000, big.NewInt(3), cheap),
+	}
+	if len(journal.seen) != len(want) {
+		t.Errorf("seen timestamp count mismatch: have %d, want %d", len(journal.seen), len(want))
+	}
+	var loaded []*types.Transaction
+	journal = newRemoteTxJournal(dir+"/remotes.rlp", 3, time.Hour)
+	err = journal.load(func(txs []*types.Transaction) []error {
+		loaded = append(loaded, txs...)
+		return make([]error, len(txs))
+	})
+	if err != nil {
+		t.Fatalf("failed to load journal: %v", err)
+	}
+	if len(loaded) != len(want) {
+		t.Fatalf("loaded transaction count mismatch: have %d, want %d", len(loaded), len(want))
+	}
+	for i, tx := range loaded {
+		if tx.Hash() != want[i].Hash() {
+			t.Errorf("transaction %d mismatch: have %x, want %x", i, tx.Hash(), want[i].Hash())
+		}
+	}
+}
+
 // TestTransactionStatusCheck tests that the pool can correctly retrieve the
 // pending status of individual transactions.
 func TestTransactionStatusCheck(t *testing.T) {
@@ -1684,6 +1860,263 @@ func TestTransactionStatusCheck(t *testing.T) {
 	}
 }
 
+func specialTransaction(nonce uint64, key *ecdsa.PrivateKey) *types.Transaction {
+	tx, _ := types.SignTx(types.NewTransaction(nonce, common.HexToAddress(common.BlockSigners), big.NewInt(0), 100000, big.NewInt(0), nil), types.HomesteadSigner{}, key)
+	return tx
+}
+
+// Tests that only the special transactions of masternodes enter the special lane and that
+// each masternode is limited to its own quota of special slots.
+func TestSpecialTransactionAdmission(t *testing.T) {
+	t.Parallel()
+
+	// Create the pool to test the special lane admission with
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.SpecialSlots = 4
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	masternode, _ := crypto.GenerateKey()
+	stranger, _ := crypto.GenerateKey()
+
+	pool.Signers = func() (map[common.Address]struct{}, error) {
+		return map[common.Address]struct{}{crypto.PubkeyToAddress(masternode.PublicKey): {}}, nil
+	}
+	// Ensure non-masternodes are priced as usual and kept out of the special lane
+	if err := pool.AddRemote(specialTransaction(0, stranger)); err != ErrUnderpriced {
+		t.Fatalf("underpriced special transaction from stranger error mismatch: have %v, want %v", err, ErrUnderpriced)
+	}
+	priced, _ := types.SignTx(types.NewTransaction(0, common.HexToAddress(common.BlockSigners), big.NewInt(0), 100000, big.NewInt(1), nil), types.HomesteadSigner{}, stranger)
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(stranger.PublicKey), big.NewInt(1000000))
+	if err := pool.AddRemote(priced); err != nil {
+		t.Fatalf("priced special transaction from stranger: failed to add: %v", err)
+	}
+	if _, ok := pool.specials[priced.Hash()]; ok {
+		t.Fatalf("special transaction from stranger tracked in the special lane")
+	}
+	if count := pool.specialCount(crypto.PubkeyToAddress(stranger.PublicKey), 1); count != 0 {
+		t.Fatalf("stranger special transaction count mismatch: have %d, want %d", count, 0)
+	}
+	pool.removeTx(priced.Hash(), TxDropInvalid)
+	// Fill up the quota of the masternode, both executable and future
+	for i := uint64(0); i < 3; i++ {
+		if err := pool.AddRemote(specialTransaction(i, masternode)); err != nil {
+			t.Fatalf("special transaction %d: failed to add: %v", i, err)
+		}
+	}
+	if err := pool.AddRemote(specialTransaction(5, masternode)); err != nil {
+		t.Fatalf("future special transaction: failed to add: %v", err)
+	}
+	pending, queued := pool.Stats()
+	if pending != 3 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 3)
+	}
+	if queued != 1 {
+		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 1)
+	}
+	// Ensure any further special transaction is rejected
+	if err := pool.AddRemote(specialTransaction(3, masternode)); err != ErrSpecialTxQuota {
+		t.Fatalf("special transaction over quota error mismatch: have %v, want %v", err, ErrSpecialTxQuota)
+	}
+}
+
+// Tests that if the special lane grows above its global allowance, transactions
+// are evicted from the masternodes holding the most slots, both for executable
+// and future special transactions.
+func TestSpecialTransactionGlobalLimiting(t *testing.T) {
+	testSpecialTransactionGlobalLimiting(t, 0)
+	testSpecialTransactionGlobalLimiting(t, 1)
+}
+
+func testSpecialTransactionGlobalLimiting(t *testing.T, first uint64) {
+	// Create the pool to test the special lane limiting with
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.SpecialSlots = 4
+	config.SpecialGlobalSlots = 6
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	keys := make([]*ecdsa.PrivateKey, 2)
+	signers := make(map[common.Address]struct{})
+	for i := 0; i < len(keys); i++ {
+		keys[i], _ = crypto.GenerateKey()
+		signers[crypto.PubkeyToAddress(keys[i].PublicKey)] = struct{}{}
+	}
+	pool.Signers = func() (map[common.Address]struct{}, error) { return signers, nil }
+
+	// Add special transactions, first filling one masternode's quota
+	for _, key := range keys {
+		for i := first; i < first+4; i++ {
+			if err := pool.AddRemote(specialTransaction(i, key)); err != nil {
+				t.Fatalf("first %d: special transaction %d: failed to add: %v", first, i, err)
+			}
+		}
+	}
+	// Ensure the lane was equalized between the two masternodes
+	for i, key := range keys {
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		if count := pool.specialCount(addr, first+4); count != 3 {
+			t.Errorf("first %d: masternode %d: special transaction count mismatch: have %d, want %d", first, i, count, 3)
+		}
+		if pool.all[specialTransaction(first+3, key).Hash()] != nil {
+			t.Errorf("first %d: masternode %d: highest nonce special transaction not evicted", first, i)
+		}
+	}
+}
+
+// Tests that special transactions of accounts rotated out of the masternode set
+// are dropped on the next pool reset.
+func TestSpecialTransactionMasternodeRotation(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	var (
+		signers = map[common.Address]struct{}{crypto.PubkeyToAddress(key.PublicKey): {}}
+		failure error
+	)
+	pool.Signers = func() (map[common.Address]struct{}, error) { return signers, failure }
+
+	for i := uint64(0); i < 3; i++ {
+		if err := pool.AddRemote(specialTransaction(i, key)); err != nil {
+			t.Fatalf("special transaction %d: failed to add: %v", i, err)
+		}
+	}
+	if pending, _ := pool.Stats(); pending != 3 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 3)
+	}
+	// Ensure the transactions are kept if the masternode set is unavailable
+	signers, failure = nil, errors.New("snapshot unavailable")
+	pool.lockedReset(nil, nil)
+
+	if pending, _ := pool.Stats(); pending != 3 {
+		t.Fatalf("special transactions dropped on masternode set failure: have %d, want %d", pending, 3)
+	}
+	// Ensure they are dropped once the masternode set is known to have rotated
+	signers, failure = map[common.Address]struct{}{}, nil
+	pool.lockedReset(nil, nil)
+
+	if pending, queued := pool.Stats(); pending+queued != 0 {
+		t.Fatalf("special transactions of retired masternode not dropped: %d pending, %d queued", pending, queued)
+	}
+}
+
+// Tests that the pool remembers why transactions were rejected or dropped, and
+// notifies subscribers about them.
+func TestTransactionDropTracking(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000000))
+
+	drops := make(chan TxDropEvent, 16)
+	sub := pool.SubscribeTxDropEvent(drops)
+	defer sub.Unsubscribe()
+
+	// Replace a pending transaction and reject one with a nonce gap
+	price := big.NewInt(common.DefaultMinGasPrice)
+
+	original := pricedTransaction(0, 100000, price, key)
+	replacement := pricedTransaction(0, 100000, new(big.Int).Mul(price, big.NewInt(2)), key)
+	gapped := pricedTransaction(100, 100000, price, key)
+
+	if err := pool.AddRemote(original); err != nil {
+		t.Fatalf("failed to add original transaction: %v", err)
+	}
+	if err := pool.AddRemote(replacement); err != nil {
+		t.Fatalf("failed to add replacement transaction: %v", err)
+	}
+	if err := pool.AddRemote(gapped); err != ErrNonceTooHigh {
+		t.Fatalf("gapped transaction error mismatch: have %v, want %v", err, ErrNonceTooHigh)
+	}
+	// Ensure the reasons are reported for the dropped ones only
+	if status, drop := pool.Lifecycle(replacement.Hash()); status != TxStatusPending || drop != nil {
+		t.Errorf("replacement lifecycle mismatch: have %v/%v, want %v/nil", status, drop, TxStatusPending)
+	}
+	if _, drop := pool.Lifecycle(original.Hash()); drop == nil || drop.Reason != TxDropReplaced || drop.Replacement != replacement.Hash() {
+		t.Errorf("original drop mismatch: have %+v, want replaced by %x", drop, replacement.Hash())
+	}
+	if _, drop := pool.Lifecycle(gapped.Hash()); drop == nil || drop.Reason != TxDropNonceGap {
+		t.Errorf("gapped drop mismatch: have %+v, want %v", drop, TxDropNonceGap)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-drops:
+		case <-time.After(time.Second):
+			t.Fatalf("drop event #%d not fired", i)
+		}
+	}
+}
+
+// Tests that a flood of dropped transactions towards a stalled subscriber does
+// not pile up goroutines, nor block the pool from shutting down.
+func TestTransactionDropFlood(t *testing.T) {
+	pool, key := setupTxPool()
+
+	drops := make(chan TxDropEvent)
+	sub := pool.SubscribeTxDropEvent(drops)
+	defer sub.Unsubscribe()
+
+	tx := transaction(0, 100000, key)
+
+	before := runtime.NumGoroutine()
+	pool.mu.Lock()
+	for i := 0; i < 2*dropChanSize; i++ {
+		pool.track(tx, &TxDrop{Hash: tx.Hash(), Reason: TxDropInvalid, Time: time.Now()})
+	}
+	pool.mu.Unlock()
+
+	if after := runtime.NumGoroutine(); after > before+10 {
+		t.Errorf("goroutines piled up: have %d, had %d", after, before)
+	}
+	done := make(chan struct{})
+	go func() {
+		pool.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("pool shutdown blocked by stalled subscriber")
+	}
+}
+
+// Tests that the drop tracker forgets records after its lifetime.
+func TestTransactionDropExpiration(t *testing.T) {
+	t.Parallel()
+
+	tracker := newTxTracker(time.Minute)
+
+	stale := &TxDrop{Hash: common.HexToHash("0x01"), Reason: TxDropExpired, Time: time.Now().Add(-2 * time.Minute)}
+	fresh := &TxDrop{Hash: common.HexToHash("0x02"), Reason: TxDropExpired, Time: time.Now()}
+
+	tracker.add(stale)
+	tracker.add(fresh)
+
+	if drop := tracker.get(stale.Hash); drop != nil {
+		t.Errorf("stale drop not forgotten: %+v", drop)
+	}
+	if drop := tracker.get(fresh.Hash); drop != fresh {
+		t.Errorf("fresh drop mismatch: have %+v, want %+v", drop, fresh)
+	}
+	if len(tracker.drops) != 1 || len(tracker.order) != 1 {
+		t.Errorf("tracker size mismatch: have %d/%d, want 1/1", len(tracker.drops), len(tracker.order))
+	}
+}
+
 // Benchmarks the speed of validating the contents of the pending queue of the
 // transaction pool.
 func BenchmarkPendingDemotion100(b *testing.B)   { benchmarkPendingDemotion(b, 100) }
@@ -0,0 +1,111 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// maxTrackedDrops is the maximum number of dropped transactions remembered by
+// the transaction pool, irrelevant of the tracking lifetime.
+const maxTrackedDrops = 65536
+
+// TxDropReason describes why a transaction was rejected from or dropped out of
+// the transaction pool.
+type TxDropReason string
+
+const (
+	TxDropUnderpriced    TxDropReason = "underpriced"                  // Pool full or replacement price bump not met
+	TxDropReplaced       TxDropReason = "replaced"                     // Replaced by another transaction with the same nonce
+	TxDropNonceTooLow    TxDropReason = "nonce too low"                // Nonce already used on chain
+	TxDropNonceGap       TxDropReason = "nonce gap"                    // Nonce too far ahead of the account's pending one
+	TxDropBlacklisted    TxDropReason = "blacklisted"                  // Sender or recipient is blacklisted
+	TxDropNoFunds        TxDropReason = "insufficient funds"           // Sender cannot pay for the transaction
+	TxDropTRC21Capacity  TxDropReason = "trc21 fee capacity exhausted" // Token issuer cannot sponsor the transaction
+	TxDropGasLimit       TxDropReason = "exceeds block gas limit"      // Gas limit above the current block's
+	TxDropRateLimit      TxDropReason = "rate limited"                 // Evicted due to account or global slot limits
+	TxDropExpired        TxDropReason = "expired"                      // Queued for longer than the pool lifetime
+	TxDropSpecialEvicted TxDropReason = "special lane evicted"         // Evicted from the special transaction lane
+	TxDropInvalid        TxDropReason = "invalid"                      // Failed any other validation rule
+)
+
+// TxDrop records a transaction that left the transaction pool without being
+// included, together with the reason for it.
+type TxDrop struct {
+	Hash        common.Hash  // Hash of the dropped transaction
+	Reason      TxDropReason // Reason the transaction was dropped for
+	Error       string       // Detailed validation error, if rejected on admission
+	Replacement common.Hash  // Hash of the replacing transaction, if replaced
+	Time        time.Time    // Time the transaction was dropped at
+}
+
+// trackedDrop is an entry in the expiration order of the tracker.
+type trackedDrop struct {
+	hash common.Hash
+	time time.Time
+}
+
+// txTracker remembers the transactions dropped by the pool for a limited time,
+// allowing users to find out why their transactions disappeared.
+type txTracker struct {
+	lifetime time.Duration           // Maximum amount of time a drop is remembered
+	drops    map[common.Hash]*TxDrop // Currently remembered drops
+	order    []trackedDrop           // Drops in insertion order for expiration
+}
+
+// newTxTracker creates a new dropped transaction tracker.
+func newTxTracker(lifetime time.Duration) *txTracker {
+	return &txTracker{
+		lifetime: lifetime,
+		drops:    make(map[common.Hash]*TxDrop),
+	}
+}
+
+// add records a dropped transaction, expiring any stale records.
+func (t *txTracker) add(drop *TxDrop) {
+	t.drops[drop.Hash] = drop
+	t.order = append(t.order, trackedDrop{drop.Hash, drop.Time})
+
+	t.expire(drop.Time)
+}
+
+// get retrieves the drop record of a transaction if it's still remembered.
+func (t *txTracker) get(hash common.Hash) *TxDrop {
+	drop := t.drops[hash]
+	if drop == nil || time.Since(drop.Time) > t.lifetime {
+		return nil
+	}
+	return drop
+}
+
+// expire forgets all drops older than the tracking lifetime, as well as the
+// oldest ones above the tracking limit.
+func (t *txTracker) expire(now time.Time) {
+	for len(t.order) > 0 {
+		head := t.order[0]
+		if len(t.order) <= maxTrackedDrops && now.Sub(head.time) <= t.lifetime {
+			break
+		}
+		// Only forget the record if it wasn't overwritten by a later drop
+		if drop := t.drops[head.hash]; drop != nil && drop.Time.Equal(head.time) {
+			delete(t.drops, head.hash)
+		}
+		t.order = t.order[1:]
+	}
+}
@@ -18,12 +18,15 @@ package vm
 
 import (
 	"crypto/sha256"
+	"encoding/binary"
 	"errors"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/blake2b"
+	"github.com/ethereum/go-ethereum/crypto/bls12381"
 	"github.com/ethereum/go-ethereum/crypto/bn256"
 	"github.com/ethereum/go-ethereum/params"
 	"golang.org/x/crypto/ripemd160"
@@ -37,6 +40,13 @@ type PrecompiledContract interface {
 	Run(input []byte) ([]byte, error) // Run runs the precompiled contract
 }
 
+// statefulPrecompiledContract is a native Go contract which needs access to the
+// block context of the EVM executing it. It's bound to the EVM before every run.
+type statefulPrecompiledContract interface {
+	PrecompiledContract
+	bind(evm *EVM) PrecompiledContract
+}
+
 // PrecompiledContractsHomestead contains the default set of pre-compiled Ethereum
 // contracts used in the Frontier and Homestead releases.
 var PrecompiledContractsHomestead = map[common.Address]PrecompiledContract{
@@ -54,9 +64,78 @@ var PrecompiledContractsByzantium = map[common.Address]PrecompiledContract{
 	common.BytesToAddress([]byte{3}): &ripemd160hash{},
 	common.BytesToAddress([]byte{4}): &dataCopy{},
 	common.BytesToAddress([]byte{5}): &bigModExp{},
-	common.BytesToAddress([]byte{6}): &bn256Add{},
-	common.BytesToAddress([]byte{7}): &bn256ScalarMul{},
-	common.BytesToAddress([]byte{8}): &bn256Pairing{},
+	common.BytesToAddress([]byte{6}): &bn256AddByzantium{},
+	common.BytesToAddress([]byte{7}): &bn256ScalarMulByzantium{},
+	common.BytesToAddress([]byte{8}): &bn256PairingByzantium{},
+}
+
+// PrecompiledContractsIstanbul contains the default set of pre-compiled Ethereum
+// contracts used in the Istanbul release, with the bn256 operations repriced by
+// EIP-1108.
+var PrecompiledContractsIstanbul = map[common.Address]PrecompiledContract{
+	common.BytesToAddress([]byte{1}): &ecrecover{},
+	common.BytesToAddress([]byte{2}): &sha256hash{},
+	common.BytesToAddress([]byte{3}): &ripemd160hash{},
+	common.BytesToAddress([]byte{4}): &dataCopy{},
+	common.BytesToAddress([]byte{5}): &bigModExp{},
+	common.BytesToAddress([]byte{6}): &bn256AddIstanbul{},
+	common.BytesToAddress([]byte{7}): &bn256ScalarMulIstanbul{},
+	common.BytesToAddress([]byte{8}): &bn256PairingIstanbul{},
+}
+
+// tipCryptoPrecompiles contains the pre-compiled contracts added by the TomoChain
+// fork introducing the BLAKE2b compression function (EIP-152) and the BLS12-381
+// curve operations (EIP-2537).
+var tipCryptoPrecompiles = map[common.Address]PrecompiledContract{
+	common.BytesToAddress([]byte{9}):  &blake2F{},
+	common.BytesToAddress([]byte{10}): &bls12381G1Add{},
+	common.BytesToAddress([]byte{11}): &bls12381G1Mul{},
+	common.BytesToAddress([]byte{12}): &bls12381G1MultiExp{},
+	common.BytesToAddress([]byte{13}): &bls12381G2Add{},
+	common.BytesToAddress([]byte{14}): &bls12381G2Mul{},
+	common.BytesToAddress([]byte{15}): &bls12381G2MultiExp{},
+	common.BytesToAddress([]byte{16}): &bls12381Pairing{},
+	common.BytesToAddress([]byte{17}): &bls12381MapG1{},
+	common.BytesToAddress([]byte{18}): &bls12381MapG2{},
+}
+
+// tipConsensusPrecompiles contains the pre-compiled contract added by the
+// TomoChain fork exposing the consensus state to contracts.
+var tipConsensusPrecompiles = map[common.Address]PrecompiledContract{
+	common.HexToAddress(common.ConsensusPrecompile): &consensus{},
+}
+
+// The TomoChain pre-compiled contracts extend the set of the Ethereum release
+// enabled by the chain config, keeping its bn256 pricing.
+var (
+	// PrecompiledContractsTIPCrypto contains the Istanbul pre-compiled contracts
+	// and the ones of the TomoChain crypto fork.
+	PrecompiledContractsTIPCrypto = extendPrecompiles(PrecompiledContractsIstanbul, tipCryptoPrecompiles)
+
+	// PrecompiledContractsTIPCryptoByzantium contains the Byzantium pre-compiled
+	// contracts and the ones of the TomoChain crypto fork.
+	PrecompiledContractsTIPCryptoByzantium = extendPrecompiles(PrecompiledContractsByzantium, tipCryptoPrecompiles)
+
+	// PrecompiledContractsTIPConsensus contains the Istanbul pre-compiled contracts
+	// and the ones of the TomoChain crypto and consensus forks.
+	PrecompiledContractsTIPConsensus = extendPrecompiles(PrecompiledContractsTIPCrypto, tipConsensusPrecompiles)
+
+	// PrecompiledContractsTIPConsensusByzantium contains the Byzantium pre-compiled
+	// contracts and the ones of the TomoChain crypto and consensus forks.
+	PrecompiledContractsTIPConsensusByzantium = extendPrecompiles(PrecompiledContractsTIPCryptoByzantium, tipConsensusPrecompiles)
+)
+
+// extendPrecompiles returns a new set of pre-compiled contracts made of the base
+// set and the extra contracts.
+func extendPrecompiles(base, extra map[common.Address]PrecompiledContract) map[common.Address]PrecompiledContract {
+	set := make(map[common.Address]PrecompiledContract, len(base)+len(extra))
+	for addr, p := range base {
+		set[addr] = p
+	}
+	for addr, p := range extra {
+		set[addr] = p
+	}
+	return set
 }
 
 // RunPrecompiledContract runs and evaluates the output of a precompiled contract.
@@ -271,15 +350,9 @@ func newTwistPoint(blob []byte) (*bn256.G2, error) {
 	return p, nil
 }
 
-// bn256Add implements a native elliptic curve point addition.
-type bn256Add struct{}
-
-// RequiredGas returns the gas required to execute the pre-compiled contract.
-func (c *bn256Add) RequiredGas(input []byte) uint64 {
-	return params.Bn256AddGas
-}
-
-func (c *bn256Add) Run(input []byte) ([]byte, error) {
+// runBn256Add implements the bn256 point addition precompile, referenced by
+// both the Byzantium and Istanbul operations.
+func runBn256Add(input []byte) ([]byte, error) {
 	x, err := newCurvePoint(getData(input, 0, 64))
 	if err != nil {
 		return nil, err
@@ -293,15 +366,35 @@ func (c *bn256Add) Run(input []byte) ([]byte, error) {
 	return res.Marshal(), nil
 }
 
-// bn256ScalarMul implements a native elliptic curve scalar multiplication.
-type bn256ScalarMul struct{}
+// bn256AddIstanbul implements a native elliptic curve point addition conforming
+// to the Istanbul consensus rules.
+type bn256AddIstanbul struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bn256AddIstanbul) RequiredGas(input []byte) uint64 {
+	return params.Bn256AddGasIstanbul
+}
+
+func (c *bn256AddIstanbul) Run(input []byte) ([]byte, error) {
+	return runBn256Add(input)
+}
+
+// bn256AddByzantium implements a native elliptic curve point addition
+// conforming to the Byzantium consensus rules.
+type bn256AddByzantium struct{}
 
 // RequiredGas returns the gas required to execute the pre-compiled contract.
-func (c *bn256ScalarMul) RequiredGas(input []byte) uint64 {
-	return params.Bn256ScalarMulGas
+func (c *bn256AddByzantium) RequiredGas(input []byte) uint64 {
+	return params.Bn256AddGasByzantium
+}
+
+func (c *bn256AddByzantium) Run(input []byte) ([]byte, error) {
+	return runBn256Add(input)
 }
 
-func (c *bn256ScalarMul) Run(input []byte) ([]byte, error) {
+// runBn256ScalarMul implements the bn256 scalar multiplication precompile,
+// referenced by both the Byzantium and Istanbul operations.
+func runBn256ScalarMul(input []byte) ([]byte, error) {
 	p, err := newCurvePoint(getData(input, 0, 64))
 	if err != nil {
 		return nil, err
@@ -311,6 +404,32 @@ func (c *bn256ScalarMul) Run(input []byte) ([]byte, error) {
 	return res.Marshal(), nil
 }
 
+// bn256ScalarMulIstanbul implements a native elliptic curve scalar
+// multiplication conforming to the Istanbul consensus rules.
+type bn256ScalarMulIstanbul struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bn256ScalarMulIstanbul) RequiredGas(input []byte) uint64 {
+	return params.Bn256ScalarMulGasIstanbul
+}
+
+func (c *bn256ScalarMulIstanbul) Run(input []byte) ([]byte, error) {
+	return runBn256ScalarMul(input)
+}
+
+// bn256ScalarMulByzantium implements a native elliptic curve scalar
+// multiplication conforming to the Byzantium consensus rules.
+type bn256ScalarMulByzantium struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bn256ScalarMulByzantium) RequiredGas(input []byte) uint64 {
+	return params.Bn256ScalarMulGasByzantium
+}
+
+func (c *bn256ScalarMulByzantium) Run(input []byte) ([]byte, error) {
+	return runBn256ScalarMul(input)
+}
+
 var (
 	// true32Byte is returned if the bn256 pairing check succeeds.
 	true32Byte = []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}
@@ -322,15 +441,9 @@ var (
 	errBadPairingInput = errors.New("bad elliptic curve pairing size")
 )
 
-// bn256Pairing implements a pairing pre-compile for the bn256 curve
-type bn256Pairing struct{}
-
-// RequiredGas returns the gas required to execute the pre-compiled contract.
-func (c *bn256Pairing) RequiredGas(input []byte) uint64 {
-	return params.Bn256PairingBaseGas + uint64(len(input)/192)*params.Bn256PairingPerPointGas
-}
-
-func (c *bn256Pairing) Run(input []byte) ([]byte, error) {
+// runBn256Pairing implements the bn256 pairing check precompile, referenced by
+// both the Byzantium and Istanbul operations.
+func runBn256Pairing(input []byte) ([]byte, error) {
 	// Handle some corner cases cheaply
 	if len(input)%192 > 0 {
 		return nil, errBadPairingInput
@@ -358,3 +471,498 @@ func (c *bn256Pairing) Run(input []byte) ([]byte, error) {
 	}
 	return false32Byte, nil
 }
+
+// bn256PairingIstanbul implements a pairing pre-compile for the bn256 curve
+// conforming to the Istanbul consensus rules.
+type bn256PairingIstanbul struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bn256PairingIstanbul) RequiredGas(input []byte) uint64 {
+	return params.Bn256PairingBaseGasIstanbul + uint64(len(input)/192)*params.Bn256PairingPerPointGasIstanbul
+}
+
+func (c *bn256PairingIstanbul) Run(input []byte) ([]byte, error) {
+	return runBn256Pairing(input)
+}
+
+// bn256PairingByzantium implements a pairing pre-compile for the bn256 curve
+// conforming to the Byzantium consensus rules.
+type bn256PairingByzantium struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bn256PairingByzantium) RequiredGas(input []byte) uint64 {
+	return params.Bn256PairingBaseGasByzantium + uint64(len(input)/192)*params.Bn256PairingPerPointGasByzantium
+}
+
+func (c *bn256PairingByzantium) Run(input []byte) ([]byte, error) {
+	return runBn256Pairing(input)
+}
+
+const blake2FInputLength = 213
+
+var (
+	errBlake2FInvalidInputLength = errors.New("invalid input length")
+	errBlake2FInvalidFinalFlag   = errors.New("invalid final flag")
+)
+
+// blake2F implements the BLAKE2b compression function F precompile of EIP-152.
+type blake2F struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *blake2F) RequiredGas(input []byte) uint64 {
+	// If the input is malformed, we can't calculate the gas, return 0 and let the
+	// actual call choke and fault.
+	if len(input) != blake2FInputLength {
+		return 0
+	}
+	return uint64(binary.BigEndian.Uint32(input[0:4])) * params.Blake2FRoundGas
+}
+
+func (c *blake2F) Run(input []byte) ([]byte, error) {
+	// Make sure the input is valid (correct length and final flag)
+	if len(input) != blake2FInputLength {
+		return nil, errBlake2FInvalidInputLength
+	}
+	if input[212] != 0 && input[212] != 1 {
+		return nil, errBlake2FInvalidFinalFlag
+	}
+	// Parse the input into the Blake2b call parameters
+	var (
+		rounds = binary.BigEndian.Uint32(input[0:4])
+		final  = input[212] == 1
+
+		h [8]uint64
+		m [16]uint64
+		t [2]uint64
+	)
+	for i := 0; i < 8; i++ {
+		offset := 4 + i*8
+		h[i] = binary.LittleEndian.Uint64(input[offset : offset+8])
+	}
+	for i := 0; i < 16; i++ {
+		offset := 68 + i*8
+		m[i] = binary.LittleEndian.Uint64(input[offset : offset+8])
+	}
+	t[0] = binary.LittleEndian.Uint64(input[196:204])
+	t[1] = binary.LittleEndian.Uint64(input[204:212])
+
+	// Execute the compression function, extract and return the result
+	blake2b.F(&h, m, t, final, rounds)
+
+	output := make([]byte, 64)
+	for i := 0; i < 8; i++ {
+		offset := i * 8
+		binary.LittleEndian.PutUint64(output[offset:offset+8], h[i])
+	}
+	return output, nil
+}
+
+var (
+	errBLS12381InvalidInputLength          = errors.New("invalid input length")
+	errBLS12381InvalidFieldElementTopBytes = errors.New("invalid field element top bytes")
+	errBLS12381G1PointSubgroup             = errors.New("g1 point is not on correct subgroup")
+	errBLS12381G2PointSubgroup             = errors.New("g2 point is not on correct subgroup")
+)
+
+// bls12381MultiExpGas returns the gas of a multi exponentiation of k pairs, at the
+// given price of a single multiplication.
+func bls12381MultiExpGas(k int, mulGas uint64) uint64 {
+	if k == 0 {
+		return 0
+	}
+	var discount uint64
+	if dLen := len(params.Bls12381MultiExpDiscountTable); k < dLen {
+		discount = params.Bls12381MultiExpDiscountTable[k-1]
+	} else {
+		discount = params.Bls12381MultiExpDiscountTable[dLen-1]
+	}
+	return (uint64(k) * mulGas * discount) / 1000
+}
+
+// bls12381G1Add implements EIP-2537 G1Add precompile.
+type bls12381G1Add struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bls12381G1Add) RequiredGas(input []byte) uint64 {
+	return params.Bls12381G1AddGas
+}
+
+func (c *bls12381G1Add) Run(input []byte) ([]byte, error) {
+	// Implements EIP-2537 G1Add precompile.
+	// > G1 addition call expects `256` bytes as an input that is interpreted as byte concatenation of two G1 points (`128` bytes each).
+	// > Output is an encoding of addition operation result - single G1 point (`128` bytes).
+	if len(input) != 256 {
+		return nil, errBLS12381InvalidInputLength
+	}
+	g := bls12381.NewG1()
+
+	p0, err := decodeBLS12381G1Point(g, input[:128])
+	if err != nil {
+		return nil, err
+	}
+	p1, err := decodeBLS12381G1Point(g, input[128:])
+	if err != nil {
+		return nil, err
+	}
+	r := g.New()
+	g.Add(r, p0, p1)
+	return encodeBLS12381G1Point(g, r), nil
+}
+
+// bls12381G1Mul implements EIP-2537 G1Mul precompile.
+type bls12381G1Mul struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bls12381G1Mul) RequiredGas(input []byte) uint64 {
+	return params.Bls12381G1MulGas
+}
+
+func (c *bls12381G1Mul) Run(input []byte) ([]byte, error) {
+	// Implements EIP-2537 G1Mul precompile.
+	// > G1 multiplication call expects `160` bytes as an input that is interpreted as byte concatenation of encoding of G1 point (`128` bytes) and encoding of a scalar value (`32` bytes).
+	// > Output is an encoding of multiplication operation result - single G1 point (`128` bytes).
+	if len(input) != 160 {
+		return nil, errBLS12381InvalidInputLength
+	}
+	g := bls12381.NewG1()
+
+	p, err := decodeBLS12381G1Point(g, input[:128])
+	if err != nil {
+		return nil, err
+	}
+	r := g.New()
+	g.MulScalarBig(r, p, new(big.Int).SetBytes(input[128:]))
+	return encodeBLS12381G1Point(g, r), nil
+}
+
+// bls12381G1MultiExp implements EIP-2537 G1MultiExp precompile.
+type bls12381G1MultiExp struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bls12381G1MultiExp) RequiredGas(input []byte) uint64 {
+	// Calculate G1 point, scalar value pair length
+	return bls12381MultiExpGas(len(input)/160, params.Bls12381G1MulGas)
+}
+
+func (c *bls12381G1MultiExp) Run(input []byte) ([]byte, error) {
+	// Implements EIP-2537 G1MultiExp precompile.
+	// G1 multiplication call expects `160*k` bytes as an input that is interpreted as byte concatenation of `k` slices each of them being a byte concatenation of encoding of G1 point (`128` bytes) and encoding of a scalar value (`32` bytes).
+	// Output is an encoding of multiexponentiation operation result - single G1 point (`128` bytes).
+	k := len(input) / 160
+	if len(input) == 0 || len(input)%160 != 0 {
+		return nil, errBLS12381InvalidInputLength
+	}
+	var (
+		g       = bls12381.NewG1()
+		points  = make([]*bls12381.PointG1, k)
+		scalars = make([]*big.Int, k)
+	)
+	for i := 0; i < k; i++ {
+		off := 160 * i
+		t0, t1, t2 := off, off+128, off+160
+
+		p, err := decodeBLS12381G1Point(g, input[t0:t1])
+		if err != nil {
+			return nil, err
+		}
+		points[i], scalars[i] = p, new(big.Int).SetBytes(input[t1:t2])
+	}
+	r := g.New()
+	if _, err := g.MultiExpBig(r, points, scalars); err != nil {
+		return nil, err
+	}
+	return encodeBLS12381G1Point(g, r), nil
+}
+
+// bls12381G2Add implements EIP-2537 G2Add precompile.
+type bls12381G2Add struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bls12381G2Add) RequiredGas(input []byte) uint64 {
+	return params.Bls12381G2AddGas
+}
+
+func (c *bls12381G2Add) Run(input []byte) ([]byte, error) {
+	// Implements EIP-2537 G2Add precompile.
+	// > G2 addition call expects `512` bytes as an input that is interpreted as byte concatenation of two G2 points (`256` bytes each).
+	// > Output is an encoding of addition operation result - single G2 point (`256` bytes).
+	if len(input) != 512 {
+		return nil, errBLS12381InvalidInputLength
+	}
+	g := bls12381.NewG2()
+
+	p0, err := decodeBLS12381G2Point(g, input[:256])
+	if err != nil {
+		return nil, err
+	}
+	p1, err := decodeBLS12381G2Point(g, input[256:])
+	if err != nil {
+		return nil, err
+	}
+	r := g.New()
+	g.Add(r, p0, p1)
+	return encodeBLS12381G2Point(g, r), nil
+}
+
+// bls12381G2Mul implements EIP-2537 G2Mul precompile.
+type bls12381G2Mul struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bls12381G2Mul) RequiredGas(input []byte) uint64 {
+	return params.Bls12381G2MulGas
+}
+
+func (c *bls12381G2Mul) Run(input []byte) ([]byte, error) {
+	// Implements EIP-2537 G2Mul precompile.
+	// > G2 multiplication call expects `288` bytes as an input that is interpreted as byte concatenation of encoding of G2 point (`256` bytes) and encoding of a scalar value (`32` bytes).
+	// > Output is an encoding of multiplication operation result - single G2 point (`256` bytes).
+	if len(input) != 288 {
+		return nil, errBLS12381InvalidInputLength
+	}
+	g := bls12381.NewG2()
+
+	p, err := decodeBLS12381G2Point(g, input[:256])
+	if err != nil {
+		return nil, err
+	}
+	r := g.New()
+	g.MulScalarBig(r, p, new(big.Int).SetBytes(input[256:]))
+	return encodeBLS12381G2Point(g, r), nil
+}
+
+// bls12381G2MultiExp implements EIP-2537 G2MultiExp precompile.
+type bls12381G2MultiExp struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bls12381G2MultiExp) RequiredGas(input []byte) uint64 {
+	// Calculate G2 point, scalar value pair length
+	return bls12381MultiExpGas(len(input)/288, params.Bls12381G2MulGas)
+}
+
+func (c *bls12381G2MultiExp) Run(input []byte) ([]byte, error) {
+	// Implements EIP-2537 G2MultiExp precompile logic
+	// > G2 multiplication call expects `288*k` bytes as an input that is interpreted as byte concatenation of `k` slices each of them being a byte concatenation of encoding of G2 point (`256` bytes) and encoding of a scalar value (`32` bytes).
+	// > Output is an encoding of multiexponentiation operation result - single G2 point (`256` bytes).
+	k := len(input) / 288
+	if len(input) == 0 || len(input)%288 != 0 {
+		return nil, errBLS12381InvalidInputLength
+	}
+	var (
+		g       = bls12381.NewG2()
+		points  = make([]*bls12381.PointG2, k)
+		scalars = make([]*big.Int, k)
+	)
+	for i := 0; i < k; i++ {
+		off := 288 * i
+		t0, t1, t2 := off, off+256, off+288
+
+		p, err := decodeBLS12381G2Point(g, input[t0:t1])
+		if err != nil {
+			return nil, err
+		}
+		points[i], scalars[i] = p, new(big.Int).SetBytes(input[t1:t2])
+	}
+	r := g.New()
+	if _, err := g.MultiExpBig(r, points, scalars); err != nil {
+		return nil, err
+	}
+	return encodeBLS12381G2Point(g, r), nil
+}
+
+// bls12381Pairing implements EIP-2537 Pairing precompile.
+type bls12381Pairing struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bls12381Pairing) RequiredGas(input []byte) uint64 {
+	return params.Bls12381PairingBaseGas + uint64(len(input)/384)*params.Bls12381PairingPerPairGas
+}
+
+func (c *bls12381Pairing) Run(input []byte) ([]byte, error) {
+	// Implements EIP-2537 Pairing precompile logic.
+	// > Pairing call expects `384*k` bytes as an inputs that is interpreted as byte concatenation of `k` slices. Each slice has the following structure:
+	// > - `128` bytes of G1 point encoding
+	// > - `256` bytes of G2 point encoding
+	// > Output is a `32` bytes where last single byte is `0x01` if pairing result is equal to multiplicative identity in a pairing target field and `0x00` otherwise
+	// > (which is equivalent of Big Endian encoding of Solidity values `uint256(1)` and `uin256(0)` respectively).
+	k := len(input) / 384
+	if len(input) == 0 || len(input)%384 != 0 {
+		return nil, errBLS12381InvalidInputLength
+	}
+	var (
+		e  = bls12381.NewEngine()
+		g1 = bls12381.NewG1()
+		g2 = bls12381.NewG2()
+	)
+	// Decode pairs
+	for i := 0; i < k; i++ {
+		off := 384 * i
+		t0, t1, t2 := off, off+128, off+384
+
+		// Decode G1 point
+		p1, err := decodeBLS12381G1Point(g1, input[t0:t1])
+		if err != nil {
+			return nil, err
+		}
+		// Decode G2 point
+		p2, err := decodeBLS12381G2Point(g2, input[t1:t2])
+		if err != nil {
+			return nil, err
+		}
+		// 'point is on curve' check already done,
+		// Here we need to apply subgroup checks.
+		if !g1.InCorrectSubgroup(p1) {
+			return nil, errBLS12381G1PointSubgroup
+		}
+		if !g2.InCorrectSubgroup(p2) {
+			return nil, errBLS12381G2PointSubgroup
+		}
+		// Pairs with a point at infinity contribute the identity and are skipped
+		if g1.IsZero(p1) || g2.IsZero(p2) {
+			continue
+		}
+		e.AddPair(p1, p2)
+	}
+	// Prepare 32 byte output
+	out := make([]byte, 32)
+
+	// Compute pairing and set the result
+	if e.Check() {
+		out[31] = 1
+	}
+	return out, nil
+}
+
+// bls12381MapG1 implements EIP-2537 MapG1 precompile.
+type bls12381MapG1 struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bls12381MapG1) RequiredGas(input []byte) uint64 {
+	return params.Bls12381MapG1Gas
+}
+
+func (c *bls12381MapG1) Run(input []byte) ([]byte, error) {
+	// Implements EIP-2537 Map_To_G1 precompile.
+	// > Field-to-curve call expects `64` bytes an an input that is interpreted as a an element of the base field.
+	// > Output of this call is `128` bytes and is G1 point following respective encoding rules.
+	if len(input) != 64 {
+		return nil, errBLS12381InvalidInputLength
+	}
+	fe, err := decodeBLS12381FieldElement(input)
+	if err != nil {
+		return nil, err
+	}
+	g := bls12381.NewG1()
+
+	r, err := g.MapToCurve(fe)
+	if err != nil {
+		return nil, err
+	}
+	return encodeBLS12381G1Point(g, r), nil
+}
+
+// bls12381MapG2 implements EIP-2537 MapG2 precompile.
+type bls12381MapG2 struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bls12381MapG2) RequiredGas(input []byte) uint64 {
+	return params.Bls12381MapG2Gas
+}
+
+func (c *bls12381MapG2) Run(input []byte) ([]byte, error) {
+	// Implements EIP-2537 Map_FP2_TO_G2 precompile logic.
+	// > Field-to-curve call expects `128` bytes an an input that is interpreted as a an element of the quadratic extension field.
+	// > Output of this call is `256` bytes and is G2 point following respective encoding rules.
+	if len(input) != 128 {
+		return nil, errBLS12381InvalidInputLength
+	}
+	c0, err := decodeBLS12381FieldElement(input[:64])
+	if err != nil {
+		return nil, err
+	}
+	c1, err := decodeBLS12381FieldElement(input[64:])
+	if err != nil {
+		return nil, err
+	}
+	g := bls12381.NewG2()
+
+	// The library expects the extension field element as c1 || c0
+	r, err := g.MapToCurve(append(c1, c0...))
+	if err != nil {
+		return nil, err
+	}
+	return encodeBLS12381G2Point(g, r), nil
+}
+
+// decodeBLS12381FieldElement decodes a 64 byte EIP-2537 field element into its
+// 48 byte big endian form, checking that the top 16 bytes are zero. The range
+// check against the modulus is left to the curve operations.
+func decodeBLS12381FieldElement(in []byte) ([]byte, error) {
+	if len(in) != 64 {
+		return nil, errBLS12381InvalidInputLength
+	}
+	// check top bytes
+	for i := 0; i < 16; i++ {
+		if in[i] != byte(0x00) {
+			return nil, errBLS12381InvalidFieldElementTopBytes
+		}
+	}
+	out := make([]byte, 48)
+	copy(out, in[16:])
+	return out, nil
+}
+
+// encodeBLS12381FieldElements pads 48 byte field elements into the 64 byte form
+// of EIP-2537.
+func encodeBLS12381FieldElements(fes ...[]byte) []byte {
+	out := make([]byte, 64*len(fes))
+	for i, fe := range fes {
+		copy(out[64*i+16:64*(i+1)], fe)
+	}
+	return out
+}
+
+// decodeBLS12381G1Point decodes a 128 byte EIP-2537 encoded G1 point, checking
+// that it's on the curve.
+func decodeBLS12381G1Point(g *bls12381.G1, in []byte) (*bls12381.PointG1, error) {
+	x, err := decodeBLS12381FieldElement(in[:64])
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeBLS12381FieldElement(in[64:])
+	if err != nil {
+		return nil, err
+	}
+	return g.FromBytes(append(x, y...))
+}
+
+// encodeBLS12381G1Point encodes a G1 point into 128 bytes as per EIP-2537.
+func encodeBLS12381G1Point(g *bls12381.G1, p *bls12381.PointG1) []byte {
+	out := g.ToBytes(p)
+	return encodeBLS12381FieldElements(out[:48], out[48:])
+}
+
+// decodeBLS12381G2Point decodes a 256 byte EIP-2537 encoded G2 point, checking
+// that it's on the curve. The coordinates are encoded as c0 || c1, whereas the
+// library expects them as c1 || c0.
+func decodeBLS12381G2Point(g *bls12381.G2, in []byte) (*bls12381.PointG2, error) {
+	fes := make([][]byte, 4)
+	for i := range fes {
+		fe, err := decodeBLS12381FieldElement(in[64*i : 64*(i+1)])
+		if err != nil {
+			return nil, err
+		}
+		fes[i] = fe
+	}
+	blob := make([]byte, 0, 192)
+	blob = append(blob, fes[1]...)
+	blob = append(blob, fes[0]...)
+	blob = append(blob, fes[3]...)
+	blob = append(blob, fes[2]...)
+	return g.FromBytes(blob)
+}
+
+// encodeBLS12381G2Point encodes a G2 point into 256 bytes as per EIP-2537.
+func encodeBLS12381G2Point(g *bls12381.G2, p *bls12381.PointG2) []byte {
+	out := g.ToBytes(p)
+	return encodeBLS12381FieldElements(out[48:96], out[:48], out[144:], out[96:144])
+}
@@ -0,0 +1,135 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	// Method selectors of the consensus precompiled contract, following the
+	// Solidity ABI so contracts can call it through a regular interface:
+	//
+	//	function epoch() view returns (uint256)
+	//	function masternodes() view returns (address[])
+	//	function validator(uint256 number) view returns (address)
+	consensusEpochMethod       = crypto.Keccak256([]byte("epoch()"))[:4]
+	consensusMasternodesMethod = crypto.Keccak256([]byte("masternodes()"))[:4]
+	consensusValidatorMethod   = crypto.Keccak256([]byte("validator(uint256)"))[:4]
+
+	errConsensusUnknownMethod = errors.New("unknown consensus method")
+	errConsensusInvalidInput  = errors.New("invalid consensus method input")
+	errConsensusUnavailable   = errors.New("consensus state unavailable")
+)
+
+// consensus implements a native contract exposing the state of the Posv
+// consensus engine: the current epoch, the masternodes of the current epoch as
+// recorded in the checkpoint header and the M2 validators of recent blocks.
+type consensus struct {
+	evm *EVM
+}
+
+func (c *consensus) bind(evm *EVM) PrecompiledContract {
+	return &consensus{evm: evm}
+}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *consensus) RequiredGas(input []byte) uint64 {
+	if len(input) < 4 {
+		return params.ConsensusBaseGas
+	}
+	switch {
+	case bytes.Equal(input[:4], consensusEpochMethod):
+		return params.ConsensusEpochGas
+	case bytes.Equal(input[:4], consensusMasternodesMethod):
+		return params.ConsensusMasternodesGas
+	case bytes.Equal(input[:4], consensusValidatorMethod):
+		return params.ConsensusValidatorGas
+	}
+	return params.ConsensusBaseGas
+}
+
+func (c *consensus) Run(input []byte) ([]byte, error) {
+	if len(input) < 4 {
+		return nil, errConsensusUnknownMethod
+	}
+	if c.evm == nil || c.evm.ChainConfig().Posv == nil || c.evm.ChainConfig().Posv.Epoch == 0 {
+		return nil, errConsensusUnavailable
+	}
+	method, args := input[:4], input[4:]
+
+	switch {
+	case bytes.Equal(method, consensusEpochMethod):
+		if len(args) != 0 {
+			return nil, errConsensusInvalidInput
+		}
+		epoch := new(big.Int).Div(c.evm.BlockNumber, new(big.Int).SetUint64(c.evm.ChainConfig().Posv.Epoch))
+		return math.PaddedBigBytes(epoch, 32), nil
+
+	case bytes.Equal(method, consensusMasternodesMethod):
+		if len(args) != 0 {
+			return nil, errConsensusInvalidInput
+		}
+		if c.evm.GetMasternodes == nil {
+			return nil, errConsensusUnavailable
+		}
+		masternodes, err := c.evm.GetMasternodes()
+		if err != nil {
+			return nil, errConsensusUnavailable
+		}
+
+		// Encode the dynamic address array: offset, length and the elements
+		out := make([]byte, 64+32*len(masternodes))
+		out[31] = 0x20
+		copy(out[32:64], math.PaddedBigBytes(big.NewInt(int64(len(masternodes))), 32))
+		for i, masternode := range masternodes {
+			copy(out[64+32*i+12:64+32*(i+1)], masternode[:])
+		}
+		return out, nil
+
+	case bytes.Equal(method, consensusValidatorMethod):
+		if len(args) != 32 {
+			return nil, errConsensusInvalidInput
+		}
+		if c.evm.GetValidator == nil {
+			return nil, errConsensusUnavailable
+		}
+		// Similarly to BLOCKHASH, only the 256 most recent blocks are available
+		var (
+			out    = make([]byte, 32)
+			num    = new(big.Int).SetBytes(args)
+			upper  = c.evm.BlockNumber
+			lower  = new(big.Int).Sub(upper, big.NewInt(256))
+			inside = num.Cmp(upper) < 0 && num.Cmp(lower) >= 0
+		)
+		if inside {
+			validator, err := c.evm.GetValidator(num.Uint64())
+			if err != nil {
+				return nil, errConsensusUnavailable
+			}
+			copy(out[12:], validator[:])
+		}
+		return out, nil
+	}
+	return nil, errConsensusUnknownMethod
+}
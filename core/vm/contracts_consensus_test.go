@@ -0,0 +1,150 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// newConsensusTestEVM creates an EVM at the given block of a Posv chain with an
+// epoch of 900 blocks, whose consensus hooks report the given masternodes and
+// derive the validator of every block from its number.
+func newConsensusTestEVM(number *big.Int, masternodes []common.Address) *EVM {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	config := *params.TestChainConfig
+	config.Posv = &params.PosvConfig{Epoch: 900}
+
+	context := Context{
+		CanTransfer:    func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:       func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber:    number,
+		GetMasternodes: func() ([]common.Address, error) { return masternodes, nil },
+		GetValidator:   func(n uint64) (common.Address, error) { return common.BigToAddress(new(big.Int).SetUint64(n)), nil },
+	}
+	return NewEVM(context, statedb, &config, Config{})
+}
+
+func TestConsensusPrecompile(t *testing.T) {
+	var (
+		number      = new(big.Int).Add(common.TIPConsensusPrecompile, big.NewInt(10))
+		masternodes = []common.Address{common.HexToAddress("0x01"), common.HexToAddress("0x02")}
+		evm         = newConsensusTestEVM(number, masternodes)
+		caller      = AccountRef(common.HexToAddress("0x1337"))
+		addr        = common.HexToAddress(common.ConsensusPrecompile)
+	)
+	call := func(input []byte) ([]byte, uint64, error) {
+		ret, left, err := evm.StaticCall(caller, addr, input, 100000)
+		return ret, 100000 - left, err
+	}
+	word := func(n uint64) []byte { return common.LeftPadBytes(new(big.Int).SetUint64(n).Bytes(), 32) }
+
+	// The epoch is derived from the block number
+	ret, used, err := call(consensusEpochMethod)
+	if err != nil {
+		t.Fatalf("epoch: call failed: %v", err)
+	}
+	if want := word(number.Uint64() / 900); !bytes.Equal(ret, want) {
+		t.Errorf("epoch: result mismatch: have %x, want %x", ret, want)
+	}
+	if used != params.ConsensusEpochGas {
+		t.Errorf("epoch: gas mismatch: have %d, want %d", used, params.ConsensusEpochGas)
+	}
+	// The masternodes are ABI encoded as a dynamic address array
+	if ret, used, err = call(consensusMasternodesMethod); err != nil {
+		t.Fatalf("masternodes: call failed: %v", err)
+	}
+	want := append(append(word(32), word(2)...), append(common.LeftPadBytes(masternodes[0][:], 32), common.LeftPadBytes(masternodes[1][:], 32)...)...)
+	if !bytes.Equal(ret, want) {
+		t.Errorf("masternodes: result mismatch: have %x, want %x", ret, want)
+	}
+	if used != params.ConsensusMasternodesGas {
+		t.Errorf("masternodes: gas mismatch: have %d, want %d", used, params.ConsensusMasternodesGas)
+	}
+	// Validators are only available for the 256 most recent blocks
+	tests := []struct {
+		number uint64
+		want   []byte
+	}{
+		{number.Uint64() - 1, word(number.Uint64() - 1)},
+		{number.Uint64() - 256, word(number.Uint64() - 256)},
+		{number.Uint64() - 257, word(0)},
+		{number.Uint64(), word(0)},
+		{number.Uint64() + 1, word(0)},
+	}
+	for _, tt := range tests {
+		ret, used, err := call(append(common.CopyBytes(consensusValidatorMethod), word(tt.number)...))
+		if err != nil {
+			t.Fatalf("validator %d: call failed: %v", tt.number, err)
+		}
+		if !bytes.Equal(ret, tt.want) {
+			t.Errorf("validator %d: result mismatch: have %x, want %x", tt.number, ret, tt.want)
+		}
+		if used != params.ConsensusValidatorGas {
+			t.Errorf("validator %d: gas mismatch: have %d, want %d", tt.number, used, params.ConsensusValidatorGas)
+		}
+	}
+	// Malformed calls must fail, short and unknown ones still being charged
+	for _, input := range [][]byte{nil, {0xde, 0xad, 0xbe, 0xef}, append(common.CopyBytes(consensusEpochMethod), 0), consensusValidatorMethod} {
+		if _, _, err := call(input); err == nil {
+			t.Errorf("input %x: expected failure", input)
+		}
+	}
+	for _, input := range [][]byte{nil, {0xde, 0xad}, {0xde, 0xad, 0xbe, 0xef}} {
+		if gas := (&consensus{}).RequiredGas(input); gas != params.ConsensusBaseGas {
+			t.Errorf("input %x: gas mismatch: have %d, want %d", input, gas, params.ConsensusBaseGas)
+		}
+	}
+	// Unavailable consensus state must fail the call instead of reporting empty data
+	evm.GetMasternodes = func() ([]common.Address, error) { return nil, errors.New("checkpoint unavailable") }
+	evm.GetValidator = func(uint64) (common.Address, error) { return common.Address{}, errors.New("header unavailable") }
+
+	if _, _, err := call(consensusMasternodesMethod); err != errConsensusUnavailable {
+		t.Errorf("unavailable masternodes: error mismatch: have %v, want %v", err, errConsensusUnavailable)
+	}
+	if _, _, err := call(append(common.CopyBytes(consensusValidatorMethod), word(number.Uint64()-1)...)); err != errConsensusUnavailable {
+		t.Errorf("unavailable validator: error mismatch: have %v, want %v", err, errConsensusUnavailable)
+	}
+}
+
+// Tests that the consensus precompile is only active from the TomoChain fork
+// introducing it.
+func TestConsensusPrecompileActivation(t *testing.T) {
+	addr := common.HexToAddress(common.ConsensusPrecompile)
+
+	before := newConsensusTestEVM(new(big.Int).Sub(common.TIPConsensusPrecompile, common.Big1), nil)
+	if _, ok := before.precompiles()[addr]; ok {
+		t.Errorf("precompile active before fork")
+	}
+	after := newConsensusTestEVM(new(big.Int).Set(common.TIPConsensusPrecompile), nil)
+	if _, ok := after.precompiles()[addr]; !ok {
+		t.Errorf("precompile inactive after fork")
+	}
+	// The blake2F and BLS12-381 precompiles must remain active
+	if _, ok := after.precompiles()[common.BytesToAddress([]byte{9})]; !ok {
+		t.Errorf("blake2F precompile inactive after fork")
+	}
+}
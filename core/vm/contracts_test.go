@@ -17,11 +17,14 @@
 package vm
 
 import (
+	"bytes"
 	"fmt"
 	"math/big"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto/bls12381"
+	"github.com/ethereum/go-ethereum/params"
 )
 
 // precompiledTest defines the input/output pairs for precompiled contract tests.
@@ -481,3 +484,221 @@ func BenchmarkPrecompiledBn256Pairing(bench *testing.B) {
 		benchmarkPrecompiled("08", test, bench)
 	}
 }
+
+// Tests that the Istanbul bn256 precompiles produce the same results as the
+// Byzantium ones, at the prices of EIP 1108.
+func TestPrecompiledBn256Istanbul(t *testing.T) {
+	tests := []struct {
+		addr       string
+		test       precompiledTest
+		byz, istan uint64
+	}{
+		{"06", bn256AddTests[0], 500, 150},
+		{"07", bn256ScalarMulTests[0], 40000, 6000},
+		{"08", bn256PairingTests[0], 260000, 113000},
+	}
+	for _, tt := range tests {
+		var (
+			addr      = common.HexToAddress(tt.addr)
+			in        = common.Hex2Bytes(tt.test.input)
+			byzantium = PrecompiledContractsByzantium[addr]
+			istanbul  = PrecompiledContractsIstanbul[addr]
+		)
+		if gas := byzantium.RequiredGas(in); gas != tt.byz {
+			t.Errorf("%s: byzantium gas mismatch: have %d, want %d", tt.test.name, gas, tt.byz)
+		}
+		if gas := istanbul.RequiredGas(in); gas != tt.istan {
+			t.Errorf("%s: istanbul gas mismatch: have %d, want %d", tt.test.name, gas, tt.istan)
+		}
+		contract := NewContract(AccountRef(common.HexToAddress("1337")), nil, new(big.Int), tt.istan)
+		if res, err := RunPrecompiledContract(istanbul, in, contract); err != nil {
+			t.Errorf("%s: istanbul execution failed: %v", tt.test.name, err)
+		} else if common.Bytes2Hex(res) != tt.test.expected {
+			t.Errorf("%s: istanbul result mismatch: have %x, want %s", tt.test.name, res, tt.test.expected)
+		}
+	}
+}
+
+// blake2FTests are the test and benchmark data for the blake2F precompiled
+// contract, taken from EIP-152.
+var blake2FTests = []precompiledTest{
+	{
+		input:    "0000000c48c9bdf267e6096a3ba7ca8485ae67bb2bf894fe72f36e3cf1361d5f3af54fa5d182e6ad7f520e511f6c3e2b8c68059b6bbd41fbabd9831f79217e1319cde05b61626300000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000300000000000000000000000000000001",
+		expected: "ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d17d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923",
+		gas:      12,
+		name:     "vector 5",
+	},
+}
+
+func TestPrecompiledBlake2F(t *testing.T) {
+	p := PrecompiledContractsTIPCrypto[common.HexToAddress("09")]
+	for _, test := range blake2FTests {
+		in := common.Hex2Bytes(test.input)
+		if gas := p.RequiredGas(in); gas != test.gas {
+			t.Errorf("%s: gas mismatch: have %d, want %d", test.name, gas, test.gas)
+		}
+		contract := NewContract(AccountRef(common.HexToAddress("1337")), nil, new(big.Int), test.gas)
+		if res, err := RunPrecompiledContract(p, in, contract); err != nil {
+			t.Errorf("%s: execution failed: %v", test.name, err)
+		} else if common.Bytes2Hex(res) != test.expected {
+			t.Errorf("%s: result mismatch: have %x, want %s", test.name, res, test.expected)
+		}
+	}
+	// Malformed inputs must be rejected
+	valid := common.Hex2Bytes(blake2FTests[0].input)
+	if _, err := p.Run(valid[1:]); err != errBlake2FInvalidInputLength {
+		t.Errorf("short input: error mismatch: have %v, want %v", err, errBlake2FInvalidInputLength)
+	}
+	if _, err := p.Run(append(valid, 0)); err != errBlake2FInvalidInputLength {
+		t.Errorf("long input: error mismatch: have %v, want %v", err, errBlake2FInvalidInputLength)
+	}
+	invalid := common.CopyBytes(valid)
+	invalid[212] = 2
+	if _, err := p.Run(invalid); err != errBlake2FInvalidFinalFlag {
+		t.Errorf("final flag: error mismatch: have %v, want %v", err, errBlake2FInvalidFinalFlag)
+	}
+}
+
+func BenchmarkPrecompiledBlake2F(bench *testing.B) {
+	p := PrecompiledContractsTIPCrypto[common.HexToAddress("09")]
+	in := common.Hex2Bytes(blake2FTests[0].input)
+	bench.ResetTimer()
+	for i := 0; i < bench.N; i++ {
+		p.Run(in)
+	}
+}
+
+// Tests that the BLS12-381 precompiles agree with the curve arithmetic of the
+// underlying library on multiples of the generators.
+func TestPrecompiledBLS12381(t *testing.T) {
+	var (
+		g1 = bls12381.NewG1()
+		g2 = bls12381.NewG2()
+
+		one1, two1, three1 = g1.One(), g1.New(), g1.New()
+		one2, two2, three2 = g2.One(), g2.New(), g2.New()
+	)
+	g1.MulScalarBig(two1, one1, big.NewInt(2))
+	g1.MulScalarBig(three1, one1, big.NewInt(3))
+	g2.MulScalarBig(two2, one2, big.NewInt(2))
+	g2.MulScalarBig(three2, one2, big.NewInt(3))
+
+	scalar := func(n int64) []byte { return common.LeftPadBytes(big.NewInt(n).Bytes(), 32) }
+	concat := func(blobs ...[]byte) (out []byte) {
+		for _, blob := range blobs {
+			out = append(out, blob...)
+		}
+		return out
+	}
+	var (
+		enc1 = func(p *bls12381.PointG1) []byte { return encodeBLS12381G1Point(g1, p) }
+		enc2 = func(p *bls12381.PointG2) []byte { return encodeBLS12381G2Point(g2, p) }
+	)
+	tests := []struct {
+		addr     byte
+		input    []byte
+		expected []byte
+		gas      uint64
+	}{
+		{10, concat(enc1(one1), enc1(two1)), enc1(three1), params.Bls12381G1AddGas},
+		{11, concat(enc1(one1), scalar(3)), enc1(three1), params.Bls12381G1MulGas},
+		{12, concat(enc1(one1), scalar(1), enc1(one1), scalar(2)), enc1(three1), 2 * params.Bls12381G1MulGas * 888 / 1000},
+		{13, concat(enc2(one2), enc2(two2)), enc2(three2), params.Bls12381G2AddGas},
+		{14, concat(enc2(one2), scalar(3)), enc2(three2), params.Bls12381G2MulGas},
+		{15, concat(enc2(one2), scalar(1), enc2(one2), scalar(2)), enc2(three2), 2 * params.Bls12381G2MulGas * 888 / 1000},
+	}
+	for _, tt := range tests {
+		p := PrecompiledContractsTIPCrypto[common.BytesToAddress([]byte{tt.addr})]
+		if gas := p.RequiredGas(tt.input); gas != tt.gas {
+			t.Errorf("precompile %d: gas mismatch: have %d, want %d", tt.addr, gas, tt.gas)
+		}
+		if res, err := p.Run(tt.input); err != nil {
+			t.Errorf("precompile %d: execution failed: %v", tt.addr, err)
+		} else if !bytes.Equal(res, tt.expected) {
+			t.Errorf("precompile %d: result mismatch: have %x, want %x", tt.addr, res, tt.expected)
+		}
+	}
+	// e(g1, g2) * e(-g1, g2) must be the identity, e(g1, g2) alone must not
+	neg1 := g1.New()
+	g1.Neg(neg1, one1)
+
+	pairing := PrecompiledContractsTIPCrypto[common.BytesToAddress([]byte{16})]
+	if gas := pairing.RequiredGas(make([]byte, 2*384)); gas != params.Bls12381PairingBaseGas+2*params.Bls12381PairingPerPairGas {
+		t.Errorf("pairing: gas mismatch: have %d", gas)
+	}
+	if res, err := pairing.Run(concat(enc1(one1), enc2(one2), enc1(neg1), enc2(one2))); err != nil {
+		t.Errorf("pairing: execution failed: %v", err)
+	} else if res[31] != 1 {
+		t.Errorf("pairing: result mismatch: have %x, want 1", res)
+	}
+	if res, err := pairing.Run(concat(enc1(one1), enc2(one2))); err != nil {
+		t.Errorf("pairing: execution failed: %v", err)
+	} else if res[31] != 0 {
+		t.Errorf("pairing: result mismatch: have %x, want 0", res)
+	}
+	if _, err := pairing.Run(nil); err != errBLS12381InvalidInputLength {
+		t.Errorf("pairing: error mismatch: have %v, want %v", err, errBLS12381InvalidInputLength)
+	}
+	// Field elements with non-zero padding must be rejected
+	invalid := concat(enc1(one1), enc1(two1))
+	invalid[0] = 1
+	if _, err := PrecompiledContractsTIPCrypto[common.BytesToAddress([]byte{10})].Run(invalid); err != errBLS12381InvalidFieldElementTopBytes {
+		t.Errorf("g1 add: error mismatch: have %v, want %v", err, errBLS12381InvalidFieldElementTopBytes)
+	}
+	// Mapped points must be on the curve and in the correct subgroup
+	fe := make([]byte, 64)
+	fe[63] = 1
+	if res, err := PrecompiledContractsTIPCrypto[common.BytesToAddress([]byte{17})].Run(fe); err != nil {
+		t.Errorf("map g1: execution failed: %v", err)
+	} else if p, err := decodeBLS12381G1Point(g1, res); err != nil || !g1.InCorrectSubgroup(p) {
+		t.Errorf("map g1: invalid point %x: %v", res, err)
+	}
+	if res, err := PrecompiledContractsTIPCrypto[common.BytesToAddress([]byte{18})].Run(append(fe, fe...)); err != nil {
+		t.Errorf("map g2: execution failed: %v", err)
+	} else if p, err := decodeBLS12381G2Point(g2, res); err != nil || !g2.InCorrectSubgroup(p) {
+		t.Errorf("map g2: invalid point %x: %v", res, err)
+	}
+}
+
+// Tests that the blake2F and BLS12-381 precompiles are only active from the
+// TomoChain fork introducing them, on top of the set enabled by the chain config
+// and with its bn256 pricing.
+func TestPrecompiledTIPCryptoActivation(t *testing.T) {
+	addrs := []common.Address{common.BytesToAddress([]byte{9}), common.BytesToAddress([]byte{18})}
+
+	istanbul := *params.TestChainConfig
+	istanbul.IstanbulBlock = new(big.Int)
+
+	tipIstanbul := *params.TestChainConfig
+	tipIstanbul.TIPIstanbulBlock = new(big.Int)
+
+	homestead := *params.TestChainConfig
+	homestead.ByzantiumBlock = nil
+
+	for _, tt := range []struct {
+		config *params.ChainConfig
+		active bool   // Whether the TomoChain precompiles are active after the fork
+		bn256  uint64 // Gas cost of a bn256 addition after the fork
+	}{
+		{params.TestChainConfig, true, params.Bn256AddGasByzantium},
+		{&istanbul, true, params.Bn256AddGasIstanbul},
+		{&tipIstanbul, true, params.Bn256AddGasIstanbul},
+		{&homestead, false, 0},
+	} {
+		before := NewEVM(Context{BlockNumber: new(big.Int).Sub(common.TIPCryptoPrecompiles, common.Big1)}, nil, tt.config, Config{})
+		after := NewEVM(Context{BlockNumber: new(big.Int).Set(common.TIPCryptoPrecompiles)}, nil, tt.config, Config{})
+		for _, addr := range addrs {
+			if _, ok := before.precompiles()[addr]; ok {
+				t.Errorf("%v: %x: precompile active before fork", tt.config, addr)
+			}
+			if _, ok := after.precompiles()[addr]; ok != tt.active {
+				t.Errorf("%v: %x: precompile activity mismatch after fork: have %v, want %v", tt.config, addr, ok, tt.active)
+			}
+		}
+		if p, ok := after.precompiles()[common.BytesToAddress([]byte{6})]; ok {
+			if gas := p.RequiredGas(nil); gas != tt.bn256 {
+				t.Errorf("%v: bn256 addition gas mismatch: have %d, want %d", tt.config, gas, tt.bn256)
+			}
+		}
+	}
+}
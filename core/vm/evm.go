@@ -36,22 +36,59 @@ type (
 	// GetHashFunc returns the nth block hash in the blockchain
 	// and is used by the BLOCKHASH EVM op code.
 	GetHashFunc func(uint64) common.Hash
+	// GetMasternodesFunc returns the masternodes of the epoch the current block
+	// belongs to, or an error if they are unavailable, and is used by the
+	// consensus precompiled contract.
+	GetMasternodesFunc func() ([]common.Address, error)
+	// GetValidatorFunc returns the M2 validator which double validated the nth
+	// block, or an error if it is unavailable, and is used by the consensus
+	// precompiled contract.
+	GetValidatorFunc func(uint64) (common.Address, error)
 )
 
 // run runs the given contract and takes care of running precompiles with a fallback to the byte code interpreter.
 func run(evm *EVM, contract *Contract, input []byte) ([]byte, error) {
 	if contract.CodeAddr != nil {
-		precompiles := PrecompiledContractsHomestead
-		if evm.ChainConfig().IsByzantium(evm.BlockNumber) {
-			precompiles = PrecompiledContractsByzantium
-		}
-		if p := precompiles[*contract.CodeAddr]; p != nil {
+		if p := evm.precompiles()[*contract.CodeAddr]; p != nil {
+			if sp, ok := p.(statefulPrecompiledContract); ok {
+				p = sp.bind(evm)
+			}
 			return RunPrecompiledContract(p, input, contract)
 		}
 	}
 	return evm.interpreter.Run(contract, input)
 }
 
+// precompiles returns the set of pre-compiled contracts active under the chain
+// rules of the current block. The TomoChain forks only extend the Byzantium and
+// Istanbul sets, with the bn256 pricing of the release enabled by the config.
+func (evm *EVM) precompiles() map[common.Address]PrecompiledContract {
+	rules := evm.chainRules
+	switch {
+	case !rules.IsByzantium:
+		return PrecompiledContractsHomestead
+	case rules.IsTIPConsensusPrecompile && rules.IsIstanbul:
+		return PrecompiledContractsTIPConsensus
+	case rules.IsTIPConsensusPrecompile:
+		return PrecompiledContractsTIPConsensusByzantium
+	case rules.IsTIPCryptoPrecompiles && rules.IsIstanbul:
+		return PrecompiledContractsTIPCrypto
+	case rules.IsTIPCryptoPrecompiles:
+		return PrecompiledContractsTIPCryptoByzantium
+	case rules.IsIstanbul:
+		return PrecompiledContractsIstanbul
+	default:
+		return PrecompiledContractsByzantium
+	}
+}
+
+// IsPrecompile reports whether addr is a pre-compiled contract under the chain
+// rules of the current block.
+func (evm *EVM) IsPrecompile(addr common.Address) bool {
+	_, ok := evm.precompiles()[addr]
+	return ok
+}
+
 // Context provides the EVM with auxiliary information. Once provided
 // it shouldn't be modified.
 type Context struct {
@@ -62,6 +99,10 @@ type Context struct {
 	Transfer TransferFunc
 	// GetHash returns the hash corresponding to n
 	GetHash GetHashFunc
+	// GetMasternodes returns the masternodes of the current epoch
+	GetMasternodes GetMasternodesFunc
+	// GetValidator returns the M2 validator of block n
+	GetValidator GetValidatorFunc
 
 	// Message information
 	Origin   common.Address // Provides information for ORIGIN
@@ -143,10 +184,12 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 
 	// Fail if we're trying to execute above the call depth limit
 	if evm.depth > int(params.CallCreateDepth) {
+		evm.captureFailedFrame(CALL, caller.Address(), addr, input, gas, 0, value, ErrDepth)
 		return nil, gas, ErrDepth
 	}
 	// Fail if we're trying to transfer more than the available balance
 	if !evm.Context.CanTransfer(evm.StateDB, caller.Address(), value) {
+		evm.captureFailedFrame(CALL, caller.Address(), addr, input, gas, 0, value, ErrInsufficientBalance)
 		return nil, gas, ErrInsufficientBalance
 	}
 
@@ -155,17 +198,12 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 		snapshot = evm.StateDB.Snapshot()
 	)
 	if !evm.StateDB.Exist(addr) {
-		precompiles := PrecompiledContractsHomestead
-		if evm.ChainConfig().IsByzantium(evm.BlockNumber) {
-			precompiles = PrecompiledContractsByzantium
-		}
-		if precompiles[addr] == nil && evm.ChainConfig().IsEIP158(evm.BlockNumber) && value.Sign() == 0 {
+		if evm.precompiles()[addr] == nil && evm.ChainConfig().IsEIP158(evm.BlockNumber) && value.Sign() == 0 {
+			evm.captureFailedFrame(CALL, caller.Address(), addr, input, gas, 0, value, nil)
 			return nil, gas, nil
 		}
 		evm.StateDB.CreateAccount(addr)
 	}
-	evm.Transfer(evm.StateDB, caller.Address(), to.Address(), value)
-
 	// Initialise a new contract and set the code that is to be used by the EVM.
 	// The contract is a scoped environment for this execution context only.
 	contract := NewContract(caller, to, value, gas)
@@ -173,14 +211,23 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 
 	start := time.Now()
 
-	// Capture the tracer start/end events in debug mode
+	// Capture the tracer start/end events in debug mode, entering the call frame
+	// before the value transfer so its balance changes are reported inside it
 	if evm.vmConfig.Debug && evm.depth == 0 {
 		evm.vmConfig.Tracer.CaptureStart(caller.Address(), addr, false, input, gas, value)
 
 		defer func() { // Lazy evaluation of the parameters
 			evm.vmConfig.Tracer.CaptureEnd(ret, gas-contract.Gas, time.Since(start), err)
 		}()
+	} else if tracer := evm.StructuredTracer(); tracer != nil {
+		tracer.CaptureEnter(CALL, caller.Address(), addr, input, gas, value)
+
+		defer func() {
+			tracer.CaptureExit(ret, gas-contract.Gas, err)
+		}()
 	}
+	evm.transfer(caller.Address(), to.Address(), value)
+
 	ret, err = run(evm, contract, input)
 
 	// When an error was returned by the EVM or when setting the creation code
@@ -209,10 +256,12 @@ func (evm *EVM) CallCode(caller ContractRef, addr common.Address, input []byte,
 
 	// Fail if we're trying to execute above the call depth limit
 	if evm.depth > int(params.CallCreateDepth) {
+		evm.captureFailedFrame(CALLCODE, caller.Address(), addr, input, gas, 0, value, ErrDepth)
 		return nil, gas, ErrDepth
 	}
 	// Fail if we're trying to transfer more than the available balance
 	if !evm.CanTransfer(evm.StateDB, caller.Address(), value) {
+		evm.captureFailedFrame(CALLCODE, caller.Address(), addr, input, gas, 0, value, ErrInsufficientBalance)
 		return nil, gas, ErrInsufficientBalance
 	}
 
@@ -226,6 +275,13 @@ func (evm *EVM) CallCode(caller ContractRef, addr common.Address, input []byte,
 	contract := NewContract(caller, to, value, gas)
 	contract.SetCallCode(&addr, evm.StateDB.GetCodeHash(addr), evm.StateDB.GetCode(addr))
 
+	if tracer := evm.StructuredTracer(); tracer != nil {
+		tracer.CaptureEnter(CALLCODE, caller.Address(), addr, input, gas, value)
+
+		defer func() {
+			tracer.CaptureExit(ret, gas-contract.Gas, err)
+		}()
+	}
 	ret, err = run(evm, contract, input)
 	if err != nil {
 		evm.StateDB.RevertToSnapshot(snapshot)
@@ -247,6 +303,7 @@ func (evm *EVM) DelegateCall(caller ContractRef, addr common.Address, input []by
 	}
 	// Fail if we're trying to execute above the call depth limit
 	if evm.depth > int(params.CallCreateDepth) {
+		evm.captureFailedFrame(DELEGATECALL, caller.Address(), addr, input, gas, 0, nil, ErrDepth)
 		return nil, gas, ErrDepth
 	}
 
@@ -259,6 +316,13 @@ func (evm *EVM) DelegateCall(caller ContractRef, addr common.Address, input []by
 	contract := NewContract(caller, to, nil, gas).AsDelegate()
 	contract.SetCallCode(&addr, evm.StateDB.GetCodeHash(addr), evm.StateDB.GetCode(addr))
 
+	if tracer := evm.StructuredTracer(); tracer != nil {
+		tracer.CaptureEnter(DELEGATECALL, caller.Address(), addr, input, gas, nil)
+
+		defer func() {
+			tracer.CaptureExit(ret, gas-contract.Gas, err)
+		}()
+	}
 	ret, err = run(evm, contract, input)
 	if err != nil {
 		evm.StateDB.RevertToSnapshot(snapshot)
@@ -279,6 +343,7 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 	}
 	// Fail if we're trying to execute above the call depth limit
 	if evm.depth > int(params.CallCreateDepth) {
+		evm.captureFailedFrame(STATICCALL, caller.Address(), addr, input, gas, 0, new(big.Int), ErrDepth)
 		return nil, gas, ErrDepth
 	}
 	// Make sure the readonly is only set if we aren't in readonly yet
@@ -299,6 +364,13 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 	contract := NewContract(caller, to, new(big.Int), gas)
 	contract.SetCallCode(&addr, evm.StateDB.GetCodeHash(addr), evm.StateDB.GetCode(addr))
 
+	if tracer := evm.StructuredTracer(); tracer != nil {
+		tracer.CaptureEnter(STATICCALL, caller.Address(), addr, input, gas, new(big.Int))
+
+		defer func() {
+			tracer.CaptureExit(ret, gas-contract.Gas, err)
+		}()
+	}
 	// When an error was returned by the EVM or when setting the creation code
 	// above we revert to the snapshot and consume any gas remaining. Additionally
 	// when we're in Homestead this also counts for code storage gas errors.
@@ -312,24 +384,26 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 	return ret, contract.Gas, err
 }
 
-// Create creates a new contract using code as deployment code.
-func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.Int) (ret []byte, contractAddr common.Address, leftOverGas uint64, err error) {
-
+// create creates a new contract at the given address using code as deployment
+// code, the hash of which is codeHash. The typ is the opcode reported to tracers.
+func (evm *EVM) create(caller ContractRef, code []byte, codeHash common.Hash, gas uint64, value *big.Int, contractAddr common.Address, typ OpCode) ([]byte, common.Address, uint64, error) {
 	// Depth check execution. Fail if we're trying to execute above the
 	// limit.
 	if evm.depth > int(params.CallCreateDepth) {
+		evm.captureFailedFrame(typ, caller.Address(), contractAddr, code, gas, 0, value, ErrDepth)
 		return nil, common.Address{}, gas, ErrDepth
 	}
 	if !evm.CanTransfer(evm.StateDB, caller.Address(), value) {
+		evm.captureFailedFrame(typ, caller.Address(), contractAddr, code, gas, 0, value, ErrInsufficientBalance)
 		return nil, common.Address{}, gas, ErrInsufficientBalance
 	}
-	// Ensure there's no existing contract already at the designated address
 	nonce := evm.StateDB.GetNonce(caller.Address())
 	evm.StateDB.SetNonce(caller.Address(), nonce+1)
 
-	contractAddr = crypto.CreateAddress(caller.Address(), nonce)
+	// Ensure there's no existing contract already at the designated address
 	contractHash := evm.StateDB.GetCodeHash(contractAddr)
 	if evm.StateDB.GetNonce(contractAddr) != 0 || (contractHash != (common.Hash{}) && contractHash != emptyCodeHash) {
+		evm.captureFailedFrame(typ, caller.Address(), contractAddr, code, gas, gas, value, ErrContractAddressCollision)
 		return nil, common.Address{}, 0, ErrContractAddressCollision
 	}
 	// Create a new account on the state
@@ -338,24 +412,31 @@ func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.I
 	if evm.ChainConfig().IsEIP158(evm.BlockNumber) {
 		evm.StateDB.SetNonce(contractAddr, 1)
 	}
-	evm.Transfer(evm.StateDB, caller.Address(), contractAddr, value)
-
 	// initialise a new contract and set the code that is to be used by the
 	// EVM. The contract is a scoped environment for this execution context
 	// only.
 	contract := NewContract(caller, AccountRef(contractAddr), value, gas)
-	contract.SetCallCode(&contractAddr, crypto.Keccak256Hash(code), code)
-
-	if evm.vmConfig.NoRecursion && evm.depth > 0 {
-		return nil, contractAddr, gas, nil
-	}
+	contract.SetCallCode(&contractAddr, codeHash, code)
 
+	// Enter the call frame before the value transfer so its balance changes are
+	// reported inside it
+	tracer := evm.StructuredTracer()
 	if evm.vmConfig.Debug && evm.depth == 0 {
 		evm.vmConfig.Tracer.CaptureStart(caller.Address(), contractAddr, true, code, gas, value)
+	} else if tracer != nil {
+		tracer.CaptureEnter(typ, caller.Address(), contractAddr, code, gas, value)
 	}
 	start := time.Now()
 
-	ret, err = run(evm, contract, nil)
+	evm.transfer(caller.Address(), contractAddr, value)
+
+	if evm.vmConfig.NoRecursion && evm.depth > 0 {
+		if tracer != nil {
+			tracer.CaptureExit(nil, 0, nil)
+		}
+		return nil, contractAddr, gas, nil
+	}
+	ret, err := run(evm, contract, nil)
 
 	// check whether the max code size has been exceeded
 	maxCodeSizeExceeded := evm.ChainConfig().IsEIP158(evm.BlockNumber) && len(ret) > params.MaxCodeSize
@@ -387,12 +468,68 @@ func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.I
 	}
 	if evm.vmConfig.Debug && evm.depth == 0 {
 		evm.vmConfig.Tracer.CaptureEnd(ret, gas-contract.Gas, time.Since(start), err)
+	} else if tracer != nil {
+		tracer.CaptureExit(ret, gas-contract.Gas, err)
 	}
 	return ret, contractAddr, contract.Gas, err
 }
 
+// Create creates a new contract using code as deployment code.
+func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.Int) (ret []byte, contractAddr common.Address, leftOverGas uint64, err error) {
+	contractAddr = crypto.CreateAddress(caller.Address(), evm.StateDB.GetNonce(caller.Address()))
+	return evm.create(caller, code, crypto.Keccak256Hash(code), gas, value, contractAddr, CREATE)
+}
+
+// Create2 creates a new contract using code as deployment code.
+//
+// The different between Create2 with Create is Create2 uses sha3(0xff ++ msg.sender ++ salt ++ sha3(init_code))[12:]
+// instead of the usual sender-and-nonce-hash as the address where the contract is initialized at.
+func (evm *EVM) Create2(caller ContractRef, code []byte, gas uint64, endowment *big.Int, salt *big.Int) (ret []byte, contractAddr common.Address, leftOverGas uint64, err error) {
+	codeHash := crypto.Keccak256Hash(code)
+	contractAddr = crypto.CreateAddress2(caller.Address(), common.BigToHash(salt), codeHash[:])
+	return evm.create(caller, code, codeHash, gas, endowment, contractAddr, CREATE2)
+}
+
 // ChainConfig returns the environment's chain configuration
 func (evm *EVM) ChainConfig() *params.ChainConfig { return evm.chainConfig }
 
 // Interpreter returns the EVM interpreter
 func (evm *EVM) Interpreter() *Interpreter { return evm.interpreter }
+
+// StructuredTracer returns the tracer of the EVM if it runs in debug mode with a
+// tracer receiving call frame and state change events, or nil otherwise.
+func (evm *EVM) StructuredTracer() StructuredTracer {
+	if !evm.vmConfig.Debug {
+		return nil
+	}
+	tracer, _ := evm.vmConfig.Tracer.(StructuredTracer)
+	return tracer
+}
+
+// captureFailedFrame reports a call frame which failed or returned before any
+// code could run, such as on exceeding the call depth limit or the available
+// balance, to the structured tracer if there's one. The top level call is not
+// reported as a frame, so it is skipped.
+func (evm *EVM) captureFailedFrame(typ OpCode, from common.Address, to common.Address, input []byte, gas, gasUsed uint64, value *big.Int, err error) {
+	if tracer := evm.StructuredTracer(); tracer != nil && evm.depth > 0 {
+		tracer.CaptureEnter(typ, from, to, input, gas, value)
+		tracer.CaptureExit(nil, gasUsed, err)
+	}
+}
+
+// transfer moves value from one account to another, reporting the balance
+// changes to the structured tracer if there's one.
+func (evm *EVM) transfer(from, to common.Address, value *big.Int) {
+	tracer := evm.StructuredTracer()
+	if tracer == nil || value.Sign() == 0 {
+		evm.Transfer(evm.StateDB, from, to, value)
+		return
+	}
+	fromPrev, toPrev := evm.StateDB.GetBalance(from), evm.StateDB.GetBalance(to)
+	evm.Transfer(evm.StateDB, from, to, value)
+
+	tracer.CaptureBalanceChange(from, fromPrev, evm.StateDB.GetBalance(from), BalanceChangeTransfer)
+	if from != to {
+		tracer.CaptureBalanceChange(to, toPrev, evm.StateDB.GetBalance(to), BalanceChangeTransfer)
+	}
+}
@@ -16,7 +16,18 @@
 
 package vm
 
-import "testing"
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
 
 func TestMemoryGasCost(t *testing.T) {
 	//size := uint64(math.MaxUint64 - 64)
@@ -34,3 +45,328 @@ func TestMemoryGasCost(t *testing.T) {
 		t.Error("expected error")
 	}
 }
+
+// constantinopleTestConfig enables Constantinople while keeping Petersburg out of
+// reach, so that the EIP-1283 net gas metering is active.
+var constantinopleTestConfig = &params.ChainConfig{
+	ChainId:             big.NewInt(1),
+	HomesteadBlock:      new(big.Int),
+	EIP150Block:         new(big.Int),
+	EIP155Block:         new(big.Int),
+	EIP158Block:         new(big.Int),
+	ByzantiumBlock:      new(big.Int),
+	ConstantinopleBlock: new(big.Int),
+	PetersburgBlock:     big.NewInt(math.MaxInt64),
+}
+
+// newConstantinopleTestEVM creates an EVM on top of a fresh state, in which the
+// given code is deployed at address 0xaa with its storage slot 0 set to original.
+func newConstantinopleTestEVM(code []byte, original byte, config *params.ChainConfig) (*EVM, *state.StateDB) {
+	var (
+		address = common.BytesToAddress([]byte("contract"))
+		db, _   = ethdb.NewMemDatabase()
+		sdb     = state.NewDatabase(db)
+	)
+	statedb, _ := state.New(common.Hash{}, sdb)
+	statedb.CreateAccount(address)
+	statedb.SetCode(address, code)
+	statedb.SetState(address, common.Hash{}, common.BytesToHash([]byte{original}))
+	root, _ := statedb.Commit(false)
+	statedb, _ = state.New(root, sdb)
+
+	context := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: new(big.Int),
+	}
+	return NewEVM(context, statedb, config, Config{}), statedb
+}
+
+var eip1283Tests = []struct {
+	original byte
+	gaspool  uint64
+	input    string
+	used     uint64
+	refund   uint64
+}{
+	{0, math.MaxUint64, "0x60006000556000600055", 412, 0},
+	{0, math.MaxUint64, "0x60006000556001600055", 20212, 0},
+	{0, math.MaxUint64, "0x60016000556000600055", 20212, 19800},
+	{0, math.MaxUint64, "0x60016000556002600055", 20212, 0},
+	{0, math.MaxUint64, "0x60016000556001600055", 20212, 0},
+	{1, math.MaxUint64, "0x60006000556000600055", 5212, 15000},
+	{1, math.MaxUint64, "0x60006000556001600055", 5212, 4800},
+	{1, math.MaxUint64, "0x60006000556002600055", 5212, 0},
+	{1, math.MaxUint64, "0x60026000556000600055", 5212, 15000},
+	{1, math.MaxUint64, "0x60026000556003600055", 5212, 0},
+	{1, math.MaxUint64, "0x60026000556001600055", 5212, 4800},
+	{1, math.MaxUint64, "0x60026000556002600055", 5212, 0},
+	{1, math.MaxUint64, "0x60016000556000600055", 5212, 15000},
+	{1, math.MaxUint64, "0x60016000556002600055", 5212, 0},
+	{1, math.MaxUint64, "0x60016000556001600055", 412, 0},
+	{0, math.MaxUint64, "0x600160005560006000556001600055", 40218, 19800},
+	{1, math.MaxUint64, "0x600060005560016000556000600055", 10218, 19800},
+}
+
+// Tests the SSTORE net gas metering of EIP-1283 against the test cases of the
+// EIP, and that Petersburg reverts to the legacy metering.
+func TestEIP1283(t *testing.T) {
+	address := common.BytesToAddress([]byte("contract"))
+	for i, tt := range eip1283Tests {
+		vmenv, statedb := newConstantinopleTestEVM(hexutil.MustDecode(tt.input), tt.original, constantinopleTestConfig)
+
+		_, gas, err := vmenv.Call(AccountRef(common.Address{}), address, nil, tt.gaspool, new(big.Int))
+		if err != nil {
+			t.Errorf("test %d: failed to execute: %v", i, err)
+			continue
+		}
+		if used := tt.gaspool - gas; used != tt.used {
+			t.Errorf("test %d: gas used mismatch: have %v, want %v", i, used, tt.used)
+		}
+		if refund := statedb.GetRefund(); refund != tt.refund {
+			t.Errorf("test %d: gas refund mismatch: have %v, want %v", i, refund, tt.refund)
+		}
+	}
+	// Petersburg drops EIP-1283, the no-op stores are charged as resets again
+	config := *constantinopleTestConfig
+	config.PetersburgBlock = new(big.Int)
+
+	vmenv, _ := newConstantinopleTestEVM(hexutil.MustDecode("0x60016000556001600055"), 1, &config)
+	_, gas, err := vmenv.Call(AccountRef(common.Address{}), address, nil, math.MaxUint64, new(big.Int))
+	if err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+	if used := math.MaxUint64 - gas; used != 2*params.SstoreResetGas+12 {
+		t.Errorf("petersburg gas used mismatch: have %v, want %v", used, 2*params.SstoreResetGas+12)
+	}
+}
+
+// tipForkTestConfig schedules the TomoChain Constantinople and Istanbul forks at
+// blocks 10 and 20, leaving the Ethereum fork blocks unset.
+var tipForkTestConfig = &params.ChainConfig{
+	ChainId:                big.NewInt(1),
+	HomesteadBlock:         new(big.Int),
+	EIP150Block:            new(big.Int),
+	EIP155Block:            new(big.Int),
+	EIP158Block:            new(big.Int),
+	ByzantiumBlock:         new(big.Int),
+	TIPConstantinopleBlock: big.NewInt(10),
+	TIPIstanbulBlock:       big.NewInt(20),
+}
+
+// newTIPForkTestEVM creates an EVM of the TomoChain fork test config at the given
+// block number, running the given code deployed at address 0xaa.
+func newTIPForkTestEVM(code []byte, original byte, number int64) (*EVM, *state.StateDB) {
+	_, statedb := newConstantinopleTestEVM(code, original, tipForkTestConfig)
+	context := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: big.NewInt(number),
+	}
+	return NewEVM(context, statedb, tipForkTestConfig, Config{}), statedb
+}
+
+// Tests that the Constantinople opcodes are enabled by the TomoChain fork block of
+// the chain config with the Petersburg rules, storage writes keeping the legacy
+// pricing instead of the EIP-1283 net gas metering until the EIP-2200 metering
+// of the TomoChain Istanbul fork.
+func TestTIPConstantinople(t *testing.T) {
+	var (
+		// PUSH1 1 PUSH1 1 SHL STOP
+		shl = hexutil.MustDecode("0x600160011b00")
+		// PUSH1 1 PUSH1 0 SSTORE PUSH1 1 PUSH1 0 SSTORE, a no-op store
+		noop = hexutil.MustDecode("0x60016000556001600055")
+	)
+	address := common.BytesToAddress([]byte("contract"))
+	for _, tt := range []struct {
+		number int64
+		valid  bool
+		used   uint64
+	}{
+		{9, false, 2*params.SstoreResetGas + 12},
+		{10, true, 2*params.SstoreResetGas + 12},
+		{19, true, 2*params.SstoreResetGas + 12},
+		{20, true, 1612},
+	} {
+		vmenv, _ := newTIPForkTestEVM(shl, 0, tt.number)
+		if _, _, err := vmenv.Call(AccountRef(common.Address{}), address, nil, 100000, new(big.Int)); (err == nil) != tt.valid {
+			t.Errorf("block %d: SHL validity mismatch: have error %v, want valid %v", tt.number, err, tt.valid)
+		}
+		vmenv, _ = newTIPForkTestEVM(noop, 1, tt.number)
+		_, gas, err := vmenv.Call(AccountRef(common.Address{}), address, nil, math.MaxUint64, new(big.Int))
+		if err != nil {
+			t.Errorf("block %d: failed to execute: %v", tt.number, err)
+			continue
+		}
+		if used := math.MaxUint64 - gas; used != tt.used {
+			t.Errorf("block %d: gas used mismatch: have %v, want %v", tt.number, used, tt.used)
+		}
+	}
+	// Ensure a slot set and cleared again is charged and refunded as two separate
+	// writes at the fork block, not at the reduced EIP-1283 rates
+	vmenv, statedb := newTIPForkTestEVM(hexutil.MustDecode("0x60016000556000600055"), 0, tipForkTestConfig.TIPConstantinopleBlock.Int64())
+	_, gas, err := vmenv.Call(AccountRef(common.Address{}), address, nil, math.MaxUint64, new(big.Int))
+	if err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+	if used, want := math.MaxUint64-gas, params.SstoreSetGas+params.SstoreClearGas+12; used != want {
+		t.Errorf("set and clear gas used mismatch: have %v, want %v", used, want)
+	}
+	if refund := statedb.GetRefund(); refund != params.SstoreRefundGas {
+		t.Errorf("set and clear gas refund mismatch: have %v, want %v", refund, params.SstoreRefundGas)
+	}
+}
+
+// Tests that CREATE2 deploys contracts to the address derived from the salt and
+// the init code hash, and that EXTCODEHASH reports the hash of the deployed code.
+func TestCreate2ExtCodeHash(t *testing.T) {
+	// PUSH1 1 PUSH1 0 RETURN: deploys a single STOP byte out of the zeroed memory
+	initCode := hexutil.MustDecode("0x60016000f3")
+
+	// PUSH5 initCode PUSH1 0 MSTORE PUSH1 7 PUSH1 5 PUSH1 27 PUSH1 0 CREATE2
+	// DUP1 EXTCODEHASH PUSH1 0 SSTORE PUSH1 1 SSTORE STOP
+	code := append([]byte{byte(PUSH5)}, initCode...)
+	code = append(code, hexutil.MustDecode("0x60005260076005601b6000f5803f60005560015500")...)
+
+	vmenv, statedb := newConstantinopleTestEVM(code, 0, constantinopleTestConfig)
+	address := common.BytesToAddress([]byte("contract"))
+	if _, _, err := vmenv.Call(AccountRef(common.Address{}), address, nil, 1000000, new(big.Int)); err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+	var salt [32]byte
+	salt[31] = 7
+	want := crypto.CreateAddress2(address, salt, crypto.Keccak256(initCode))
+	if have := common.BytesToAddress(statedb.GetState(address, common.BigToHash(big.NewInt(1))).Bytes()); have != want {
+		t.Errorf("created address mismatch: have %x, want %x", have, want)
+	}
+	if have, want := statedb.GetState(address, common.Hash{}), crypto.Keccak256Hash([]byte{0x00}); have != want {
+		t.Errorf("code hash mismatch: have %x, want %x", have, want)
+	}
+}
+
+var eip2200Tests = []struct {
+	original byte
+	gaspool  uint64
+	input    string
+	used     uint64
+	refund   uint64
+	failure  error
+}{
+	{0, math.MaxUint64, "0x60006000556000600055", 1612, 0, nil},                // 0 -> 0 -> 0
+	{0, math.MaxUint64, "0x60006000556001600055", 20812, 0, nil},               // 0 -> 0 -> 1
+	{0, math.MaxUint64, "0x60016000556000600055", 20812, 19200, nil},           // 0 -> 1 -> 0
+	{0, math.MaxUint64, "0x60016000556002600055", 20812, 0, nil},               // 0 -> 1 -> 2
+	{0, math.MaxUint64, "0x60016000556001600055", 20812, 0, nil},               // 0 -> 1 -> 1
+	{1, math.MaxUint64, "0x60006000556000600055", 5812, 15000, nil},            // 1 -> 0 -> 0
+	{1, math.MaxUint64, "0x60006000556001600055", 5812, 4200, nil},             // 1 -> 0 -> 1
+	{1, math.MaxUint64, "0x60006000556002600055", 5812, 0, nil},                // 1 -> 0 -> 2
+	{1, math.MaxUint64, "0x60026000556000600055", 5812, 15000, nil},            // 1 -> 2 -> 0
+	{1, math.MaxUint64, "0x60026000556003600055", 5812, 0, nil},                // 1 -> 2 -> 3
+	{1, math.MaxUint64, "0x60026000556001600055", 5812, 4200, nil},             // 1 -> 2 -> 1
+	{1, math.MaxUint64, "0x60026000556002600055", 5812, 0, nil},                // 1 -> 2 -> 2
+	{1, math.MaxUint64, "0x60016000556000600055", 5812, 15000, nil},            // 1 -> 1 -> 0
+	{1, math.MaxUint64, "0x60016000556002600055", 5812, 0, nil},                // 1 -> 1 -> 2
+	{1, math.MaxUint64, "0x60016000556001600055", 1612, 0, nil},                // 1 -> 1 -> 1
+	{0, math.MaxUint64, "0x600160005560006000556001600055", 40818, 19200, nil}, // 0 -> 1 -> 0 -> 1
+	{1, math.MaxUint64, "0x600060005560016000556000600055", 10818, 19200, nil}, // 1 -> 0 -> 1 -> 0
+	{1, 2306, "0x6001600055", 2306, 0, ErrOutOfGas},                            // 1 -> 1 (2300 sentry + 2xPUSH)
+	{1, 2307, "0x6001600055", 806, 0, nil},                                     // 1 -> 1 (2301 sentry + 2xPUSH)
+}
+
+// Tests the SSTORE net gas metering of EIP-2200 activated by Istanbul, including
+// the reentrancy sentry.
+func TestEIP2200(t *testing.T) {
+	config := *constantinopleTestConfig
+	config.IstanbulBlock = new(big.Int)
+
+	address := common.BytesToAddress([]byte("contract"))
+	for i, tt := range eip2200Tests {
+		vmenv, statedb := newConstantinopleTestEVM(hexutil.MustDecode(tt.input), tt.original, &config)
+
+		_, gas, err := vmenv.Call(AccountRef(common.Address{}), address, nil, tt.gaspool, new(big.Int))
+		if err != tt.failure {
+			t.Errorf("test %d: failure mismatch: have %v, want %v", i, err, tt.failure)
+		}
+		if used := tt.gaspool - gas; used != tt.used {
+			t.Errorf("test %d: gas used mismatch: have %v, want %v", i, used, tt.used)
+		}
+		if refund := statedb.GetRefund(); refund != tt.refund {
+			t.Errorf("test %d: gas refund mismatch: have %v, want %v", i, refund, tt.refund)
+		}
+	}
+}
+
+// Tests that CHAINID and SELFBALANCE are only available from Istanbul on, and
+// that they report the chain id and the balance of the executing contract.
+func TestChainIDSelfBalance(t *testing.T) {
+	// CHAINID PUSH1 0 SSTORE SELFBALANCE PUSH1 1 SSTORE STOP
+	code := hexutil.MustDecode("0x466000554760015500")
+
+	istanbul := *constantinopleTestConfig
+	istanbul.ChainId = big.NewInt(88)
+	istanbul.IstanbulBlock = new(big.Int)
+
+	address := common.BytesToAddress([]byte("contract"))
+	for _, config := range []*params.ChainConfig{constantinopleTestConfig, &istanbul} {
+		vmenv, statedb := newConstantinopleTestEVM(code, 0, config)
+		statedb.AddBalance(address, big.NewInt(1000))
+
+		_, _, err := vmenv.Call(AccountRef(common.Address{}), address, nil, 100000, new(big.Int))
+		if config.IstanbulBlock == nil {
+			if err == nil {
+				t.Errorf("pre-istanbul execution succeeded")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("failed to execute: %v", err)
+		}
+		if have := statedb.GetState(address, common.Hash{}).Big(); have.Cmp(big.NewInt(88)) != 0 {
+			t.Errorf("chain id mismatch: have %v, want 88", have)
+		}
+		if have := statedb.GetState(address, common.BigToHash(big.NewInt(1))).Big(); have.Cmp(big.NewInt(1000)) != 0 {
+			t.Errorf("self balance mismatch: have %v, want 1000", have)
+		}
+	}
+	// A chain config without a chain id reports zero
+	istanbul.ChainId = nil
+
+	vmenv, statedb := newConstantinopleTestEVM(code, 0, &istanbul)
+	if _, _, err := vmenv.Call(AccountRef(common.Address{}), address, nil, 100000, new(big.Int)); err != nil {
+		t.Fatalf("failed to execute without chain id: %v", err)
+	}
+	if have := statedb.GetState(address, common.Hash{}); have != (common.Hash{}) {
+		t.Errorf("missing chain id mismatch: have %x, want zero", have)
+	}
+}
+
+// Tests that the Istanbul opcodes and gas prices are enabled by the TomoChain
+// fork block of the chain config, independently of the Constantinople one.
+func TestTIPIstanbul(t *testing.T) {
+	// CHAINID PUSH1 0 SSTORE STOP
+	code := hexutil.MustDecode("0x4660005500")
+
+	address := common.BytesToAddress([]byte("contract"))
+	for _, tt := range []struct {
+		number int64
+		valid  bool
+	}{
+		{10, false},
+		{19, false},
+		{20, true},
+	} {
+		vmenv, _ := newTIPForkTestEVM(code, 0, tt.number)
+		if _, _, err := vmenv.Call(AccountRef(common.Address{}), address, nil, 100000, new(big.Int)); (err == nil) != tt.valid {
+			t.Errorf("block %d: CHAINID validity mismatch: have error %v, want valid %v", tt.number, err, tt.valid)
+		}
+		if have := tipForkTestConfig.GasTable(big.NewInt(tt.number)) == params.GasTableIstanbul; have != tt.valid {
+			t.Errorf("block %d: istanbul gas table mismatch: have %v, want %v", tt.number, have, tt.valid)
+		}
+	}
+	// Moving the fork block moves the activation along, whatever the mainnet says
+	config := *tipForkTestConfig
+	config.TIPIstanbulBlock = big.NewInt(30)
+	if config.IsTIPIstanbul(big.NewInt(20)) || !config.IsTIPIstanbul(big.NewInt(30)) {
+		t.Errorf("istanbul activation not following the configured fork block")
+	}
+}
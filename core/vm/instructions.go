@@ -396,6 +396,11 @@ func opBalance(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *
 	return nil, nil
 }
 
+func opSelfBalance(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
+	stack.push(evm.interpreter.intPool.get().Set(evm.StateDB.GetBalance(contract.Address())))
+	return nil, nil
+}
+
 func opOrigin(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
 	stack.push(evm.Origin.Big())
 	return nil, nil
@@ -463,6 +468,21 @@ func opExtCodeSize(pc *uint64, evm *EVM, contract *Contract, memory *Memory, sta
 	return nil, nil
 }
 
+// opExtCodeHash returns the code hash of a specified account. Non-existent and
+// deleted accounts (as well as empty ones, e.g. untouched precompiles) yield zero,
+// accounts without code yield the empty code hash, and accounts suicided within
+// the current transaction still yield the hash of their code.
+func opExtCodeHash(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
+	slot := stack.peek()
+	address := common.BigToAddress(slot)
+	if evm.StateDB.Empty(address) {
+		slot.SetUint64(0)
+	} else {
+		slot.SetBytes(evm.StateDB.GetCodeHash(address).Bytes())
+	}
+	return nil, nil
+}
+
 func opCodeSize(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
 	l := evm.interpreter.intPool.get().SetInt64(int64(len(contract.Code)))
 	stack.push(l)
@@ -540,6 +560,11 @@ func opGasLimit(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack
 	return nil, nil
 }
 
+func opChainID(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
+	stack.push(evm.interpreter.intPool.get().Set(evm.chainRules.ChainId))
+	return nil, nil
+}
+
 func opPop(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
 	evm.interpreter.intPool.put(stack.pop())
 	return nil, nil
@@ -580,6 +605,9 @@ func opSload(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *St
 func opSstore(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
 	loc := common.BigToHash(stack.pop())
 	val := stack.pop()
+	if tracer := evm.StructuredTracer(); tracer != nil {
+		tracer.CaptureStorageChange(contract.Address(), loc, evm.StateDB.GetState(contract.Address(), loc), common.BigToHash(val))
+	}
 	evm.StateDB.SetState(contract.Address(), loc, common.BigToHash(val))
 
 	evm.interpreter.intPool.put(val)
@@ -666,6 +694,34 @@ func opCreate(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *S
 	return nil, nil
 }
 
+func opCreate2(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
+	var (
+		endowment    = stack.pop()
+		offset, size = stack.pop(), stack.pop()
+		salt         = stack.pop()
+		input        = memory.Get(offset.Int64(), size.Int64())
+		gas          = contract.Gas
+	)
+
+	// Apply EIP150
+	gas -= gas / 64
+	contract.UseGas(gas)
+	res, addr, returnGas, suberr := evm.Create2(contract, input, gas, endowment, salt)
+	// Push item on the stack based on the returned error.
+	if suberr != nil {
+		stack.push(evm.interpreter.intPool.getZero())
+	} else {
+		stack.push(addr.Big())
+	}
+	contract.Gas += returnGas
+	evm.interpreter.intPool.put(endowment, offset, size, salt)
+
+	if suberr == errExecutionReverted {
+		return res, nil
+	}
+	return nil, nil
+}
+
 func opCall(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
 	// Pop gas. The actual gas in in evm.callGasTemp.
 	evm.interpreter.intPool.put(stack.pop())
@@ -795,10 +851,29 @@ func opStop(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Sta
 }
 
 func opSuicide(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
-	balance := evm.StateDB.GetBalance(contract.Address())
-	evm.StateDB.AddBalance(common.BigToAddress(stack.pop()), balance)
+	var (
+		beneficiary = common.BigToAddress(stack.pop())
+		balance     = evm.StateDB.GetBalance(contract.Address())
+		tracer      = evm.StructuredTracer()
+		prev        *big.Int
+	)
+	if tracer != nil {
+		tracer.CaptureEnter(SELFDESTRUCT, contract.Address(), beneficiary, nil, 0, balance)
+		prev = evm.StateDB.GetBalance(beneficiary)
+	}
+	evm.StateDB.AddBalance(beneficiary, balance)
 
 	evm.StateDB.Suicide(contract.Address())
+
+	if tracer != nil {
+		if balance.Sign() > 0 {
+			tracer.CaptureBalanceChange(contract.Address(), balance, new(big.Int), BalanceChangeSelfDestruct)
+			if beneficiary != contract.Address() {
+				tracer.CaptureBalanceChange(beneficiary, prev, evm.StateDB.GetBalance(beneficiary), BalanceChangeSelfDestruct)
+			}
+		}
+		tracer.CaptureExit(nil, 0, nil)
+	}
 	return nil, nil
 }
 
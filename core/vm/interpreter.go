@@ -19,6 +19,7 @@ package vm
 import (
 	"fmt"
 	"sync/atomic"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/params"
@@ -35,6 +36,9 @@ type Config struct {
 	NoRecursion bool
 	// Enable recording of SHA3/keccak preimages
 	EnablePreimageRecording bool
+	// Profiler aggregates the time and gas spent per contract
+	// and opcode, if set.
+	Profiler *Profiler
 	// JumpTable contains the EVM instruction table. This
 	// may be left uninitialised and will be set to the default
 	// table.
@@ -53,6 +57,8 @@ type Interpreter struct {
 
 	readOnly   bool   // Whether to throw on stateful modifications
 	returnData []byte // Last CALL's return data for subsequent reuse
+
+	profiled time.Duration // Wall time of the profiled call frames, used to exclude sub-calls
 }
 
 // NewInterpreter returns a new instance of the Interpreter.
@@ -62,7 +68,9 @@ func NewInterpreter(evm *EVM, cfg Config) *Interpreter {
 	// we'll set the default jump table.
 	if !cfg.JumpTable[STOP].valid {
 		switch {
-		case evm.ChainConfig().IsConstantinople(evm.BlockNumber):
+		case evm.chainRules.IsIstanbul:
+			cfg.JumpTable = istanbulInstructionSet
+		case evm.chainRules.IsConstantinople:
 			cfg.JumpTable = constantinopleInstructionSet
 		case evm.ChainConfig().IsByzantium(evm.BlockNumber):
 			cfg.JumpTable = byzantiumInstructionSet
@@ -130,9 +138,29 @@ func (in *Interpreter) Run(contract *Contract, input []byte) (ret []byte, err er
 		pcCopy  uint64 // needed for the deferred Tracer
 		gasCopy uint64 // for Tracer to log gas remaining before execution
 		logged  bool   // deferred Tracer should ignore already logged steps
+		// per-opcode costs of this call frame if profiling
+		frame    *[256]ProfileStat
+		opStart  time.Time
+		opNested time.Duration
+		opGas    uint64
 	)
 	contract.Input = input
 
+	if in.cfg.Profiler != nil {
+		frame = new([256]ProfileStat)
+		start, nested := time.Now(), in.profiled
+		defer func() {
+			// Account the whole frame as nested time of the parent's current op
+			in.profiled = nested + time.Since(start)
+
+			addr := contract.Address()
+			if contract.CodeAddr != nil {
+				addr = *contract.CodeAddr
+			}
+			in.cfg.Profiler.merge(addr, frame)
+		}()
+	}
+
 	if in.cfg.Debug {
 		defer func() {
 			if err != nil {
@@ -153,6 +181,9 @@ func (in *Interpreter) Run(contract *Contract, input []byte) (ret []byte, err er
 			// Capture pre-execution values for tracing.
 			logged, pcCopy, gasCopy = false, pc, contract.Gas
 		}
+		if frame != nil {
+			opStart, opNested = time.Now(), in.profiled
+		}
 
 		// Get the operation from the jump table and validate the stack to ensure there are
 		// enough stack items available to perform the operation.
@@ -192,6 +223,14 @@ func (in *Interpreter) Run(contract *Contract, input []byte) (ret []byte, err er
 		if memorySize > 0 {
 			mem.Resize(memorySize)
 		}
+		if frame != nil {
+			// The gas forwarded to sub-calls is accounted to the callee
+			opGas = cost
+			switch op {
+			case CALL, CALLCODE, DELEGATECALL, STATICCALL:
+				opGas -= in.evm.callGasTemp
+			}
+		}
 
 		if in.cfg.Debug {
 			in.cfg.Tracer.CaptureState(in.evm, pc, op, gasCopy, cost, mem, stack, contract, in.evm.depth, err)
@@ -210,6 +249,12 @@ func (in *Interpreter) Run(contract *Contract, input []byte) (ret []byte, err er
 		if operation.returns {
 			in.returnData = res
 		}
+		if frame != nil {
+			stat := &frame[op]
+			stat.Count++
+			stat.Gas += opGas
+			stat.Time += time.Since(opStart) - (in.profiled - opNested)
+		}
 
 		switch {
 		case err != nil:
@@ -55,8 +55,31 @@ var (
 	homesteadInstructionSet      = NewHomesteadInstructionSet()
 	byzantiumInstructionSet      = NewByzantiumInstructionSet()
 	constantinopleInstructionSet = NewConstantinopleInstructionSet()
+	istanbulInstructionSet       = NewIstanbulInstructionSet()
 )
 
+// NewIstanbulInstructionSet returns the frontier, homestead, byzantium,
+// contantinople and istanbul instructions.
+func NewIstanbulInstructionSet() [256]operation {
+	// instructions that can be executed during the constantinople phase.
+	instructionSet := NewConstantinopleInstructionSet()
+	instructionSet[CHAINID] = operation{
+		execute:       opChainID,
+		gasCost:       constGasFunc(GasQuickStep),
+		validateStack: makeStackFunc(0, 1),
+		valid:         true,
+	}
+	instructionSet[SELFBALANCE] = operation{
+		execute:       opSelfBalance,
+		gasCost:       constGasFunc(GasFastStep),
+		validateStack: makeStackFunc(0, 1),
+		valid:         true,
+	}
+	// Net gas metering of SSTORE is brought back by EIP-2200
+	instructionSet[SSTORE].gasCost = gasSStoreEIP2200
+	return instructionSet
+}
+
 // NewConstantinopleInstructionSet returns the frontier, homestead
 // byzantium and contantinople instructions.
 func NewConstantinopleInstructionSet() [256]operation {
@@ -80,6 +103,21 @@ func NewConstantinopleInstructionSet() [256]operation {
 		validateStack: makeStackFunc(2, 1),
 		valid:         true,
 	}
+	instructionSet[EXTCODEHASH] = operation{
+		execute:       opExtCodeHash,
+		gasCost:       gasExtCodeHash,
+		validateStack: makeStackFunc(1, 1),
+		valid:         true,
+	}
+	instructionSet[CREATE2] = operation{
+		execute:       opCreate2,
+		gasCost:       gasCreate2,
+		validateStack: makeStackFunc(4, 1),
+		memorySize:    memoryCreate2,
+		valid:         true,
+		writes:        true,
+		returns:       true,
+	}
 	return instructionSet
 }
 
@@ -98,6 +98,65 @@ type Tracer interface {
 	CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) error
 }
 
+// BalanceChangeReason is the cause of a change in the balance of an account
+// reported to a StructuredTracer.
+type BalanceChangeReason byte
+
+const (
+	BalanceChangeUnspecified  BalanceChangeReason = iota
+	BalanceChangeTransfer                         // Value transferred by a transaction, call or contract creation
+	BalanceChangeGasBuy                           // Gas bought upfront by the sender of a transaction
+	BalanceChangeGasRefund                        // Unused gas refunded to the sender of a transaction
+	BalanceChangeGasFee                           // Fee of the used gas paid to the coinbase
+	BalanceChangeTRC21Fee                         // Fee of the used gas paid to the coinbase by a TRC21 token issuer
+	BalanceChangeSelfDestruct                     // Balance moved to the beneficiary of a self destructed contract
+	BalanceChangeReward                           // Rewards paid by the consensus engine when finalizing a block
+)
+
+var balanceChangeReasonNames = [...]string{
+	BalanceChangeUnspecified:  "unspecified",
+	BalanceChangeTransfer:     "transfer",
+	BalanceChangeGasBuy:       "gasBuy",
+	BalanceChangeGasRefund:    "gasRefund",
+	BalanceChangeGasFee:       "gasFee",
+	BalanceChangeTRC21Fee:     "trc21Fee",
+	BalanceChangeSelfDestruct: "selfDestruct",
+	BalanceChangeReward:       "reward",
+}
+
+func (r BalanceChangeReason) String() string {
+	if int(r) < len(balanceChangeReasonNames) {
+		return balanceChangeReasonNames[r]
+	}
+	return fmt.Sprintf("BalanceChangeReason(%d)", r)
+}
+
+// StructuredTracer is a Tracer which is additionally notified of every call
+// frame entered and exited below the top level one, and of the balance and
+// storage changes made during execution. Unlike CaptureState, these events are
+// also emitted for the balance changes made by TomoChain outside of the EVM,
+// such as gas purchases and refunds, TRC21 fees and block rewards.
+//
+// The events are emitted when the changes are made, so changes inside a call
+// frame which is later reverted are reported as well.
+type StructuredTracer interface {
+	Tracer
+
+	// CaptureEnter is called when the EVM enters a new call frame, with typ being
+	// one of CALL, CALLCODE, DELEGATECALL, STATICCALL, CREATE, CREATE2 or
+	// SELFDESTRUCT. Calls to precompiled contracts are reported as well.
+	CaptureEnter(typ OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int)
+
+	// CaptureExit is called when the EVM exits the last entered call frame.
+	CaptureExit(output []byte, gasUsed uint64, err error)
+
+	// CaptureBalanceChange is called when the balance of an account changes.
+	CaptureBalanceChange(addr common.Address, prev, next *big.Int, reason BalanceChangeReason)
+
+	// CaptureStorageChange is called when a storage slot of an account is written.
+	CaptureStorageChange(addr common.Address, slot, prev, next common.Hash)
+}
+
 // StructLogger is an EVM state logger and implements Tracer.
 //
 // StructLogger can capture state based on the given Log configuration and also keeps
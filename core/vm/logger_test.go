@@ -17,10 +17,16 @@
 package vm
 
 import (
+	"fmt"
 	"math/big"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -68,3 +74,93 @@ func TestStoreCapture(t *testing.T) {
 		t.Errorf("expected %x, got %x", exp, logger.changedValues[contract.Address()][index])
 	}
 }
+
+// eventTracer is a StructuredTracer recording the events it receives.
+type eventTracer struct {
+	events []string
+}
+
+func (t *eventTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.events = append(t.events, fmt.Sprintf("start %x -> %x", from[19:], to[19:]))
+	return nil
+}
+func (t *eventTracer) CaptureState(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory, stack *Stack, contract *Contract, depth int, err error) error {
+	return nil
+}
+func (t *eventTracer) CaptureFault(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory, stack *Stack, contract *Contract, depth int, err error) error {
+	return nil
+}
+func (t *eventTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	t.events = append(t.events, fmt.Sprintf("end %v", err))
+	return nil
+}
+func (t *eventTracer) CaptureEnter(typ OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	t.events = append(t.events, fmt.Sprintf("enter %v %x -> %x value %v", typ, from[19:], to[19:], value))
+}
+func (t *eventTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	t.events = append(t.events, fmt.Sprintf("exit %v", err))
+}
+func (t *eventTracer) CaptureBalanceChange(addr common.Address, prev, next *big.Int, reason BalanceChangeReason) {
+	t.events = append(t.events, fmt.Sprintf("balance %x %v -> %v %v", addr[19:], prev, next, reason))
+}
+func (t *eventTracer) CaptureStorageChange(addr common.Address, slot, prev, next common.Hash) {
+	t.events = append(t.events, fmt.Sprintf("storage %x %x %x -> %x", addr[19:], slot[31:], prev[31:], next[31:]))
+}
+
+// Tests that a structured tracer is notified of the call frames entered and the
+// balance and storage changes made during execution, in order. Value transfers
+// must be reported inside the call frame they are made for, and calls failing
+// before running any code must still enter and exit a frame.
+func TestStructuredTracer(t *testing.T) {
+	var (
+		caller     = common.HexToAddress("0xaa")
+		contract   = common.HexToAddress("0xcc")
+		db, _      = ethdb.NewMemDatabase()
+		statedb, _ = state.New(common.Hash{}, state.NewDatabase(db))
+		tracer     = new(eventTracer)
+	)
+	statedb.SetBalance(caller, big.NewInt(100))
+	statedb.SetBalance(contract, big.NewInt(10))
+
+	// CALL 0xdd with more value than available and with 1 wei, STATICCALL the
+	// identity precompile, store 2 in slot 1 and self destruct
+	statedb.SetCode(contract, hexutil.MustDecode("0x60006000600060006103e860dd5af15060006000600060006001"+
+		"60dd5af150600060006000600060045afa50600260015560bbff"))
+
+	context := Context{
+		CanTransfer: func(db StateDB, addr common.Address, amount *big.Int) bool {
+			return db.GetBalance(addr).Cmp(amount) >= 0
+		},
+		Transfer: func(db StateDB, sender, recipient common.Address, amount *big.Int) {
+			db.SubBalance(sender, amount)
+			db.AddBalance(recipient, amount)
+		},
+		BlockNumber: new(big.Int),
+	}
+	evm := NewEVM(context, statedb, params.TestChainConfig, Config{Debug: true, Tracer: tracer})
+	if _, _, err := evm.Call(AccountRef(caller), contract, nil, 200000, big.NewInt(5)); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	want := []string{
+		"start aa -> cc",
+		"balance aa 100 -> 95 transfer",
+		"balance cc 10 -> 15 transfer",
+		"enter CALL cc -> dd value 1000",
+		"exit insufficient balance for transfer",
+		"enter CALL cc -> dd value 1",
+		"balance cc 15 -> 14 transfer",
+		"balance dd 0 -> 1 transfer",
+		"exit <nil>",
+		"enter STATICCALL cc -> 04 value 0",
+		"exit <nil>",
+		"storage cc 01 00 -> 02",
+		"enter SELFDESTRUCT cc -> bb value 14",
+		"balance cc 14 -> 0 selfDestruct",
+		"balance bb 0 -> 14 selfDestruct",
+		"exit <nil>",
+		"end <nil>",
+	}
+	if !reflect.DeepEqual(tracer.events, want) {
+		t.Errorf("event mismatch:\nhave %q\nwant %q", tracer.events, want)
+	}
+}
@@ -0,0 +1,253 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ProfileStat is the aggregated execution cost of a set of executed opcodes.
+type ProfileStat struct {
+	Count uint64        // Number of times the opcodes were executed
+	Gas   uint64        // Gas consumed, excluding the gas forwarded to sub-calls
+	Time  time.Duration // Wall time spent, excluding the time spent in sub-calls
+}
+
+// add accumulates the costs of another stat into s.
+func (s *ProfileStat) add(other *ProfileStat) {
+	s.Count += other.Count
+	s.Gas += other.Gas
+	s.Time += other.Time
+}
+
+// ContractProfile is the execution cost of the code of a single contract, in
+// total and broken down by opcode.
+type ContractProfile struct {
+	Address common.Address
+	Total   ProfileStat
+	Ops     map[OpCode]ProfileStat
+}
+
+// Profiler aggregates the wall time and gas spent by the interpreter, keyed by
+// the address of the executed code and the opcode, over a window starting at
+// the last reset. It is safe for concurrent use by multiple EVMs.
+type Profiler struct {
+	start     time.Time
+	contracts map[common.Address]*[256]ProfileStat
+	lock      sync.Mutex
+}
+
+// NewProfiler creates a new profiler with an empty window starting now.
+func NewProfiler() *Profiler {
+	return &Profiler{
+		start:     time.Now(),
+		contracts: make(map[common.Address]*[256]ProfileStat),
+	}
+}
+
+// Reset discards all the gathered statistics and starts a new window.
+func (p *Profiler) Reset() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.start = time.Now()
+	p.contracts = make(map[common.Address]*[256]ProfileStat)
+}
+
+// merge adds the costs gathered during a single call frame executing the code
+// of the given address to the window.
+func (p *Profiler) merge(addr common.Address, frame *[256]ProfileStat) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	ops := p.contracts[addr]
+	if ops == nil {
+		ops = new([256]ProfileStat)
+		p.contracts[addr] = ops
+	}
+	for op := range frame {
+		if frame[op].Count > 0 {
+			ops[op].add(&frame[op])
+		}
+	}
+}
+
+// Profile returns the costs gathered in the current window, sorted by the time
+// spent executing the code of each contract, most expensive first. The start of
+// the window is also returned.
+func (p *Profiler) Profile() ([]*ContractProfile, time.Time) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.profile()
+}
+
+// Swap returns the costs gathered in the current window like Profile, and starts
+// a new one without losing the costs merged in between.
+func (p *Profiler) Swap() ([]*ContractProfile, time.Time) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	profiles, start := p.profile()
+	p.start = time.Now()
+	p.contracts = make(map[common.Address]*[256]ProfileStat)
+	return profiles, start
+}
+
+// profile aggregates the costs of the current window. The lock must be held.
+func (p *Profiler) profile() ([]*ContractProfile, time.Time) {
+	profiles := make([]*ContractProfile, 0, len(p.contracts))
+	for addr, ops := range p.contracts {
+		profile := &ContractProfile{Address: addr, Ops: make(map[OpCode]ProfileStat)}
+		for op := range ops {
+			if ops[op].Count > 0 {
+				profile.Ops[OpCode(op)] = ops[op]
+				profile.Total.add(&ops[op])
+			}
+		}
+		profiles = append(profiles, profile)
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		if profiles[i].Total.Time != profiles[j].Total.Time {
+			return profiles[i].Total.Time > profiles[j].Total.Time
+		}
+		return bytes.Compare(profiles[i].Address[:], profiles[j].Address[:]) < 0
+	})
+	return profiles, p.start
+}
+
+// WriteProfile writes the costs gathered in the current window to w as a gzip
+// compressed pprof protocol buffer, so it can be explored by `go tool pprof`.
+// Each sample is an opcode called from the contract executing it, valued by its
+// execution count, wall time and gas.
+func (p *Profiler) WriteProfile(w io.Writer) error {
+	profiles, start := p.Profile()
+
+	var (
+		buf     = new(pprofBuffer)
+		strings = map[string]uint64{"": 0}
+		table   = []string{""}
+	)
+	str := func(s string) uint64 {
+		if idx, ok := strings[s]; ok {
+			return idx
+		}
+		strings[s] = uint64(len(table))
+		table = append(table, s)
+		return strings[s]
+	}
+	valueType := func(typ, unit string) []byte {
+		msg := new(pprofBuffer)
+		msg.uint64(1, str(typ))
+		msg.uint64(2, str(unit))
+		return msg.Bytes()
+	}
+	// Define the sample values: call count, exclusive time and gas consumed
+	buf.bytes(1, valueType("calls", "count"))
+	buf.bytes(1, valueType("time", "nanoseconds"))
+	buf.bytes(1, valueType("gas", "gas"))
+
+	// Opcodes share the location ids 1-256, contracts are numbered after them
+	var (
+		used  [256]bool
+		frame = func(id uint64, name string) {
+			loc := new(pprofBuffer)
+			loc.uint64(1, id)
+			line := new(pprofBuffer)
+			line.uint64(1, id)
+			loc.bytes(4, line.Bytes())
+			buf.bytes(4, loc.Bytes())
+
+			fn := new(pprofBuffer)
+			fn.uint64(1, id)
+			fn.uint64(2, str(name))
+			fn.uint64(3, str(name))
+			buf.bytes(5, fn.Bytes())
+		}
+	)
+	for i, profile := range profiles {
+		contract := uint64(257 + i)
+		for op, stat := range profile.Ops {
+			sample := new(pprofBuffer)
+			sample.packed(1, []uint64{uint64(op) + 1, contract})
+			sample.packed(2, []uint64{stat.Count, uint64(stat.Time), stat.Gas})
+			buf.bytes(2, sample.Bytes())
+
+			used[op] = true
+		}
+		frame(contract, profile.Address.Hex())
+	}
+	for op := range used {
+		if used[op] {
+			frame(uint64(op)+1, OpCode(op).String())
+		}
+	}
+	buf.uint64(9, uint64(start.UnixNano()))
+	buf.uint64(10, uint64(time.Since(start)))
+	buf.uint64(14, str("time"))
+
+	for _, s := range table {
+		buf.bytes(6, []byte(s))
+	}
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// pprofBuffer is a minimal protocol buffer encoder for the fields used by the
+// pprof profile format.
+type pprofBuffer struct {
+	bytes.Buffer
+}
+
+// varint appends an unsigned varint to the buffer.
+func (b *pprofBuffer) varint(x uint64) {
+	var enc [binary.MaxVarintLen64]byte
+	b.Write(enc[:binary.PutUvarint(enc[:], x)])
+}
+
+// uint64 appends a varint encoded field to the buffer.
+func (b *pprofBuffer) uint64(field int, x uint64) {
+	b.varint(uint64(field) << 3)
+	b.varint(x)
+}
+
+// bytes appends a length delimited field to the buffer.
+func (b *pprofBuffer) bytes(field int, data []byte) {
+	b.varint(uint64(field)<<3 | 2)
+	b.varint(uint64(len(data)))
+	b.Write(data)
+}
+
+// packed appends a packed repeated varint field to the buffer.
+func (b *pprofBuffer) packed(field int, xs []uint64) {
+	data := new(pprofBuffer)
+	for _, x := range xs {
+		data.varint(x)
+	}
+	b.bytes(field, data.Bytes())
+}
@@ -0,0 +1,132 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the profiler aggregates the executed opcodes per code address,
+// accounting the gas forwarded to sub-calls to the callee, and that the gathered
+// profile can be exported in the pprof format.
+func TestProfiler(t *testing.T) {
+	var (
+		caller     = common.HexToAddress("0xaa")
+		outer      = common.HexToAddress("0xcc")
+		inner      = common.HexToAddress("0xdd")
+		db, _      = ethdb.NewMemDatabase()
+		statedb, _ = state.New(common.Hash{}, state.NewDatabase(db))
+		profiler   = NewProfiler()
+	)
+	// CALL the inner contract with 50000 gas, which stores 1 in slot 0
+	statedb.SetCode(outer, hexutil.MustDecode("0x6000600060006000600060dd61c350f100"))
+	statedb.SetCode(inner, hexutil.MustDecode("0x600160005500"))
+
+	context := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: new(big.Int),
+	}
+	evm := NewEVM(context, statedb, params.TestChainConfig, Config{Profiler: profiler})
+	for i := 0; i < 2; i++ {
+		if _, _, err := evm.Call(AccountRef(caller), outer, nil, 100000, new(big.Int)); err != nil {
+			t.Fatalf("call %d failed: %v", i, err)
+		}
+	}
+	profiles, _ := profiler.Profile()
+	if len(profiles) != 2 {
+		t.Fatalf("profiled contract count mismatch: have %d, want 2", len(profiles))
+	}
+	stats := make(map[common.Address]*ContractProfile)
+	for _, profile := range profiles {
+		stats[profile.Address] = profile
+	}
+	calls := evm.ChainConfig().GasTable(evm.BlockNumber).Calls
+	for _, test := range []struct {
+		addr  common.Address
+		op    OpCode
+		count uint64
+		gas   uint64
+	}{
+		{outer, PUSH1, 12, 12 * GasFastestStep},
+		{outer, PUSH2, 2, 2 * GasFastestStep},
+		{outer, CALL, 2, 2 * calls},
+		{outer, STOP, 2, 0},
+		{inner, PUSH1, 4, 4 * GasFastestStep},
+		{inner, SSTORE, 2, params.SstoreSetGas + params.SstoreResetGas},
+		{inner, STOP, 2, 0},
+	} {
+		profile := stats[test.addr]
+		if profile == nil {
+			t.Fatalf("contract %x not profiled", test.addr)
+		}
+		stat, ok := profile.Ops[test.op]
+		if !ok {
+			t.Errorf("contract %x: op %v not profiled", test.addr, test.op)
+			continue
+		}
+		if stat.Count != test.count || stat.Gas != test.gas {
+			t.Errorf("contract %x: op %v: have count %d gas %d, want count %d gas %d", test.addr, test.op, stat.Count, stat.Gas, test.count, test.gas)
+		}
+	}
+	if have := len(stats[outer].Ops); have != 4 {
+		t.Errorf("outer op count mismatch: have %d, want 4", have)
+	}
+	// Export the profile and check it references the contracts and opcodes
+	var buf bytes.Buffer
+	if err := profiler.WriteProfile(&buf); err != nil {
+		t.Fatalf("failed to write profile: %v", err)
+	}
+	zr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("profile not gzipped: %v", err)
+	}
+	blob, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("failed to decompress profile: %v", err)
+	}
+	for _, name := range []string{outer.Hex(), inner.Hex(), "SSTORE", "CALL", "nanoseconds"} {
+		if !bytes.Contains(blob, []byte(name)) {
+			t.Errorf("profile missing %q", name)
+		}
+	}
+	// Swapping should return the gathered window and start a new, empty one
+	if profiles, _ := profiler.Swap(); len(profiles) != 2 {
+		t.Errorf("swapped contract count mismatch: have %d, want 2", len(profiles))
+	}
+	if profiles, _ := profiler.Profile(); len(profiles) != 0 {
+		t.Errorf("profile not swapped: %d contracts", len(profiles))
+	}
+	// Resetting should start a new, empty window too
+	if _, _, err := evm.Call(AccountRef(caller), outer, nil, 100000, new(big.Int)); err != nil {
+		t.Fatalf("call after swap failed: %v", err)
+	}
+	profiler.Reset()
+	if profiles, _ := profiler.Profile(); len(profiles) != 0 {
+		t.Errorf("profile not reset: %d contracts", len(profiles))
+	}
+}
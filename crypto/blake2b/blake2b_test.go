@@ -0,0 +1,90 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package blake2b
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+)
+
+// sum512 hashes a message with the unkeyed 64 byte BLAKE2b, built on top of the
+// compression function.
+func sum512(msg []byte) []byte {
+	h := iv
+	h[0] ^= 0x01010040 // digest length 64, fanout 1, depth 1
+
+	var (
+		m      [16]uint64
+		block  [128]byte
+		offset uint64
+	)
+	for {
+		n := copy(block[:], msg)
+		for i := n; i < len(block); i++ {
+			block[i] = 0
+		}
+		msg = msg[n:]
+		offset += uint64(n)
+
+		for i := range m {
+			m[i] = binary.LittleEndian.Uint64(block[i*8:])
+		}
+		final := len(msg) == 0
+		F(&h, m, [2]uint64{offset, 0}, final, 12)
+		if final {
+			break
+		}
+	}
+	out := make([]byte, 64)
+	for i, word := range h {
+		binary.LittleEndian.PutUint64(out[i*8:], word)
+	}
+	return out
+}
+
+// Tests the compression function against BLAKE2b-512 digests of the RFC 7693
+// example and of single and multiple block messages.
+func TestF(t *testing.T) {
+	long := make([]byte, 200)
+	for i := range long {
+		long[i] = byte(i)
+	}
+	tests := []struct {
+		msg  []byte
+		hash string
+	}{
+		{[]byte("abc"), "ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d17d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923"},
+		{nil, "786a02f742015903c6c6fd852552d272912f4740e15847618a86e217f71f5419d25e1031afee585313896444934eb04b903a685b1448b755d56f701afe9be2ce"},
+		{long, "fb3c1f0f56a56f8e316fdf5d853c8c872c39635d083634c3904fc3ac07d1b578e85ff0e480e92d44ade33b62e893ee32343e79ddf6ef292e89b582d312502314"},
+	}
+	for i, tt := range tests {
+		if have := hex.EncodeToString(sum512(tt.msg)); have != tt.hash {
+			t.Errorf("test %d: hash mismatch: have %s, want %s", i, have, tt.hash)
+		}
+	}
+}
+
+func BenchmarkF(b *testing.B) {
+	var (
+		h = iv
+		m [16]uint64
+	)
+	for i := 0; i < b.N; i++ {
+		F(&h, m, [2]uint64{128, 0}, false, 12)
+	}
+}
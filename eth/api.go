@@ -19,17 +19,22 @@ package eth
 import (
 	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"math/big"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/pruner"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/params"
@@ -291,6 +296,39 @@ func (api *PrivateAdminAPI) ImportChain(file string) (bool, error) {
 	return true, nil
 }
 
+// TxPoolPolicy retrieves the configuration of the admission policies currently
+// enforced by the transaction pool.
+func (api *PrivateAdminAPI) TxPoolPolicy() core.TxPoolPolicyConfig {
+	return api.eth.TxPool().Policy()
+}
+
+// SetTxPoolPolicy reloads the admission policies of the transaction pool. Any
+// transaction already pooled is left untouched.
+func (api *PrivateAdminAPI) SetTxPoolPolicy(config core.TxPoolPolicyConfig) bool {
+	api.eth.TxPool().SetPolicy(config)
+	return true
+}
+
+// PruneState starts deleting the stale state of the chain database in the
+// background, retaining the state of the given number of recent blocks (two
+// epochs or 128 blocks by default) using a bloom filter of the given megabytes.
+func (api *PrivateAdminAPI) PruneState(retain *uint64, bloomSize *uint64) (bool, error) {
+	var (
+		blocks uint64
+		size   uint64 = pruner.DefaultBloomSize
+	)
+	if retain != nil {
+		blocks = *retain
+	}
+	if bloomSize != nil {
+		size = *bloomSize
+	}
+	if err := api.eth.PruneState(blocks, size); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // PublicDebugAPI is the collection of Ethereum full node APIs exposed
 // over the public debugging endpoint.
 type PublicDebugAPI struct {
@@ -484,3 +522,283 @@ func (api *PrivateDebugAPI) getModifiedAccounts(startBlock, endBlock *types.Bloc
 	}
 	return dirty, nil
 }
+
+// StateDiffResult is the result of a debug_getStateDiff API call, listing the
+// accounts changed by a block.
+type StateDiffResult struct {
+	BlockHash   common.Hash                `json:"blockHash"`
+	BlockNumber hexutil.Uint64             `json:"blockNumber"`
+	Accounts    []ethapi.AccountDiffResult `json:"accounts"`
+}
+
+// newStateDiffResult converts the state changes of a block into their RPC
+// representation.
+func newStateDiffResult(block *types.Block, diff *state.StateDiff) *StateDiffResult {
+	return &StateDiffResult{
+		BlockHash:   block.Hash(),
+		BlockNumber: hexutil.Uint64(block.NumberU64()),
+		Accounts:    ethapi.FormatStateDiff(diff),
+	}
+}
+
+// GetStateDiff returns the changes the given block made to the state. The state
+// changes are only available for the blocks imported with --statediffs enabled.
+func (api *PrivateDebugAPI) GetStateDiff(ctx context.Context, blockNr rpc.BlockNumber) (*StateDiffResult, error) {
+	var block *types.Block
+	switch blockNr {
+	case rpc.PendingBlockNumber:
+		return nil, fmt.Errorf("state diff not available for the pending block")
+	case rpc.LatestBlockNumber:
+		block = api.eth.blockchain.CurrentBlock()
+	default:
+		block = api.eth.blockchain.GetBlockByNumber(uint64(blockNr))
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block #%d not found", blockNr)
+	}
+	diff := core.GetStateDiff(api.eth.ChainDb(), block.Hash(), block.NumberU64())
+	if diff == nil {
+		return nil, fmt.Errorf("state diff of block #%d not found", block.NumberU64())
+	}
+	return newStateDiffResult(block, diff), nil
+}
+
+// StateDiffs creates a subscription that fires with the state changes of every
+// newly imported block.
+func (api *PrivateDebugAPI) StateDiffs(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		diffs := make(chan core.StateDiffEvent)
+		diffsSub := api.eth.blockchain.SubscribeStateDiffEvent(diffs)
+
+		for {
+			select {
+			case ev := <-diffs:
+				notifier.Notify(rpcSub.ID, newStateDiffResult(ev.Block, ev.Diff))
+			case <-rpcSub.Err():
+				diffsSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				diffsSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// StorageHistoryResult is the result of a debug_getStorageHistory API call,
+// listing the changes of a storage slot within a block range.
+type StorageHistoryResult struct {
+	Address   common.Address  `json:"address"`
+	Slot      common.Hash     `json:"slot"`
+	FromBlock hexutil.Uint64  `json:"fromBlock"`
+	ToBlock   hexutil.Uint64  `json:"toBlock"`
+	Initial   *common.Hash    `json:"initial"` // Value at the start of the range, nil if unknown
+	Changes   []StorageChange `json:"changes"`
+}
+
+// StorageChange is a single change point of a storage slot. Slots wiped by the
+// destruction of their account change to zero.
+type StorageChange struct {
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	From        *common.Hash   `json:"from"` // Value before the change, nil if unknown
+	To          common.Hash    `json:"to"`
+}
+
+// GetStorageHistory returns how a storage slot of an account evolved across a
+// range of canonical blocks, listing the blocks changing it. It requires state
+// diffs to be recorded.
+func (api *PrivateDebugAPI) GetStorageHistory(address common.Address, slot common.Hash, fromBlock, toBlock rpc.BlockNumber) (*StorageHistoryResult, error) {
+	if api.eth.storageIndexer == nil {
+		return nil, errors.New("storage history requires state diffs to be recorded")
+	}
+	from, err := api.resolveBlockNumber(fromBlock)
+	if err != nil {
+		return nil, err
+	}
+	to, err := api.resolveBlockNumber(toBlock)
+	if err != nil {
+		return nil, err
+	}
+	if from > to {
+		return nil, fmt.Errorf("start block (%d) must be less than or equal to end block (%d)", from, to)
+	}
+	db := api.eth.ChainDb()
+	result := &StorageHistoryResult{
+		Address:   address,
+		Slot:      slot,
+		FromBlock: hexutil.Uint64(from),
+		ToBlock:   hexutil.Uint64(to),
+		Changes:   []StorageChange{},
+	}
+	sections, _, _ := api.eth.storageIndexer.Sections()
+	numbers, err := storageChanges(db, sections, storageHistorySectionSize, address, slot, from, to)
+	if err != nil {
+		return nil, err
+	}
+	var prev *common.Hash // Value of the slot before the current change, nil if unknown
+	for i, number := range numbers {
+		hash := core.GetCanonicalHash(db, number)
+		diff := core.GetStateDiff(db, hash, number)
+		if diff == nil {
+			return nil, fmt.Errorf("state diff of block #%d not found", number)
+		}
+		account := changedAccount(diff, address)
+		if account == nil {
+			return nil, fmt.Errorf("state diff of block #%d misses account %x", number, address)
+		}
+		change := StorageChange{BlockNumber: hexutil.Uint64(number), BlockHash: hash}
+		if slotDiff := storageDiff(account, slot); slotDiff != nil {
+			value := slotDiff.From
+			change.From, change.To = &value, slotDiff.To
+		} else {
+			// The slot was implicitly wiped by destructing the account. Its value
+			// before is the last known one, or the one in the parent state.
+			if prev == nil && i == 0 && number > 0 {
+				prev = api.storageAt(number-1, address, slot)
+			}
+			if prev != nil && *prev == (common.Hash{}) {
+				continue
+			}
+			change.From = prev
+		}
+		result.Changes = append(result.Changes, change)
+		prev = &change.To
+	}
+	// The value at the start of the range is the one before the first change, or
+	// the final one if the slot never changed and that state is still available
+	if len(result.Changes) > 0 {
+		result.Initial = result.Changes[0].From
+	} else {
+		result.Initial = api.storageAt(to, address, slot)
+	}
+	return result, nil
+}
+
+// storageAt returns the value of a storage slot of an account after the given
+// canonical block, or nil if its state is not available.
+func (api *PrivateDebugAPI) storageAt(number uint64, address common.Address, slot common.Hash) *common.Hash {
+	block := api.eth.blockchain.GetBlockByNumber(number)
+	if block == nil {
+		return nil
+	}
+	statedb, err := api.eth.blockchain.StateAt(block.Root())
+	if err != nil {
+		return nil
+	}
+	value := statedb.GetState(address, slot)
+	return &value
+}
+
+// GetCandidateCapHistory returns how the cap of a masternode candidate evolved
+// across a range of canonical blocks. It requires state diffs to be recorded.
+func (api *PrivateDebugAPI) GetCandidateCapHistory(candidate common.Address, fromBlock, toBlock rpc.BlockNumber) (*StorageHistoryResult, error) {
+	return api.GetStorageHistory(common.HexToAddress(common.MasternodeVotingSMC), state.CandidateCapSlot(candidate), fromBlock, toBlock)
+}
+
+// errNoEVMProfiler is returned by the EVM profiling methods if the node was not
+// started with the profiler enabled.
+var errNoEVMProfiler = errors.New("EVM profiling not enabled")
+
+// OpProfileResult is the execution cost of an opcode, with the time given in
+// nanoseconds.
+type OpProfileResult struct {
+	Count uint64 `json:"count"`
+	Gas   uint64 `json:"gas"`
+	Time  uint64 `json:"time"`
+}
+
+// newOpProfileResult converts an EVM profile stat into its RPC representation.
+func newOpProfileResult(stat vm.ProfileStat) OpProfileResult {
+	return OpProfileResult{Count: stat.Count, Gas: stat.Gas, Time: uint64(stat.Time)}
+}
+
+// ContractProfileResult is the execution cost of the code of a contract, in
+// total and per opcode.
+type ContractProfileResult struct {
+	Address common.Address             `json:"address"`
+	Total   OpProfileResult            `json:"total"`
+	Ops     map[string]OpProfileResult `json:"ops"`
+}
+
+// EvmProfileResult is the execution cost of the contracts run since the start
+// of the profiling window, most expensive first.
+type EvmProfileResult struct {
+	Start     time.Time               `json:"start"`
+	Duration  uint64                  `json:"duration"`
+	Contracts []ContractProfileResult `json:"contracts"`
+}
+
+// EvmProfile returns the wall time and gas spent by the EVM per contract and
+// opcode since the last reset, limited to the given number of most expensive
+// contracts if set. If reset is true, a new profiling window is started.
+func (api *PrivateDebugAPI) EvmProfile(limit *int, reset *bool) (*EvmProfileResult, error) {
+	profiler := api.eth.blockchain.GetVMConfig().Profiler
+	if profiler == nil {
+		return nil, errNoEVMProfiler
+	}
+	var (
+		profiles []*vm.ContractProfile
+		start    time.Time
+	)
+	if reset != nil && *reset {
+		profiles, start = profiler.Swap()
+	} else {
+		profiles, start = profiler.Profile()
+	}
+	if limit != nil && *limit >= 0 && *limit < len(profiles) {
+		profiles = profiles[:*limit]
+	}
+	result := &EvmProfileResult{
+		Start:     start,
+		Duration:  uint64(time.Since(start)),
+		Contracts: make([]ContractProfileResult, 0, len(profiles)),
+	}
+	for _, profile := range profiles {
+		contract := ContractProfileResult{
+			Address: profile.Address,
+			Total:   newOpProfileResult(profile.Total),
+			Ops:     make(map[string]OpProfileResult),
+		}
+		for op, stat := range profile.Ops {
+			contract.Ops[op.String()] = newOpProfileResult(stat)
+		}
+		result.Contracts = append(result.Contracts, contract)
+	}
+	return result, nil
+}
+
+// WriteEvmProfile writes the EVM profile gathered since the last reset to the
+// given file in the pprof format.
+func (api *PrivateDebugAPI) WriteEvmProfile(file string) error {
+	profiler := api.eth.blockchain.GetVMConfig().Profiler
+	if profiler == nil {
+		return errNoEVMProfiler
+	}
+	out, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	return profiler.WriteProfile(out)
+}
+
+// resolveBlockNumber converts an RPC block number into a canonical one.
+func (api *PrivateDebugAPI) resolveBlockNumber(number rpc.BlockNumber) (uint64, error) {
+	switch number {
+	case rpc.PendingBlockNumber:
+		return 0, errors.New("pending block not supported")
+	case rpc.LatestBlockNumber:
+		return api.eth.blockchain.CurrentBlock().NumberU64(), nil
+	default:
+		return uint64(number), nil
+	}
+}
@@ -163,6 +163,10 @@ func (b *EthApiBackend) SendTx(ctx context.Context, signedTx *types.Transaction)
 	return b.eth.txPool.AddLocal(signedTx)
 }
 
+func (b *EthApiBackend) SendRemoteTxs(ctx context.Context, txs types.Transactions) []error {
+	return b.eth.txPool.AddRemotes(txs)
+}
+
 func (b *EthApiBackend) GetPoolTransactions() (types.Transactions, error) {
 	pending, err := b.eth.txPool.Pending()
 	if err != nil {
@@ -195,6 +199,14 @@ func (b *EthApiBackend) SubscribeTxPreEvent(ch chan<- core.TxPreEvent) event.Sub
 	return b.eth.TxPool().SubscribeTxPreEvent(ch)
 }
 
+func (b *EthApiBackend) GetPoolLifecycle(hash common.Hash) (core.TxStatus, *core.TxDrop) {
+	return b.eth.txPool.Lifecycle(hash)
+}
+
+func (b *EthApiBackend) SubscribeTxDropEvent(ch chan<- core.TxDropEvent) event.Subscription {
+	return b.eth.TxPool().SubscribeTxDropEvent(ch)
+}
+
 func (b *EthApiBackend) Downloader() *downloader.Downloader {
 	return b.eth.Downloader()
 }
@@ -207,6 +219,10 @@ func (b *EthApiBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	return b.gpo.SuggestPrice(ctx)
 }
 
+func (b *EthApiBackend) FeeHistory(ctx context.Context, blocks uint64, lastBlock rpc.BlockNumber, percentiles []float64) (*big.Int, [][]*big.Int, []float64, error) {
+	return b.gpo.FeeHistory(ctx, blocks, lastBlock, percentiles)
+}
+
 func (b *EthApiBackend) ChainDb() ethdb.Database {
 	return b.eth.ChainDb()
 }
@@ -0,0 +1,279 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// newTestEthereum creates an Ethereum service backed by a chain importing the
+// given blocks, with just enough set up to serve the state related APIs.
+func newTestEthereum(t *testing.T, db ethdb.Database, gspec *core.Genesis, blocks []*types.Block) *Ethereum {
+	chain, err := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		chain.Stop()
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	eth := &Ethereum{chainConfig: gspec.Config, blockchain: chain, chainDb: db}
+	eth.ApiBackend = &EthApiBackend{eth, nil}
+	return eth
+}
+
+// Tests that bundles of signed and unsigned transactions are simulated in order
+// on top of the overridden state, reporting the changes of every transaction.
+func TestCallBundle(t *testing.T) {
+	var (
+		db, _    = ethdb.NewMemDatabase()
+		key, _   = crypto.GenerateKey()
+		address  = crypto.PubkeyToAddress(key.PublicKey)
+		sender   = common.Address{0xaa}
+		receiver = common.Address{0xbb}
+		contract = common.Address{0xcc}
+		slot     = common.Hash{}
+		gspec    = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				address: {Balance: big.NewInt(1000000000)},
+				// PUSH1 0 CALLDATALOAD DUP1 PUSH1 0 SSTORE PUSH1 0 MSTORE PUSH1 32 PUSH1 0 LOG0:
+				// stores the call data into slot 0 and logs it
+				contract: {Balance: new(big.Int), Code: common.FromHex("0x6000358060005560005260206000a000")},
+			},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.HomesteadSigner{}
+	)
+	eth := newTestEthereum(t, db, gspec, nil)
+	defer eth.blockchain.Stop()
+
+	api := ethapi.NewPublicBlockChainAPI(eth.ApiBackend)
+
+	tx, _ := types.SignTx(types.NewTransaction(0, contract, new(big.Int), 100000, big.NewInt(1), common.HexToHash("0x01").Bytes()), signer, key)
+	raw, _ := rlp.EncodeToBytes(tx)
+
+	txs := []ethapi.BundleTxArgs{
+		{Raw: raw},
+		{CallArgs: ethapi.CallArgs{From: sender, To: &contract, Data: common.HexToHash("0x02").Bytes()}},
+		{CallArgs: ethapi.CallArgs{From: sender, To: &receiver, Value: hexutil.Big(*big.NewInt(50))}},
+		{CallArgs: ethapi.CallArgs{From: sender, To: &receiver, Gas: 30000, GasPrice: hexutil.Big(*big.NewInt(1))}},
+	}
+	balance := hexutil.Big(*big.NewInt(100000))
+	overrides := &ethapi.StateOverride{sender: {Balance: &balance}}
+
+	result, err := api.CallBundle(context.Background(), txs, rpc.LatestBlockNumber, overrides)
+	if err != nil {
+		t.Fatalf("failed to simulate bundle: %v", err)
+	}
+	if result.BlockHash != genesis.Hash() {
+		t.Errorf("block hash mismatch: have %x, want %x", result.BlockHash, genesis.Hash())
+	}
+	if len(result.Results) != len(txs) {
+		t.Fatalf("result count mismatch: have %d, want %d", len(result.Results), len(txs))
+	}
+	var gas hexutil.Uint64
+	for i, res := range result.Results {
+		if res.Failed {
+			t.Errorf("transaction %d: execution failed", i)
+		}
+		gas += res.GasUsed
+	}
+	if result.GasUsed != gas {
+		t.Errorf("gas used mismatch: have %d, want %d", result.GasUsed, gas)
+	}
+	// The signed transaction pays for its gas and bumps the nonce of its sender
+	first := result.Results[0]
+	if first.TxHash == nil || *first.TxHash != tx.Hash() {
+		t.Errorf("transaction hash mismatch: have %v, want %x", first.TxHash, tx.Hash())
+	}
+	if len(first.Logs) != 1 || first.Logs[0].TxHash != tx.Hash() || common.BytesToHash(first.Logs[0].Data) != common.HexToHash("0x01") {
+		t.Errorf("signed transaction logs mismatch: have %v", first.Logs)
+	}
+	diff := accountDiff(first.StateDiff, address)
+	if diff == nil || diff.Nonce == nil || diff.Nonce.To != 1 || diff.Balance == nil {
+		t.Errorf("signed transaction sender diff mismatch: have %+v", diff)
+	}
+	// The unsigned call sees the changes of the signed transaction
+	second := result.Results[1]
+	if second.TxHash != nil {
+		t.Errorf("unsigned call has a transaction hash: %x", *second.TxHash)
+	}
+	if len(second.Logs) != 1 || common.BytesToHash(second.Logs[0].Data) != common.HexToHash("0x02") {
+		t.Errorf("unsigned call logs mismatch: have %v", second.Logs)
+	}
+	diff = accountDiff(second.StateDiff, contract)
+	if diff == nil || diff.Storage[slot].From != common.HexToHash("0x01") || diff.Storage[slot].To != common.HexToHash("0x02") {
+		t.Errorf("unsigned call storage diff mismatch: have %+v", diff)
+	}
+	if diff := accountDiff(second.StateDiff, sender); diff == nil || diff.Balance != nil || diff.Nonce == nil || diff.Nonce.To != 1 {
+		t.Errorf("unsigned call sender diff mismatch: have %+v", diff)
+	}
+	// The value transfer is funded by the overridden balance
+	third := result.Results[2]
+	if diff := accountDiff(third.StateDiff, sender); diff == nil || diff.Balance == nil || diff.Balance.From.ToInt().Int64() != 100000 || diff.Balance.To.ToInt().Int64() != 99950 {
+		t.Errorf("sender diff mismatch: have %+v", diff)
+	}
+	if diff := accountDiff(third.StateDiff, receiver); diff == nil || !diff.Exists || diff.Existed || diff.Balance.To.ToInt().Int64() != 50 {
+		t.Errorf("receiver diff mismatch: have %+v", diff)
+	}
+	// Calls with a gas price pay for their gas, as they aren't TRC21 transactions
+	fourth := result.Results[3]
+	if diff := accountDiff(fourth.StateDiff, sender); diff == nil || diff.Balance == nil || diff.Balance.To.ToInt().Int64() != 99950-int64(fourth.GasUsed) {
+		t.Errorf("fee paying sender diff mismatch: have %+v", diff)
+	}
+	// Simulations must not leak into the state of the chain
+	statedb, _ := eth.blockchain.State()
+	if nonce := statedb.GetNonce(address); nonce != 0 {
+		t.Errorf("chain state modified: nonce %d", nonce)
+	}
+	// Invalid transactions and overrides fail the whole bundle
+	if _, err := api.CallBundle(context.Background(), []ethapi.BundleTxArgs{{Raw: raw}, {Raw: raw}}, rpc.LatestBlockNumber, nil); err == nil {
+		t.Errorf("replayed transaction accepted")
+	}
+	if _, err := api.CallBundle(context.Background(), txs[2:], rpc.LatestBlockNumber, nil); err == nil {
+		t.Errorf("transfer from unfunded sender accepted")
+	}
+	if _, err := api.CallBundle(context.Background(), make([]ethapi.BundleTxArgs, 101), rpc.LatestBlockNumber, nil); err == nil {
+		t.Errorf("oversized bundle accepted")
+	}
+	overfull := []ethapi.BundleTxArgs{
+		{CallArgs: ethapi.CallArgs{From: sender, To: &receiver, Gas: hexutil.Uint64(genesis.GasLimit() + 1)}},
+	}
+	if _, err := api.CallBundle(context.Background(), overfull, rpc.LatestBlockNumber, nil); err == nil {
+		t.Errorf("bundle exceeding the block gas limit accepted")
+	}
+	storage := map[common.Hash]common.Hash{}
+	invalid := &ethapi.StateOverride{contract: {State: &storage, StateDiff: &storage}}
+	if _, err := api.CallBundle(context.Background(), txs, rpc.LatestBlockNumber, invalid); err == nil {
+		t.Errorf("override with both state and state diff accepted")
+	}
+}
+
+// accountDiff returns the change of the given account, if any.
+func accountDiff(diff []ethapi.AccountDiffResult, addr common.Address) *ethapi.AccountDiffResult {
+	for i := range diff {
+		if diff[i].Address == addr {
+			return &diff[i]
+		}
+	}
+	return nil
+}
+
+// Tests that calls and gas estimations are executed on top of the overridden
+// state, without the overrides leaking into the chain.
+func TestCallStateOverride(t *testing.T) {
+	var (
+		db, _   = ethdb.NewMemDatabase()
+		sender  = common.Address{0xaa}
+		reader  = common.Address{0xcc}
+		guarded = common.Address{0xdd}
+		proxy   = common.Address{0xee}
+		slot    = common.Hash{}
+		// PUSH1 0 SLOAD PUSH1 0 MSTORE CALLER BALANCE PUSH1 32 MSTORE PUSH1 64 PUSH1 0 RETURN:
+		// returns slot 0 and the balance of the caller
+		readerCode = common.FromHex("0x600054600052333160205260406000f3")
+		gspec      = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				reader: {Balance: new(big.Int), Code: readerCode, Storage: map[common.Hash]common.Hash{slot: common.HexToHash("0x07")}},
+				// PUSH1 0 SLOAD PUSH1 10 JUMPI PUSH1 0 DUP1 REVERT JUMPDEST STOP: reverts unless slot 0 is set
+				guarded: {Balance: new(big.Int), Code: common.FromHex("0x600054600a57600080fd5b00")},
+			},
+		}
+	)
+	gspec.MustCommit(db)
+	eth := newTestEthereum(t, db, gspec, nil)
+	defer eth.blockchain.Stop()
+
+	api := ethapi.NewPublicBlockChainAPI(eth.ApiBackend)
+
+	balance := hexutil.Big(*big.NewInt(5))
+	code := hexutil.Bytes(readerCode)
+	tests := []struct {
+		to        common.Address
+		overrides *ethapi.StateOverride
+		slot      common.Hash
+		balance   *big.Int
+	}{
+		// Without overrides, the sender is funded by eth_call
+		{reader, nil, common.HexToHash("0x07"), math.MaxBig256},
+		// Overriding single slots and the balance of the sender
+		{reader, &ethapi.StateOverride{
+			reader: {StateDiff: &map[common.Hash]common.Hash{slot: common.HexToHash("0x09")}},
+			sender: {Balance: &balance},
+		}, common.HexToHash("0x09"), big.NewInt(5)},
+		// Replacing the whole storage of the contract
+		{reader, &ethapi.StateOverride{
+			reader: {State: &map[common.Hash]common.Hash{}},
+		}, common.Hash{}, math.MaxBig256},
+		// Deploying code to an empty account
+		{proxy, &ethapi.StateOverride{
+			proxy: {Code: &code, StateDiff: &map[common.Hash]common.Hash{slot: common.HexToHash("0x0a")}},
+		}, common.HexToHash("0x0a"), math.MaxBig256},
+	}
+	for i, tt := range tests {
+		to := tt.to
+		res, err := api.Call(context.Background(), ethapi.CallArgs{From: sender, To: &to}, rpc.LatestBlockNumber, tt.overrides)
+		if err != nil {
+			t.Fatalf("test %d: failed to execute call: %v", i, err)
+		}
+		if len(res) != 64 {
+			t.Fatalf("test %d: result length mismatch: have %d, want 64", i, len(res))
+		}
+		if have := common.BytesToHash(res[:32]); have != tt.slot {
+			t.Errorf("test %d: slot mismatch: have %x, want %x", i, have, tt.slot)
+		}
+		if have := new(big.Int).SetBytes(res[32:]); have.Cmp(tt.balance) != 0 {
+			t.Errorf("test %d: balance mismatch: have %v, want %v", i, have, tt.balance)
+		}
+	}
+	// Gas estimations run on top of the overrides too
+	if _, err := api.EstimateGas(context.Background(), ethapi.CallArgs{From: sender, To: &guarded}, nil); err == nil {
+		t.Errorf("always failing call estimated")
+	}
+	overrides := &ethapi.StateOverride{guarded: {StateDiff: &map[common.Hash]common.Hash{slot: common.HexToHash("0x01")}}}
+	if gas, err := api.EstimateGas(context.Background(), ethapi.CallArgs{From: sender, To: &guarded}, overrides); err != nil {
+		t.Errorf("failed to estimate gas: %v", err)
+	} else if gas <= hexutil.Uint64(params.TxGas) {
+		t.Errorf("gas estimation too low: have %d, want above %d", gas, params.TxGas)
+	}
+	// None of the overrides must leak into the chain
+	statedb, _ := eth.blockchain.State()
+	if value := statedb.GetState(reader, slot); value != common.HexToHash("0x07") {
+		t.Errorf("chain state modified: have %x, want %x", value, common.HexToHash("0x07"))
+	}
+	if statedb.GetCodeSize(proxy) != 0 {
+		t.Errorf("chain code modified")
+	}
+}
@@ -69,18 +69,20 @@ type txTraceResult struct {
 // blockTraceTask represents a single block trace task when an entire chain is
 // being traced.
 type blockTraceTask struct {
-	statedb *state.StateDB   // Intermediate state prepped for tracing
-	block   *types.Block     // Block to trace the transactions from
-	rootref common.Hash      // Trie root reference held for this task
-	results []*txTraceResult // Trace results procudes by the task
+	statedb  *state.StateDB   // Intermediate state prepped for tracing
+	block    *types.Block     // Block to trace the transactions from
+	rootref  common.Hash      // Trie root reference held for this task
+	results  []*txTraceResult // Trace results procudes by the task
+	finalize *txTraceResult   // Trace of the block level changes, if traced
 }
 
 // blockTraceResult represets the results of tracing a single block when an entire
 // chain is being traced.
 type blockTraceResult struct {
-	Block  hexutil.Uint64   `json:"block"`  // Block number corresponding to this trace
-	Hash   common.Hash      `json:"hash"`   // Block hash corresponding to this trace
-	Traces []*txTraceResult `json:"traces"` // Trace results produced by the task
+	Block    hexutil.Uint64   `json:"block"`              // Block number corresponding to this trace
+	Hash     common.Hash      `json:"hash"`               // Block hash corresponding to this trace
+	Traces   []*txTraceResult `json:"traces"`             // Trace results produced by the task
+	Finalize *txTraceResult   `json:"finalize,omitempty"` // Trace of the block level changes made after the transactions
 }
 
 // txTraceTask represents a single transaction trace task when an entire block
@@ -124,7 +126,9 @@ func (api *PrivateDebugAPI) TraceChain(ctx context.Context, start, end rpc.Block
 
 // traceChain configures a new tracer according to the provided configuration, and
 // executes all the transactions contained within. The return value will be one item
-// per transaction, dependent on the requestd tracer.
+// per transaction, dependent on the requestd tracer. Native structured tracers also
+// trace the TRC21 fee settlement and the rewards of every block, reported apart
+// from the transactions.
 func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Block, config *TraceConfig) (*rpc.Subscription, error) {
 	// Tracing a chain is a **long** operation, only do with subscriptions
 	notifier, supported := rpc.NotifierFromContext(ctx)
@@ -269,24 +273,34 @@ func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Bl
 				failed = fmt.Errorf("block #%d not found", number)
 				break
 			}
-			// Send the block over to the concurrent tracers (if not in the fast-forward phase)
+			// Prepare the block for the concurrent tracers (if not in the fast-forward phase)
+			var (
+				task   *blockTraceTask
+				tracer tracers.ResultTracer
+			)
 			if number > origin {
-				txs := block.Transactions()
-
-				select {
-				case tasks <- &blockTraceTask{statedb: statedb.Copy(), block: block, rootref: proot, results: make([]*txTraceResult, len(txs))}:
-				case <-notifier.Closed():
-					return
-				}
-				traced += uint64(len(txs))
+				task = &blockTraceTask{statedb: statedb.Copy(), block: block, rootref: proot, results: make([]*txTraceResult, len(block.Transactions()))}
+				tracer = newFinalizeTracer(config)
 			}
 			feeCapacity := state.GetTRC21FeeCapacityFromState(statedb)
-			// Generate the next state snapshot fast without tracing
-			_, _, _, err := api.eth.blockchain.Processor().Process(block, statedb, vm.Config{}, feeCapacity)
+			// Generate the next state snapshot fast without tracing the transactions
+			_, _, _, err := api.eth.blockchain.Processor().Process(block, statedb, vm.Config{Tracer: tracer}, feeCapacity)
 			if err != nil {
 				failed = err
 				break
 			}
+			// Send the block over to the concurrent tracers
+			if task != nil {
+				if tracer != nil {
+					task.finalize = finalizeResult(tracer)
+				}
+				select {
+				case tasks <- task:
+				case <-notifier.Closed():
+					return
+				}
+				traced += uint64(block.Transactions().Len())
+			}
 			// Finalize the state so any modifications are written to the trie
 			root, err := statedb.Commit(true)
 			if err != nil {
@@ -317,9 +331,10 @@ func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Bl
 		for res := range results {
 			// Queue up next received result
 			result := &blockTraceResult{
-				Block:  hexutil.Uint64(res.block.NumberU64()),
-				Hash:   res.block.Hash(),
-				Traces: res.results,
+				Block:    hexutil.Uint64(res.block.NumberU64()),
+				Hash:     res.block.Hash(),
+				Traces:   res.results,
+				Finalize: res.finalize,
 			}
 			done[uint64(result.Block)] = result
 
@@ -484,6 +499,43 @@ func (api *PrivateDebugAPI) traceBlock(ctx context.Context, block *types.Block,
 	return results, nil
 }
 
+// TraceBlockFinalize returns the trace of the block level changes made after the
+// transactions of a block, the TRC21 fee settlement and the rewards. Only native
+// structured tracers are supported.
+func (api *PrivateDebugAPI) TraceBlockFinalize(ctx context.Context, hash common.Hash, config *TraceConfig) (*txTraceResult, error) {
+	block := api.eth.blockchain.GetBlockByHash(hash)
+	if block == nil {
+		return nil, fmt.Errorf("block #%x not found", hash)
+	}
+	return api.traceBlockFinalize(ctx, block, config)
+}
+
+// traceBlockFinalize processes a block on top of its parent state, tracing only the
+// changes made after its transactions with the configured native structured tracer.
+func (api *PrivateDebugAPI) traceBlockFinalize(ctx context.Context, block *types.Block, config *TraceConfig) (*txTraceResult, error) {
+	tracer := newFinalizeTracer(config)
+	if tracer == nil {
+		return nil, errors.New("block level changes require a native structured tracer")
+	}
+	parent := api.eth.blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent %x not found", block.ParentHash())
+	}
+	reexec := defaultTraceReexec
+	if config != nil && config.Reexec != nil {
+		reexec = *config.Reexec
+	}
+	statedb, err := api.computeStateDB(parent, reexec)
+	if err != nil {
+		return nil, err
+	}
+	feeCapacity := state.GetTRC21FeeCapacityFromState(statedb)
+	if _, _, _, err := api.eth.blockchain.Processor().Process(block, statedb, vm.Config{Tracer: tracer}, feeCapacity); err != nil {
+		return nil, err
+	}
+	return finalizeResult(tracer), nil
+}
+
 // computeStateDB retrieves the state database associated with a certain block.
 // If no state is locally available for the given block, a number of blocks are
 // attempted to be reexecuted to generate the desired state.
@@ -571,50 +623,109 @@ func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, hash common.Ha
 	return api.traceTx(ctx, msg, vmctx, statedb, config)
 }
 
+// TraceCall lets you trace a given eth_call on top of the state of the given
+// block. The call is executed through the same machinery as eth_call, so the
+// sender, gas and TRC21 fee defaults are identical. The return value will be
+// tracer dependent.
+func (api *PrivateDebugAPI) TraceCall(ctx context.Context, args ethapi.CallArgs, blockNr rpc.BlockNumber, config *TraceConfig) (interface{}, error) {
+	tracer, timeout, cancel, err := api.newTracer(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	ret, gas, failed, err := ethapi.DoCall(ctx, api.eth.ApiBackend, args, blockNr, nil, vm.Config{Debug: true, Tracer: tracer}, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("tracing failed: %v", err)
+	}
+	return traceResult(tracer, ret, gas, failed)
+}
+
 // traceTx configures a new tracer according to the provided configuration, and
 // executes the given message in the provided environment. The return value will
 // be tracer dependent.
 func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, vmctx vm.Context, statedb *state.StateDB, config *TraceConfig) (interface{}, error) {
-	// Assemble the structured logger or the JavaScript tracer
-	var (
-		tracer vm.Tracer
-		err    error
-	)
+	tracer, _, cancel, err := api.newTracer(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	// Run the transaction with tracing enabled.
+	vmenv := vm.NewEVM(vmctx, statedb, api.config, vm.Config{Debug: true, Tracer: tracer})
+
+	ret, gas, failed, err := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.Gas()))
+	if err != nil {
+		return nil, fmt.Errorf("tracing failed: %v", err)
+	}
+	return traceResult(tracer, ret, gas, failed)
+}
+
+// newTracer assembles the structured logger, the native or the JavaScript tracer
+// requested by the configuration. It returns the timeout of a single trace along
+// with a function releasing the resources held for aborting custom tracers.
+func (api *PrivateDebugAPI) newTracer(ctx context.Context, config *TraceConfig) (vm.Tracer, time.Duration, func(), error) {
+	// Define a meaningful timeout of a single transaction trace
+	timeout := defaultTraceTimeout
+	if config != nil && config.Timeout != nil {
+		var err error
+		if timeout, err = time.ParseDuration(*config.Timeout); err != nil {
+			return nil, 0, nil, err
+		}
+	}
 	switch {
 	case config != nil && config.Tracer != nil:
-		// Define a meaningful timeout of a single transaction trace
-		timeout := defaultTraceTimeout
-		if config.Timeout != nil {
-			if timeout, err = time.ParseDuration(*config.Timeout); err != nil {
-				return nil, err
-			}
-		}
-		// Constuct the JavaScript tracer to execute with
-		if tracer, err = tracers.New(*config.Tracer); err != nil {
-			return nil, err
+		// Constuct the native or JavaScript tracer to execute with
+		traced, err := tracers.NewTracer(*config.Tracer)
+		if err != nil {
+			return nil, 0, nil, err
 		}
 		// Handle timeouts and RPC cancellations
 		deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
 		go func() {
 			<-deadlineCtx.Done()
-			tracer.(*tracers.Tracer).Stop(errors.New("execution timeout"))
+			traced.Stop(errors.New("execution timeout"))
 		}()
-		defer cancel()
+		return traced, timeout, cancel, nil
 
 	case config == nil:
-		tracer = vm.NewStructLogger(nil)
+		return vm.NewStructLogger(nil), timeout, func() {}, nil
 
 	default:
-		tracer = vm.NewStructLogger(config.LogConfig)
+		return vm.NewStructLogger(config.LogConfig), timeout, func() {}, nil
 	}
-	// Run the transaction with tracing enabled.
-	vmenv := vm.NewEVM(vmctx, statedb, api.config, vm.Config{Debug: true, Tracer: tracer})
+}
 
-	ret, gas, failed, err := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.Gas()))
+// newFinalizeTracer creates the tracer of the block level changes made after the
+// transactions of a block, the TRC21 fee settlement and the rewards, if the
+// configured tracer is a native structured one. It returns nil otherwise.
+func newFinalizeTracer(config *TraceConfig) tracers.ResultTracer {
+	if config == nil || config.Tracer == nil {
+		return nil
+	}
+	tracer, ok := tracers.NewNative(*config.Tracer)
+	if !ok {
+		return nil
+	}
+	if _, ok := tracer.(vm.StructuredTracer); !ok {
+		return nil
+	}
+	return tracer
+}
+
+// finalizeResult formats the output of a finished trace of the block level
+// changes, which is reported apart from the results of the transactions.
+func finalizeResult(tracer tracers.ResultTracer) *txTraceResult {
+	res, err := tracer.GetResult()
 	if err != nil {
-		return nil, fmt.Errorf("tracing failed: %v", err)
+		return &txTraceResult{Error: err.Error()}
 	}
-	// Depending on the tracer type, format and return the output
+	return &txTraceResult{Result: res}
+}
+
+// traceResult formats the output of a finished trace depending on the type of
+// the tracer that collected it.
+func traceResult(tracer vm.Tracer, ret []byte, gas uint64, failed bool) (interface{}, error) {
 	switch tracer := tracer.(type) {
 	case *vm.StructLogger:
 		return &ethapi.ExecutionResult{
@@ -624,7 +735,7 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 			StructLogs:  ethapi.FormatLogs(tracer.StructLogs()),
 		}, nil
 
-	case *tracers.Tracer:
+	case tracers.ResultTracer:
 		return tracer.GetResult()
 
 	default:
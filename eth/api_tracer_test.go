@@ -0,0 +1,183 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Tests that calls can be traced on top of the state of arbitrary blocks with
+// both the structured logger and the custom tracers.
+func TestTraceCall(t *testing.T) {
+	var (
+		db, _    = ethdb.NewMemDatabase()
+		key, _   = crypto.GenerateKey()
+		address  = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.Address{0xcc}
+		slot     = common.Hash{}
+		gspec    = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				address: {Balance: big.NewInt(1000000000)},
+				// PUSH1 0 CALLDATALOAD PUSH1 0 SSTORE: stores the call data into slot 0
+				contract: {Balance: new(big.Int), Code: common.FromHex("0x60003560005500"), Storage: map[common.Hash]common.Hash{slot: common.HexToHash("0x07")}},
+			},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.HomesteadSigner{}
+	)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 1, func(i int, block *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(address), contract, new(big.Int), 100000, new(big.Int), common.HexToHash("0x01").Bytes()), signer, key)
+		block.AddTx(tx)
+	})
+	eth := newTestEthereum(t, db, gspec, blocks)
+	defer eth.blockchain.Stop()
+
+	api := NewPrivateDebugAPI(gspec.Config, eth)
+
+	args := ethapi.CallArgs{
+		From: address,
+		To:   &contract,
+		Data: common.HexToHash("0x02").Bytes(),
+	}
+	// Trace the call with the structured logger and check the executed opcodes
+	res, err := api.TraceCall(context.Background(), args, rpc.LatestBlockNumber, nil)
+	if err != nil {
+		t.Fatalf("failed to trace call: %v", err)
+	}
+	result, ok := res.(*ethapi.ExecutionResult)
+	if !ok {
+		t.Fatalf("result type mismatch: have %T, want *ethapi.ExecutionResult", res)
+	}
+	if result.Failed {
+		t.Errorf("call failed")
+	}
+	ops := []string{"PUSH1", "CALLDATALOAD", "PUSH1", "SSTORE", "STOP"}
+	if len(result.StructLogs) != len(ops) {
+		t.Fatalf("struct log count mismatch: have %d, want %d", len(result.StructLogs), len(ops))
+	}
+	for i, log := range result.StructLogs {
+		if log.Op != ops[i] {
+			t.Errorf("struct log %d: op mismatch: have %s, want %s", i, log.Op, ops[i])
+		}
+	}
+	// Trace the call with a custom tracer on top of the various blocks and ensure
+	// the storage slot is overwritten from the value at the requested block
+	tracer := "balanceTracer"
+	for number, prev := range map[rpc.BlockNumber]common.Hash{
+		0:                     common.HexToHash("0x07"),
+		1:                     common.HexToHash("0x01"),
+		rpc.LatestBlockNumber: common.HexToHash("0x01"),
+	} {
+		res, err := api.TraceCall(context.Background(), args, number, &TraceConfig{Tracer: &tracer})
+		if err != nil {
+			t.Fatalf("block %d: failed to trace call: %v", number, err)
+		}
+		var changes struct {
+			Storage []struct {
+				Address common.Address `json:"address"`
+				From    common.Hash    `json:"from"`
+				To      common.Hash    `json:"to"`
+			} `json:"storage"`
+		}
+		if err := json.Unmarshal(res.(json.RawMessage), &changes); err != nil {
+			t.Fatalf("block %d: failed to unmarshal trace: %v", number, err)
+		}
+		if len(changes.Storage) != 1 {
+			t.Fatalf("block %d: storage change count mismatch: have %d, want 1", number, len(changes.Storage))
+		}
+		if change := changes.Storage[0]; change.Address != contract || change.From != prev || change.To != common.HexToHash("0x02") {
+			t.Errorf("block %d: storage change mismatch: have %x %x -> %x, want %x %x -> %x", number, change.Address, change.From, change.To, contract, prev, common.HexToHash("0x02"))
+		}
+	}
+	// Tracing must not leak into the state of the chain
+	statedb, _ := eth.blockchain.State()
+	if value := statedb.GetState(contract, slot); value != common.HexToHash("0x01") {
+		t.Errorf("chain state modified: have %x, want %x", value, common.HexToHash("0x01"))
+	}
+	// Unknown tracers should be rejected before executing anything
+	unknown := "unknownTracer"
+	if _, err := api.TraceCall(context.Background(), args, rpc.LatestBlockNumber, &TraceConfig{Tracer: &unknown}); err == nil {
+		t.Errorf("unknown tracer accepted")
+	}
+}
+
+// Tests that block traces map one to one to the transactions of the block, the
+// block level changes being traced by a separate method.
+func TestTraceBlockFinalize(t *testing.T) {
+	var (
+		db, _   = ethdb.NewMemDatabase()
+		key, _  = crypto.GenerateKey()
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{address: {Balance: big.NewInt(1000000000)}},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.HomesteadSigner{}
+	)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 1, func(i int, block *core.BlockGen) {
+		for j := 0; j < 2; j++ {
+			tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{0xbb}, big.NewInt(1), 21000, new(big.Int), nil), signer, key)
+			block.AddTx(tx)
+		}
+	})
+	eth := newTestEthereum(t, db, gspec, blocks)
+	defer eth.blockchain.Stop()
+
+	api := NewPrivateDebugAPI(gspec.Config, eth)
+
+	tracer := "balanceTracer"
+	config := &TraceConfig{Tracer: &tracer}
+
+	results, err := api.TraceBlockByHash(context.Background(), blocks[0].Hash(), config)
+	if err != nil {
+		t.Fatalf("failed to trace block: %v", err)
+	}
+	if len(results) != len(blocks[0].Transactions()) {
+		t.Fatalf("trace count mismatch: have %d, want %d", len(results), len(blocks[0].Transactions()))
+	}
+	for i, res := range results {
+		if res.Error != "" {
+			t.Errorf("transaction %d: trace failed: %v", i, res.Error)
+		}
+	}
+	res, err := api.TraceBlockFinalize(context.Background(), blocks[0].Hash(), config)
+	if err != nil {
+		t.Fatalf("failed to trace block level changes: %v", err)
+	}
+	if res.Error != "" || res.Result == nil {
+		t.Errorf("block level trace mismatch: have %+v", res)
+	}
+	// The block level changes can only be traced by native structured tracers
+	if _, err := api.TraceBlockFinalize(context.Background(), blocks[0].Hash(), nil); err == nil {
+		t.Errorf("block level changes traced without a native structured tracer")
+	}
+}
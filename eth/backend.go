@@ -44,6 +44,7 @@ import (
 	contractValidator "github.com/ethereum/go-ethereum/contracts/validator/contract"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
+	"github.com/ethereum/go-ethereum/core/state/pruner"
 
 	//"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -93,6 +94,8 @@ type Ethereum struct {
 	bloomRequests chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
 	bloomIndexer  *core.ChainIndexer             // Bloom indexer operating during block imports
 
+	storageIndexer *core.ChainIndexer // Storage history indexer, only running if state diffs are recorded
+
 	ApiBackend *EthApiBackend
 
 	miner     *miner.Miner
@@ -102,6 +105,11 @@ type Ethereum struct {
 	networkId     uint64
 	netRPCService *ethapi.PublicNetAPI
 
+	datadir   string         // Data directory to persist the state pruning progress into
+	pruneStop chan struct{}  // Quit channel of the background state pruning, nil if not running
+	pruneWg   sync.WaitGroup // Wait group of the background state pruning
+	pruneLock sync.Mutex     // Protects the background state pruning
+
 	lock sync.RWMutex // Protects the variadic fields (e.g. gas price and etherbase)
 }
 
@@ -144,6 +152,7 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 		etherbase:      config.Etherbase,
 		bloomRequests:  make(chan chan *bloombits.Retrieval),
 		bloomIndexer:   NewBloomIndexer(chainDb, params.BloomBitsBlocks),
+		datadir:        ctx.ResolvePath(""),
 	}
 
 	log.Info("Initialising Ethereum protocol", "versions", ProtocolVersions, "network", config.NetworkId)
@@ -157,8 +166,11 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 	}
 	var (
 		vmConfig    = vm.Config{EnablePreimageRecording: config.EnablePreimageRecording}
-		cacheConfig = &core.CacheConfig{Disabled: config.NoPruning, TrieNodeLimit: config.TrieCache, TrieTimeLimit: config.TrieTimeout}
+		cacheConfig = &core.CacheConfig{Disabled: config.NoPruning, TrieNodeLimit: config.TrieCache, TrieTimeLimit: config.TrieTimeout, AncientThreshold: config.AncientThreshold, SnapshotLimit: config.SnapshotCache, StateDiffs: config.StateDiffs, StateDiffsRetain: config.StateDiffsRetain}
 	)
+	if config.EVMProfile {
+		vmConfig.Profiler = vm.NewProfiler()
+	}
 	eth.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, eth.chainConfig, eth.engine, vmConfig)
 	if err != nil {
 		return nil, err
@@ -170,10 +182,17 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 		core.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
 	eth.bloomIndexer.Start(eth.blockchain)
+	if config.StateDiffs {
+		eth.storageIndexer = NewStorageHistoryIndexer(chainDb, storageHistorySectionSize)
+		eth.storageIndexer.Start(eth.blockchain)
+	}
 
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = ctx.ResolvePath(config.TxPool.Journal)
 	}
+	if config.TxPool.RemoteJournal != "" {
+		config.TxPool.RemoteJournal = ctx.ResolvePath(config.TxPool.RemoteJournal)
+	}
 	eth.txPool = core.NewTxPool(config.TxPool, eth.chainConfig, eth.blockchain)
 
 	if common.RollbackHash != common.HexToHash("0x0000000000000000000000000000000000000000000000000000000000000000") {
@@ -219,8 +238,14 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 				log.Error("Cannot get etherbase for append m2 header", "err", err)
 				return fmt.Errorf("etherbase missing: %v", err)
 			}
-			ok := eth.txPool.IsSigner != nil && eth.txPool.IsSigner(eb)
-			if !ok {
+			if eth.txPool.Signers == nil {
+				return nil
+			}
+			signers, err := eth.txPool.Signers()
+			if err != nil {
+				return nil
+			}
+			if _, ok := signers[eb]; !ok {
 				return nil
 			}
 			if block.NumberU64()%common.MergeSignRange == 0 || !eth.chainConfig.IsTIP2019(block.Number()) {
@@ -553,7 +578,7 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 			return nil
 		}
 
-		eth.txPool.IsSigner = func(address common.Address) bool {
+		eth.txPool.Signers = func() (map[common.Address]struct{}, error) {
 			currentHeader := eth.blockchain.CurrentHeader()
 			header := currentHeader
 			// Sometimes, the latest block hasn't been inserted to chain yet
@@ -566,12 +591,9 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 			snap, err := c.GetSnapshot(eth.blockchain, header)
 			if err != nil {
 				log.Error("Can't get snapshot with at ", "number", header.Number, "hash", header.Hash().Hex(), "err", err)
-				return false
-			}
-			if _, ok := snap.Signers[address]; ok {
-				return true
+				return nil, err
 			}
-			return false
+			return snap.Signers, nil
 		}
 	}
 	return eth, nil
@@ -596,11 +618,19 @@ func makeExtraData(extra []byte) []byte {
 
 // CreateDB creates the chain database.
 func CreateDB(ctx *node.ServiceContext, config *Config, name string) (ethdb.Database, error) {
-	db, err := ctx.OpenDatabase(name, config.DatabaseCache, config.DatabaseHandles)
+	var (
+		db  ethdb.Database
+		err error
+	)
+	if config.SyncMode == downloader.LightSync {
+		db, err = ctx.OpenDatabase(name, config.DatabaseCache, config.DatabaseHandles)
+	} else {
+		db, err = ctx.OpenDatabaseWithFreezer(name, config.DatabaseCache, config.DatabaseHandles, config.DatabaseFreezer, core.FreezerTables)
+	}
 	if err != nil {
 		return nil, err
 	}
-	if db, ok := db.(*ethdb.LDBDatabase); ok {
+	if db, ok := db.(interface{ Meter(prefix string) }); ok {
 		db.Meter("eth/db/chaindata/")
 	}
 	return db, nil
@@ -818,6 +848,72 @@ func (s *Ethereum) EthVersion() int                    { return int(s.protocolMa
 func (s *Ethereum) NetVersion() uint64                 { return s.networkId }
 func (s *Ethereum) Downloader() *downloader.Downloader { return s.protocolManager.downloader }
 
+// PruneState starts deleting the stale state trie nodes from the chain database
+// in the background, retaining the state of the given number of recent blocks.
+// An interrupted pruning is resumed on the next call, or by the offline pruner.
+func (s *Ethereum) PruneState(retain uint64, bloomSize uint64) error {
+	s.pruneLock.Lock()
+	defer s.pruneLock.Unlock()
+
+	if s.pruneStop != nil {
+		return errors.New("state pruning already running")
+	}
+	if s.datadir == "" {
+		return errors.New("state pruning requires a data directory")
+	}
+	// The state downloaded by fast sync bypasses the trie database
+	if atomic.LoadUint32(&s.protocolManager.fastSync) == 1 {
+		return errors.New("state pruning unavailable during fast sync")
+	}
+	if retain == 0 {
+		retain = pruner.DefaultRetain(s.chainConfig)
+	}
+	prune, err := pruner.NewOnlinePruner(s.chainDb, s.blockchain.StateCache().TrieDB(), pruner.Config{
+		Datadir:   s.datadir,
+		BloomSize: bloomSize,
+		Retain:    retain,
+	})
+	if err != nil {
+		return err
+	}
+	stop := make(chan struct{})
+	s.pruneStop = stop
+
+	s.pruneWg.Add(1)
+	go func() {
+		defer s.pruneWg.Done()
+
+		start := time.Now()
+		switch err := prune.Prune(stop); err {
+		case nil:
+			log.Info("State pruning done", "elapsed", common.PrettyDuration(time.Since(start)))
+		case pruner.ErrInterrupted:
+			log.Warn("State pruning interrupted, restart it to resume")
+		default:
+			log.Error("State pruning failed", "err", err)
+		}
+		s.pruneLock.Lock()
+		if s.pruneStop == stop {
+			s.pruneStop = nil
+		}
+		s.pruneLock.Unlock()
+	}()
+	return nil
+}
+
+// stopPruning interrupts the background state pruning if running, waiting for
+// it to persist its progress.
+func (s *Ethereum) stopPruning() {
+	s.pruneLock.Lock()
+	if s.pruneStop != nil {
+		close(s.pruneStop)
+		s.pruneStop = nil
+	}
+	s.pruneLock.Unlock()
+
+	s.pruneWg.Wait()
+}
+
 // Protocols implements node.Service, returning all the currently configured
 // network protocols to start.
 func (s *Ethereum) Protocols() []p2p.Protocol {
@@ -858,7 +954,11 @@ func (s *Ethereum) Stop() error {
 	if s.stopDbUpgrade != nil {
 		s.stopDbUpgrade()
 	}
+	s.stopPruning()
 	s.bloomIndexer.Close()
+	if s.storageIndexer != nil {
+		s.storageIndexer.Close()
+	}
 	s.blockchain.Stop()
 	s.protocolManager.Stop()
 	if s.lesServer != nil {
@@ -50,6 +50,8 @@ var DefaultConfig = Config{
 	TrieTimeout:   5 * time.Minute,
 	GasPrice:      big.NewInt(0.25 * params.Shannon),
 
+	StateDiffsRetain: 90000,
+
 	TxPool: core.DefaultTxPoolConfig,
 	GPO: gasprice.Config{
 		Blocks:     20,
@@ -93,6 +95,11 @@ type Config struct {
 	DatabaseCache      int
 	TrieCache          int
 	TrieTimeout        time.Duration
+	DatabaseFreezer    string
+	AncientThreshold   uint64
+	SnapshotCache      int
+	StateDiffs         bool
+	StateDiffsRetain   uint64
 
 	// Mining-related options
 	Etherbase    common.Address `toml:",omitempty"`
@@ -112,6 +119,9 @@ type Config struct {
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
+	// Enables profiling the time and gas spent in the VM per contract
+	EVMProfile bool
+
 	// Miscellaneous options
 	DocRoot string `toml:"-"`
 }
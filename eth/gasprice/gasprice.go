@@ -18,19 +18,39 @@ package gasprice
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"math/big"
 	"sort"
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
 var maxPrice = big.NewInt(500 * params.Shannon)
 
+// maxFeeHistory is the maximum number of blocks a fee history can span.
+const maxFeeHistory = 1024
+
+var (
+	errInvalidPercentile = errors.New("invalid reward percentile")
+	errRequestBeyondHead = errors.New("request beyond head block")
+	errStateUnavailable  = errors.New("state unavailable")
+)
+
+// OracleBackend includes all the chain access needed by the oracle.
+type OracleBackend interface {
+	HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error)
+	BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error)
+	StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error)
+	GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error)
+	ChainConfig() *params.ChainConfig
+}
+
 type Config struct {
 	Blocks     int
 	Percentile int
@@ -40,7 +60,7 @@ type Config struct {
 // Oracle recommends gas prices based on the content of recent
 // blocks. Suitable for both light and full clients.
 type Oracle struct {
-	backend   ethapi.Backend
+	backend   OracleBackend
 	lastHead  common.Hash
 	lastPrice *big.Int
 	cacheLock sync.RWMutex
@@ -51,7 +71,7 @@ type Oracle struct {
 }
 
 // NewOracle returns a new oracle.
-func NewOracle(backend ethapi.Backend, params Config) *Oracle {
+func NewOracle(backend OracleBackend, params Config) *Oracle {
 	blocks := params.Blocks
 	if blocks < 1 {
 		blocks = 1
@@ -63,9 +83,14 @@ func NewOracle(backend ethapi.Backend, params Config) *Oracle {
 	if percent > 100 {
 		percent = 100
 	}
+	// Never suggest less than the minimum gas price accepted by the pool
+	price := params.Default
+	if price == nil || price.Cmp(common.MinGasPrice) < 0 {
+		price = new(big.Int).Set(common.MinGasPrice)
+	}
 	return &Oracle{
 		backend:     backend,
-		lastPrice:   params.Default,
+		lastPrice:   price,
 		checkBlocks: blocks,
 		maxEmpty:    blocks / 2,
 		maxBlocks:   blocks * 5,
@@ -112,6 +137,10 @@ func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	maxEmpty := gpo.maxEmpty
 	for exp > 0 {
 		res := <-ch
+		if res.err == errStateUnavailable {
+			// The sponsored transactions cannot be told apart, keep the last price
+			return lastPrice, nil
+		}
 		if res.err != nil {
 			return lastPrice, res.err
 		}
@@ -165,13 +194,19 @@ func (t transactionsByGasPrice) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
 func (t transactionsByGasPrice) Less(i, j int) bool { return t[i].GasPrice().Cmp(t[j].GasPrice()) < 0 }
 
 // getBlockPrices calculates the lowest transaction gas price in a given block
-// and sends it to the result channel. If the block is empty, price is nil.
+// and sends it to the result channel. If the block has no transaction paying a
+// market price, price is nil.
 func (gpo *Oracle) getBlockPrices(ctx context.Context, signer types.Signer, blockNum uint64, ch chan getBlockPricesResult) {
 	block, err := gpo.backend.BlockByNumber(ctx, rpc.BlockNumber(blockNum))
 	if block == nil {
 		ch <- getBlockPricesResult{nil, err}
 		return
 	}
+	tokens, err := gpo.sponsoredTokens(ctx, block)
+	if err != nil {
+		ch <- getBlockPricesResult{nil, err}
+		return
+	}
 
 	blockTxs := block.Transactions()
 	txs := make([]*types.Transaction, len(blockTxs))
@@ -179,6 +214,9 @@ func (gpo *Oracle) getBlockPrices(ctx context.Context, signer types.Signer, bloc
 	sort.Sort(transactionsByGasPrice(txs))
 
 	for _, tx := range txs {
+		if !eligible(tx, tokens) {
+			continue
+		}
 		sender, err := types.Sender(signer, tx)
 		if err == nil && sender != block.Coinbase() {
 			ch <- getBlockPricesResult{tx.GasPrice(), nil}
@@ -188,6 +226,179 @@ func (gpo *Oracle) getBlockPrices(ctx context.Context, signer types.Signer, bloc
 	ch <- getBlockPricesResult{nil, nil}
 }
 
+// sponsoredTokens returns the fee capacities of the TRC21 tokens registered in
+// the state the block was applied on, which decide the transactions of the block
+// having their fees sponsored by the token issuers. Every token is a separate
+// state read, retrieved from the network by light clients, so the state is only
+// consulted if the block contains transactions calling a contract.
+func (gpo *Oracle) sponsoredTokens(ctx context.Context, block *types.Block) (map[common.Address]*big.Int, error) {
+	number := block.NumberU64()
+	if number == 0 {
+		return nil, nil
+	}
+	var calls bool
+	for _, tx := range block.Transactions() {
+		if !tx.IsSpecialTransaction() && tx.To() != nil {
+			calls = true
+			break
+		}
+	}
+	if !calls {
+		return nil, nil
+	}
+	statedb, _, err := gpo.backend.StateAndHeaderByNumber(ctx, rpc.BlockNumber(number-1))
+	if statedb == nil || err != nil {
+		return nil, errStateUnavailable
+	}
+	tokens := state.GetTRC21FeeCapacityFromState(statedb)
+	if statedb.Error() != nil {
+		return nil, errStateUnavailable
+	}
+	return tokens, nil
+}
+
+// eligible reports whether the transaction paid a market gas price, ruling out
+// the zero-priced block signing and randomization transactions as well as the
+// ones whose fees are sponsored by a TRC21 token issuer with capacity left.
+func eligible(tx *types.Transaction, tokens map[common.Address]*big.Int) bool {
+	if tx.IsSpecialTransaction() {
+		return false
+	}
+	if to := tx.To(); to != nil {
+		if capacity, ok := tokens[*to]; ok && capacity.Sign() > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// FeeHistory returns the gas prices paid in the given number of blocks ending with
+// lastBlock, at the requested percentiles of the gas used by the transactions
+// paying a market price in every block, along with the ratio of gas used to the
+// gas limit of the blocks. The prices are never lower than the minimum gas price,
+// which is reported for blocks without such transactions. The number of the
+// oldest block of the range is returned first. The fees sponsored by TRC21 token
+// issuers are told apart using the state of every block, failing the request if
+// it is unavailable.
+func (gpo *Oracle) FeeHistory(ctx context.Context, blocks uint64, lastBlock rpc.BlockNumber, percentiles []float64) (*big.Int, [][]*big.Int, []float64, error) {
+	for i, p := range percentiles {
+		if p < 0 || p > 100 {
+			return nil, nil, nil, fmt.Errorf("%v: %f", errInvalidPercentile, p)
+		}
+		if i > 0 && p < percentiles[i-1] {
+			return nil, nil, nil, fmt.Errorf("%v: #%d:%f > #%d:%f", errInvalidPercentile, i-1, percentiles[i-1], i, p)
+		}
+	}
+	if blocks == 0 {
+		return common.Big0, nil, nil, nil
+	}
+	if blocks > maxFeeHistory {
+		blocks = maxFeeHistory
+	}
+	// Resolve the range of blocks, pending being the head for light clients too
+	head, err := gpo.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if head == nil || err != nil {
+		return nil, nil, nil, err
+	}
+	last := head.Number.Uint64()
+	if lastBlock >= 0 {
+		if uint64(lastBlock) > last {
+			return nil, nil, nil, fmt.Errorf("%v: requested %d, head %d", errRequestBeyondHead, lastBlock, last)
+		}
+		last = uint64(lastBlock)
+	}
+	if blocks > last+1 {
+		blocks = last + 1
+	}
+	var (
+		count   = int(blocks)
+		oldest  = last + 1 - blocks
+		rewards [][]*big.Int
+		ratios  = make([]float64, count)
+	)
+	if len(percentiles) > 0 {
+		rewards = make([][]*big.Int, count)
+	}
+	for i := 0; i < count; i++ {
+		block, err := gpo.backend.BlockByNumber(ctx, rpc.BlockNumber(oldest+uint64(i)))
+		if block == nil {
+			if err == nil {
+				err = fmt.Errorf("block #%d not found", oldest+uint64(i))
+			}
+			return nil, nil, nil, err
+		}
+		if block.GasLimit() > 0 {
+			ratios[i] = float64(block.GasUsed()) / float64(block.GasLimit())
+		}
+		if len(percentiles) > 0 {
+			if rewards[i], err = gpo.blockRewards(ctx, block, percentiles); err != nil {
+				if err == errStateUnavailable {
+					err = fmt.Errorf("%v: block #%d", err, block.NumberU64()-1)
+				}
+				return nil, nil, nil, err
+			}
+		}
+	}
+	return new(big.Int).SetUint64(oldest), rewards, ratios, nil
+}
+
+// txGasAndPrice is the gas used by a transaction and the price it paid for it.
+type txGasAndPrice struct {
+	gasUsed uint64
+	price   *big.Int
+}
+
+// blockRewards calculates the gas prices paid in the block at the given
+// percentiles, weighting the transactions paying a market price by the amount
+// of gas they used.
+func (gpo *Oracle) blockRewards(ctx context.Context, block *types.Block, percentiles []float64) ([]*big.Int, error) {
+	receipts, err := gpo.backend.GetReceipts(ctx, block.Hash())
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := gpo.sponsoredTokens(ctx, block)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		sorted []txGasAndPrice
+		total  uint64
+	)
+	for i, tx := range block.Transactions() {
+		if !eligible(tx, tokens) || i >= len(receipts) {
+			continue
+		}
+		sorted = append(sorted, txGasAndPrice{gasUsed: receipts[i].GasUsed, price: tx.GasPrice()})
+		total += receipts[i].GasUsed
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].price.Cmp(sorted[j].price) < 0
+	})
+	rewards := make([]*big.Int, len(percentiles))
+	if len(sorted) == 0 {
+		for i := range rewards {
+			rewards[i] = new(big.Int).Set(common.MinGasPrice)
+		}
+		return rewards, nil
+	}
+	var (
+		index   = 0
+		gasUsed = sorted[0].gasUsed
+	)
+	for i, p := range percentiles {
+		threshold := uint64(float64(total) * p / 100)
+		for gasUsed < threshold && index < len(sorted)-1 {
+			index++
+			gasUsed += sorted[index].gasUsed
+		}
+		rewards[i] = new(big.Int).Set(sorted[index].price)
+		if rewards[i].Cmp(common.MinGasPrice) < 0 {
+			rewards[i].Set(common.MinGasPrice)
+		}
+	}
+	return rewards, nil
+}
+
 type bigIntArray []*big.Int
 
 func (s bigIntArray) Len() int           { return len(s) }
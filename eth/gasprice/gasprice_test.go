@@ -0,0 +1,246 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// testBackend is an oracle backend serving a fixed chain, along with the state
+// every block was applied on. A missing state fails to be retrieved.
+type testBackend struct {
+	blocks   []*types.Block
+	receipts map[common.Hash]types.Receipts
+	states   []*state.StateDB
+}
+
+func (b *testBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
+	block, err := b.BlockByNumber(ctx, blockNr)
+	if block == nil {
+		return nil, err
+	}
+	return block.Header(), nil
+}
+
+func (b *testBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error) {
+	if blockNr == rpc.LatestBlockNumber || blockNr == rpc.PendingBlockNumber {
+		blockNr = rpc.BlockNumber(len(b.blocks) - 1)
+	}
+	if int(blockNr) >= len(b.blocks) {
+		return nil, nil
+	}
+	return b.blocks[blockNr], nil
+}
+
+func (b *testBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	header, err := b.HeaderByNumber(ctx, blockNr)
+	if header == nil {
+		return nil, nil, err
+	}
+	statedb := b.states[header.Number.Uint64()]
+	if statedb == nil {
+		return nil, nil, errors.New("missing trie node")
+	}
+	return statedb, header, nil
+}
+
+func (b *testBackend) GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error) {
+	return b.receipts[blockHash], nil
+}
+
+func (b *testBackend) ChainConfig() *params.ChainConfig {
+	return params.TestChainConfig
+}
+
+// registerTokens creates a state registering the given TRC21 tokens as
+// sponsoring the fees of their transactions, up to their capacity.
+func registerTokens(t *testing.T, tokens []common.Address, capacities []*big.Int) *state.StateDB {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(db))
+	if err != nil {
+		t.Fatalf("failed to create state: %v", err)
+	}
+	slot := common.BigToHash(new(big.Int).SetUint64(state.SlotTRC21Issuer["tokens"]))
+	statedb.SetState(common.TRC21IssuerSMC, slot, common.BigToHash(big.NewInt(int64(len(tokens)))))
+	for i, token := range tokens {
+		statedb.SetState(common.TRC21IssuerSMC, state.GetLocDynamicArrAtElement(slot, uint64(i), 1), token.Hash())
+		key := state.GetLocMappingAtKey(token.Hash(), state.SlotTRC21Issuer["tokensState"])
+		statedb.SetState(common.TRC21IssuerSMC, common.BigToHash(key), common.BigToHash(capacities[i]))
+	}
+	return statedb
+}
+
+// newTestBackend creates a chain of four blocks on top of an empty genesis. Every
+// block contains a zero priced signing transaction, a sponsored TRC21 transaction
+// paying the minimum price, a transaction to a TRC21 token with no capacity left
+// paying a growing market price and a transaction paying twice that price.
+func newTestBackend(t *testing.T, token, drained common.Address) *testBackend {
+	var (
+		key, _  = crypto.GenerateKey()
+		backend = &testBackend{receipts: make(map[common.Hash]types.Receipts)}
+		parent  = types.NewBlock(&types.Header{Number: new(big.Int), GasLimit: 1000000}, nil, nil, nil)
+		nonce   uint64
+	)
+	backend.blocks = append(backend.blocks, parent)
+	for i := 1; i <= 4; i++ {
+		var (
+			signer   = types.MakeSigner(params.TestChainConfig, big.NewInt(int64(i)))
+			txs      []*types.Transaction
+			receipts []*types.Receipt
+			gasUsed  uint64
+		)
+		for _, tx := range []*types.Transaction{
+			types.NewTransaction(nonce, common.HexToAddress(common.BlockSigners), new(big.Int), 100000, new(big.Int), nil),
+			types.NewTransaction(nonce+1, token, new(big.Int), 21000, common.MinGasPrice, nil),
+			types.NewTransaction(nonce+2, drained, new(big.Int), 21000, testPrice(i), nil),
+			types.NewTransaction(nonce+3, common.Address{0xaa}, new(big.Int), 21000, new(big.Int).Mul(testPrice(i), big.NewInt(2)), nil),
+		} {
+			signed, err := types.SignTx(tx, signer, key)
+			if err != nil {
+				t.Fatalf("failed to sign transaction: %v", err)
+			}
+			gasUsed += 21000
+			receipt := types.NewReceipt(nil, false, gasUsed)
+			receipt.GasUsed = 21000
+
+			txs = append(txs, signed)
+			receipts = append(receipts, receipt)
+		}
+		nonce += 4
+
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     big.NewInt(int64(i)),
+			GasLimit:   1000000,
+			GasUsed:    gasUsed,
+		}
+		parent = types.NewBlock(header, txs, nil, receipts)
+		backend.blocks = append(backend.blocks, parent)
+		backend.receipts[parent.Hash()] = receipts
+	}
+	// The token sponsors the transactions of all blocks, but is no longer
+	// registered at the head
+	for i := 0; i < 4; i++ {
+		backend.states = append(backend.states, registerTokens(t, []common.Address{token, drained}, []*big.Int{big.NewInt(params.Ether), new(big.Int)}))
+	}
+	backend.states = append(backend.states, registerTokens(t, []common.Address{drained}, []*big.Int{new(big.Int)}))
+	return backend
+}
+
+// testPrice is the market price paid by the transactions of a test block.
+func testPrice(number int) *big.Int {
+	return new(big.Int).Mul(common.MinGasPrice, big.NewInt(int64(number+2)))
+}
+
+// Tests that the oracle only samples the transactions paying a market price,
+// leaving out the special ones and the ones sponsored by a TRC21 token issuer at
+// the time the block was applied.
+func TestSuggestPrice(t *testing.T) {
+	backend := newTestBackend(t, common.Address{0xee}, common.Address{0xdd})
+
+	// The lowest market prices of the last two blocks are sampled
+	oracle := NewOracle(backend, Config{Blocks: 2, Percentile: 60})
+	price, err := oracle.SuggestPrice(context.Background())
+	if err != nil {
+		t.Fatalf("failed to suggest price: %v", err)
+	}
+	if price.Cmp(testPrice(3)) != 0 {
+		t.Errorf("suggested price mismatch: have %v, want %v", price, testPrice(3))
+	}
+	// Without the state to tell the sponsored transactions apart, the last price
+	// is kept
+	backend.states[3] = nil
+
+	def := new(big.Int).Mul(common.MinGasPrice, big.NewInt(100))
+	oracle = NewOracle(backend, Config{Blocks: 2, Percentile: 60, Default: def})
+	price, err = oracle.SuggestPrice(context.Background())
+	if err != nil {
+		t.Fatalf("failed to suggest price without state: %v", err)
+	}
+	if price.Cmp(def) != 0 {
+		t.Errorf("suggested price mismatch without state: have %v, want %v", price, def)
+	}
+}
+
+// Tests that the fee history weights the market prices by gas used, reporting
+// the minimum price for blocks without any, and that invalid requests are
+// rejected.
+func TestFeeHistory(t *testing.T) {
+	backend := newTestBackend(t, common.Address{0xee}, common.Address{0xdd})
+	oracle := NewOracle(backend, Config{Blocks: 2, Percentile: 60})
+
+	oldest, rewards, ratios, err := oracle.FeeHistory(context.Background(), 3, 2, []float64{0, 50, 100})
+	if err != nil {
+		t.Fatalf("failed to retrieve fee history: %v", err)
+	}
+	if oldest.Uint64() != 0 {
+		t.Errorf("oldest block mismatch: have %v, want 0", oldest)
+	}
+	min := common.MinGasPrice
+	want := [][]*big.Int{
+		{min, min, min},
+		{testPrice(1), testPrice(1), new(big.Int).Mul(testPrice(1), big.NewInt(2))},
+		{testPrice(2), testPrice(2), new(big.Int).Mul(testPrice(2), big.NewInt(2))},
+	}
+	if len(rewards) != len(want) || len(ratios) != len(want) {
+		t.Fatalf("fee history length mismatch: have %d rewards and %d ratios, want %d", len(rewards), len(ratios), len(want))
+	}
+	for i := range want {
+		for j := range want[i] {
+			if rewards[i][j].Cmp(want[i][j]) != 0 {
+				t.Errorf("block %d, percentile %d: reward mismatch: have %v, want %v", i, j, rewards[i][j], want[i][j])
+			}
+		}
+		if (i == 0) != (ratios[i] == 0) {
+			t.Errorf("block %d: gas used ratio mismatch: have %f", i, ratios[i])
+		}
+	}
+	// Oversized block counts are capped to the chain
+	oldest, _, ratios, err = oracle.FeeHistory(context.Background(), math.MaxUint64, rpc.LatestBlockNumber, nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve oversized fee history: %v", err)
+	}
+	if oldest.Uint64() != 0 || len(ratios) != len(backend.blocks) {
+		t.Errorf("oversized fee history mismatch: have oldest %v and %d blocks, want 0 and %d", oldest, len(ratios), len(backend.blocks))
+	}
+	// Invalid requests are rejected
+	if _, _, _, err := oracle.FeeHistory(context.Background(), 1, rpc.LatestBlockNumber, []float64{50, 10}); err == nil {
+		t.Errorf("unsorted percentiles accepted")
+	}
+	if _, _, _, err := oracle.FeeHistory(context.Background(), 1, rpc.LatestBlockNumber, []float64{101}); err == nil {
+		t.Errorf("out of range percentile accepted")
+	}
+	if _, _, _, err := oracle.FeeHistory(context.Background(), 1, 5, nil); err == nil {
+		t.Errorf("block beyond head accepted")
+	}
+	backend.states[3] = nil
+	if _, _, _, err := oracle.FeeHistory(context.Background(), 1, 4, []float64{50}); err == nil {
+		t.Errorf("block without state accepted")
+	}
+}
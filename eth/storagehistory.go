@@ -0,0 +1,203 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// storageHistorySectionSize is the number of blocks covered by a single section
+	// of the storage history index.
+	storageHistorySectionSize = 4096
+
+	// storageHistoryConfirms is the number of confirmation blocks before a storage
+	// history section is considered probably final and gets indexed.
+	storageHistoryConfirms = 256
+
+	// storageHistoryThrottling is the time to wait between processing two
+	// consecutive index sections.
+	storageHistoryThrottling = 100 * time.Millisecond
+
+	// storageHistoryMaxScan is the maximum number of unindexed blocks whose state
+	// diffs are scanned one by one when looking up the history of a slot.
+	storageHistoryMaxScan = 2 * storageHistorySectionSize
+)
+
+// StorageHistoryIndexer implements a core.ChainIndexer, building up an index of
+// the blocks which changed each storage slot out of the recorded state diffs.
+type StorageHistoryIndexer struct {
+	db ethdb.Database // database instance to read state diffs from and write index data into
+
+	section   uint64                                      // Section is the section number being processed currently
+	head      common.Hash                                 // Head is the hash of the last header processed
+	changes   map[common.Address]map[common.Hash][]uint64 // Blocks changing each slot in the current section
+	destructs map[common.Address][]uint64                 // Blocks destructing each account in the current section
+}
+
+// NewStorageHistoryIndexer returns a chain indexer that generates the storage
+// history index for the canonical chain. It requires state diffs to be recorded.
+func NewStorageHistoryIndexer(db ethdb.Database, size uint64) *core.ChainIndexer {
+	backend := &StorageHistoryIndexer{
+		db: db,
+	}
+	table := ethdb.NewTable(db, string(core.StorageHistIndexPrefix))
+
+	return core.NewChainIndexer(db, table, backend, size, storageHistoryConfirms, storageHistoryThrottling, "storagehistory")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new storage history
+// index section.
+func (b *StorageHistoryIndexer) Reset(section uint64, lastSectionHead common.Hash) error {
+	b.section, b.head = section, common.Hash{}
+	b.changes = make(map[common.Address]map[common.Hash][]uint64)
+	b.destructs = make(map[common.Address][]uint64)
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding the storage changes of a
+// new header's block into the index.
+func (b *StorageHistoryIndexer) Process(header *types.Header) {
+	number, hash := header.Number.Uint64(), header.Hash()
+	diff := core.GetStateDiff(b.db, hash, number)
+	if diff == nil {
+		log.Warn("State diff missing, storage history incomplete", "number", number, "hash", hash)
+	} else {
+		for _, account := range diff.Accounts {
+			if account.Destructed {
+				b.destructs[account.Address] = append(b.destructs[account.Address], number)
+			}
+			if len(account.Storage) == 0 {
+				continue
+			}
+			slots := b.changes[account.Address]
+			if slots == nil {
+				slots = make(map[common.Hash][]uint64)
+				b.changes[account.Address] = slots
+			}
+			for _, slot := range account.Storage {
+				slots[slot.Key] = append(slots[slot.Key], number)
+			}
+		}
+	}
+	b.head = hash
+}
+
+// Commit implements core.ChainIndexerBackend, finalizing the storage history
+// section and writing it out into the database.
+func (b *StorageHistoryIndexer) Commit() error {
+	batch := b.db.NewBatch()
+
+	for address, slots := range b.changes {
+		for slot, numbers := range slots {
+			if err := core.WriteStorageHistory(batch, address, slot, b.section, b.head, numbers); err != nil {
+				return err
+			}
+		}
+		if numbers, ok := b.destructs[address]; ok {
+			if err := core.WriteDestructHistory(batch, address, b.section, b.head, numbers); err != nil {
+				return err
+			}
+		}
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	for address, numbers := range b.destructs {
+		if _, ok := b.changes[address]; ok {
+			continue
+		}
+		if err := core.WriteDestructHistory(batch, address, b.section, b.head, numbers); err != nil {
+			return err
+		}
+	}
+	return batch.Write()
+}
+
+// storageChanges returns the numbers of the canonical blocks in the [from, to]
+// range which changed a storage slot of an account, either explicitly or by
+// destructing the account. The first sections of the given size are looked up
+// in the storage history index, the remaining blocks are scanned one by one, up
+// to storageHistoryMaxScan of them.
+func storageChanges(db ethdb.Database, sections, size uint64, address common.Address, slot common.Hash, from, to uint64) ([]uint64, error) {
+	var numbers []uint64
+
+	next := from
+	for section := from / size; section < sections && section*size <= to; section++ {
+		head := core.GetCanonicalHash(db, (section+1)*size-1)
+
+		indexed := append(core.GetStorageHistory(db, address, slot, section, head), core.GetDestructHistory(db, address, section, head)...)
+		sort.Slice(indexed, func(i, j int) bool { return indexed[i] < indexed[j] })
+		for i, number := range indexed {
+			if number >= from && number <= to && (i == 0 || indexed[i-1] != number) {
+				numbers = append(numbers, number)
+			}
+		}
+		next = (section + 1) * size
+	}
+	if next <= to && to-next >= storageHistoryMaxScan {
+		return nil, fmt.Errorf("too many unindexed blocks to scan (%d > %d)", to-next+1, storageHistoryMaxScan)
+	}
+	for number := next; number <= to; number++ {
+		hash := core.GetCanonicalHash(db, number)
+		if hash == (common.Hash{}) {
+			break
+		}
+		diff := core.GetStateDiff(db, hash, number)
+		if diff == nil {
+			return nil, fmt.Errorf("state diff of block #%d not found", number)
+		}
+		if account := changedAccount(diff, address); account != nil && (account.Destructed || storageDiff(account, slot) != nil) {
+			numbers = append(numbers, number)
+		}
+	}
+	return numbers, nil
+}
+
+// changedAccount returns the change of an account within a state diff, or nil if
+// the account was not changed.
+func changedAccount(diff *state.StateDiff, address common.Address) *state.AccountDiff {
+	for _, account := range diff.Accounts {
+		if account.Address == address {
+			return account
+		}
+	}
+	return nil
+}
+
+// storageDiff returns the change of a storage slot within an account diff, or
+// nil if the slot was not explicitly changed.
+func storageDiff(account *state.AccountDiff, slot common.Hash) *state.StorageDiff {
+	for _, change := range account.Storage {
+		if change.Key == slot {
+			return change
+		}
+	}
+	return nil
+}
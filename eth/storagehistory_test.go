@@ -0,0 +1,142 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that storage slot changes, explicit or caused by destructing the account,
+// are found both in the indexed sections and in the unindexed tail of the chain.
+func TestStorageHistory(t *testing.T) {
+	var (
+		db, _    = ethdb.NewMemDatabase()
+		key, _   = crypto.GenerateKey()
+		address  = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.Address{0xcc}
+		doomed   = common.Address{0xdd}
+		slot     = common.Hash{}
+		gspec    = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				address: {Balance: big.NewInt(1000000000)},
+				// PUSH1 0 CALLDATALOAD PUSH1 0 SSTORE: stores the call data into slot 0
+				contract: {Balance: new(big.Int), Code: common.FromHex("0x60003560005500"), Storage: map[common.Hash]common.Hash{slot: common.HexToHash("0x07")}},
+				// CALLER SELFDESTRUCT: wipes slot 0
+				doomed: {Balance: new(big.Int), Code: common.FromHex("0x33ff"), Storage: map[common.Hash]common.Hash{slot: common.HexToHash("0x07")}},
+			},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.HomesteadSigner{}
+		changes = map[uint64]common.Hash{2: common.HexToHash("0x01"), 5: common.HexToHash("0x02"), 9: common.HexToHash("0x03")}
+	)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 10, func(i int, block *core.BlockGen) {
+		if value, ok := changes[uint64(i+1)]; ok {
+			tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(address), contract, new(big.Int), 100000, new(big.Int), value[:]), signer, key)
+			block.AddTx(tx)
+		}
+		if i == 6 {
+			tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(address), doomed, new(big.Int), 100000, new(big.Int), nil), signer, key)
+			block.AddTx(tx)
+		}
+	})
+	chain, err := core.NewBlockChain(db, &core.CacheConfig{TrieNodeLimit: 256 * 1024 * 1024, TrieTimeLimit: 5 * time.Minute, StateDiffs: true}, gspec.Config, ethash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	// Index the first two sections of four blocks, leaving the rest unindexed
+	const size = 4
+	indexer := &StorageHistoryIndexer{db: db}
+	for section := uint64(0); section < 2; section++ {
+		if err := indexer.Reset(section, common.Hash{}); err != nil {
+			t.Fatalf("section %d: failed to reset indexer: %v", section, err)
+		}
+		for number := section * size; number < (section+1)*size; number++ {
+			indexer.Process(chain.GetHeaderByNumber(number))
+		}
+		if err := indexer.Commit(); err != nil {
+			t.Fatalf("section %d: failed to commit index: %v", section, err)
+		}
+	}
+	tests := []struct {
+		from, to uint64
+		want     []uint64
+	}{
+		{0, 10, []uint64{2, 5, 9}},
+		{3, 10, []uint64{5, 9}},
+		{2, 5, []uint64{2, 5}},
+		{6, 8, nil},
+		{9, 9, []uint64{9}},
+	}
+	for i, tt := range tests {
+		have, err := storageChanges(db, 2, size, contract, slot, tt.from, tt.to)
+		if err != nil {
+			t.Fatalf("test %d: failed to look up changes: %v", i, err)
+		}
+		if !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("test %d: changes mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+	// Ensure destructions are reported, both from the index and the unindexed tail
+	for _, sections := range []uint64{2, 1} {
+		have, err := storageChanges(db, sections, size, doomed, slot, 0, 10)
+		if err != nil {
+			t.Fatalf("%d sections: failed to look up destructions: %v", sections, err)
+		}
+		if want := []uint64{7}; !reflect.DeepEqual(have, want) {
+			t.Errorf("%d sections: destructions mismatch: have %v, want %v", sections, have, want)
+		}
+	}
+	// Ensure the change points carry the slot values around them
+	prev := common.HexToHash("0x07")
+	for _, number := range []uint64{2, 5, 9} {
+		account := changedAccount(core.GetStateDiff(db, core.GetCanonicalHash(db, number), number), contract)
+		if account == nil {
+			t.Fatalf("block %d: account change missing", number)
+		}
+		diff := storageDiff(account, slot)
+		if diff == nil {
+			t.Fatalf("block %d: storage change missing", number)
+		}
+		if diff.From != prev || diff.To != changes[number] {
+			t.Errorf("block %d: storage change mismatch: have %x -> %x, want %x -> %x", number, diff.From, diff.To, prev, changes[number])
+		}
+		prev = diff.To
+	}
+	// Ensure missing state diffs in the unindexed tail are reported
+	core.DeleteStateDiff(db, core.GetCanonicalHash(db, 10), 10)
+	if _, err := storageChanges(db, 2, size, contract, slot, 0, 10); err == nil {
+		t.Errorf("missing state diff not reported")
+	}
+}
@@ -92,7 +92,7 @@ func _4byte_tracerJs() (*asset, error) {
 	return a, nil
 }
 
-var _call_tracerJs = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xd4\x59\xdf\x6f\x1b\x37\xf2\x7f\x96\xfe\x8a\x49\x1e\x6a\x09\x51\x24\x27\xe9\xb7\x5f\xc0\xae\x7a\xd0\x39\x4a\x6a\xc0\x8d\x03\x5b\x69\x10\x04\x79\xa0\x76\x67\x25\xd6\x5c\x72\x4b\x72\x2d\xef\xa5\xfe\xdf\x0f\x33\xe4\xae\x56\x3f\xec\xe8\x7a\xb8\x43\xef\x45\xd0\x2e\x67\x86\xc3\x99\xcf\xfc\xe2\x8e\x46\x70\x66\x8a\xca\xca\xc5\xd2\xc3\xcb\xe3\x17\xff\x0f\xb3\x25\xc2\xc2\x3c\x47\xbf\x44\x8b\x65\x0e\x93\xd2\x2f\x8d\x75\xdd\xd1\x08\x66\x4b\xe9\x20\x93\x0a\x41\x3a\x28\x84\xf5\x60\x32\xf0\x5b\xf4\x4a\xce\xad\xb0\xd5\xb0\x3b\x1a\x05\x9e\xbd\xcb\x24\x21\xb3\x88\xe0\x4c\xe6\x57\xc2\xe2\x09\x54\xa6\x84\x44\x68\xb0\x98\x4a\xe7\xad\x9c\x97\x1e\x41\x7a\x10\x3a\x1d\x19\x0b\xb9\x49\x65\x56\x91\x48\xe9\xa1\xd4\x29\x5a\xde\xda\xa3\xcd\x5d\xad\xc7\xdb\x77\x1f\xe0\x02\x9d\x43\x0b\x6f\x51\xa3\x15\x0a\xde\x97\x73\x25\x13\xb8\x90\x09\x6a\x87\x20\x1c\x14\xf4\xc6\x2d\x31\x85\x39\x8b\x23\xc6\x37\xa4\xca\x75\x54\x05\xde\x98\x52\xa7\xc2\x4b\xa3\x07\x80\x92\x34\x87\x5b\xb4\x4e\x1a\x0d\xaf\xea\xad\xa2\xc0\x01\x18\x4b\x42\x7a\xc2\xd3\x01\x2c\x98\x82\xf8\xfa\x20\x74\x05\x4a\xf8\x35\xeb\x01\x06\x59\x9f\x3b\x05\xa9\x79\x9b\xa5\x29\x10\xfc\x52\x78\x3a\xf5\x4a\x2a\x05\x73\x84\xd2\x61\x56\xaa\x01\x49\x9b\x97\x1e\x3e\x9e\xcf\x7e\xbe\xfc\x30\x83\xc9\xbb\x4f\xf0\x71\x72\x75\x35\x79\x37\xfb\x74\x0a\x2b\xe9\x97\xa6\xf4\x80\xb7\x18\x44\xc9\xbc\x50\x12\x53\x58\x09\x6b\x85\xf6\x15\x98\x8c\x24\xfc\x32\xbd\x3a\xfb\x79\xf2\x6e\x36\xf9\xfb\xf9\xc5\xf9\xec\x13\x18\x0b\x6f\xce\x67\xef\xa6\xd7\xd7\xf0\xe6\xf2\x0a\x26\xf0\x7e\x72\x35\x3b\x3f\xfb\x70\x31\xb9\x82\xf7\x1f\xae\xde\x5f\x5e\x4f\x87\x70\x8d\xa4\x15\x12\xff\xb7\x6d\x9e\xb1\xf7\x2c\x42\x8a\x5e\x48\xe5\x6a\x4b\x7c\x32\x25\xb8\xa5\x29\x55\x0a\x4b\x71\x8b\x60\x31\x41\x79\x8b\x29\x08\x48\x4c\x51\x1d\xec\x54\x92\x25\x94\xd1\x0b\x3e\xf3\x83\x80\x84\xf3\x0c\xb4\xf1\x03\x70\x88\xf0\xe3\xd2\xfb\xe2\x64\x34\x5a\xad\x56\xc3\x85\x2e\x87\xc6\x2e\x46\x2a\x88\x73\xa3\x9f\x86\x5d\x92\x99\x08\xa5\x66\x56\x24\x68\xc9\x39\x02\xb2\x92\xcc\xaf\xcc\x4a\x83\xb7\x42\x3b\x91\x90\xab\xe9\x7f\xc2\x60\x14\x1e\xf0\x8e\x9e\xbc\x23\xd0\x82\xc5\xc2\x58\xfa\xaf\x54\x8d\x33\xa9\x3d\x5a\x2d\x14\xcb\x76\x90\x8b\x14\x61\x5e\x81\x68\x0b\x1c\xb4\x0f\x43\x30\x0a\xee\x06\xa9\x33\x63\x73\x86\xe5\xb0\xfb\xb5\xdb\x89\x1a\x3a\x2f\x92\x1b\x52\x90\xe4\x27\xa5\xb5\xa8\x3d\x99\xb2\xb4\x4e\xde\x22\x93\x40\xa0\x89\xf6\x9c\xfe\xfa\x0b\xe0\x1d\x26\x65\x90\xd4\x69\x84\x9c\xc0\xe7\xaf\xf7\x5f\x06\x5d\x16\x9d\xa2\x4b\x50\xa7\x98\xf2\xf9\x6e\x1c\xac\x96\x6c\x51\x58\xe1\xd1\x2d\xc2\x6f\xa5\xf3\x2d\x9a\xcc\x9a\x1c\x84\x06\x53\x12\xe2\xdb\xd6\x91\xda\x1b\x16\x28\xe8\xbf\x46\xcb\x1a\x0d\xbb\x9d\x86\xf9\x04\x32\xa1\x1c\xc6\x7d\x9d\xc7\x82\x4e\x23\xf5\xad\xb9\x21\xc9\xc6\x12\x84\x6d\x05\xa6\x48\x4c\x1a\x83\x81\xce\xd1\x1c\x03\xdd\xb0\xdb\x21\xbe\x13\xc8\x4a\xcd\xdb\xf6\x94\x59\x0c\x20\x9d\xf7\xe1\x6b\xb7\x43\x62\xcf\x44\xe1\x4b\x8b\x6c\x4f\xb4\xd6\x58\x07\x32\xcf\x31\x95\xc2\xa3\xaa\xba\x9d\xce\xad\xb0\x61\x01\xc6\xa0\xcc\x62\xb8\x40\x3f\xa5\xc7\x5e\xff\xb4\xdb\xe9\xc8\x0c\x7a\x61\xf5\xc9\x78\xcc\xd9\x27\x93\x1a\xd3\x20\xbe\xe3\x97\xd2\x0d\x33\x51\x2a\xdf\xec\x4b\x4c\x1d\x8b\xbe\xb4\x9a\xfe\xde\x07\x2d\x3e\x22\x18\xad\x2a\x48\x28\xcb\x88\x39\x85\xa7\xab\x9c\xc7\x3c\x1e\xce\x0d\x20\x13\x8e\x4c\x28\x33\x58\x21\x14\x16\x9f\x27\x4b\x24\xdf\xe9\x04\xa3\x96\xae\x72\xec\xd4\x31\xd0\x6e\x43\x53\x0c\xbd\x79\x57\xe6\x73\xb4\xbd\x3e\x7c\x07\xc7\x77\xd9\x71\x1f\xc6\x63\xfe\x53\xeb\x1e\x79\xa2\xbe\x24\xc5\x14\xf1\xa0\xcc\x7f\xed\xad\xd4\x8b\x70\xd6\xa8\xeb\x79\x06\x02\x34\xae\x20\x31\x9a\x41\x4d\x5e\x99\xa3\xd4\x0b\x48\x2c\x0a\x8f\xe9\x00\x44\x9a\x82\x37\x01\x79\x0d\xce\x36\xb7\x84\xef\xbe\xe3\xbd\xc6\x70\x74\x76\x35\x9d\xcc\xa6\x47\x2d\x25\xa4\xbe\xcc\xb2\xa8\x07\xf3\x0e\x0b\xc4\x9b\xde\x8b\xfe\xf0\x56\xa8\x12\x2f\xb3\xa0\x51\xa4\x9d\xea\x14\xc6\x91\xe7\xd9\x36\xcf\xcb\x0d\x1e\x62\x1a\x8d\x60\xe2\x1c\xe6\x73\x85\xbb\xb1\x17\x83\x93\xe3\xd4\x79\x4a\x4e\x04\xb4\xc4\xe4\x85\x42\x02\x50\xbd\x6b\xb4\x34\x6b\xdc\xf1\x55\x81\x27\x00\x00\xa6\x18\xf0\x0b\x82\x3d\xbf\xf0\xe6\x67\xbc\x63\x77\xd4\xd6\x22\x00\x4d\xd2\xd4\xa2\x73\xbd\x7e\x3f\x90\x4b\x5d\x94\xfe\x64\x83\x3c\xc7\xdc\xd8\x6a\xe8\x28\xf7\xf4\xf8\x68\x83\x70\xd2\x9a\x67\x21\xdc\xb9\x26\x9e\x08\xca\xb7\xc2\xf5\xd6\x4b\x67\xc6\xf9\x93\x7a\x89\x1e\xea\x35\xb6\x05\xb1\x1d\x1d\xdf\x1d\xed\x5a\xeb\xb8\xbf\x76\xfa\x8b\x1f\xfa\xc4\x72\x7f\xda\x40\xb9\xc9\x08\xc3\xa2\x74\xcb\x1e\x23\x67\xbd\xba\x8e\xfa\x31\x78\x5b\xe2\x5e\xa4\x33\x7a\x76\x91\xe3\x50\x65\x94\x36\xbc\x2d\x13\x46\xd0\x42\x70\x52\xe1\xa0\x16\x94\x64\x5d\x39\x67\x9b\x7b\x63\x1e\x04\xd2\xf5\xf4\xe2\xcd\xeb\xe9\xf5\xec\xea\xc3\xd9\xac\x0d\x27\x85\x99\x27\xa5\x36\xcf\xa0\x50\x2f\xfc\x92\xf5\x27\x71\x9b\xab\x9f\x89\xe7\xf9\x8b\x2f\xe1\x0d\x8c\xf7\x44\x77\xe7\x71\x0e\xf8\xfc\x85\x65\xdf\xef\x9a\x6f\x93\x34\x18\xf3\x6b\x00\x91\x29\xee\xdb\x39\x62\x4f\xd8\xe5\xe8\x97\x26\xe5\x3c\x98\x88\x90\x4a\x6b\x2b\xa6\x46\xe3\xc1\xc1\xd7\xab\xa3\x6f\x72\x71\x71\x04\x7f\xfc\x01\xad\xe7\xb3\xcb\xd7\xd3\xf6\xbb\xd7\xd3\x8b\xe9\xdb\xc9\x6c\xba\x4d\x7b\x3d\x9b\xcc\xce\xcf\xf8\x6d\x3f\x5a\x65\x34\x82\xeb\x1b\x59\x70\x42\xe5\x34\x65\xf2\x82\x3b\xc3\x46\x5f\x37\x00\xbf\x34\xd4\x73\xd9\x58\x2f\x32\xa1\x93\x3a\x8f\xbb\xda\x69\xde\x90\xcb\x4c\x1d\x2b\xbb\xa9\xa0\x0d\xd4\x7e\xe3\x46\xe9\xde\x5b\x8c\x9b\xa6\x3d\x6f\x6a\xbd\xd6\x06\x0d\x1e\xe1\x5c\xc7\x49\xa6\x77\xf8\x21\xe1\x6f\x70\x0c\x27\xf0\x22\x66\x92\x47\x52\xd5\x4b\x78\x46\xe2\xff\x44\xc2\x7a\xb5\x87\xf3\xaf\x99\xb6\xbc\x61\xe2\x9a\xdc\x9b\xff\x7e\x3a\x33\xa5\xbf\xcc\xb2\x13\xd8\x36\xe2\xf7\x3b\x46\x6c\xe8\x2f\x50\xef\xd2\xff\xdf\x0e\xfd\x3a\xf5\x11\xaa\x4c\x01\x4f\x76\x20\x12\x12\xcf\x93\xad\x38\x88\xc6\xe5\x6e\x86\xa5\xc1\xf8\x81\x64\xfb\x72\x13\xc3\x0f\x65\x8b\x7f\x2b\xd9\xee\xed\xca\xa8\xf7\xda\xec\xbb\x06\x60\xd1\x5b\x89\xb7\x34\x59\x1d\x39\x16\x49\xfd\xa9\x59\x09\x9d\xe0\x10\x3e\x62\x90\xa8\x11\x39\xb9\xc4\x7e\x96\xda\x11\x6e\xf1\xa8\x27\x8d\x93\x09\x43\x4c\x70\xdb\x69\x11\x72\x51\xd1\x64\x92\x95\xfa\xa6\x82\x85\x70\x90\x56\x5a\xe4\x32\x71\x41\x1e\xf7\xb2\x16\x17\xc2\xb2\x58\x8b\xbf\x97\xe8\x68\xcc\x21\x20\x8b\xc4\x97\x42\xa9\x0a\x16\x92\x66\x15\xe2\xee\xbd\x7c\x75\x7c\x0c\xce\xcb\x02\x75\x3a\x80\x1f\x5e\x8d\x7e\xf8\x1e\x6c\xa9\xb0\x3f\xec\xb6\xd2\x78\x73\xd4\xe8\x0d\x5a\x88\xe8\x79\x8d\x85\x5f\xf6\xfa\xf0\xd3\x03\xf5\xe0\x81\xe4\xbe\x97\x16\x9e\xc3\x8b\x2f\x43\xd2\x6b\xbc\x81\xdb\xe0\x49\x40\xe5\x30\x4a\xa3\xf9\xee\xf2\xf5\x65\xef\x46\x58\xa1\xc4\x1c\xfb\x27\x3c\xef\xb1\xad\x56\x22\x36\xfc\xe4\x14\x28\x94\x90\x1a\x44\x92\x98\x52\x7b\x32\x7c\xdd\xbb\xab\x8a\xf2\xfb\x91\xaf\xe5\xf1\x68\x24\x92\x04\x9d\xab\xd3\x3d\x7b\x8d\xd4\x11\x39\x71\x83\xd4\x4e\xa6\xd8\xf2\x0a\x65\x07\xc3\xa9\x39\x52\xd0\xe4\x58\x0b\xcc\x8d\xa3\x4d\xe6\x08\x2b\x4b\x73\x86\x93\x3a\xe1\x41\x3b\x45\xb2\xb6\x03\xa3\x41\x80\x32\x3c\xdd\x73\x8c\x83\xb0\x0b\x37\x0c\xf9\x9e\xb6\xa5\x9c\xa3\xcd\x6a\xb8\x09\xe4\x36\x54\xb9\xa3\xdf\x6a\x07\x34\xe0\x9d\x74\x9e\x1b\x48\xd2\x52\x3a\x08\x48\x96\x7a\x31\x80\xc2\x14\x9c\xa7\x0f\xec\x25\xaf\xa6\xbf\x4e\xaf\x9a\xe2\x7f\xb8\x13\xeb\x16\xff\x69\x33\x01\x81\xa5\xf1\xc2\x63\xfa\x74\x4f\xcf\xbe\x07\x50\xe3\x07\x00\x45\xf2\xd7\xb5\xf1\x7d\xeb\x38\x4a\x38\xbf\x76\xcc\x02\xc3\xf8\xd2\x56\xc0\x95\xca\xbb\xad\xdc\xbd\x9d\x1c\x4c\x51\x57\x08\x52\x8a\xd3\x0e\x25\xf6\x3d\x9d\x75\x34\xb8\x6f\x03\x4f\x40\xa0\x69\x25\x00\x5e\xaf\x3b\x34\x11\x72\x3e\x6b\x68\x4a\x4f\x4e\xa7\x2a\xbd\x4e\x71\x0b\xe1\x3e\x38\xf6\x6d\x4c\x72\x73\xb9\x38\xd7\xbe\x57\x2f\x9e\x6b\x78\x0e\xf5\x03\xa5\x6e\x78\xbe\x11\x2b\x7b\x72\x60\x27\x45\x85\x1e\x61\x2d\xe2\x14\xb6\x5e\x91\xa0\x70\x68\x36\x8d\x45\xbf\x5b\x82\x8f\xa3\x34\x32\xcb\x13\x8b\x7e\x88\xbf\x97\x42\xb9\xde\x71\xd3\x12\x84\x13\x78\xc3\x45\x6c\xdc\x94\xb1\xba\xce\x11\xcf\x46\x93\x11\x05\x06\xb6\x68\x8d\x9a\x2d\x9d\x87\xda\x94\xe2\xa3\x12\xa2\x88\x98\x1c\x1a\x8f\x45\xf8\xed\xeb\x32\x3b\x6d\x02\x78\xda\x94\xfd\x4c\x48\x55\x5a\x7c\x7a\x0a\x7b\x92\x8b\x2b\x6d\x26\x12\xf6\xa5\x43\xe0\x11\xd4\x81\x33\x39\x2e\xcd\x2a\x28\xb0\x2f\x45\xed\x82\xa3\xc1\xc1\x56\x91\xe0\xbb\x14\xe1\xa0\x74\x62\x81\x2d\x70\x34\x06\xaf\x1d\xb5\x77\x2e\xfe\xd3\xd0\x79\xd6\x3c\x7e\x03\x45\x61\x97\x6f\x42\xe3\x31\x6c\xec\xf5\xf2\x4e\x2f\x53\x13\x71\x47\xd3\x7a\xa8\x55\x0d\x0d\x47\x83\x9c\x7f\xc5\xef\xff\x19\xc7\x07\xcf\xc7\xdf\x43\x03\x6d\x9b\x36\x9c\x71\x93\x38\x9c\x74\xdd\xc4\x7c\x1b\x05\xcd\xea\x43\x00\x78\xa8\x3f\x22\xa8\xea\xdf\x30\xf1\x6b\xb8\x72\x4b\x43\x4f\x85\xc5\x5b\x69\x4a\xaa\x56\xf8\xbf\x34\xff\x35\xfd\xdd\x7d\xb7\x73\x1f\xef\xbc\xd8\x7d\xed\x4b\xaf\xd5\x32\xde\xd9\x86\xd6\xa8\x55\x2b\x0c\x17\xd2\x78\x15\x96\x85\xdb\xd4\x0e\xf3\x3f\x72\xf9\x15\xe3\xdd\x9b\x82\x6a\x7f\x2c\x45\xca\xa2\x48\xab\xa6\xfa\x0d\x42\xd7\x01\x4b\xa1\xd3\x38\x79\x88\x34\x95\x24\x8f\xb1\x48\x1a\x8a\x85\x90\xba\xbb\xd7\x8c\xdf\x2c\xb9\xfb\x90\xb1\xd3\xc8\xb6\xab\x66\x9c\x18\x69\xbc\x63\x8d\xbb\x07\x54\xc7\xad\x58\xda\xbe\xc7\x8b\x57\x81\x46\xbb\x32\xe7\xb6\x17\xc4\xad\x90\x4a\xd0\xa8\xc5\xed\x94\x4e\x21\x51\x28\x74\xb8\xbd\xc7\xcc\x9b\x5b\xb4\xae\x7b\x00\xc8\xff\x0c\xc6\xb7\x92\x63\xfd\x18\xcd\x71\x78\xcc\x1e\x1a\xb1\xe1\xf8\x6f\x94\xf0\x3e\xc2\xab\x65\xde\x10\x59\xd2\xf3\x87\x1d\xd4\xbe\x7b\x58\x48\x71\x83\x44\x34\x3f\xc1\x71\xab\x09\xff\xab\x04\xd9\x2e\xc4\x2e\x9a\x66\x2c\x1e\xde\x1b\x33\x00\x85\x82\x47\xa2\xfa\xb3\x4b\xdd\x7c\x3e\x36\xa1\xd5\xd1\x1b\xda\xb7\x9d\xf0\xe5\x4b\xac\x25\xd6\xd7\x1d\xa1\x8f\x9f\x23\x6a\x90\x1e\xad\xa0\xe1\x87\xd0\x15\xbf\x14\x90\x96\x8e\xc5\xb1\x5f\x24\x05\x5d\x14\x1c\xaf\xed\xa9\x3e\x4b\xbd\x18\x76\x3b\xe1\x7d\x2b\xde\x13\x7f\xb7\x8e\xf7\x50\x0c\x99\x33\x5e\x00\x34\xf3\x7f\xe2\xef\xb8\x67\xe4\x19\x79\xeb\x12\x80\xd6\xe8\x55\x18\xa0\xb7\x46\x7e\x66\x8c\x63\xff\xf6\xcd\x22\xad\xf1\xbb\x0d\x80\x33\xe9\x42\xb8\x20\x66\x2b\x24\xfc\xdd\x6e\x44\xd4\x0c\x14\x0c\x27\xfb\x19\x68\x69\x0f\xd3\xd6\x35\x04\x11\xf3\xab\xb0\x1a\x0a\xfb\x49\x7b\x35\xbc\x8a\x07\x95\x79\xcb\x36\x32\x67\xdb\xdc\x9f\xee\x4f\x72\xc7\x35\x1e\xf7\x27\x33\xb2\x79\x03\xd8\x07\x58\xdb\x83\xc5\x2e\xc9\x63\xa9\x92\xa5\xd7\x99\xed\x01\x56\x96\xde\x6a\x3d\xfc\xdd\xe1\x22\x1b\xe2\xb6\x8a\x1b\x34\xfb\x84\xc4\x3c\x13\xe9\x82\x65\x6b\x01\x01\xd5\x41\x57\x46\xb4\xfc\x07\x46\x89\xed\xf8\xa9\x97\xc0\x62\xf8\xb0\xc0\x0d\x29\x85\x8f\x99\x73\xf1\x2f\x1d\xcd\x8c\xeb\xb8\x48\xd1\x49\x8b\x29\x64\x12\x55\x0a\x26\x45\xcb\x13\xe9\x6f\xce\xe8\xf0\x09\x09\xad\x24\x89\xe1\x53\x59\xf8\x6a\xcd\x1f\xf0\xb4\x4c\xd0\x57\x90\xa1\xe0\x6f\x41\xde\x40\x21\x9c\x83\x1c\x05\xcd\xa0\x59\xa9\x54\x05\xc6\xa6\x48\xc2\x9b\xa1\x8c\x42\xd2\x40\xe9\xd0\x3a\x58\x2d\x4d\x2c\x93\xdc\xa5\x15\xd4\x74\x4a\x3f\x88\xf7\x2e\xd2\x15\x4a\x54\x20\x3d\x95\xe4\x78\xa8\x76\x94\x36\x1f\x60\xf8\x2b\x8e\xa1\xaa\xbb\x1b\xa2\xf5\x5c\xb7\x19\xa3\xfc\x9a\x9e\x36\xa3\x33\xce\x35\x9b\x71\xb9\xbe\x91\xda\x0c\xc2\xba\x6c\x6c\x46\x5a\xbb\x08\x6d\x86\x13\xaf\xf0\xd3\x66\x20\xb5\xfa\x65\x5e\x60\x70\x34\x0c\xfc\xb4\x15\x5a\xac\x65\x8c\xad\xf0\xb9\xb1\x21\xe7\xa7\x41\x04\x0c\x79\xb1\x47\xc6\xb9\xc1\x8a\x32\x71\xb0\x51\xab\xac\x84\x17\x9f\x6f\xb0\xfa\xb2\xbf\x8a\x44\x38\xb6\xe8\x9a\xb2\x51\x43\x3a\xac\x3d\x12\xc8\x8d\x16\x72\x7c\x7c\x0a\xf2\xc7\x36\x43\x5d\xf9\x40\x3e\x7b\x56\xef\xd9\x5e\xff\x2c\xbf\xd4\xd1\xd9\x20\x7e\x6b\xbd\xbf\xa1\x51\x8c\x91\x40\x43\x41\xd1\xbd\xef\xfe\x33\x00\x00\xff\xff\xb5\x25\x8b\x4d\x94\x21\x00\x00")
+var _call_tracerJs = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xd4\x59\xdf\x73\xdb\x36\xf2\x7f\x96\xfe\x8a\x4d\x1e\x6a\x69\xa2\x50\x8e\xd3\x6f\xbf\x33\x72\xd5\x1b\x9d\xa3\xa4\x9a\x71\xe3\x8c\xad\x34\x93\xf1\xf8\x01\x22\x97\x12\x6a\x10\x60\x01\x50\x32\x9b\xfa\x7f\xbf\x59\x10\xa0\x48\x49\x76\x9c\xde\xdc\x4d\xef\x8d\x04\xb0\x8b\xc5\xee\x67\x7f\x01\xc3\x21\x9c\xa9\xbc\xd4\x7c\xb9\xb2\x70\x72\xfc\xea\xff\x61\xbe\x42\x58\xaa\x97\x68\x57\xa8\xb1\xc8\x60\x52\xd8\x95\xd2\xa6\x3b\x1c\xc2\x7c\xc5\x0d\xa4\x5c\x20\x70\x03\x39\xd3\x16\x54\x0a\x76\x67\xbd\xe0\x0b\xcd\x74\x19\x75\x87\xc3\x8a\xe6\xe0\x34\x71\x48\x35\x22\x18\x95\xda\x0d\xd3\x38\x82\x52\x15\x10\x33\x09\x1a\x13\x6e\xac\xe6\x8b\xc2\x22\x70\x0b\x4c\x26\x43\xa5\x21\x53\x09\x4f\x4b\x62\xc9\x2d\x14\x32\x41\xed\xb6\xb6\xa8\x33\x13\xe4\x78\xf7\xfe\x23\x9c\xa3\x31\xa8\xe1\x1d\x4a\xd4\x4c\xc0\x87\x62\x21\x78\x0c\xe7\x3c\x46\x69\x10\x98\x81\x9c\x46\xcc\x0a\x13\x58\x38\x76\x44\xf8\x96\x44\xb9\xf2\xa2\xc0\x5b\x55\xc8\x84\x59\xae\xe4\x00\x90\x93\xe4\xb0\x46\x6d\xb8\x92\xf0\x3a\x6c\xe5\x19\x0e\x40\x69\x62\xd2\x63\x96\x0e\xa0\x41\xe5\x44\xd7\x07\x26\x4b\x10\xcc\x6e\x49\x9f\xa0\x90\xed\xb9\x13\xe0\xd2\x1d\x6f\xa5\x72\x04\xbb\x62\x96\x34\xb1\xe1\x42\xc0\x02\xa1\x30\x98\x16\x62\x40\xdc\x16\x85\x85\x4f\xb3\xf9\xcf\x17\x1f\xe7\x30\x79\xff\x19\x3e\x4d\x2e\x2f\x27\xef\xe7\x9f\x4f\x61\xc3\xed\x4a\x15\x16\x70\x8d\x15\x2b\x9e\xe5\x82\x63\x02\x1b\xa6\x35\x93\xb6\x04\x95\x12\x87\x5f\xa6\x97\x67\x3f\x4f\xde\xcf\x27\xff\x9c\x9d\xcf\xe6\x9f\x41\x69\x78\x3b\x9b\xbf\x9f\x5e\x5d\xc1\xdb\x8b\x4b\x98\xc0\x87\xc9\xe5\x7c\x76\xf6\xf1\x7c\x72\x09\x1f\x3e\x5e\x7e\xb8\xb8\x9a\x46\x70\x85\x24\x15\x12\xfd\xd7\x75\x9e\x3a\xeb\x69\x84\x04\x2d\xe3\xc2\x04\x4d\x7c\x56\x05\x98\x95\x2a\x44\x02\x2b\xb6\x46\xd0\x18\x23\x5f\x63\x02\x0c\x62\x95\x97\x4f\x36\x2a\xf1\x62\x42\xc9\xa5\x3b\xf3\x83\x80\x84\x59\x0a\x52\xd9\x01\x18\x44\xf8\x71\x65\x6d\x3e\x1a\x0e\x37\x9b\x4d\xb4\x94\x45\xa4\xf4\x72\x28\x2a\x76\x66\xf8\x53\xd4\x25\x9e\x31\x13\x62\xae\x59\x8c\x9a\xd0\xca\x20\x2d\x48\xfd\x42\x6d\x24\x58\xcd\xa4\x61\x31\x99\x9a\xbe\x69\x89\x33\x12\xde\xd1\x9f\x35\x04\x5a\xd0\x98\x2b\x4d\xdf\x42\x04\x9c\x71\x69\x51\x4b\x26\x1c\x6f\x03\x19\x4b\x10\x16\x25\xb0\x26\xc3\x41\xf3\x30\x04\xa3\xca\xdc\xc0\x65\xaa\x74\xe6\x60\x19\x75\xbf\x74\x3b\x5e\x42\x63\x59\x7c\x4b\x02\x12\xff\xb8\xd0\x1a\xa5\x25\x55\x16\xda\xf0\x35\xba\x25\x50\xad\xf1\xfa\x9c\xfe\xfa\x0b\xe0\x1d\xc6\x45\xc5\xa9\x53\x33\x19\xc1\xf5\x97\xfb\x9b\x41\xd7\xb1\x4e\xd0\xc4\x28\x13\x4c\x48\xb4\xf8\xd6\xc0\x66\xe5\x34\x0a\x1b\x3c\x5a\x23\xfc\x56\x18\xdb\x58\x93\x6a\x95\x01\x93\xa0\x0a\x42\x7c\x53\x3b\x5c\x5a\xe5\x18\x32\xfa\x96\xa8\x9d\x44\x51\xb7\x53\x13\x8f\x20\x65\xc2\xa0\xdf\xd7\x58\xcc\xe9\x34\x5c\xae\xd5\x2d\x26\x0e\x3c\xb8\x46\x5d\x82\xca\x63\x95\x78\x67\xa0\xb3\xd6\xc7\x40\x13\x75\x3b\x44\x37\x82\xb4\x90\x6e\xdb\x9e\x50\xcb\x01\x24\x8b\x3e\x7c\xe9\x76\x68\xf7\x33\x96\xdb\x42\xa3\x73\x4b\xd4\x5a\x69\x03\x3c\xcb\x30\xe1\xcc\xa2\x28\xbb\x9d\xce\x9a\xe9\x6a\x02\xc6\x20\xd4\x32\x5a\xa2\x9d\xd2\x6f\xaf\x7f\xda\xed\x74\x78\x0a\xbd\x6a\xf6\xd9\x78\xec\xa2\x4f\xca\x25\x26\x15\xfb\x8e\x5d\x71\x13\xa5\xac\x10\xb6\xde\x97\x88\x3a\x1a\x6d\xa1\x25\x7d\xde\x57\x52\x7c\x42\x50\x52\x94\x10\x53\x94\x61\x0b\x72\x4f\x53\x1a\x8b\x99\x3f\x9c\x19\x40\xca\x0c\xa9\x90\xa7\xb0\x41\xc8\x35\xbe\x8c\x57\x18\xdf\x82\x92\x31\x7a\x29\x4d\x69\x48\x85\x30\x06\xda\x2d\x52\x79\x64\xd5\xfb\x22\x5b\xa0\xee\xf5\xe1\x3b\x38\xbe\x4b\x8f\xfb\x30\x1e\xbb\x8f\x20\xbb\xa7\xf1\xf2\xd2\x59\x55\xee\x0f\xea\xe8\xaf\xac\xe6\x72\xd9\xeb\x37\x64\x9d\xa5\xc0\x40\xe2\x06\x62\x25\x09\x02\x96\xac\xb2\x40\x2e\x97\x10\x6b\x64\x16\x93\x01\xb0\x24\x01\xab\x1c\xaa\xb6\x38\x6b\x6f\x09\xdf\x7d\x07\x3d\xda\x6c\x0c\x47\x67\x97\xd3\xc9\x7c\x7a\x04\x7f\xfe\x09\xad\x91\x93\xa3\x7e\x43\x32\x2e\x2f\xd2\xd4\x0b\xe7\x70\x19\xe5\x88\xb7\xbd\x57\xfd\x68\xcd\x44\x81\x17\x69\x25\xa6\x5f\x3b\x95\x09\x8c\x3d\xcd\x8b\x5d\x9a\x93\x16\x0d\x99\x64\x38\x84\x89\x31\x98\x2d\x04\xee\x3b\xa4\xf7\x58\xe7\xbc\xc6\x2a\x5d\x85\xae\x58\x65\xb9\x40\x42\x55\xd8\xd5\xab\xdf\x49\xdc\xb1\x65\x8e\x23\x00\x00\x95\x0f\xdc\x00\xf9\x82\x1b\xb0\xea\x67\xbc\x73\x36\x0a\x2a\x24\x54\x4d\x92\x44\xa3\x31\xbd\x7e\xbf\x5a\xce\x65\x5e\xd8\x51\x6b\x79\x86\x99\xd2\x65\x64\x28\x20\xf5\xdc\xd1\x06\xd5\x49\x03\xcd\x92\x99\x99\x24\x1a\x8f\xd4\x77\xcc\xf4\xb6\x53\x67\xca\xd8\x51\x98\xa2\x9f\x30\xe7\x74\x41\x64\x47\xc7\x77\x47\xfb\xda\x3a\xee\x6f\x91\xf0\xea\x87\x3e\xb1\xbb\x3f\xad\xf1\x5d\x87\x89\x28\x2f\xcc\xaa\x47\xbf\xfd\xed\xec\x36\x14\x8c\xc1\xea\x02\x0f\xc2\xdf\x41\x6a\x1f\x4e\x06\x45\x4a\xb1\xc4\xea\x22\x76\xb0\x5a\x32\x17\x69\x9c\xa7\x33\x8a\xbc\xa6\x58\xd0\x7e\x60\x95\xda\x47\x97\x87\xd2\xd5\xf4\xfc\xed\x9b\xe9\xd5\xfc\xf2\xe3\xd9\xfc\xa8\x01\x27\x81\xa9\x85\x31\xec\x9c\x41\xa0\x5c\xda\x95\x93\x9f\xfc\xa3\x3d\x7b\x4d\x34\x2f\x5f\xdd\x54\x23\x30\x3e\xe0\xf2\x9d\xc7\x29\xe0\xfa\xc6\xf1\xbe\xef\x7e\x65\x69\xa5\xcc\x2f\x15\x88\x54\x7e\xdf\x0c\x1c\x07\x7c\x31\x43\xbb\x52\x54\x1c\xac\x55\xec\x32\xc1\x56\x8b\x89\x92\xf8\xed\x1e\x39\x39\x3f\x6f\xf9\xe3\xe4\xfc\xfc\xec\xe2\x4d\xcb\x47\xdf\x4c\xcf\xa7\xef\x26\xf3\xe9\xee\xda\xab\xf9\x64\x3e\x3b\x73\xa3\xc1\x7d\x87\x43\xb8\xba\xe5\xb9\x8b\xb2\x2e\x76\xa9\x2c\x77\xe5\x62\x2d\xaf\x19\x80\x5d\x29\x2a\xc4\xb4\x4f\x22\x29\x93\x71\x08\xee\x26\x18\xcd\x2a\x32\x99\x0a\xbe\xb2\x03\xd4\x57\x6d\xa0\xf6\x6b\x33\x72\xf3\x41\x23\xf9\x2b\x17\x98\xf4\xac\x0a\x72\x6d\x15\xea\x34\xea\x70\xa1\x5c\x90\xe9\x3d\xfd\x90\xf0\x0f\x38\x86\x11\xbc\xf2\x91\xe4\x91\x50\x75\x02\x2f\x40\xa5\xe9\x5f\x08\x58\xaf\x0f\x50\xfe\x3d\xc3\x96\x55\x8e\x3a\x2c\xb7\xea\xbf\x1f\xce\x54\x61\x2f\xd2\x74\x04\xbb\x4a\xfc\x7e\x4f\x89\xf5\xfa\x73\x94\xfb\xeb\xff\x6f\x6f\xfd\x36\xf4\x11\xaa\x54\x0e\xcf\xf6\x20\x52\x05\x9e\x67\x3b\x7e\xe0\x95\x4b\xae\x5d\x19\x1f\xc6\x0f\x04\xdb\x93\x36\x86\x1f\x8a\x16\xff\x56\xb0\x3d\x58\xaa\x51\x41\xd6\x2e\xc6\x06\xa0\xd1\x6a\x8e\x6b\x6a\xb7\x8e\x8c\x63\x49\x45\xab\xda\x30\x19\x63\x04\x9f\x68\x83\xe1\x10\x24\x52\x35\xa8\x42\x91\x0b\x3c\x05\xca\x75\xae\x50\xf5\xed\x0a\xb1\xa3\x1e\x8b\xe2\x37\x42\xc6\x4a\x6a\x57\xd2\x42\xde\x96\xb0\x64\x06\x92\x52\xb2\x8c\xc7\xe4\xe6\xc3\xa1\xa3\x03\x8d\x4b\xa6\x1d\x5b\x8d\xbf\x17\x68\xa8\xf7\xa1\xfc\xcb\x62\x5b\x30\x21\x4a\x58\x72\x6a\x60\x88\xba\x77\xf2\xfa\xf8\x18\x8c\xe5\x39\xca\x64\x00\x3f\xbc\x1e\xfe\xf0\x3d\xe8\x42\x60\x3f\xf2\x11\xae\xad\x1d\x6f\x0d\x32\xa1\x47\xcf\x1b\xcc\xed\xaa\xd7\x87\x9f\x1e\xc8\x07\xc1\x7e\xed\xc9\xeb\x83\x6b\xe1\x25\xbc\xba\x89\x48\xae\xba\x60\x74\x69\xb8\xb2\x24\xa0\x30\xe8\xb9\x51\x17\x7c\xf1\xe6\xa2\x77\xcb\x34\x13\x6c\x81\xfd\x91\x6b\xb2\x9d\xae\x36\xcc\x77\x01\x64\x14\xc8\x05\xe3\x12\x58\x1c\xab\x42\x5a\x52\x7c\x28\xe8\x45\x09\x89\x92\x47\x36\xf0\x73\xfd\x12\x8b\x63\x34\x26\x84\x7b\x67\x35\x12\x87\x65\x44\x0d\x5c\x1a\x4e\x7c\xc3\x4e\xa4\x54\xa3\x5c\x68\xf6\x2b\xa8\x9d\x0c\x0c\x33\x65\xac\x70\xd6\xda\x68\xea\xa4\x0c\x97\x31\xc1\x01\x12\x24\x6d\x1b\x50\x12\x18\x08\xe5\x5a\x7e\x57\xb2\x00\xd3\x4b\x13\x55\xf1\x9e\xb6\xa5\x52\x49\xaa\x4d\xd4\x06\xf2\x16\x77\xe3\xaa\xcc\xdf\x29\x07\x24\xe0\x1d\x37\x96\x12\x98\xd3\x07\x37\x04\xc6\x42\x4b\x2e\x97\x03\xc8\x55\x4e\x9e\xf9\xd5\x74\xe6\x83\xf5\xe5\xf4\xd7\xe9\x65\x9d\xfc\x9f\x6e\xc4\x50\xf7\x3f\xaf\xdb\x22\xd0\xd4\x73\x58\x4c\x9e\x1f\x28\xe4\x0f\x00\x6a\xfc\x00\xa0\x88\xbf\x17\x67\x38\x84\x0f\x8d\xe3\x08\x66\xec\xd6\x30\x4b\xb4\x6e\xb4\x29\x80\x29\x84\x35\x3b\xb1\x7b\x67\x93\x5c\xe5\x21\x43\x90\x50\xc4\x2e\xa2\xc0\xbe\x5b\x6d\x1f\x9a\x38\xa9\xa3\x55\x65\x8a\x5a\xc7\x04\xc9\x46\x99\xe6\x8a\x7d\x77\x15\x52\x47\x09\xb7\x34\xcc\xb3\x2a\x31\xb8\xec\xa3\x0a\x4b\xc8\x88\x55\x82\xdb\x38\xb8\x64\xe6\xa3\xc1\x64\x1b\x09\x17\x7c\x39\x93\xb6\x17\x26\x67\x12\x5e\x42\xf8\xa1\xf8\x0e\x2f\x5b\x0e\x75\x20\x50\x76\x12\x14\x68\xb1\xa6\x9a\xc9\x53\xd8\x19\x22\x46\x95\x66\x9c\xfe\x34\xda\xfd\x3c\x7d\xec\xb9\x91\xee\x9e\x69\xb4\x11\xfe\x5e\x30\x61\x7a\xc7\x75\xdd\xe0\x9a\xe3\xc8\x2a\x97\xe9\xc6\x75\xae\x0b\xc9\x90\x68\x9a\xc2\xf9\x52\xc4\x1f\xdc\x6b\x23\x90\x25\x0b\x3a\xd2\x99\x4a\xf0\x51\x0e\x9e\x85\x8f\x20\xb5\x59\x3d\x46\x0f\x95\xa2\x9d\xe6\x02\x78\x5e\xd7\x06\x29\xe3\xa2\xd0\xf8\xfc\x14\x0e\x44\x20\x53\xe8\x94\xc5\x2e\x3e\x18\x04\xd7\xbc\x1a\x30\x2a\xc3\x95\xda\x54\x02\x1c\x8a\x63\x8f\xe1\xa4\x9d\x49\x08\x23\x14\x16\x0a\xc3\x96\xd8\x00\x47\xad\xf0\x60\x28\x78\xf6\xf0\x99\xbe\x1d\x3a\x2f\xea\xdf\xaf\xa0\xa8\xdb\x79\x12\x34\x1e\xc3\xc6\x41\x2b\xef\x15\x3c\x61\x91\xeb\xe2\x1a\x3f\x41\xd4\xaa\x2a\xa9\x91\xf3\x2d\x76\xff\xcf\x18\xbe\xb2\x7c\xe7\xfe\x9b\x1c\x6d\x77\x6d\x55\x9b\xb5\x17\x57\x27\xdd\x56\x3a\x5f\x47\x41\x3d\xfb\x10\x00\x0e\xc4\x86\x7b\x1f\x6c\x67\xf2\x37\x8c\xed\x16\xae\xae\xee\xa1\xbf\x5c\xe3\x9a\xab\x82\x52\x1a\xfe\x2f\x35\x89\x75\x11\x78\xdf\xed\xdc\xfb\xdb\x32\xe7\xb7\xcd\xeb\xb2\xcd\xca\xdf\xf6\x56\xf5\xd3\xf6\xa2\x8f\xf2\x36\x5d\xd0\xb9\x7b\x26\x87\x10\xba\x35\x73\xf4\x8f\x5c\x9b\x79\x7f\xb7\x2a\xcf\x54\x9d\xaf\x84\x46\x96\x94\x75\x8a\x1c\x54\xa5\x09\xac\x98\x4c\x7c\x7b\xc2\x92\x84\x13\x3f\x17\x84\x48\x42\xb6\x64\x5c\xfa\xd4\xb9\x73\xd2\x83\x3a\x6f\xe6\xe5\x43\xc8\xd8\xab\x76\x9b\xa9\xd5\xb7\x95\xd4\x03\x3a\x89\xbb\x4f\x48\xa1\x3b\xbe\xb4\x7b\x03\xe8\x2f\x11\x95\x34\x45\xe6\x6a\x63\x60\x6b\xc6\x05\xa3\x7e\x8c\x62\x0d\xc5\xb7\x58\x20\x93\xae\xbe\x22\xe3\x29\x7a\x31\xf0\x27\x7e\x14\xe4\x7f\x05\xe3\x3b\xc1\x31\xfc\x7a\x75\x3c\xdd\x67\x9f\xea\xb1\xd5\xf1\xdf\x0a\x66\xad\x87\x57\x43\xbd\x95\x67\x71\xeb\x9e\x84\x50\xda\xee\xd3\x5c\x8a\xa0\xe0\xd6\xfc\x04\xc7\x5e\x15\x7f\x27\x27\xdb\x87\xd8\x79\x5d\xb1\xf9\xc3\x5b\xa5\x06\x20\x90\x4a\x71\x6e\xc3\x83\x4d\xa8\x50\xdb\x5b\xb5\x99\x07\xef\xad\x6a\xbc\x3d\xf7\x25\x9d\x12\x2b\x7f\x27\x52\x3d\x8e\x2c\x10\x25\x70\x8b\x9a\x6e\x5e\x81\xd0\xe5\xdf\x18\xc8\x11\x8c\x0b\x06\x44\x93\x72\x4a\x00\x9e\xb1\xbf\xf0\xa7\x3a\x8d\xcb\x65\xd4\xed\x54\xe3\x0d\x7f\x8f\xed\xdd\xd6\xdf\xc9\x6a\x9e\xd2\xdf\x12\xd4\x97\x04\xb1\xbd\x73\xf5\xe3\xa0\xbb\x7f\x53\x40\x73\xd4\x07\x56\xcd\xfc\xce\xbd\x00\x4d\x86\xbb\x81\xdd\xeb\x47\x9a\x73\x63\x2d\x80\x3b\x2e\x4b\x66\x2a\x36\x3b\x2e\x61\xef\xf6\x3d\x22\x10\x90\x33\x8c\x0e\x13\xd0\xd4\x01\xa2\x9d\xbb\x0a\x92\xc7\x0d\x55\xe2\x56\x89\x7d\xd4\x9c\xad\x86\xfc\x41\x79\xd6\xd0\x0d\xcf\x90\x46\xef\x03\xb2\x77\x90\x76\x1c\xf0\x78\x38\x98\x91\xce\x6b\xc0\x3e\x40\x1a\x90\x78\x98\xfb\x63\xa1\xd2\x71\x0f\x91\xed\x01\xd2\xd3\x6e\xbb\xf4\xb0\x77\x4f\x67\x59\x2f\x6e\x8a\xd8\x5a\x73\x88\x89\x8f\x33\x7e\x5d\xa5\xd9\xc0\xa0\xf2\xbd\x2a\x76\x38\x44\xf3\x3f\xd0\x73\x6c\xfa\x4f\x98\xa2\xe7\x2e\xea\x52\xd0\x15\xa4\xe4\x3e\x6a\xe1\x92\x7f\x61\xa8\xb1\xdc\xfa\x45\x82\x86\x6b\x7a\x54\xe2\x28\x12\x50\xf4\x86\x4c\x6d\xeb\x6f\x86\xee\xf6\xe9\xf1\x09\x35\x67\x82\xff\xe1\xae\x2a\xa3\xea\xbd\xdb\x3d\xfd\x49\x1e\xa3\x2d\x21\x45\xe6\x5e\x91\xac\x82\x9c\x19\x03\x19\x32\x6a\x54\xe9\x61\xb0\x04\xa5\x13\x24\xe6\x75\xe7\x46\x2e\xa9\xe8\xb1\x56\xd3\xeb\x99\xf2\x69\xd2\x95\xe7\x39\x15\x9d\xdc\x0e\xfc\xe5\x0c\x37\xb9\x60\x25\x70\x4b\x29\xd9\x1f\xaa\xe9\xa5\xf5\xd3\x0d\xb9\xa8\x51\x9a\x42\xc0\x9e\x8b\x86\x1e\xaf\xed\xa3\x84\x1d\xe7\x9e\x6d\xef\xf4\x7d\x4d\xdb\x2f\xb7\xd7\x56\x6d\x27\x0c\x69\xa3\xed\x69\x61\x94\xfe\xda\xee\xe4\x66\x9c\x27\xb5\x1d\x29\xe4\x94\x30\xe1\x40\x53\x13\xb8\xbf\x1d\xd7\x22\x82\xe0\x5b\x2e\x43\x9b\x7a\xb9\xfb\x1b\x78\xc0\x90\x15\x7b\xa4\x9c\x5b\x2c\x29\x12\x57\x3a\xf2\x48\x23\x38\x56\x03\xd7\xb7\x58\xde\x1c\xce\x22\x1e\x8e\x8d\x75\x75\xda\x08\x90\xae\xe6\x1e\x71\xe4\x5a\x0a\x3e\x3e\x3e\x05\xfe\x63\x93\x20\x64\x3e\xe0\x2f\x5e\x84\x3d\x9b\xf3\xd7\xfc\x26\x78\x67\x40\x40\x6b\xc3\x6b\x7e\xb3\xad\x6f\x1b\x3e\x52\xad\x39\xed\x76\xee\xbb\xf7\xdd\x7f\x0d\x00\xc9\x42\x24\x52\xce\x21\x00\x00")
 
 func call_tracerJsBytes() ([]byte, error) {
 	return bindataRead(
@@ -172,7 +172,7 @@ func opcount_tracerJs() (*asset, error) {
 	return a, nil
 }
 
-var _prestate_tracerJs = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xa4\x57\xdd\x6f\x1b\xb9\x11\x7f\xde\xfd\x2b\xa6\x7e\x91\x84\x53\x56\xce\x15\xb8\x02\x72\x5d\x60\xa3\x28\x89\x00\x9d\x6d\x48\x4a\x5d\xf7\x70\x0f\x5c\x72\x76\xc5\x13\x45\x2e\x48\xae\x3e\x10\xf8\x7f\x2f\x86\xfb\x21\xcb\x67\x27\x6e\xeb\x27\x2f\x39\xfc\xcd\xf7\x6f\x46\xa3\x11\x4c\x4c\x79\xb4\xb2\x58\x7b\xf8\xf9\xf2\xfd\xdf\x60\xb5\x46\x28\xcc\x3b\xf4\x6b\xb4\x58\x6d\x21\xad\xfc\xda\x58\x17\x8f\x46\xb0\x5a\x4b\x07\xb9\x54\x08\xd2\x41\xc9\xac\x07\x93\x83\x7f\x26\xaf\x64\x66\x99\x3d\x26\xf1\x68\x54\xbf\x79\xf1\x9a\x10\x72\x8b\x08\xce\xe4\x7e\xcf\x2c\x8e\xe1\x68\x2a\xe0\x4c\x83\x45\x21\x9d\xb7\x32\xab\x3c\x82\xf4\xc0\xb4\x18\x19\x0b\x5b\x23\x64\x7e\x24\x48\xe9\xa1\xd2\x02\x6d\x50\xed\xd1\x6e\x5d\x6b\xc7\xe7\x9b\xaf\x30\x47\xe7\xd0\xc2\x67\xd4\x68\x99\x82\xbb\x2a\x53\x92\xc3\x5c\x72\xd4\x0e\x81\x39\x28\xe9\xc4\xad\x51\x40\x16\xe0\xe8\xe1\x27\x32\x65\xd9\x98\x02\x9f\x4c\xa5\x05\xf3\xd2\xe8\x21\xa0\x24\xcb\x61\x87\xd6\x49\xa3\xe1\xaf\xad\xaa\x06\x70\x08\xc6\x12\x48\x9f\x79\x72\xc0\x82\x29\xe9\xdd\x00\x98\x3e\x82\x62\xfe\xf4\xf4\x0d\x01\x39\xf9\x2d\x40\xea\xa0\x66\x6d\x4a\x04\xbf\x66\x9e\xbc\xde\x4b\xa5\x20\x43\xa8\x1c\xe6\x95\x1a\x12\x5a\x56\x79\xb8\x9f\xad\xbe\xdc\x7e\x5d\x41\x7a\xf3\x00\xf7\xe9\x62\x91\xde\xac\x1e\xae\x60\x2f\xfd\xda\x54\x1e\x70\x87\x35\x94\xdc\x96\x4a\xa2\x80\x3d\xb3\x96\x69\x7f\x04\x93\x13\xc2\xaf\xd3\xc5\xe4\x4b\x7a\xb3\x4a\x3f\xcc\xe6\xb3\xd5\x03\x18\x0b\x9f\x66\xab\x9b\xe9\x72\x09\x9f\x6e\x17\x90\xc2\x5d\xba\x58\xcd\x26\x5f\xe7\xe9\x02\xee\xbe\x2e\xee\x6e\x97\xd3\x04\x96\x48\x56\x21\xbd\xff\x71\xcc\xf3\x90\x3d\x8b\x20\xd0\x33\xa9\x5c\x1b\x89\x07\x53\x81\x5b\x9b\x4a\x09\x58\xb3\x1d\x82\x45\x8e\x72\x87\x02\x18\x70\x53\x1e\xdf\x9c\x54\xc2\x62\xca\xe8\x22\xf8\xfc\x6a\x41\xc2\x2c\x07\x6d\xfc\x10\x1c\x22\xfc\x7d\xed\x7d\x39\x1e\x8d\xf6\xfb\x7d\x52\xe8\x2a\x31\xb6\x18\xa9\x1a\xce\x8d\xfe\x91\xc4\x84\x59\x5a\x74\x9e\x79\x5c\x59\xc6\xd1\x82\xa9\x7c\x59\x79\x07\xae\xca\x73\xc9\x25\x6a\x0f\x52\xe7\xc6\x6e\x43\xa5\x80\x37\xc0\x2d\x32\x8f\xc0\x40\x19\xce\x14\xe0\x01\x79\x15\xee\xea\x48\x87\x72\xb5\x4c\x3b\xc6\xc3\x69\x6e\xcd\x96\x7c\xad\x9c\xa7\x7f\x9c\xc3\x6d\xa6\x50\x40\x81\x1a\x9d\x74\x90\x29\xc3\x37\x49\xfc\x2d\x8e\x9e\x18\x43\x75\x12\x3c\x6c\x84\x42\x6d\xec\xb1\x67\x11\xb2\x4a\x2a\x21\x75\x91\xc4\x51\x2b\x3d\x06\x5d\x29\x35\x8c\x03\x84\x32\x66\x53\x95\x29\xe7\xa6\x0a\xb6\xff\x81\xdc\xd7\x60\xae\x44\x2e\x73\x2a\x0e\xd6\xdd\x7a\x13\xae\x3a\xbd\x26\x23\xf9\x24\x8e\xce\x60\xc6\x90\x57\x3a\xb8\xd3\x67\x42\xd8\x21\x88\x6c\xf0\x2d\x8e\xa2\x1d\xb3\x84\x05\xd7\xe0\xcd\x17\x3c\x84\xcb\xc1\x55\x1c\x45\x32\x87\xbe\x5f\x4b\x97\xb4\xc0\xbf\x31\xce\x7f\x87\xeb\xeb\xeb\xd0\xd4\xb9\xd4\x28\x06\x40\x10\xd1\x4b\x62\xf5\x4d\x94\x31\xc5\x34\xc7\x31\xf4\x2e\x0f\x3d\xf8\x09\x44\x96\x14\xe8\x3f\xd4\xa7\xb5\xb2\xc4\x9b\xa5\xb7\x52\x17\xfd\xf7\xbf\x0c\x86\xe1\x95\x36\xe1\x0d\x34\xe2\x37\xa6\x13\xae\xef\xb9\x11\xe1\xba\xb1\xb9\x96\x9a\x18\xd1\x08\x35\x52\xce\x1b\xcb\x0a\x1c\xc3\xb7\x47\xfa\x7e\x24\xaf\x1e\xe3\xe8\xf1\x2c\xca\xcb\x5a\xe8\x95\x28\x37\x10\x80\xda\xdb\xae\xce\x0b\x49\x9d\xfa\x34\x01\x01\xef\x7b\x49\x58\xb6\xa6\x3c\x4b\xc2\x06\x8f\x3f\xce\x04\x5d\x48\x71\xe8\x2e\x36\x78\x1c\x5c\xc5\xaf\xa6\x28\x69\x8c\xfe\x4d\x8a\xc3\xcb\xf9\x22\xc0\x1d\x53\x1d\x60\x1d\xbf\x25\x21\x9c\xec\x1a\x04\xdd\x41\x07\xc9\xfe\xe5\x1a\x2e\x2e\x0f\x97\xff\xe7\xdf\x45\x63\xc1\x0b\x25\xf3\xcc\xec\x37\x98\xf6\x78\x9e\x4f\x8b\xae\x52\x9e\xda\x4e\xea\x9d\xd9\x10\x81\xae\x29\x4f\x4a\x85\xd4\x98\x92\xaa\xc6\xd5\x0c\x96\x21\x6a\x90\x1e\x2d\x23\x0a\x37\x3b\xb4\x34\xbd\xc0\xa2\xaf\xac\x76\x5d\x3a\x73\xa9\x99\x6a\x81\x9b\xec\x7b\xcb\x78\xdd\xbb\xf5\xf9\x93\x9c\x72\x7f\x08\xd9\x0c\x3e\x8e\x46\x90\x7a\x20\x3f\xa1\x34\x52\xfb\x21\xec\x11\x34\xa2\x20\x02\x12\x28\x2a\xee\x03\x5e\x6f\xc7\x54\x85\xbd\x9a\x64\x88\xaa\xc3\x53\x53\xd1\x44\x7a\x42\x42\xc3\x60\xe0\xd6\xec\xc2\xa8\xcd\x18\xdf\x40\xd3\xf8\xc6\xca\x42\xea\xb8\x89\xe9\x59\xd3\x93\x45\x09\x01\x07\xb3\x42\xcd\x50\xee\xe9\xe4\x43\xc8\x7f\x26\x8b\x99\xf6\xcf\x8a\xa8\x8e\x7c\xfb\x74\xf0\x7b\xd2\x34\x71\xe2\x88\x78\xfb\x3f\x0f\x86\xf0\xfe\x97\xae\x32\xbd\x21\x28\xf8\x31\x98\x37\xaf\x43\xc5\xcf\x2b\xe2\xe5\x67\x41\x0d\x31\xc9\x4f\x41\x6b\xe2\xaa\x8c\xd2\x51\xfb\x19\xe2\x78\xce\x26\x57\xdf\xc1\x3d\xf7\xad\xc5\x6d\x42\x93\x30\x21\x5e\x07\xad\x53\xf4\x11\xb9\xc5\x2d\x4d\x17\xca\x02\x67\x4a\xa1\xed\x39\x08\xdc\x35\x6c\xca\x29\xe4\x0b\xb7\xa5\x3f\xb6\x33\xc7\x33\x5b\xa0\x77\x3f\x36\x2c\xe0\xbc\x7b\xd7\x52\x71\x08\xc5\xb1\x44\xb8\xbe\x86\xde\x64\x31\x4d\x57\xd3\x5e\xd3\x4c\xa3\x11\xdc\x63\xd8\xc8\x32\x25\x33\xa1\x8e\x20\x50\xa1\xc7\xda\x2e\xa3\x43\x88\x3a\x6a\x1a\xd2\x6a\x45\x4b\x0f\x1e\xa4\xf3\x52\x17\x50\x33\xd6\x9e\xe6\x7b\x03\x17\x7a\x84\xb3\xca\x51\xb5\x3e\x1b\x86\xde\xd0\x66\x63\x91\xf8\x8d\xe6\x50\x68\x37\xa6\x64\xb7\x09\xe5\xd2\x3a\x0f\xa5\x62\x1c\x13\xc2\xeb\x8c\x79\x3d\xbf\x0d\x33\x93\xea\x45\x68\xc1\x00\x74\x1a\xb4\x4c\xd1\xa0\x26\xf5\x0e\xfa\x2d\xc6\x20\x8e\x22\xdb\x4a\x3f\xc1\xbe\x3a\x51\x82\xf3\x58\x3e\x25\x04\x5a\x70\x70\x87\x44\xe5\x81\x0d\xea\xa1\x4c\xba\xfe\xf9\x6b\xb3\x05\xa0\x4b\xe2\x88\xde\x3d\xe9\x6b\x65\x8a\xf3\xbe\x16\x75\x58\x78\x65\x2d\xe5\xbf\x1b\x05\x39\xf5\xf8\x1f\x95\xf3\x14\x53\x4b\xe1\x69\xd8\xe2\x25\xb2\x0e\xd4\x4c\x53\x7f\xf0\xe7\x21\x4a\xf3\x33\xcc\x2b\x52\xd7\x4c\xcb\x7a\xab\x2c\x8d\x47\xed\x25\x53\xea\x48\x79\xd8\x5b\x5a\xa7\x68\x81\x1a\x82\x93\x24\x15\x18\x27\x88\x4a\xcd\x55\x25\xea\x32\x08\x75\xdc\xe0\xb9\x60\xf3\xf9\x1e\xb6\x45\xe7\x58\x81\x09\x55\x52\x2e\x0f\xcd\x26\xab\xa1\x57\x93\x5c\x7f\xd0\x4b\x3a\x23\xcf\x29\x46\x99\x22\x69\x8b\x8c\xb8\x3a\x15\xc2\xa2\x73\xfd\x41\xc3\x39\x5d\x66\xef\xd7\xa8\x29\xf8\xa0\x71\x0f\xdd\x8a\xc4\x38\xa7\x95\x51\x0c\x81\x09\x41\xd4\xf6\x6c\x9d\x89\xa3\xc8\xed\xa5\xe7\x6b\x08\x9a\x4c\x79\xea\xc5\x41\x53\xff\x9c\x39\x84\x8b\xe9\xbf\x56\x93\xdb\x8f\xd3\xc9\xed\xdd\xc3\xc5\x18\xce\xce\x96\xb3\x7f\x4f\xbb\xb3\x0f\xe9\x3c\xbd\x99\x4c\x2f\xc6\xa7\x39\x74\xee\x90\x37\xad\x0b\xa4\xd0\x79\xc6\x37\x49\x89\xb8\xe9\x5f\x9e\xf3\xc0\xc9\xc1\x28\xca\x2c\xb2\xcd\xd5\xc9\x98\xba\x41\x1b\x1d\x2d\xe5\xc2\x35\xbc\x1a\xac\xab\xd7\xad\x99\x34\xf2\xfd\x96\xc8\x4f\x2b\x51\xa0\x8a\xef\xda\x91\xce\xe7\x9d\xe7\xf4\x41\xe1\xe8\x0e\x3e\x4e\xe7\xd3\xcf\xe9\x6a\x7a\x26\xb5\x5c\xa5\xab\xd9\xa4\x3e\xfa\xaf\x43\xf4\xfe\xcd\x21\xea\x2d\x97\xab\xdb\xc5\xb4\x37\x6e\xbe\xe6\xb7\xe9\xc7\xde\x9f\x14\x36\x7b\xd3\xf7\x8a\xcc\x9b\x7b\x63\xc5\xff\x92\xab\x27\xbb\x43\xce\x5e\x5a\x1d\x02\x09\x71\x5f\x3d\xfb\x89\x00\x4c\xb7\xfc\x91\xd7\x3f\x93\xa2\xf0\xfe\x45\xc6\x78\x8c\x1f\xe3\xff\x04\x00\x00\xff\xff\xb5\x44\x89\xaf\xbc\x0f\x00\x00")
+var _prestate_tracerJs = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xa4\x57\x4b\x6f\xe3\x38\x12\x3e\x4b\xbf\xa2\x36\x17\xdb\x18\xb7\x9c\x64\x80\x59\x20\xd9\x2c\xa0\x76\xbb\xbb\x03\x78\x92\xc0\x76\x6f\x36\x3b\x98\x03\x45\x96\x64\x8e\x69\x52\x20\x29\x3f\xd0\xc8\x7f\x5f\x14\x25\xf9\x91\x37\x76\x73\x8a\xc9\xe2\x57\xef\xaf\x4a\x83\x01\x0c\x4d\xb9\xb5\xb2\x98\x7b\x38\x3f\x3d\xfb\x3b\xcc\xe6\x08\x85\xf9\x84\x7e\x8e\x16\xab\x25\xa4\x95\x9f\x1b\xeb\xe2\xc1\x00\x66\x73\xe9\x20\x97\x0a\x41\x3a\x28\x99\xf5\x60\x72\xf0\x4f\xe4\x95\xcc\x2c\xb3\xdb\x24\x1e\x0c\xea\x37\x2f\x5e\x13\x42\x6e\x11\xc1\x99\xdc\xaf\x99\xc5\x0b\xd8\x9a\x0a\x38\xd3\x60\x51\x48\xe7\xad\xcc\x2a\x8f\x20\x3d\x30\x2d\x06\xc6\xc2\xd2\x08\x99\x6f\x09\x52\x7a\xa8\xb4\x40\x1b\x54\x7b\xb4\x4b\xd7\xda\xf1\xed\xe6\x07\x8c\xd1\x39\xb4\xf0\x0d\x35\x5a\xa6\xe0\xae\xca\x94\xe4\x30\x96\x1c\xb5\x43\x60\x0e\x4a\x3a\x71\x73\x14\x90\x05\x38\x7a\xf8\x95\x4c\x99\x36\xa6\xc0\x57\x53\x69\xc1\xbc\x34\xba\x0f\x28\xc9\x72\x58\xa1\x75\xd2\x68\xf8\xb5\x55\xd5\x00\xf6\xc1\x58\x02\xe9\x32\x4f\x0e\x58\x30\x25\xbd\xeb\x01\xd3\x5b\x50\xcc\xef\x9f\x7e\x20\x20\x7b\xbf\x05\x48\x1d\xdc\x9b\x9b\x12\xc1\xcf\x99\xa7\x48\xac\xa5\x52\x90\x21\x54\x0e\xf3\x4a\xf5\x09\x2d\xab\x3c\xdc\x5f\xcf\xbe\xdf\xfe\x98\x41\x7a\xf3\x00\xf7\xe9\x64\x92\xde\xcc\x1e\x2e\x61\x2d\xfd\xdc\x54\x1e\x70\x85\x35\x94\x5c\x96\x4a\xa2\x80\x35\xb3\x96\x69\xbf\x05\x93\x13\xc2\xef\xa3\xc9\xf0\x7b\x7a\x33\x4b\x3f\x5f\x8f\xaf\x67\x0f\x60\x2c\x7c\xbd\x9e\xdd\x8c\xa6\x53\xf8\x7a\x3b\x81\x14\xee\xd2\xc9\xec\x7a\xf8\x63\x9c\x4e\xe0\xee\xc7\xe4\xee\x76\x3a\x4a\x60\x8a\x64\x15\xd2\xfb\xf7\x63\x9e\x87\xec\x59\x04\x81\x9e\x49\xe5\xda\x48\x3c\x98\x0a\xdc\xdc\x54\x4a\xc0\x9c\xad\x10\x2c\x72\x94\x2b\x14\xc0\x80\x9b\x72\xfb\xe1\xa4\x12\x16\x53\x46\x17\xc1\xe7\x57\x0b\x12\xae\x73\xd0\xc6\xf7\xc1\x21\xc2\x3f\xe6\xde\x97\x17\x83\xc1\x7a\xbd\x4e\x0a\x5d\x25\xc6\x16\x03\x55\xc3\xb9\xc1\x3f\x93\x98\x30\x4b\x8b\xce\x33\x8f\x33\xcb\x38\x5a\x30\x95\x2f\x2b\xef\xc0\x55\x79\x2e\xb9\x44\xed\x41\xea\xdc\xd8\x65\xa8\x14\xf0\x06\xb8\x45\xe6\x11\x18\x28\xc3\x99\x02\xdc\x20\xaf\xc2\x5d\x1d\x69\x32\xcc\x5b\xa6\x1d\xe3\xe1\x34\xb7\x66\x49\xbe\x56\xce\xd3\x3f\xce\xe1\x32\x53\x28\xa0\x40\x8d\x4e\x3a\xc8\x94\xe1\x8b\x24\xfe\x19\x47\x07\xc6\x50\xe3\x10\x50\x2b\x14\x6a\x63\x8d\x1d\x8b\x90\x55\x52\x09\xa9\x8b\x24\x8e\x5a\xe9\x0b\xd0\x95\x52\xfd\x38\x40\x28\x63\x16\x55\x99\x72\x6e\xaa\x60\xfb\x5f\xc8\x3d\x01\x20\xb8\x12\xb9\xcc\xa9\x38\xd8\xee\xd6\x9b\x70\xb5\xd3\x6b\x32\x92\x4f\xe2\xe8\x08\xe6\x02\xf2\x4a\x07\x77\xba\x4c\x08\xdb\x07\x91\xf5\x7e\xc6\x51\xb4\x62\x16\x18\xe7\x70\x05\xde\x7c\xc7\x4d\xb8\xec\x5d\xc6\x51\x24\x73\xe8\xfa\xb9\x74\x49\x0b\xfc\x07\xe3\xfc\x4f\xb8\xba\xba\x0a\x4d\x9d\x4b\x8d\xa2\x07\x04\x11\xbd\x24\x56\xdf\x44\x19\x53\x4c\x73\xbc\x80\xce\xe9\xa6\x03\xbf\x80\xc8\x92\x02\xfd\xe7\xfa\xb4\x56\x96\x78\x33\xf5\x56\xea\xa2\x7b\xf6\x5b\xaf\x1f\x5e\x69\x13\xde\x40\x23\x7e\x63\x76\xc2\xf5\x3d\x37\x22\x5c\x37\x36\xd7\x52\x43\x23\x1a\xa1\x46\xca\x79\x63\x59\x81\x17\xf0\xf3\x91\x7e\x3f\x92\x57\x8f\x71\xf4\x78\x14\xe5\x69\x2d\xf4\x4a\x94\x1b\x08\x40\xed\xed\xae\xce\x0b\x49\x9d\x7a\x98\x80\x80\xf7\x56\x12\x1a\x2d\xcf\x92\xb0\xc0\xed\xfb\x99\xa0\x14\x49\xb1\xd9\x5d\x2c\x70\xdb\xbb\x8c\x5f\x4d\x51\xd2\x18\xfd\x87\x14\x9b\x97\xf3\x45\x80\x2b\xa6\x76\x80\x75\xfc\xa6\x84\xb0\xb7\xab\x17\xaa\x20\xe8\x20\xd9\xbf\x5d\xc1\xc9\xe9\xe6\xf4\xff\xfc\x3b\x69\x2c\x88\xde\x35\xfb\x03\xa6\x3d\x1e\xe7\xd3\xa2\xab\x94\xa7\xb6\x93\x7a\x65\x16\x44\xa0\x73\xca\x93\x52\x21\x6b\xa6\xa4\xaa\x71\x35\x83\x65\x88\x1a\xa4\x47\xcb\x88\xc2\xcd\x0a\x2d\x4d\x2f\xb0\xe8\x2b\xab\xdd\x2e\x9d\xb9\xd4\x4c\xb5\xc0\x4d\xf6\xbd\x65\xbc\xee\xdd\xfa\xfc\x20\xa7\xdc\x6f\x42\x36\x83\x8f\x83\x01\xa4\x1e\xc8\x4f\x28\x8d\xd4\xbe\x0f\x6b\x04\x8d\x28\x88\x80\x04\x8a\x8a\xd3\x2d\x42\x67\xc5\x54\x85\x9d\x9a\x64\x88\xaa\x23\xd2\x6e\x2a\x8f\xf6\x90\x84\xfa\xc1\xc0\xa5\x59\x85\x51\x9b\x31\xbe\x80\xa6\xf1\x8d\x95\x85\xd4\x71\xd3\x86\x47\x4d\xdf\xe5\x7e\x93\x10\x70\x30\x2b\xd4\x0c\xe5\x9e\x4e\x3e\x87\xfc\x67\xb2\xb8\xd6\xfe\x49\x11\xd5\x91\x6f\x9f\xf6\xfe\x4c\x9a\x26\x4e\x1c\x11\x6f\xf7\xbc\xd7\x87\xb3\xdf\x76\x95\xe9\x0d\x41\xc1\xfb\x60\xde\xbc\x0e\xd5\x5a\xff\xce\xb3\xa0\x86\x98\xe4\x97\xa0\x35\x71\x55\x46\xe9\xf0\x41\x30\xc4\xf1\x98\x4d\x2e\xdf\xc0\x3d\xf6\xad\xc5\x6d\x42\x93\x30\x21\x5e\x07\xad\xb3\xfb\x05\xb9\xc5\x25\x4d\x17\xca\x02\x67\x4a\xa1\xed\x38\x08\xdc\xd5\x6f\xca\x29\xe4\x0b\x97\xa5\xdf\xb6\x33\xc7\x33\x5b\xa0\x77\xef\x1b\x16\x70\x3e\x7d\x6a\xa9\x98\x8c\xf1\xdb\x12\xe1\xea\x0a\x3a\xc3\xc9\x28\x9d\x8d\x3a\x4d\x33\x0d\x06\x70\x4f\x06\x68\xc8\x94\xcc\x84\xda\x82\x40\x85\x3e\x0c\x7e\xe0\x46\x87\x10\xed\xa8\xa9\x4f\xab\x15\x2d\x3d\xb8\x91\xce\x4b\x5d\x40\x38\x86\x35\xcd\xf7\x06\x2e\xf4\x08\x67\x95\x43\xf1\x6c\x18\x7a\x43\x9b\x8d\x45\x1a\x32\x34\x87\x42\xbb\x31\x25\x77\x9b\x50\x2e\xad\xf3\x50\x2a\xc6\x31\x21\xbc\x9d\x31\x2f\xbb\x4b\x65\xd1\x30\x33\x45\x75\x12\x5a\x30\x00\xed\x07\x2d\x53\x34\xa8\x49\xbd\x83\x6e\x8b\xd1\x8b\xa3\xc8\xb6\xd2\x07\xd8\x97\x7b\x4a\x70\x1e\xcb\x43\x42\xa0\x05\x07\x57\x48\x54\x1e\xd8\xa0\x5e\xd8\x48\xd7\xbf\x7e\x6f\xb6\x00\x74\x49\x1c\xd1\xbb\x83\xbe\x56\xa6\x38\xee\x6b\x51\x87\x85\x57\xd6\x52\xfe\x77\xa3\x20\xa7\x1e\xff\xab\x72\x9e\x62\x6a\x89\x5a\x1a\xb6\x78\x89\xac\x03\x35\xd3\xd4\xef\x3d\x1f\xa2\x34\x3f\xc3\xbc\x22\x2f\x9a\x69\x59\x6f\x95\xa5\xf1\xa8\xbd\x64\x4a\x6d\x29\x0f\x6b\x4b\xeb\x14\x2d\x50\x7d\x70\x92\xa4\x08\xa7\x16\x95\x9a\xab\x4a\xd0\x09\x42\x68\x8e\x06\xcf\x05\x9b\x8f\xf7\xb0\x25\x3a\xc7\x0a\x4c\xa8\x92\x72\xb9\x69\x36\x59\x0d\x9d\x9a\xe4\xba\xbd\x4e\x12\x47\x2f\x52\x8c\x32\x45\xd2\x16\x19\x8d\x91\x54\x08\x8b\xce\x75\x7b\x0d\xe7\xec\x32\x7b\x3f\x47\x4d\xc1\x07\x8d\xeb\xa6\xe6\xa4\xa3\x89\x47\x2b\xa3\xe8\x03\x13\x82\xa8\xed\xc9\x3a\x13\x47\x91\x5b\x4b\xcf\xe7\x10\x34\x99\x72\xdf\x8b\xbd\xa6\xfe\x39\x73\x08\x27\xa3\x7f\xcf\x86\xb7\x5f\x46\xc3\xdb\xbb\x87\x93\x0b\x38\x3a\x9b\x5e\xff\x67\xb4\x3b\xfb\x9c\x8e\xd3\x9b\xe1\xe8\xe4\x22\x8e\x5e\x76\xc8\x9b\xd6\x05\x52\xe8\x3c\xe3\x8b\xa4\x44\x5c\x74\x4f\x8f\x79\x60\xef\x60\x14\x65\x16\xd9\xe2\x72\x6f\x4c\xdd\xa0\x8d\x8e\x96\x72\xe1\x0a\x5e\x0d\xd6\xe5\xeb\xd6\x0c\x1b\xf9\x6e\x4b\xe4\xfb\x95\x88\x4e\x3e\x60\xc7\xf9\x53\x43\xde\xb7\x84\x6c\x96\xfa\x36\xcf\x1b\xd1\x83\x30\x9c\xf5\x6a\x56\xbc\xcd\x8f\x85\x47\x5a\xc0\x55\xf3\xe8\x97\xa7\x8f\xce\x9f\x3d\x7a\xd3\xd7\xf3\xc6\xd9\x27\x28\xbf\x1e\x67\xa0\x1f\xb4\x2c\x71\x69\xec\xb6\x99\x28\x41\x7d\xbf\xb6\xe6\xed\xc8\xa4\xe3\xf1\xae\x26\x86\xe9\x78\x4c\xc5\xb3\x3b\xf8\x32\x1a\x8f\xbe\xa5\xb3\xd1\x91\xd4\x74\x96\xce\xae\x87\xf5\xd1\xeb\x2e\xb4\x81\x7c\x62\xfa\xd9\x87\x8b\xa7\x33\x9d\xce\x6e\x27\xa3\xce\x45\xf3\x6b\x7c\x9b\x7e\xe9\x3c\x53\xd8\x6c\x94\x6f\xb5\x9f\x37\xf7\xc6\x8a\xff\xa5\x8a\x0f\xb6\xaa\x9c\xbd\xb4\x54\x11\x65\x30\xee\xab\x27\x1f\x4f\xc0\x74\xcb\xac\x79\xfd\x01\x19\xe5\xec\x78\x47\xda\x73\xe9\x63\xfc\x18\xff\x77\x00\xca\x27\x84\xd1\xd6\x10\x00\x00")
 
 func prestate_tracerJsBytes() ([]byte, error) {
 	return bindataRead(
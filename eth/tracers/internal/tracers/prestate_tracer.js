@@ -89,6 +89,12 @@
 				var from = log.contract.getAddress();
 				this.lookupAccount(toContract(from, db.getNonce(from)), db);
 				break;
+			case "CREATE2":
+				var from  = log.contract.getAddress();
+				var inOff = log.stack.peek(1).valueOf();
+				var inEnd = inOff + log.stack.peek(2).valueOf();
+				this.lookupAccount(toContract2(from, log.stack.peek(3).toString(16), log.memory.slice(inOff, inEnd)), db);
+				break;
 			case "CALL": case "CALLCODE": case "DELEGATECALL": case "STATICCALL":
 				this.lookupAccount(toAddress(log.stack.peek(1).toString(16)), db);
 				break;
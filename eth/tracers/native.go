@@ -0,0 +1,106 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// ResultTracer is a transaction tracer assembling its output into a JSON result
+// that may be aborted midway. Both the JavaScript and the native Go tracers
+// implement it.
+type ResultTracer interface {
+	vm.Tracer
+
+	// GetResult returns the JSON result of the trace, or any error accumulated
+	// during tracing.
+	GetResult() (json.RawMessage, error)
+
+	// Stop terminates the tracing at the first opportune moment.
+	Stop(err error)
+}
+
+// natives contains the constructors of all the built in Go tracers by name.
+// These take precedence over the JavaScript tracers of the same name.
+var natives = map[string]func() ResultTracer{
+	"callTracer":     func() ResultTracer { return newCallTracer() },
+	"prestateTracer": func() ResultTracer { return newPrestateTracer() },
+	"4byteTracer":    func() ResultTracer { return newFourByteTracer() },
+	"balanceTracer":  func() ResultTracer { return newBalanceTracer() },
+}
+
+// NewNative creates a built in Go tracer by name, returning false if there is
+// no native tracer with the given name.
+func NewNative(name string) (ResultTracer, bool) {
+	ctor, ok := natives[name]
+	if !ok {
+		return nil, false
+	}
+	return ctor(), true
+}
+
+// NewTracer creates a tracer for the given name or JavaScript code, preferring
+// the native implementation if one is available.
+func NewTracer(code string) (ResultTracer, error) {
+	if tracer, ok := NewNative(code); ok {
+		return tracer, nil
+	}
+	return New(code)
+}
+
+// nativeInterrupt implements the interruption logic shared by the native tracers.
+type nativeInterrupt struct {
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (ni *nativeInterrupt) Stop(err error) {
+	ni.reason = err
+	atomic.StoreUint32(&ni.interrupt, 1)
+}
+
+// stopped returns whether the tracer was interrupted.
+func (ni *nativeInterrupt) stopped() bool {
+	return atomic.LoadUint32(&ni.interrupt) > 0
+}
+
+// peekStack returns the n-th element from the top of the stack, or zero if the
+// stack is not deep enough, matching the JavaScript stack wrapper.
+func peekStack(stack *vm.Stack, n int) *big.Int {
+	if len(stack.Data()) <= n || n < 0 {
+		log.Warn("Tracer accessed out of bound stack", "size", len(stack.Data()), "index", n)
+		return new(big.Int)
+	}
+	return stack.Back(n)
+}
+
+// sliceMemory returns a copy of the memory between offset and offset+size, or
+// nil if it is out of bounds, matching the JavaScript memory wrapper.
+func sliceMemory(memory *vm.Memory, offset, size *big.Int) []byte {
+	end := new(big.Int).Add(offset, size)
+	if !end.IsInt64() || int64(memory.Len()) < end.Int64() {
+		log.Warn("Tracer accessed out of bound memory", "available", memory.Len(), "offset", offset, "size", size)
+		return nil
+	}
+	return memory.Get(offset.Int64(), size.Int64())
+}
@@ -0,0 +1,100 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// fourByteTracer is the native Go version of the JavaScript 4byteTracer,
+// counting the 4 byte method identifiers invoked by a transaction together
+// with the size of the supplied call data.
+type fourByteTracer struct {
+	nativeInterrupt
+
+	ids   map[string]int // Number of invocations of each identifier-size pair
+	input []byte         // Call data of the outermost call
+}
+
+// newFourByteTracer creates a native 4byte tracer.
+func newFourByteTracer() *fourByteTracer {
+	return &fourByteTracer{ids: make(map[string]int)}
+}
+
+// store counts an invocation of a method identifier with the given data size.
+func (t *fourByteTracer) store(id []byte, size *big.Int) {
+	t.ids[hexutil.Encode(id)+"-"+size.String()]++
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (t *fourByteTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.input = common.CopyBytes(input)
+	return nil
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *fourByteTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if t.stopped() {
+		return nil
+	}
+	// Find the stack position of the input memory offset of call opcodes
+	var ct int
+	switch op {
+	case vm.CALL, vm.CALLCODE:
+		ct = 3
+	case vm.DELEGATECALL, vm.STATICCALL:
+		ct = 2
+	default:
+		return nil
+	}
+	if env.IsPrecompile(common.BigToAddress(peekStack(stack, 1))) {
+		return nil
+	}
+	if size := peekStack(stack, ct+1); size.Cmp(big.NewInt(4)) >= 0 {
+		id := sliceMemory(memory, peekStack(stack, ct), big.NewInt(4))
+		t.store(id, new(big.Int).Sub(size, big.NewInt(4)))
+	}
+	return nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *fourByteTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *fourByteTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	return nil
+}
+
+// GetResult returns the collected method identifiers as JSON.
+func (t *fourByteTracer) GetResult() (json.RawMessage, error) {
+	if t.reason != nil {
+		return nil, t.reason
+	}
+	if len(t.input) > 4 {
+		t.store(t.input[:4], big.NewInt(int64(len(t.input)-4)))
+	}
+	return json.Marshal(t.ids)
+}
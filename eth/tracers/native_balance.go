@@ -0,0 +1,139 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// balanceChange is a single balance change reported by the balance tracer.
+type balanceChange struct {
+	Address common.Address `json:"address"`
+	From    *hexutil.Big   `json:"from"`
+	To      *hexutil.Big   `json:"to"`
+	Reason  string         `json:"reason"`
+	Depth   int            `json:"depth"`
+}
+
+// storageChange is a single storage write reported by the balance tracer.
+type storageChange struct {
+	Address common.Address `json:"address"`
+	Slot    common.Hash    `json:"slot"`
+	From    common.Hash    `json:"from"`
+	To      common.Hash    `json:"to"`
+	Depth   int            `json:"depth"`
+}
+
+// balanceTracer is a native Go tracer without a JavaScript counterpart, listing
+// the balance changes of a transaction with their reasons, including the ones
+// made outside of the EVM such as gas purchases, refunds and TRC21 fees, along
+// with all the storage writes.
+type balanceTracer struct {
+	nativeInterrupt
+
+	depth    int
+	balances []*balanceChange
+	storage  []*storageChange
+}
+
+// newBalanceTracer creates a native balance tracer.
+func newBalanceTracer() *balanceTracer {
+	return &balanceTracer{
+		balances: []*balanceChange{},
+		storage:  []*storageChange{},
+	}
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (t *balanceTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	return nil
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *balanceTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *balanceTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *balanceTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	return nil
+}
+
+// CaptureEnter implements the StructuredTracer interface to track the depth of
+// the entered call frames.
+func (t *balanceTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	t.depth++
+}
+
+// CaptureExit implements the StructuredTracer interface to track the depth of
+// the exited call frames.
+func (t *balanceTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	t.depth--
+}
+
+// CaptureBalanceChange implements the StructuredTracer interface to record a
+// balance change.
+func (t *balanceTracer) CaptureBalanceChange(addr common.Address, prev, next *big.Int, reason vm.BalanceChangeReason) {
+	if t.stopped() {
+		return
+	}
+	t.balances = append(t.balances, &balanceChange{
+		Address: addr,
+		From:    (*hexutil.Big)(new(big.Int).Set(prev)),
+		To:      (*hexutil.Big)(new(big.Int).Set(next)),
+		Reason:  reason.String(),
+		Depth:   t.depth,
+	})
+}
+
+// CaptureStorageChange implements the StructuredTracer interface to record a
+// storage write.
+func (t *balanceTracer) CaptureStorageChange(addr common.Address, slot, prev, next common.Hash) {
+	if t.stopped() {
+		return
+	}
+	t.storage = append(t.storage, &storageChange{
+		Address: addr,
+		Slot:    slot,
+		From:    prev,
+		To:      next,
+		Depth:   t.depth,
+	})
+}
+
+// GetResult returns the collected balance and storage changes as JSON.
+func (t *balanceTracer) GetResult() (json.RawMessage, error) {
+	if t.reason != nil {
+		return nil, t.reason
+	}
+	return json.Marshal(map[string]interface{}{
+		"balances": t.balances,
+		"storage":  t.storage,
+	})
+}
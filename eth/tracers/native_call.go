@@ -0,0 +1,257 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// callFrame is a single call of the call tracer, serialized in the same field
+// order as the JavaScript callTracer.
+type callFrame struct {
+	Type    string       `json:"type,omitempty"`
+	From    string       `json:"from,omitempty"`
+	To      string       `json:"to,omitempty"`
+	Value   string       `json:"value,omitempty"`
+	Gas     string       `json:"gas,omitempty"`
+	GasUsed string       `json:"gasUsed,omitempty"`
+	Input   string       `json:"input,omitempty"`
+	Output  string       `json:"output,omitempty"`
+	Error   string       `json:"error,omitempty"`
+	Time    string       `json:"time,omitempty"`
+	Calls   []*callFrame `json:"calls,omitempty"`
+
+	gasIn   uint64   // Gas available before the call opcode
+	gasCost uint64   // Cost of the call opcode
+	gas     *uint64  // Gas available at the first step of the callee, if entered
+	outOff  *big.Int // Memory offset of the call output
+	outLen  *big.Int // Memory size of the call output
+}
+
+// finalize serializes the remaining bookkeeping fields of a call frame.
+func (f *callFrame) finalize() {
+	if f.gas != nil {
+		f.Gas = hexutil.EncodeUint64(*f.gas)
+	}
+}
+
+// callTracer is the native Go version of the JavaScript callTracer, gathering
+// the call frames of a transaction into a nested tree.
+type callTracer struct {
+	nativeInterrupt
+
+	callstack []*callFrame
+	descended bool
+
+	create  bool
+	from    common.Address
+	to      common.Address
+	input   []byte
+	gas     uint64
+	value   *big.Int
+	output  []byte
+	gasUsed uint64
+	time    time.Duration
+	err     error
+}
+
+// newCallTracer creates a native call tracer.
+func newCallTracer() *callTracer {
+	return &callTracer{callstack: []*callFrame{{}}}
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (t *callTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.create, t.from, t.to, t.input, t.gas, t.value = create, from, to, common.CopyBytes(input), gas, value
+	return nil
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *callTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if t.stopped() {
+		return nil
+	}
+	if err != nil {
+		t.fault(err)
+		return nil
+	}
+	syscall := op&0xf0 == 0xf0
+
+	switch {
+	case syscall && (op == vm.CREATE || op == vm.CREATE2):
+		t.callstack = append(t.callstack, &callFrame{
+			Type:    op.String(),
+			From:    hexutil.Encode(contract.Address().Bytes()),
+			Input:   hexutil.Encode(sliceMemory(memory, peekStack(stack, 1), peekStack(stack, 2))),
+			Value:   hexutil.EncodeBig(peekStack(stack, 0)),
+			gasIn:   gas,
+			gasCost: cost,
+		})
+		t.descended = true
+		return nil
+
+	case syscall && op == vm.SELFDESTRUCT:
+		parent := t.callstack[len(t.callstack)-1]
+		parent.Calls = append(parent.Calls, &callFrame{Type: op.String()})
+		return nil
+
+	case syscall && (op == vm.CALL || op == vm.CALLCODE || op == vm.DELEGATECALL || op == vm.STATICCALL):
+		to := common.BigToAddress(peekStack(stack, 1))
+		if env.IsPrecompile(to) {
+			return nil
+		}
+		off := 1
+		if op == vm.DELEGATECALL || op == vm.STATICCALL {
+			off = 0
+		}
+		call := &callFrame{
+			Type:    op.String(),
+			From:    hexutil.Encode(contract.Address().Bytes()),
+			To:      hexutil.Encode(to.Bytes()),
+			Input:   hexutil.Encode(sliceMemory(memory, peekStack(stack, 2+off), peekStack(stack, 3+off))),
+			gasIn:   gas,
+			gasCost: cost,
+			outOff:  new(big.Int).Set(peekStack(stack, 4+off)),
+			outLen:  new(big.Int).Set(peekStack(stack, 5+off)),
+		}
+		if off == 1 {
+			call.Value = hexutil.EncodeBig(peekStack(stack, 2))
+		}
+		t.callstack = append(t.callstack, call)
+		t.descended = true
+		return nil
+	}
+	// If we've just descended into an inner call, retrieve it's true allowance
+	if t.descended {
+		if depth >= len(t.callstack) {
+			allowance := gas
+			t.callstack[len(t.callstack)-1].gas = &allowance
+		}
+		t.descended = false
+	}
+	if syscall && op == vm.REVERT {
+		t.callstack[len(t.callstack)-1].Error = "execution reverted"
+		return nil
+	}
+	// If we've just returned from an inner call, pop it off the stack
+	if depth == len(t.callstack)-1 {
+		call := t.callstack[len(t.callstack)-1]
+		t.callstack = t.callstack[:len(t.callstack)-1]
+
+		ret := peekStack(stack, 0)
+		if call.Type == vm.CREATE.String() || call.Type == vm.CREATE2.String() {
+			call.GasUsed = "0x" + strconv.FormatInt(int64(call.gasIn)-int64(call.gasCost)-int64(gas), 16)
+			if ret.Sign() != 0 {
+				addr := common.BigToAddress(ret)
+				call.To = hexutil.Encode(addr.Bytes())
+				call.Output = hexutil.Encode(env.StateDB.GetCode(addr))
+			} else if call.Error == "" {
+				call.Error = "internal failure"
+			}
+		} else if call.gas != nil {
+			call.GasUsed = "0x" + strconv.FormatInt(int64(call.gasIn)-int64(call.gasCost)+int64(*call.gas)-int64(gas), 16)
+			if ret.Sign() != 0 {
+				call.Output = hexutil.Encode(sliceMemory(memory, call.outOff, call.outLen))
+			} else if call.Error == "" {
+				call.Error = "internal failure"
+			}
+		}
+		call.finalize()
+
+		parent := t.callstack[len(t.callstack)-1]
+		parent.Calls = append(parent.Calls, call)
+	}
+	return nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *callTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if !t.stopped() {
+		t.fault(err)
+	}
+	return nil
+}
+
+// fault pops the currently executing call off the stack, marking it failed.
+func (t *callTracer) fault(err error) {
+	// If the topmost call already reverted, don't handle the additional fault again
+	if t.callstack[len(t.callstack)-1].Error != "" {
+		return
+	}
+	call := t.callstack[len(t.callstack)-1]
+	t.callstack = t.callstack[:len(t.callstack)-1]
+
+	call.Error = err.Error()
+	call.finalize()
+	if call.gas != nil {
+		call.GasUsed = call.Gas
+	}
+	if len(t.callstack) > 0 {
+		parent := t.callstack[len(t.callstack)-1]
+		parent.Calls = append(parent.Calls, call)
+		return
+	}
+	t.callstack = append(t.callstack, call)
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *callTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	t.output, t.gasUsed, t.time, t.err = common.CopyBytes(output), gasUsed, d, err
+	return nil
+}
+
+// GetResult assembles the outermost call frame and returns it as JSON.
+func (t *callTracer) GetResult() (json.RawMessage, error) {
+	if t.reason != nil {
+		return nil, t.reason
+	}
+	result := &callFrame{
+		Type:    vm.CALL.String(),
+		From:    hexutil.Encode(t.from.Bytes()),
+		To:      hexutil.Encode(t.to.Bytes()),
+		Value:   hexutil.EncodeBig(new(big.Int)),
+		Gas:     hexutil.EncodeUint64(t.gas),
+		GasUsed: hexutil.EncodeUint64(t.gasUsed),
+		Input:   hexutil.Encode(t.input),
+		Output:  hexutil.Encode(t.output),
+		Time:    t.time.String(),
+		Calls:   t.callstack[0].Calls,
+	}
+	if t.create {
+		result.Type = vm.CREATE.String()
+	}
+	if t.value != nil {
+		result.Value = hexutil.EncodeBig(t.value)
+	}
+	if t.callstack[0].Error != "" {
+		result.Error = t.callstack[0].Error
+	} else if t.err != nil {
+		result.Error = t.err.Error()
+	}
+	if result.Error != "" {
+		result.Output = ""
+	}
+	return json.Marshal(result)
+}
@@ -0,0 +1,160 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// prestateAccount is the state of a single account prior to the execution of
+// the traced transaction.
+type prestateAccount struct {
+	Balance *hexutil.Big                `json:"balance"`
+	Nonce   uint64                      `json:"nonce"`
+	Code    hexutil.Bytes               `json:"code"`
+	Storage map[common.Hash]common.Hash `json:"storage"`
+}
+
+// prestateTracer is the native Go version of the JavaScript prestateTracer,
+// collecting the pre-transaction state of all the accounts and storage slots
+// touched by a transaction.
+type prestateTracer struct {
+	nativeInterrupt
+
+	prestate map[common.Address]*prestateAccount
+	db       vm.StateDB
+
+	create bool
+	from   common.Address
+	to     common.Address
+	value  *big.Int
+}
+
+// newPrestateTracer creates a native prestate tracer.
+func newPrestateTracer() *prestateTracer {
+	return &prestateTracer{prestate: make(map[common.Address]*prestateAccount)}
+}
+
+// lookupAccount retrieves the state of an account if it wasn't yet accessed.
+func (t *prestateTracer) lookupAccount(addr common.Address) {
+	if _, ok := t.prestate[addr]; ok {
+		return
+	}
+	t.prestate[addr] = &prestateAccount{
+		Balance: (*hexutil.Big)(new(big.Int).Set(t.db.GetBalance(addr))),
+		Nonce:   t.db.GetNonce(addr),
+		Code:    common.CopyBytes(t.db.GetCode(addr)),
+		Storage: make(map[common.Hash]common.Hash),
+	}
+}
+
+// lookupStorage retrieves a non-empty storage slot if it wasn't yet accessed.
+func (t *prestateTracer) lookupStorage(addr common.Address, key common.Hash) {
+	t.lookupAccount(addr)
+
+	storage := t.prestate[addr].Storage
+	if _, ok := storage[key]; ok {
+		return
+	}
+	if val := t.db.GetState(addr, key); val != (common.Hash{}) {
+		storage[key] = val
+	}
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (t *prestateTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.create, t.from, t.to, t.value = create, from, to, value
+	return nil
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *prestateTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if t.stopped() {
+		return nil
+	}
+	if t.db == nil {
+		t.db = env.StateDB
+		t.lookupAccount(contract.Address())
+	}
+	switch op {
+	case vm.EXTCODECOPY, vm.EXTCODESIZE, vm.BALANCE:
+		t.lookupAccount(common.BigToAddress(peekStack(stack, 0)))
+	case vm.CREATE:
+		from := contract.Address()
+		t.lookupAccount(crypto.CreateAddress(from, t.db.GetNonce(from)))
+	case vm.CREATE2:
+		var (
+			from = contract.Address()
+			code = sliceMemory(memory, peekStack(stack, 1), peekStack(stack, 2))
+			salt = common.BigToHash(peekStack(stack, 3))
+		)
+		t.lookupAccount(crypto.CreateAddress2(from, salt, crypto.Keccak256(code)))
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		t.lookupAccount(common.BigToAddress(peekStack(stack, 1)))
+	case vm.SSTORE, vm.SLOAD:
+		t.lookupStorage(contract.Address(), common.BigToHash(peekStack(stack, 0)))
+	}
+	return nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *prestateTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *prestateTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	return nil
+}
+
+// GetResult rewinds the sender and recipient to their state prior to the value
+// transfer and nonce increment, returning the collected prestate as JSON.
+func (t *prestateTracer) GetResult() (json.RawMessage, error) {
+	if t.reason != nil {
+		return nil, t.reason
+	}
+	// Plain transfers never execute code, there is no state to rewind
+	if t.db == nil {
+		return json.Marshal(t.prestate)
+	}
+	t.lookupAccount(t.from)
+	t.lookupAccount(t.to)
+
+	value := t.value
+	if value == nil {
+		value = new(big.Int)
+	}
+	fromBal := new(big.Int).Add(t.prestate[t.from].Balance.ToInt(), value)
+	toBal := new(big.Int).Sub(t.prestate[t.to].Balance.ToInt(), value)
+
+	t.prestate[t.to].Balance = (*hexutil.Big)(toBal)
+	t.prestate[t.from].Balance = (*hexutil.Big)(fromBal)
+	t.prestate[t.from].Nonce--
+
+	if t.create {
+		delete(t.prestate, t.to)
+	}
+	return json.Marshal(t.prestate)
+}
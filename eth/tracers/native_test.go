@@ -0,0 +1,216 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+// runTracerTest executes the transaction of a call tracer test case with the
+// given tracer attached, returning the raw trace result.
+func runTracerTest(t *testing.T, test *callTracerTest, tracer ResultTracer) json.RawMessage {
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(common.FromHex(test.Input), tx); err != nil {
+		t.Fatalf("failed to parse testcase input: %v", err)
+	}
+	signer := types.MakeSigner(test.Genesis.Config, new(big.Int).SetUint64(uint64(test.Context.Number)))
+	origin, _ := signer.Sender(tx)
+
+	context := vm.Context{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		Origin:      origin,
+		Coinbase:    test.Context.Miner,
+		BlockNumber: new(big.Int).SetUint64(uint64(test.Context.Number)),
+		Time:        new(big.Int).SetUint64(uint64(test.Context.Time)),
+		Difficulty:  (*big.Int)(test.Context.Difficulty),
+		GasLimit:    uint64(test.Context.GasLimit),
+		GasPrice:    tx.GasPrice(),
+	}
+	db, _ := ethdb.NewMemDatabase()
+	statedb := tests.MakePreState(db, test.Genesis.Alloc)
+
+	evm := vm.NewEVM(context, statedb, test.Genesis.Config, vm.Config{Debug: true, Tracer: tracer})
+
+	msg, err := tx.AsMessage(signer, nil)
+	if err != nil {
+		t.Fatalf("failed to prepare transaction for tracing: %v", err)
+	}
+	st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
+	if _, _, _, err = st.TransitionDb(); err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	return res
+}
+
+// stripTime removes the non-deterministic execution time from a call trace.
+func stripTime(t *testing.T, blob json.RawMessage) map[string]interface{} {
+	var res map[string]interface{}
+	if err := json.Unmarshal(blob, &res); err != nil {
+		t.Fatalf("failed to unmarshal trace result: %v", err)
+	}
+	delete(res, "time")
+	return res
+}
+
+// Tests that the native tracers produce the exact same results as their
+// JavaScript counterparts for all the datasets in the tracer test harness.
+func TestNativeTracers(t *testing.T) {
+	files, err := ioutil.ReadDir("testdata")
+	if err != nil {
+		t.Fatalf("failed to retrieve tracer test suite: %v", err)
+	}
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name(), "call_tracer_") {
+			continue
+		}
+		file := file // capture range variable
+		t.Run(camel(strings.TrimSuffix(strings.TrimPrefix(file.Name(), "call_tracer_"), ".json")), func(t *testing.T) {
+			t.Parallel()
+
+			blob, err := ioutil.ReadFile(filepath.Join("testdata", file.Name()))
+			if err != nil {
+				t.Fatalf("failed to read testcase: %v", err)
+			}
+			test := new(callTracerTest)
+			if err := json.Unmarshal(blob, test); err != nil {
+				t.Fatalf("failed to parse testcase: %v", err)
+			}
+			for name := range natives {
+				if _, ok := tracer(name); !ok {
+					continue // Native only tracer, nothing to compare against
+				}
+				native, _ := NewNative(name)
+				script, err := New(name)
+				if err != nil {
+					t.Fatalf("%s: failed to create JavaScript tracer: %v", name, err)
+				}
+				have := stripTime(t, runTracerTest(t, test, native))
+				want := stripTime(t, runTracerTest(t, test, script))
+				if !reflect.DeepEqual(have, want) {
+					t.Errorf("%s: trace mismatch: have %+v, want %+v", name, have, want)
+				}
+			}
+			// Ensure the native call tracer also matches the recorded results
+			native, _ := NewNative("callTracer")
+			ret := new(callTrace)
+			if err := json.Unmarshal(runTracerTest(t, test, native), ret); err != nil {
+				t.Fatalf("failed to unmarshal trace result: %v", err)
+			}
+			if !reflect.DeepEqual(ret, test.Result) {
+				t.Fatalf("trace mismatch: have %+v, want %+v", ret, test.Result)
+			}
+		})
+	}
+}
+
+// Tests that native tracers report the reason they were stopped for.
+func TestNativeTracerStop(t *testing.T) {
+	for name := range natives {
+		tracer, _ := NewNative(name)
+		tracer.Stop(errors.New("stopped"))
+		if _, err := tracer.GetResult(); err == nil || err.Error() != "stopped" {
+			t.Errorf("%s: stop error mismatch: have %v, want %v", name, err, "stopped")
+		}
+	}
+	if _, ok := NewNative("noopTracer"); ok {
+		t.Errorf("unknown native tracer created")
+	}
+}
+
+// Tests that the balance tracer reports the gas purchase, refund and fee of the
+// transactions in the tracer test harness at the top level, and that these add
+// up regardless of the execution outcome. Call frames must be entered and exited
+// in pairs, keeping the depth of all changes non-negative.
+func TestBalanceTracer(t *testing.T) {
+	files, err := ioutil.ReadDir("testdata")
+	if err != nil {
+		t.Fatalf("failed to retrieve tracer test suite: %v", err)
+	}
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name(), "call_tracer_") {
+			continue
+		}
+		blob, err := ioutil.ReadFile(filepath.Join("testdata", file.Name()))
+		if err != nil {
+			t.Fatalf("%s: failed to read testcase: %v", file.Name(), err)
+		}
+		test := new(callTracerTest)
+		if err := json.Unmarshal(blob, test); err != nil {
+			t.Fatalf("%s: failed to parse testcase: %v", file.Name(), err)
+		}
+		tracer, _ := NewNative("balanceTracer")
+
+		var res struct {
+			Balances []*balanceChange `json:"balances"`
+			Storage  []*storageChange `json:"storage"`
+		}
+		if err := json.Unmarshal(runTracerTest(t, test, tracer), &res); err != nil {
+			t.Fatalf("%s: failed to unmarshal trace result: %v", file.Name(), err)
+		}
+		if len(res.Balances) < 2 {
+			t.Fatalf("%s: too few balance changes: %d", file.Name(), len(res.Balances))
+		}
+		first, last := res.Balances[0], res.Balances[len(res.Balances)-1]
+		if first.Reason != "gasBuy" || first.Depth != 0 {
+			t.Errorf("%s: first change mismatch: have %s at depth %d, want gasBuy at depth 0", file.Name(), first.Reason, first.Depth)
+		}
+		if last.Reason != "gasFee" || last.Depth != 0 || last.Address != test.Context.Miner {
+			t.Errorf("%s: last change mismatch: have %s of %x at depth %d, want gasFee of %x at depth 0", file.Name(), last.Reason, last.Address, last.Depth, test.Context.Miner)
+		}
+		// The fee paid to the miner must be the gas bought minus the gas refunded
+		cost := new(big.Int)
+		for _, change := range res.Balances {
+			switch change.Reason {
+			case "gasBuy":
+				cost.Add(cost, new(big.Int).Sub(change.From.ToInt(), change.To.ToInt()))
+			case "gasRefund":
+				if change.Address != first.Address || change.Depth != 0 {
+					t.Errorf("%s: refund of %x at depth %d, want %x at depth 0", file.Name(), change.Address, change.Depth, first.Address)
+				}
+				cost.Sub(cost, new(big.Int).Sub(change.To.ToInt(), change.From.ToInt()))
+			}
+		}
+		if fee := new(big.Int).Sub(last.To.ToInt(), last.From.ToInt()); fee.Cmp(cost) != 0 {
+			t.Errorf("%s: fee mismatch: have %v, want %v", file.Name(), fee, cost)
+		}
+		for _, change := range res.Storage {
+			if change.Depth < 0 {
+				t.Errorf("%s: storage change of %x at negative depth %d", file.Name(), change.Address, change.Depth)
+			}
+		}
+	}
+}
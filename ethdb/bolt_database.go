@@ -0,0 +1,350 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "github.com/coreos/bbolt"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// boltFileName is the name of the data file of a bolt database, within the
+	// database directory.
+	boltFileName = "bolt.db"
+
+	// boltIteratorChunk is the number of items an iterator reads at once.
+	boltIteratorChunk = 1024
+
+	// boltBatchDelay is the time a single write waits for concurrent ones to be
+	// committed together with it in one transaction.
+	boltBatchDelay = time.Millisecond
+)
+
+var (
+	// boltBucket is the single bucket all the key/value pairs are stored in.
+	boltBucket = []byte("ethdb")
+
+	// errNotFound is returned if a key is requested that is not found in the
+	// bolt database.
+	errNotFound = errors.New("not found")
+)
+
+// BoltDatabase is a key-value store backed by a single bolt B+tree file. Bolt
+// doesn't support empty keys, so every key is stored with a one byte prefix.
+//
+// Every write transaction is fsynced on commit, so a committed write survives a
+// crash. Concurrent single writes are coalesced into a shared transaction, bulk
+// writes should be done through batches to amortize the sync.
+type BoltDatabase struct {
+	fn string   // filename for reporting
+	db *bolt.DB // bolt instance
+
+	log log.Logger // Contextual logger tracking the database path
+}
+
+// NewBoltDatabase returns a bolt wrapped object, creating the database directory
+// and data file if they don't exist yet. The cache is used as the initial size
+// of the memory map, in megabytes.
+func NewBoltDatabase(file string, cache int) (*BoltDatabase, error) {
+	logger := log.New("database", file)
+
+	if cache < 16 {
+		cache = 16
+	}
+	logger.Info("Allocated memory map", "size", cache)
+
+	if err := os.MkdirAll(file, 0755); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(filepath.Join(file, boltFileName), 0600, &bolt.Options{
+		Timeout:         time.Second,
+		FreelistType:    bolt.FreelistMapType,
+		InitialMmapSize: cache * 1024 * 1024,
+	})
+	if err != nil {
+		return nil, err
+	}
+	db.MaxBatchDelay = boltBatchDelay
+
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltDatabase{
+		fn:  file,
+		db:  db,
+		log: logger,
+	}, nil
+}
+
+// boltKey converts a database key into the internal bolt key.
+func boltKey(key []byte) []byte {
+	return append([]byte{0}, key...)
+}
+
+// Path returns the path to the database directory.
+func (db *BoltDatabase) Path() string {
+	return db.fn
+}
+
+// Put puts the given key / value into the database.
+func (db *BoltDatabase) Put(key []byte, value []byte) error {
+	batch := &boltBatch{db: db.db}
+	batch.Put(key, value)
+	return batch.commit(db.db.Batch)
+}
+
+// Has checks whether the given key is present in the database.
+func (db *BoltDatabase) Has(key []byte) (bool, error) {
+	var ok bool
+	err := db.db.View(func(tx *bolt.Tx) error {
+		ok = tx.Bucket(boltBucket).Get(boltKey(key)) != nil
+		return nil
+	})
+	return ok, err
+}
+
+// Get returns the given key if it's present.
+func (db *BoltDatabase) Get(key []byte) ([]byte, error) {
+	var dat []byte
+	err := db.db.View(func(tx *bolt.Tx) error {
+		// Values are only valid for the life of the transaction
+		if value := tx.Bucket(boltBucket).Get(boltKey(key)); value != nil {
+			dat = append([]byte{}, value...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if dat == nil {
+		return nil, errNotFound
+	}
+	return dat, nil
+}
+
+// Delete deletes the key from the database.
+func (db *BoltDatabase) Delete(key []byte) error {
+	batch := &boltBatch{db: db.db}
+	batch.Delete(key)
+	return batch.commit(db.db.Batch)
+}
+
+// NewIterator returns an iterator over the entire database content.
+func (db *BoltDatabase) NewIterator() Iterator {
+	return db.NewIteratorWithPrefix(nil)
+}
+
+// NewIteratorWithPrefix returns an iterator over the subset of the database
+// content with a particular key prefix.
+func (db *BoltDatabase) NewIteratorWithPrefix(prefix []byte) Iterator {
+	return &boltIterator{
+		db:     db.db,
+		prefix: boltKey(prefix),
+		next:   boltKey(prefix),
+	}
+}
+
+// NewIteratorWithStart returns an iterator over the subset of the database
+// content starting at a particular initial key.
+func (db *BoltDatabase) NewIteratorWithStart(start []byte) Iterator {
+	return &boltIterator{
+		db:     db.db,
+		prefix: boltKey(nil),
+		next:   boltKey(start),
+	}
+}
+
+// DeleteRange deletes all the keys in the range [start, limit).
+func (db *BoltDatabase) DeleteRange(start, limit []byte) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(boltBucket).Cursor()
+		for key, _ := cursor.Seek(boltKey(start)); key != nil; key, _ = cursor.Seek(boltKey(start)) {
+			if limit != nil && bytes.Compare(key[1:], limit) >= 0 {
+				break
+			}
+			if err := cursor.Delete(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Stat returns a particular internal stat of the database. The only property
+// supported is bolt.stats, the transaction and page statistics of the database.
+func (db *BoltDatabase) Stat(property string) (string, error) {
+	if property != "bolt.stats" {
+		return "", fmt.Errorf("unknown property: %s", property)
+	}
+	stats := db.db.Stats()
+	return fmt.Sprintf("Free pages: %d\nPending pages: %d\nFree allocated (bytes): %d\nFreelist in use (bytes): %d\nRead transactions: %d (%d open)\nPages allocated: %d (%d bytes)\nNodes spilled: %d\nWrites: %d (%v)\n",
+		stats.FreePageN, stats.PendingPageN, stats.FreeAlloc, stats.FreelistInuse, stats.TxN, stats.OpenTxN,
+		stats.TxStats.PageCount, stats.TxStats.PageAlloc, stats.TxStats.Spill, stats.TxStats.Write, stats.TxStats.WriteTime), nil
+}
+
+// Compact returns ErrCompactionUnsupported. Bolt reuses the pages freed by
+// deletions in place and the data file can only be shrunk by copying it over
+// into a new one.
+func (db *BoltDatabase) Compact(start []byte, limit []byte) error {
+	return ErrCompactionUnsupported
+}
+
+// Close closes the database.
+func (db *BoltDatabase) Close() {
+	if err := db.db.Close(); err == nil {
+		db.log.Info("Database closed")
+	} else {
+		db.log.Error("Failed to close database", "err", err)
+	}
+}
+
+// NewBatch creates a write-only batch, committed in a single transaction.
+func (db *BoltDatabase) NewBatch() Batch {
+	return &boltBatch{db: db.db}
+}
+
+type boltBatch struct {
+	db     *bolt.DB
+	writes []kv
+	size   int
+}
+
+func (b *boltBatch) Put(key, value []byte) error {
+	b.writes = append(b.writes, kv{boltKey(key), append([]byte{}, value...), false})
+	b.size += len(value)
+	return nil
+}
+
+func (b *boltBatch) Delete(key []byte) error {
+	b.writes = append(b.writes, kv{boltKey(key), nil, true})
+	b.size += len(key)
+	return nil
+}
+
+func (b *boltBatch) Write() error {
+	return b.commit(b.db.Update)
+}
+
+// commit applies the writes of the batch in a transaction run by the given bolt
+// method, which may run the function multiple times.
+func (b *boltBatch) commit(run func(func(*bolt.Tx) error) error) error {
+	return run(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+		for _, kv := range b.writes {
+			var err error
+			if kv.del {
+				err = bucket.Delete(kv.k)
+			} else {
+				err = bucket.Put(kv.k, kv.v)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (b *boltBatch) ValueSize() int {
+	return b.size
+}
+
+func (b *boltBatch) Reset() {
+	b.writes = b.writes[:0]
+	b.size = 0
+}
+
+// boltIterator is an iterator over the content of a bolt database with a key
+// prefix. To avoid holding a read transaction open, which would block the memory
+// map from growing on writes, the items are read in chunks in separate short
+// transactions, so the iterator doesn't see a consistent snapshot.
+type boltIterator struct {
+	db     *bolt.DB
+	prefix []byte
+	next   []byte // Internal key to continue reading the next chunk from
+	done   bool   // Whether the last chunk was read
+
+	keys   [][]byte
+	values [][]byte
+	pos    int
+	err    error
+}
+
+// fill reads the next chunk of items from the database.
+func (it *boltIterator) fill() {
+	it.keys, it.values, it.pos = it.keys[:0], it.values[:0], 0
+	it.err = it.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(boltBucket).Cursor()
+		for key, value := cursor.Seek(it.next); key != nil && bytes.HasPrefix(key, it.prefix); key, value = cursor.Next() {
+			if len(it.keys) == boltIteratorChunk {
+				it.next = append([]byte{}, key...)
+				return nil
+			}
+			it.keys = append(it.keys, append([]byte{}, key[1:]...))
+			it.values = append(it.values, append([]byte{}, value...))
+		}
+		it.done = true
+		return nil
+	})
+	if it.err != nil {
+		it.done = true
+	}
+}
+
+func (it *boltIterator) Next() bool {
+	if it.pos+1 < len(it.keys) {
+		it.pos++
+		return true
+	}
+	if it.done {
+		it.keys, it.values, it.pos = nil, nil, 0
+		return false
+	}
+	it.fill()
+	return len(it.keys) > 0
+}
+
+func (it *boltIterator) Error() error { return it.err }
+
+func (it *boltIterator) Key() []byte {
+	if it.pos < len(it.keys) {
+		return it.keys[it.pos]
+	}
+	return nil
+}
+
+func (it *boltIterator) Value() []byte {
+	if it.pos < len(it.values) {
+		return it.values[it.pos]
+	}
+	return nil
+}
+
+func (it *boltIterator) Release() {
+	it.keys, it.values, it.done = nil, nil, true
+}
@@ -17,6 +17,7 @@
 package ethdb
 
 import (
+	"bytes"
 	"strconv"
 	"strings"
 	"sync"
@@ -27,7 +28,6 @@ import (
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/errors"
 	"github.com/syndtr/goleveldb/leveldb/filter"
-	"github.com/syndtr/goleveldb/leveldb/iterator"
 	"github.com/syndtr/goleveldb/leveldb/opt"
 	"github.com/syndtr/goleveldb/leveldb/util"
 )
@@ -118,15 +118,54 @@ func (db *LDBDatabase) Delete(key []byte) error {
 	return db.db.Delete(key, nil)
 }
 
-func (db *LDBDatabase) NewIterator() iterator.Iterator {
+// NewIterator returns an iterator over the entire database content.
+func (db *LDBDatabase) NewIterator() Iterator {
 	return db.db.NewIterator(nil, nil)
 }
 
 // NewIteratorWithPrefix returns a iterator to iterate over subset of database content with a particular prefix.
-func (db *LDBDatabase) NewIteratorWithPrefix(prefix []byte) iterator.Iterator {
+func (db *LDBDatabase) NewIteratorWithPrefix(prefix []byte) Iterator {
 	return db.db.NewIterator(util.BytesPrefix(prefix), nil)
 }
 
+// NewIteratorWithStart returns an iterator over the subset of database content
+// starting at a particular initial key.
+func (db *LDBDatabase) NewIteratorWithStart(start []byte) Iterator {
+	return db.db.NewIterator(&util.Range{Start: start}, nil)
+}
+
+// DeleteRange deletes all the keys in the range [start, limit).
+func (db *LDBDatabase) DeleteRange(start, limit []byte) error {
+	it := db.db.NewIterator(&util.Range{Start: start, Limit: limit}, nil)
+	defer it.Release()
+
+	batch := db.NewBatch()
+	for it.Next() {
+		batch.Delete(it.Key())
+		if batch.ValueSize() >= IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+// Stat returns a particular internal stat of the database, see the property
+// names supported by LevelDB (e.g. leveldb.stats, leveldb.iostats).
+func (db *LDBDatabase) Stat(property string) (string, error) {
+	return db.db.GetProperty(property)
+}
+
+// Compact flattens the underlying data store for the given key range.
+func (db *LDBDatabase) Compact(start []byte, limit []byte) error {
+	return db.db.CompactRange(util.Range{Start: start, Limit: limit})
+}
+
 func (db *LDBDatabase) Close() {
 	// Stop the metrics collection to avoid internal database races
 	db.quitLock.Lock()
@@ -147,10 +186,6 @@ func (db *LDBDatabase) Close() {
 	}
 }
 
-func (db *LDBDatabase) LDB() *leveldb.DB {
-	return db.db
-}
-
 // Meter configures the database metrics collectors and
 func (db *LDBDatabase) Meter(prefix string) {
 	// Short circuit metering if the metrics system is disabled
@@ -311,6 +346,12 @@ func (b *ldbBatch) Put(key, value []byte) error {
 	return nil
 }
 
+func (b *ldbBatch) Delete(key []byte) error {
+	b.b.Delete(key)
+	b.size += len(key)
+	return nil
+}
+
 func (b *ldbBatch) Write() error {
 	return b.db.Write(b.b, nil)
 }
@@ -354,10 +395,107 @@ func (dt *table) Delete(key []byte) error {
 	return dt.db.Delete(append([]byte(dt.prefix), key...))
 }
 
+// NewIterator returns an iterator over the content of the table, with the table
+// prefix stripped from the keys.
+func (dt *table) NewIterator() Iterator {
+	return dt.NewIteratorWithPrefix(nil)
+}
+
+// NewIteratorWithPrefix returns an iterator over the content of the table with a
+// particular key prefix, with the table prefix stripped from the keys.
+func (dt *table) NewIteratorWithPrefix(prefix []byte) Iterator {
+	return &tableIterator{
+		it:     dt.db.NewIteratorWithPrefix(append([]byte(dt.prefix), prefix...)),
+		prefix: []byte(dt.prefix),
+	}
+}
+
+// NewIteratorWithStart returns an iterator over the content of the table starting
+// at a particular initial key, with the table prefix stripped from the keys.
+func (dt *table) NewIteratorWithStart(start []byte) Iterator {
+	return &tableIterator{
+		it:     dt.db.NewIteratorWithStart(append([]byte(dt.prefix), start...)),
+		prefix: []byte(dt.prefix),
+	}
+}
+
+// DeleteRange deletes all the keys of the table in the range [start, limit).
+func (dt *table) DeleteRange(start, limit []byte) error {
+	return dt.db.DeleteRange(dt.bounds(start, limit))
+}
+
+// Stat returns a particular internal stat of the underlying database.
+func (dt *table) Stat(property string) (string, error) {
+	return dt.db.Stat(property)
+}
+
+// Compact flattens the underlying data store for the given key range of the table.
+func (dt *table) Compact(start []byte, limit []byte) error {
+	return dt.db.Compact(dt.bounds(start, limit))
+}
+
+// bounds converts a key range of the table into a key range of the underlying
+// database, where nil keys denote the start and end of the table.
+func (dt *table) bounds(start, limit []byte) ([]byte, []byte) {
+	start = append([]byte(dt.prefix), start...)
+	if limit != nil {
+		return start, append([]byte(dt.prefix), limit...)
+	}
+	// Find the first key after all the keys with the table prefix
+	end := []byte(dt.prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end = append(end[:i:i], end[i]+1)
+			return start, end
+		}
+	}
+	return start, nil
+}
+
 func (dt *table) Close() {
 	// Do nothing; don't close the underlying DB.
 }
 
+// tableIterator is an iterator over a table, stripping the table prefix from
+// the keys of the underlying database iterator and stopping at the first key
+// outside of the table.
+type tableIterator struct {
+	it     Iterator
+	prefix []byte
+	done   bool
+}
+
+func (it *tableIterator) Next() bool {
+	if it.done {
+		return false
+	}
+	if !it.it.Next() || !bytes.HasPrefix(it.it.Key(), it.prefix) {
+		it.done = true
+		return false
+	}
+	return true
+}
+
+func (it *tableIterator) Error() error { return it.it.Error() }
+func (it *tableIterator) Release()     { it.it.Release() }
+
+func (it *tableIterator) Key() []byte {
+	if it.done {
+		return nil
+	}
+	if key := it.it.Key(); key != nil {
+		return key[len(it.prefix):]
+	}
+	return nil
+}
+
+func (it *tableIterator) Value() []byte {
+	if it.done {
+		return nil
+	}
+	return it.it.Value()
+}
+
 type tableBatch struct {
 	batch  Batch
 	prefix string
@@ -376,6 +514,10 @@ func (tb *tableBatch) Put(key, value []byte) error {
 	return tb.batch.Put(append([]byte(tb.prefix), key...), value)
 }
 
+func (tb *tableBatch) Delete(key []byte) error {
+	return tb.batch.Delete(append([]byte(tb.prefix), key...))
+}
+
 func (tb *tableBatch) Write() error {
 	return tb.batch.Write()
 }
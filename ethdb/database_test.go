@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"sort"
 	"strconv"
 	"sync"
 	"testing"
@@ -44,6 +45,22 @@ func newTestLDB() (*ethdb.LDBDatabase, func()) {
 	}
 }
 
+func newTestBoltDB() (*ethdb.BoltDatabase, func()) {
+	dirname, err := ioutil.TempDir(os.TempDir(), "ethdb_test_")
+	if err != nil {
+		panic("failed to create test file: " + err.Error())
+	}
+	db, err := ethdb.NewBoltDatabase(dirname, 0)
+	if err != nil {
+		panic("failed to create test database: " + err.Error())
+	}
+
+	return db, func() {
+		db.Close()
+		os.RemoveAll(dirname)
+	}
+}
+
 var test_values = []string{"", "a", "1251", "\x00123\x00"}
 
 func TestLDB_PutGet(t *testing.T) {
@@ -57,6 +74,17 @@ func TestMemoryDB_PutGet(t *testing.T) {
 	testPutGet(db, t)
 }
 
+func TestBoltDB_PutGet(t *testing.T) {
+	db, remove := newTestBoltDB()
+	defer remove()
+	testPutGet(db, t)
+}
+
+func TestTable_PutGet(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	testPutGet(ethdb.NewTable(db, "t-"), t)
+}
+
 func testPutGet(db ethdb.Database, t *testing.T) {
 	t.Parallel()
 
@@ -135,6 +163,12 @@ func TestMemoryDB_ParallelPutGet(t *testing.T) {
 	testParallelPutGet(db, t)
 }
 
+func TestBoltDB_ParallelPutGet(t *testing.T) {
+	db, remove := newTestBoltDB()
+	defer remove()
+	testParallelPutGet(db, t)
+}
+
 func testParallelPutGet(db ethdb.Database, t *testing.T) {
 	const n = 8
 	var pending sync.WaitGroup
@@ -190,3 +224,107 @@ func testParallelPutGet(db ethdb.Database, t *testing.T) {
 	}
 	pending.Wait()
 }
+
+func TestLDB_IteratorDeleteRange(t *testing.T) {
+	db, remove := newTestLDB()
+	defer remove()
+	testIteratorDeleteRange(db, t)
+}
+
+func TestMemoryDB_IteratorDeleteRange(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	testIteratorDeleteRange(db, t)
+}
+
+func TestBoltDB_IteratorDeleteRange(t *testing.T) {
+	db, remove := newTestBoltDB()
+	defer remove()
+	testIteratorDeleteRange(db, t)
+}
+
+func TestBoltDB_Compact(t *testing.T) {
+	db, remove := newTestBoltDB()
+	defer remove()
+
+	if err := db.Compact(nil, nil); err != ethdb.ErrCompactionUnsupported {
+		t.Fatalf("compaction error mismatch: have %v, want %v", err, ethdb.ErrCompactionUnsupported)
+	}
+}
+
+func TestTable_IteratorDeleteRange(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	db.Put([]byte("s"), []byte("outside"))
+	db.Put([]byte("u"), []byte("outside"))
+	testIteratorDeleteRange(ethdb.NewTable(db, "t"), t)
+
+	if ok, _ := db.Has([]byte("s")); !ok {
+		t.Errorf("key before the table deleted")
+	}
+	if ok, _ := db.Has([]byte("u")); !ok {
+		t.Errorf("key after the table deleted")
+	}
+}
+
+// testIteratorDeleteRange checks that the iterators return the database content
+// in key order, bounded by the prefix or start key, and that range deletion only
+// removes the keys within the range.
+func testIteratorDeleteRange(db ethdb.Database, t *testing.T) {
+	t.Parallel()
+
+	// Insert enough keys to span multiple iterator chunks of the bolt backend
+	const n = 3000
+	keys := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		keys = append(keys, fmt.Sprintf("%c%04d", 'a'+i%3, i))
+	}
+	sort.Strings(keys)
+	batch := db.NewBatch()
+	for _, key := range keys {
+		batch.Put([]byte(key), []byte("v"+key))
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatalf("batch write failed: %v", err)
+	}
+	check := func(name string, it ethdb.Iterator, want []string) {
+		defer it.Release()
+
+		var have []string
+		for it.Next() {
+			if !bytes.Equal(it.Value(), []byte("v"+string(it.Key()))) {
+				t.Fatalf("%s: value mismatch for key %q: %q", name, it.Key(), it.Value())
+			}
+			have = append(have, string(it.Key()))
+		}
+		if err := it.Error(); err != nil {
+			t.Fatalf("%s: iteration failed: %v", name, err)
+		}
+		if len(have) != len(want) {
+			t.Fatalf("%s: item count mismatch: have %d, want %d", name, len(have), len(want))
+		}
+		for i := range want {
+			if have[i] != want[i] {
+				t.Fatalf("%s: item %d mismatch: have %q, want %q", name, i, have[i], want[i])
+			}
+		}
+	}
+	check("all", db.NewIterator(), keys)
+	check("prefix", db.NewIteratorWithPrefix([]byte("b")), keys[n/3:2*n/3])
+	check("start", db.NewIteratorWithStart([]byte("b")), keys[n/3:])
+	check("missing prefix", db.NewIteratorWithPrefix([]byte("d")), nil)
+
+	if err := db.DeleteRange([]byte("a1000"), []byte("b")); err != nil {
+		t.Fatalf("range deletion failed: %v", err)
+	}
+	var remaining []string
+	for _, key := range keys {
+		if key < "a1000" || key >= "b" {
+			remaining = append(remaining, key)
+		}
+	}
+	check("after deletion", db.NewIterator(), remaining)
+
+	if err := db.DeleteRange([]byte("c"), nil); err != nil {
+		t.Fatalf("open range deletion failed: %v", err)
+	}
+	check("after open deletion", db.NewIterator(), remaining[:len(remaining)-n/3])
+}
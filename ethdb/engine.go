@@ -0,0 +1,64 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethdb
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// The supported key-value store backends.
+const (
+	EngineLevelDB = "leveldb"
+	EngineBolt    = "bolt"
+)
+
+// DetectEngine returns the backend of an existing database in the given
+// directory, or an empty string if there is no database yet.
+func DetectEngine(file string) string {
+	switch {
+	case common.FileExist(filepath.Join(file, "CURRENT")):
+		return EngineLevelDB
+	case common.FileExist(filepath.Join(file, boltFileName)):
+		return EngineBolt
+	}
+	return ""
+}
+
+// NewDatabase opens a key-value store with the given backend in the given
+// directory. An empty engine picks the backend of an existing database, or
+// LevelDB for a new one. Opening an existing database with a different backend
+// is rejected.
+func NewDatabase(engine string, file string, cache int, handles int) (Database, error) {
+	existing := DetectEngine(file)
+	if engine == "" {
+		engine = existing
+	}
+	if existing != "" && existing != engine {
+		return nil, fmt.Errorf("database %s already exists with engine %s, can't open with %s", file, existing, engine)
+	}
+	switch engine {
+	case EngineLevelDB, "":
+		return NewLDBDatabase(file, cache, handles)
+	case EngineBolt:
+		return NewBoltDatabase(file, cache)
+	default:
+		return nil, fmt.Errorf("unknown database engine: %s", engine)
+	}
+}
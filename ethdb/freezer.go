@@ -0,0 +1,233 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethdb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+var (
+	// errUnknownTable is returned if the user attempts to read from a table that
+	// is not tracked by the freezer.
+	errUnknownTable = errors.New("unknown table")
+
+	// errMissingItem is returned if an append doesn't contain an item for each
+	// of the tables tracked by the freezer.
+	errMissingItem = errors.New("missing item for table")
+)
+
+// Freezer is an append-only store of immutable items, numbered sequentially and
+// grouped into a set of flat file tables. An item is appended into all the tables
+// at once, so they always contain the same number of items.
+//
+// It is used to move old, finalized chain segments out of the key-value store,
+// which would otherwise grow huge and slow to compact.
+type Freezer struct {
+	frozen uint64 // Number of items already frozen (atomic)
+
+	tables map[string]*freezerTable // Data tables for storing everything
+	lock   sync.Mutex               // Mutex serializing appends and truncations
+}
+
+// NewFreezer creates a freezer in the given directory with the given tables,
+// truncating them to the same length if a crash left them out of sync.
+func NewFreezer(datadir string, tables []string) (*Freezer, error) {
+	if err := os.MkdirAll(datadir, 0755); err != nil {
+		return nil, err
+	}
+	freezer := &Freezer{
+		tables: make(map[string]*freezerTable),
+	}
+	for _, name := range tables {
+		table, err := newFreezerTable(datadir, name)
+		if err != nil {
+			freezer.Close()
+			return nil, err
+		}
+		freezer.tables[name] = table
+	}
+	if err := freezer.repair(); err != nil {
+		freezer.Close()
+		return nil, err
+	}
+	log.Info("Opened ancient store", "path", datadir, "items", freezer.frozen)
+	return freezer, nil
+}
+
+// repair truncates all the tables to the length of the shortest one.
+func (f *Freezer) repair() error {
+	min := ^uint64(0)
+	for _, table := range f.tables {
+		if items := table.Items(); items < min {
+			min = items
+		}
+	}
+	if len(f.tables) == 0 {
+		min = 0
+	}
+	for _, table := range f.tables {
+		if err := table.truncate(min); err != nil {
+			return err
+		}
+	}
+	atomic.StoreUint64(&f.frozen, min)
+	return nil
+}
+
+// Close terminates the freezer, closing all the data files.
+func (f *Freezer) Close() error {
+	var errs []error
+	for _, table := range f.tables {
+		if err := table.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if errs != nil {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
+
+// HasAncient returns an indicator whether the specified ancient data exists in
+// the freezer.
+func (f *Freezer) HasAncient(kind string, number uint64) bool {
+	if table := f.tables[kind]; table != nil {
+		return number < table.Items()
+	}
+	return false
+}
+
+// Ancient retrieves an ancient binary blob from the append-only immutable files.
+func (f *Freezer) Ancient(kind string, number uint64) ([]byte, error) {
+	if table := f.tables[kind]; table != nil {
+		return table.Retrieve(number)
+	}
+	return nil, errUnknownTable
+}
+
+// Ancients returns the number of items frozen in the freezer.
+func (f *Freezer) Ancients() uint64 {
+	return atomic.LoadUint64(&f.frozen)
+}
+
+// AncientSize returns the size of the given table of the freezer.
+func (f *Freezer) AncientSize(kind string) (uint64, error) {
+	if table := f.tables[kind]; table != nil {
+		return table.Size(), nil
+	}
+	return 0, errUnknownTable
+}
+
+// AppendAncient injects an item into every table of the freezer. The number must
+// be the next one in line and the items must contain an entry for each table,
+// otherwise the append is rejected. If any of the writes fail, the tables are
+// rolled back to keep them in sync.
+func (f *Freezer) AppendAncient(number uint64, items map[string][]byte) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if frozen := atomic.LoadUint64(&f.frozen); number != frozen {
+		return errOutOrderInsertion
+	}
+	for name := range f.tables {
+		if _, ok := items[name]; !ok {
+			return fmt.Errorf("%v: %s", errMissingItem, name)
+		}
+	}
+	for name, table := range f.tables {
+		if err := table.Append(number, items[name]); err != nil {
+			for _, table := range f.tables {
+				if err := table.truncate(number); err != nil {
+					log.Error("Failed to roll back ancient append", "number", number, "err", err)
+				}
+			}
+			return fmt.Errorf("table %s: %v", name, err)
+		}
+	}
+	atomic.AddUint64(&f.frozen, 1)
+	return nil
+}
+
+// TruncateAncients discards any recent items above the provided threshold.
+func (f *Freezer) TruncateAncients(items uint64) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if atomic.LoadUint64(&f.frozen) <= items {
+		return nil
+	}
+	for _, table := range f.tables {
+		if err := table.truncate(items); err != nil {
+			return err
+		}
+	}
+	atomic.StoreUint64(&f.frozen, items)
+	return nil
+}
+
+// Sync flushes all the tables to disk.
+func (f *Freezer) Sync() error {
+	var errs []error
+	for _, table := range f.tables {
+		if err := table.Sync(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if errs != nil {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
+
+// freezerdb is a database wrapper that attaches an ancient store to a key-value
+// store, serving both through a single handle.
+type freezerdb struct {
+	Database
+	*Freezer
+}
+
+// NewDatabaseWithFreezer attaches an ancient store with the given tables in the
+// freezer directory to the key-value store. The ancient store is closed along
+// with the key-value store.
+func NewDatabaseWithFreezer(db Database, freezer string, tables []string) (Database, error) {
+	frdb, err := NewFreezer(freezer, tables)
+	if err != nil {
+		return nil, err
+	}
+	return &freezerdb{Database: db, Freezer: frdb}, nil
+}
+
+// Close implements Database, closing both the ancient and the key-value store.
+func (db *freezerdb) Close() {
+	if err := db.Freezer.Close(); err != nil {
+		log.Error("Failed to close ancient store", "err", err)
+	}
+	db.Database.Close()
+}
+
+// Meter configures the metrics collectors of the key-value store, if supported.
+func (db *freezerdb) Meter(prefix string) {
+	if meterer, ok := db.Database.(interface{ Meter(prefix string) }); ok {
+		meterer.Meter(prefix)
+	}
+}
@@ -0,0 +1,263 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+)
+
+// indexEntrySize is the size of a single index entry, the big endian encoded end
+// offset of an item within the data file.
+const indexEntrySize = 8
+
+var (
+	// errOutOfBounds is returned if the item requested is not contained within
+	// the freezer table.
+	errOutOfBounds = errors.New("out of bounds")
+
+	// errOutOrderInsertion is returned if the user attempts to inject out-of-order
+	// items into the freezer table.
+	errOutOrderInsertion = errors.New("the append operation is out-order")
+
+	// errClosed is returned if an operation attempts to read from or write to
+	// the freezer table after it has already been closed.
+	errClosed = errors.New("closed")
+)
+
+// freezerTable is an append-only flat file of variable sized items. The items
+// are numbered sequentially and the end offset of each is kept in an index file
+// alongside, so any of them can be looked up with two reads.
+type freezerTable struct {
+	items uint64 // Number of items stored in the table (atomic)
+	size  uint64 // Number of bytes stored in the data file
+
+	data  *os.File // File descriptor of the item data
+	index *os.File // File descriptor of the item end offsets
+
+	lock sync.RWMutex // Mutex protecting the file descriptors
+}
+
+// newFreezerTable opens the data and index files of a freezer table, creating
+// them if they don't exist yet, and repairs any damage left by a crash.
+func newFreezerTable(path, name string) (*freezerTable, error) {
+	data, err := os.OpenFile(filepath.Join(path, name+".dat"), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	index, err := os.OpenFile(filepath.Join(path, name+".idx"), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		data.Close()
+		return nil, err
+	}
+	table := &freezerTable{
+		data:  data,
+		index: index,
+	}
+	if err := table.repair(); err != nil {
+		table.Close()
+		return nil, err
+	}
+	return table, nil
+}
+
+// repair cross checks the data and index files and truncates them to be in sync
+// with each other after a potential crash during an append.
+func (t *freezerTable) repair() error {
+	stat, err := t.index.Stat()
+	if err != nil {
+		return err
+	}
+	// Drop any partially written index entry
+	items := uint64(stat.Size()) / indexEntrySize
+	if err := t.index.Truncate(int64(items * indexEntrySize)); err != nil {
+		return err
+	}
+	if stat, err = t.data.Stat(); err != nil {
+		return err
+	}
+	size := uint64(stat.Size())
+
+	// Drop any item whose data didn't make it to disk, then any dangling data
+	for ; items > 0; items-- {
+		end, err := t.offset(items)
+		if err != nil {
+			return err
+		}
+		if end <= size {
+			size = end
+			break
+		}
+	}
+	if items == 0 {
+		size = 0
+	}
+	if err := t.index.Truncate(int64(items * indexEntrySize)); err != nil {
+		return err
+	}
+	if err := t.data.Truncate(int64(size)); err != nil {
+		return err
+	}
+	t.items, t.size = items, size
+	return nil
+}
+
+// offset retrieves the end offset of the given number of items from the index.
+func (t *freezerTable) offset(items uint64) (uint64, error) {
+	if items == 0 {
+		return 0, nil
+	}
+	buf := make([]byte, indexEntrySize)
+	if _, err := t.index.ReadAt(buf, int64((items-1)*indexEntrySize)); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(buf), nil
+}
+
+// Items returns the number of items stored in the table.
+func (t *freezerTable) Items() uint64 {
+	return atomic.LoadUint64(&t.items)
+}
+
+// Size returns the total size of the table, the data and index files included.
+func (t *freezerTable) Size() uint64 {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.size + t.Items()*indexEntrySize
+}
+
+// Append injects a binary blob at the end of the freezer table. The item number
+// must be the next one in line, otherwise the append is rejected.
+func (t *freezerTable) Append(item uint64, blob []byte) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.data == nil {
+		return errClosed
+	}
+	if atomic.LoadUint64(&t.items) != item {
+		return errOutOrderInsertion
+	}
+	// Write the data first so a crash can't index missing content
+	if _, err := t.data.WriteAt(blob, int64(t.size)); err != nil {
+		return err
+	}
+	entry := make([]byte, indexEntrySize)
+	binary.BigEndian.PutUint64(entry, t.size+uint64(len(blob)))
+	if _, err := t.index.WriteAt(entry, int64(item*indexEntrySize)); err != nil {
+		return err
+	}
+	t.size += uint64(len(blob))
+	atomic.AddUint64(&t.items, 1)
+	return nil
+}
+
+// Retrieve looks up the data offset of an item and returns the raw binary blob.
+func (t *freezerTable) Retrieve(item uint64) ([]byte, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if t.data == nil {
+		return nil, errClosed
+	}
+	if atomic.LoadUint64(&t.items) <= item {
+		return nil, errOutOfBounds
+	}
+	start, err := t.offset(item)
+	if err != nil {
+		return nil, err
+	}
+	end, err := t.offset(item + 1)
+	if err != nil {
+		return nil, err
+	}
+	if end < start {
+		return nil, fmt.Errorf("corrupted index entry %d: end %d before start %d", item, end, start)
+	}
+	blob := make([]byte, end-start)
+	if _, err := t.data.ReadAt(blob, int64(start)); err != nil {
+		return nil, err
+	}
+	return blob, nil
+}
+
+// truncate discards any items above the provided limit.
+func (t *freezerTable) truncate(items uint64) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.data == nil {
+		return errClosed
+	}
+	if atomic.LoadUint64(&t.items) <= items {
+		return nil
+	}
+	size, err := t.offset(items)
+	if err != nil {
+		return err
+	}
+	if err := t.index.Truncate(int64(items * indexEntrySize)); err != nil {
+		return err
+	}
+	if err := t.data.Truncate(int64(size)); err != nil {
+		return err
+	}
+	t.size = size
+	atomic.StoreUint64(&t.items, items)
+	return nil
+}
+
+// Sync pushes any pending data from memory out to disk.
+func (t *freezerTable) Sync() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.data == nil {
+		return errClosed
+	}
+	if err := t.data.Sync(); err != nil {
+		return err
+	}
+	return t.index.Sync()
+}
+
+// Close closes all opened files.
+func (t *freezerTable) Close() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var errs []error
+	for _, f := range []*os.File{t.data, t.index} {
+		if f == nil {
+			continue
+		}
+		if err := f.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	t.data, t.index = nil, nil
+	if errs != nil {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethdb
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Tests that items appended into the freezer can be retrieved, also after the
+// freezer is reopened, and that out of order appends are rejected.
+func TestFreezerAppendRetrieve(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tables := []string{"a", "b"}
+	f, err := NewFreezer(dir, tables)
+	if err != nil {
+		t.Fatalf("failed to create freezer: %v", err)
+	}
+	for i := uint64(0); i < 10; i++ {
+		items := map[string][]byte{"a": {byte(i)}, "b": bytes.Repeat([]byte{byte(i)}, int(i))}
+		if err := f.AppendAncient(i, items); err != nil {
+			t.Fatalf("item %d: failed to append: %v", i, err)
+		}
+	}
+	if err := f.AppendAncient(20, map[string][]byte{"a": nil, "b": nil}); err != errOutOrderInsertion {
+		t.Errorf("out of order append error mismatch: have %v, want %v", err, errOutOrderInsertion)
+	}
+	if err := f.AppendAncient(10, map[string][]byte{"a": nil}); err == nil {
+		t.Errorf("incomplete append succeeded")
+	}
+	f.Close()
+
+	if f, err = NewFreezer(dir, tables); err != nil {
+		t.Fatalf("failed to reopen freezer: %v", err)
+	}
+	defer f.Close()
+
+	if frozen := f.Ancients(); frozen != 10 {
+		t.Fatalf("ancients mismatch: have %d, want %d", frozen, 10)
+	}
+	for i := uint64(0); i < 10; i++ {
+		if blob, err := f.Ancient("b", i); err != nil || !bytes.Equal(blob, bytes.Repeat([]byte{byte(i)}, int(i))) {
+			t.Errorf("item %d: retrieved mismatch: %x, %v", i, blob, err)
+		}
+	}
+	// Table "b" holds 45 bytes of data and an 8 byte index entry for each item
+	if size, err := f.AncientSize("b"); err != nil || size != 45+10*indexEntrySize {
+		t.Errorf("table size mismatch: have %d/%v, want %d", size, err, 45+10*indexEntrySize)
+	}
+	if _, err := f.Ancient("b", 10); err != errOutOfBounds {
+		t.Errorf("out of bounds error mismatch: have %v, want %v", err, errOutOfBounds)
+	}
+	if _, err := f.Ancient("c", 0); err != errUnknownTable {
+		t.Errorf("unknown table error mismatch: have %v, want %v", err, errUnknownTable)
+	}
+}
+
+// Tests that tables left out of sync by a crash are repaired when the freezer
+// is opened, and that truncation discards the recent items.
+func TestFreezerRepairTruncate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tables := []string{"a", "b"}
+	f, err := NewFreezer(dir, tables)
+	if err != nil {
+		t.Fatalf("failed to create freezer: %v", err)
+	}
+	for i := uint64(0); i < 10; i++ {
+		if err := f.AppendAncient(i, map[string][]byte{"a": []byte(fmt.Sprint(i)), "b": []byte(fmt.Sprint(i))}); err != nil {
+			t.Fatalf("item %d: failed to append: %v", i, err)
+		}
+	}
+	f.Close()
+
+	// Simulate a crash with a partial index entry in one table and lost data in the other
+	index, _ := os.OpenFile(filepath.Join(dir, "a.idx"), os.O_RDWR, 0644)
+	index.Truncate(9*indexEntrySize + 3)
+	index.Close()
+
+	data, _ := os.OpenFile(filepath.Join(dir, "b.dat"), os.O_RDWR, 0644)
+	data.Truncate(7)
+	data.Close()
+
+	if f, err = NewFreezer(dir, tables); err != nil {
+		t.Fatalf("failed to reopen freezer: %v", err)
+	}
+	defer f.Close()
+
+	if frozen := f.Ancients(); frozen != 7 {
+		t.Fatalf("ancients mismatch after repair: have %d, want %d", frozen, 7)
+	}
+	if err := f.AppendAncient(7, map[string][]byte{"a": []byte("7"), "b": []byte("7")}); err != nil {
+		t.Fatalf("failed to append after repair: %v", err)
+	}
+	if blob, _ := f.Ancient("b", 7); string(blob) != "7" {
+		t.Errorf("appended item mismatch: have %q, want %q", blob, "7")
+	}
+	if err := f.TruncateAncients(3); err != nil {
+		t.Fatalf("failed to truncate: %v", err)
+	}
+	if frozen := f.Ancients(); frozen != 3 {
+		t.Errorf("ancients mismatch after truncation: have %d, want %d", frozen, 3)
+	}
+	if f.HasAncient("a", 3) || !f.HasAncient("a", 2) {
+		t.Errorf("truncated items still available")
+	}
+}
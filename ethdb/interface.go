@@ -16,6 +16,12 @@
 
 package ethdb
 
+import "errors"
+
+// ErrCompactionUnsupported is returned by Compact if the backing data store
+// can't be compacted in place.
+var ErrCompactionUnsupported = errors.New("compaction not supported")
+
 // Code using batches should try to add this much data to the batch.
 // The value was determined empirically.
 const IdealBatchSize = 100 * 1024
@@ -25,12 +31,83 @@ type Putter interface {
 	Put(key []byte, value []byte) error
 }
 
+// Deleter wraps the database delete operation supported by both batches and regular databases.
+type Deleter interface {
+	Delete(key []byte) error
+}
+
+// Iterator iterates over a database's key/value pairs in ascending key order.
+//
+// An iterator must be released after use, but it is not necessary to read it
+// until exhaustion. The contents of the returned slices must not be modified and
+// are only valid until the next call to Next.
+type Iterator interface {
+	// Next moves the iterator to the next key/value pair, returning whether the
+	// iterator is exhausted.
+	Next() bool
+
+	// Error returns any accumulated error. Exhausting all the key/value pairs
+	// is not considered to be an error.
+	Error() error
+
+	// Key returns the key of the current key/value pair, or nil if done.
+	Key() []byte
+
+	// Value returns the value of the current key/value pair, or nil if done.
+	Value() []byte
+
+	// Release releases associated resources.
+	Release()
+}
+
+// Iteratee wraps the NewIterator methods of a backing data store.
+type Iteratee interface {
+	// NewIterator creates an iterator over the entire keyspace contained within
+	// the database.
+	NewIterator() Iterator
+
+	// NewIteratorWithPrefix creates an iterator over the subset of the database
+	// content with a particular key prefix.
+	NewIteratorWithPrefix(prefix []byte) Iterator
+
+	// NewIteratorWithStart creates an iterator over the subset of the database
+	// content starting at a particular initial key (or after, if it does not
+	// exist).
+	NewIteratorWithStart(start []byte) Iterator
+}
+
+// RangeDeleter wraps the DeleteRange method of a backing data store.
+type RangeDeleter interface {
+	// DeleteRange deletes all the keys in the range [start, limit). A nil start
+	// is treated as a key before all keys and a nil limit as a key after all keys.
+	DeleteRange(start, limit []byte) error
+}
+
+// Stater wraps the Stat method of a backing data store.
+type Stater interface {
+	// Stat returns a particular internal stat of the database.
+	Stat(property string) (string, error)
+}
+
+// Compacter wraps the Compact method of a backing data store.
+type Compacter interface {
+	// Compact flattens the underlying data store for the given key range. A nil
+	// start is treated as a key before all keys and a nil limit as a key after
+	// all keys, so Compact(nil, nil) compacts the entire data store. Stores which
+	// can't be compacted return ErrCompactionUnsupported.
+	Compact(start []byte, limit []byte) error
+}
+
 // Database wraps all database operations. All methods are safe for concurrent use.
 type Database interface {
 	Putter
+	Deleter
+	Iteratee
+	RangeDeleter
+	Stater
+	Compacter
 	Get(key []byte) ([]byte, error)
 	Has(key []byte) (bool, error)
-	Delete(key []byte) error
 	Close()
 	NewBatch() Batch
 }
@@ -39,8 +116,37 @@ type Database interface {
 // when Write is called. Batch cannot be used concurrently.
 type Batch interface {
 	Putter
+	Deleter
 	ValueSize() int // amount of data in the batch
 	Write() error
 	// Reset resets the batch for reuse
 	Reset()
 }
+
+// AncientReader wraps the read operations of an ancient store, holding immutable
+// items numbered sequentially in a set of tables.
+type AncientReader interface {
+	// HasAncient returns an indicator whether the specified ancient item exists.
+	HasAncient(kind string, number uint64) bool
+
+	// Ancient retrieves an ancient item from the given table.
+	Ancient(kind string, number uint64) ([]byte, error)
+
+	// Ancients returns the number of items in the ancient store.
+	Ancients() uint64
+
+	// AncientSize returns the size of the given table of the ancient store.
+	AncientSize(kind string) (uint64, error)
+}
+
+// AncientWriter wraps the write operations of an ancient store.
+type AncientWriter interface {
+	// AppendAncient injects an item into every table of the ancient store.
+	AppendAncient(number uint64, items map[string][]byte) error
+
+	// TruncateAncients discards all but the first n ancient items.
+	TruncateAncients(n uint64) error
+
+	// Sync flushes all the ancient items to disk.
+	Sync() error
+}
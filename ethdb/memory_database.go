@@ -17,7 +17,10 @@
 package ethdb
 
 import (
+	"bytes"
 	"errors"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -88,6 +91,69 @@ func (db *MemDatabase) Delete(key []byte) error {
 	return nil
 }
 
+// NewIterator returns an iterator over a snapshot of the entire database content.
+func (db *MemDatabase) NewIterator() Iterator {
+	return db.NewIteratorWithPrefix(nil)
+}
+
+// NewIteratorWithPrefix returns an iterator over a snapshot of the database
+// content with a particular key prefix.
+func (db *MemDatabase) NewIteratorWithPrefix(prefix []byte) Iterator {
+	return db.snapshot(func(key string) bool { return strings.HasPrefix(key, string(prefix)) })
+}
+
+// NewIteratorWithStart returns an iterator over a snapshot of the database
+// content starting at a particular initial key.
+func (db *MemDatabase) NewIteratorWithStart(start []byte) Iterator {
+	return db.snapshot(func(key string) bool { return key >= string(start) })
+}
+
+// snapshot creates an iterator over the sorted key/value pairs of the database
+// whose key matches the filter.
+func (db *MemDatabase) snapshot(filter func(key string) bool) *memIterator {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	var (
+		keys   = make([]string, 0, len(db.db))
+		values = make([][]byte, 0, len(db.db))
+	)
+	for key := range db.db {
+		if filter(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		values = append(values, db.db[key])
+	}
+	return &memIterator{keys: keys, values: values, index: -1}
+}
+
+// DeleteRange deletes all the keys in the range [start, limit).
+func (db *MemDatabase) DeleteRange(start, limit []byte) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	for key := range db.db {
+		if bytes.Compare([]byte(key), start) >= 0 && (limit == nil || bytes.Compare([]byte(key), limit) < 0) {
+			delete(db.db, key)
+		}
+	}
+	return nil
+}
+
+// Stat returns a particular internal stat of the database. The memory database
+// doesn't track any.
+func (db *MemDatabase) Stat(property string) (string, error) {
+	return "", errors.New("unknown property")
+}
+
+// Compact is a no-op, the memory database has nothing to compact.
+func (db *MemDatabase) Compact(start []byte, limit []byte) error {
+	return nil
+}
+
 func (db *MemDatabase) Close() {}
 
 func (db *MemDatabase) NewBatch() Batch {
@@ -96,7 +162,10 @@ func (db *MemDatabase) NewBatch() Batch {
 
 func (db *MemDatabase) Len() int { return len(db.db) }
 
-type kv struct{ k, v []byte }
+type kv struct {
+	k, v []byte
+	del  bool
+}
 
 type memBatch struct {
 	db     *MemDatabase
@@ -105,16 +174,26 @@ type memBatch struct {
 }
 
 func (b *memBatch) Put(key, value []byte) error {
-	b.writes = append(b.writes, kv{common.CopyBytes(key), common.CopyBytes(value)})
+	b.writes = append(b.writes, kv{common.CopyBytes(key), common.CopyBytes(value), false})
 	b.size += len(value)
 	return nil
 }
 
+func (b *memBatch) Delete(key []byte) error {
+	b.writes = append(b.writes, kv{common.CopyBytes(key), nil, true})
+	b.size += len(key)
+	return nil
+}
+
 func (b *memBatch) Write() error {
 	b.db.lock.Lock()
 	defer b.db.lock.Unlock()
 
 	for _, kv := range b.writes {
+		if kv.del {
+			delete(b.db.db, string(kv.k))
+			continue
+		}
 		b.db.db[string(kv.k)] = kv.v
 	}
 	return nil
@@ -128,3 +207,38 @@ func (b *memBatch) Reset() {
 	b.writes = b.writes[:0]
 	b.size = 0
 }
+
+// memIterator is an iterator over a sorted snapshot of a memory database.
+type memIterator struct {
+	keys   []string
+	values [][]byte
+	index  int
+}
+
+func (it *memIterator) Next() bool {
+	if it.index >= len(it.keys) {
+		return false
+	}
+	it.index++
+	return it.index < len(it.keys)
+}
+
+func (it *memIterator) Error() error { return nil }
+
+func (it *memIterator) Key() []byte {
+	if it.index < 0 || it.index >= len(it.keys) {
+		return nil
+	}
+	return []byte(it.keys[it.index])
+}
+
+func (it *memIterator) Value() []byte {
+	if it.index < 0 || it.index >= len(it.keys) {
+		return nil
+	}
+	return it.values[it.index]
+}
+
+func (it *memIterator) Release() {
+	it.keys, it.values, it.index = nil, nil, 0
+}
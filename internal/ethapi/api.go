@@ -24,6 +24,7 @@ import (
 	"math/big"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts"
@@ -45,12 +46,11 @@ import (
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
-	"github.com/syndtr/goleveldb/leveldb"
-	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 const (
 	defaultGasPrice = 50 * params.Shannon
+	maxBundleTxs    = 100 // Maximum number of transactions simulated by eth_callBundle
 	// statuses of candidates
 	statusMasternode = "MASTERNODE"
 	statusSlashed    = "SLASHED"
@@ -80,6 +80,37 @@ func (s *PublicEthereumAPI) GasPrice(ctx context.Context) (*big.Int, error) {
 	return s.b.SuggestPrice(ctx)
 }
 
+// feeHistoryResult is the result of an eth_feeHistory API call.
+type feeHistoryResult struct {
+	OldestBlock  *hexutil.Big     `json:"oldestBlock"`
+	Reward       [][]*hexutil.Big `json:"reward,omitempty"`
+	GasUsedRatio []float64        `json:"gasUsedRatio"`
+}
+
+// FeeHistory returns the gas prices paid by the transactions of the given number
+// of blocks ending with lastBlock at the requested percentiles, leaving out the
+// special and the TRC21 sponsored transactions.
+func (s *PublicEthereumAPI) FeeHistory(ctx context.Context, blockCount hexutil.Uint64, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*feeHistoryResult, error) {
+	oldest, rewards, ratios, err := s.b.FeeHistory(ctx, uint64(blockCount), lastBlock, rewardPercentiles)
+	if err != nil {
+		return nil, err
+	}
+	result := &feeHistoryResult{
+		OldestBlock:  (*hexutil.Big)(oldest),
+		GasUsedRatio: ratios,
+	}
+	if rewards != nil {
+		result.Reward = make([][]*hexutil.Big, len(rewards))
+		for i, block := range rewards {
+			result.Reward[i] = make([]*hexutil.Big, len(block))
+			for j, reward := range block {
+				result.Reward[i][j] = (*hexutil.Big)(reward)
+			}
+		}
+	}
+	return result, nil
+}
+
 // ProtocolVersion returns the current Ethereum protocol version this node supports
 func (s *PublicEthereumAPI) ProtocolVersion() hexutil.Uint {
 	return hexutil.Uint(s.b.ProtocolVersion())
@@ -190,6 +221,120 @@ func (s *PublicTxPoolAPI) Inspect() map[string]map[string]map[string]string {
 	return content
 }
 
+// Export retrieves the content of the transaction pool as an RLP encoded list,
+// pending transactions first and each account's transactions in nonce order,
+// suitable for importing it into another node.
+func (s *PublicTxPoolAPI) Export() (hexutil.Bytes, error) {
+	pending, queue := s.b.TxPoolContent()
+
+	txs := make(types.Transactions, 0, len(pending)+len(queue))
+	for _, batch := range pending {
+		txs = append(txs, batch...)
+	}
+	for _, batch := range queue {
+		txs = append(txs, batch...)
+	}
+	return rlp.EncodeToBytes(txs)
+}
+
+// Import injects an RLP encoded list of transactions, as produced by Export, into
+// the transaction pool as remote ones, returning the number of transactions that
+// were accepted and dropped.
+func (s *PublicTxPoolAPI) Import(ctx context.Context, data hexutil.Bytes) (map[string]hexutil.Uint, error) {
+	var txs types.Transactions
+	if err := rlp.DecodeBytes(data, &txs); err != nil {
+		return nil, err
+	}
+	// Sort by nonce to avoid needlessly queueing transactions of the same account
+	sort.Sort(types.TxByNonce(txs))
+
+	imported, dropped := 0, 0
+	for i, err := range s.b.SendRemoteTxs(ctx, txs) {
+		if err != nil {
+			log.Debug("Failed to import pool transaction", "hash", txs[i].Hash(), "err", err)
+			dropped++
+			continue
+		}
+		imported++
+	}
+	return map[string]hexutil.Uint{
+		"imported": hexutil.Uint(imported),
+		"dropped":  hexutil.Uint(dropped),
+	}, nil
+}
+
+// GetTransactionStatus returns the lifecycle status of a transaction: whether it
+// is pending or queued in the pool, included in the chain, or was dropped from
+// the pool recently, in which case the reason is also reported.
+func (s *PublicTxPoolAPI) GetTransactionStatus(hash common.Hash) map[string]interface{} {
+	status, drop := s.b.GetPoolLifecycle(hash)
+	switch status {
+	case core.TxStatusPending:
+		return map[string]interface{}{"hash": hash, "status": "pending"}
+	case core.TxStatusQueued:
+		return map[string]interface{}{"hash": hash, "status": "queued"}
+	}
+	if tx, blockHash, blockNumber, index := core.GetTransaction(s.b.ChainDb(), hash); tx != nil {
+		return map[string]interface{}{
+			"hash":             hash,
+			"status":           "included",
+			"blockHash":        blockHash,
+			"blockNumber":      hexutil.Uint64(blockNumber),
+			"transactionIndex": hexutil.Uint64(index),
+		}
+	}
+	if drop != nil {
+		return newRPCTxDrop(drop)
+	}
+	return map[string]interface{}{"hash": hash, "status": "unknown"}
+}
+
+// DroppedTransactions creates a subscription that is triggered each time a
+// transaction is rejected from or dropped out of the transaction pool.
+func (s *PublicTxPoolAPI) DroppedTransactions(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		drops := make(chan core.TxDropEvent, 128)
+		dropSub := s.b.SubscribeTxDropEvent(drops)
+
+		for {
+			select {
+			case ev := <-drops:
+				notifier.Notify(rpcSub.ID, newRPCTxDrop(ev.Drop))
+			case <-rpcSub.Err():
+				dropSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				dropSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// newRPCTxDrop flattens a dropped transaction record into its RPC representation.
+func newRPCTxDrop(drop *core.TxDrop) map[string]interface{} {
+	fields := map[string]interface{}{
+		"hash":   drop.Hash,
+		"status": "dropped",
+		"reason": drop.Reason,
+		"time":   hexutil.Uint64(drop.Time.Unix()),
+	}
+	if drop.Error != "" {
+		fields["error"] = drop.Error
+	}
+	if drop.Replacement != (common.Hash{}) {
+		fields["replacedBy"] = drop.Replacement
+	}
+	return fields
+}
+
 // PublicAccountAPI provides an API to access accounts managed by this node.
 // It offers only methods that can retrieve accounts.
 type PublicAccountAPI struct {
@@ -625,6 +770,79 @@ func (s *PublicBlockChainAPI) GetStorageAt(ctx context.Context, address common.A
 	return res[:], state.Error()
 }
 
+// AccountResult is the result of an eth_getProof call, in the format of EIP-1186.
+type AccountResult struct {
+	Address      common.Address  `json:"address"`
+	AccountProof []string        `json:"accountProof"`
+	Balance      *hexutil.Big    `json:"balance"`
+	CodeHash     common.Hash     `json:"codeHash"`
+	Nonce        hexutil.Uint64  `json:"nonce"`
+	StorageHash  common.Hash     `json:"storageHash"`
+	StorageProof []StorageResult `json:"storageProof"`
+}
+
+// StorageResult is the Merkle proof of a single storage slot of an account.
+type StorageResult struct {
+	Key   string       `json:"key"`
+	Value *hexutil.Big `json:"value"`
+	Proof []string     `json:"proof"`
+}
+
+// GetProof returns the Merkle proof of an account and optionally of some of its
+// storage slots at the given block number.
+func (s *PublicBlockChainAPI) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNr rpc.BlockNumber) (*AccountResult, error) {
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	var (
+		storageTrie  = state.StorageTrie(address)
+		storageHash  = types.EmptyRootHash
+		codeHash     = state.GetCodeHash(address)
+		storageProof = make([]StorageResult, len(storageKeys))
+	)
+	// Non-existent accounts have neither storage nor code
+	if storageTrie != nil {
+		storageHash = storageTrie.Hash()
+	} else {
+		codeHash = crypto.Keccak256Hash(nil)
+	}
+	for i, key := range storageKeys {
+		if storageTrie == nil {
+			storageProof[i] = StorageResult{key, &hexutil.Big{}, []string{}}
+			continue
+		}
+		proof, err := state.GetStorageProof(address, common.HexToHash(key))
+		if err != nil {
+			return nil, err
+		}
+		value := state.GetState(address, common.HexToHash(key))
+		storageProof[i] = StorageResult{key, (*hexutil.Big)(value.Big()), encodeProof(proof)}
+	}
+	accountProof, err := state.GetProof(address)
+	if err != nil {
+		return nil, err
+	}
+	return &AccountResult{
+		Address:      address,
+		AccountProof: encodeProof(accountProof),
+		Balance:      (*hexutil.Big)(state.GetBalance(address)),
+		CodeHash:     codeHash,
+		Nonce:        hexutil.Uint64(state.GetNonce(address)),
+		StorageHash:  storageHash,
+		StorageProof: storageProof,
+	}, state.Error()
+}
+
+// encodeProof converts the nodes of a Merkle proof into hex strings.
+func encodeProof(proof [][]byte) []string {
+	nodes := make([]string, len(proof))
+	for i, node := range proof {
+		nodes[i] = hexutil.Encode(node)
+	}
+	return nodes
+}
+
 func (s *PublicBlockChainAPI) GetBlockSignersByHash(ctx context.Context, blockHash common.Hash) ([]common.Address, error) {
 	block, err := s.b.GetBlock(ctx, blockHash)
 	if err != nil || block == nil {
@@ -1022,17 +1240,58 @@ type CallArgs struct {
 	Data     hexutil.Bytes   `json:"data"`
 }
 
-func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, vmCfg vm.Config, timeout time.Duration) ([]byte, uint64, bool, error) {
-	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
+// OverrideAccount indicates the overriding fields of an account during the
+// execution of a simulated call. The state and stateDiff fields are mutually
+// exclusive: the former replaces the whole storage of the account, the latter
+// only the given slots.
+type OverrideAccount struct {
+	Nonce     *hexutil.Uint64              `json:"nonce"`
+	Code      *hexutil.Bytes               `json:"code"`
+	Balance   *hexutil.Big                 `json:"balance"`
+	State     *map[common.Hash]common.Hash `json:"state"`
+	StateDiff *map[common.Hash]common.Hash `json:"stateDiff"`
+}
 
-	statedb, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
-	if statedb == nil || err != nil {
-		return nil, 0, false, err
+// StateOverride is the collection of overridden accounts.
+type StateOverride map[common.Address]OverrideAccount
+
+// Apply overrides the fields of the specified accounts into the given state.
+func (diff *StateOverride) Apply(state *state.StateDB) error {
+	if diff == nil {
+		return nil
 	}
+	for addr, account := range *diff {
+		if account.State != nil && account.StateDiff != nil {
+			return fmt.Errorf("account %s has both 'state' and 'stateDiff'", addr.Hex())
+		}
+		if account.Nonce != nil {
+			state.SetNonce(addr, uint64(*account.Nonce))
+		}
+		if account.Code != nil {
+			state.SetCode(addr, *account.Code)
+		}
+		if account.Balance != nil {
+			state.SetBalance(addr, (*big.Int)(account.Balance))
+		}
+		if account.State != nil {
+			state.SetStorage(addr, *account.State)
+		}
+		if account.StateDiff != nil {
+			for key, value := range *account.StateDiff {
+				state.SetState(addr, key, value)
+			}
+		}
+	}
+	return nil
+}
+
+// defaults returns the sender, gas and gas price of the call, falling back to the
+// first local account as sender and to default gas and gas price values if unset.
+func (args *CallArgs) defaults(b Backend) (common.Address, uint64, *big.Int) {
 	// Set sender address or use a default if none specified
 	addr := args.From
 	if addr == (common.Address{}) {
-		if wallets := s.b.AccountManager().Wallets(); len(wallets) > 0 {
+		if wallets := b.AccountManager().Wallets(); len(wallets) > 0 {
 			if accounts := wallets[0].Accounts(); len(accounts) > 0 {
 				addr = accounts[0].Address
 			}
@@ -1046,9 +1305,24 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	if gasPrice.Sign() == 0 {
 		gasPrice = new(big.Int).SetUint64(defaultGasPrice)
 	}
-	balanceTokenFee := big.NewInt(0).SetUint64(gas)
-	balanceTokenFee = balanceTokenFee.Mul(balanceTokenFee, gasPrice)
-	// Create new call message
+	return addr, gas, gasPrice
+}
+
+// DoCall executes the call arguments on top of the state of the given block, with
+// the optional state overrides applied, running the EVM with the supplied
+// configuration. It is shared by eth_call, eth_estimateGas and the call tracer of
+// the debug namespace.
+func DoCall(ctx context.Context, b Backend, args CallArgs, blockNr rpc.BlockNumber, overrides *StateOverride, vmCfg vm.Config, timeout time.Duration) ([]byte, uint64, bool, error) {
+	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
+
+	statedb, header, err := b.StateAndHeaderByNumber(ctx, blockNr)
+	if statedb == nil || err != nil {
+		return nil, 0, false, err
+	}
+	// The gas of the call is sponsored like the fee of a TRC21 transaction, so the
+	// sender doesn't need any balance to pay for it
+	addr, gas, gasPrice := args.defaults(b)
+	balanceTokenFee := new(big.Int).Mul(new(big.Int).SetUint64(gas), gasPrice)
 	msg := types.NewMessage(addr, args.To, 0, args.Value.ToInt(), gas, gasPrice, args.Data, false, balanceTokenFee)
 
 	// Setup context so it may be cancelled the call has completed
@@ -1064,10 +1338,15 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	defer cancel()
 
 	// Get a new instance of the EVM.
-	evm, vmError, err := s.b.GetEVM(ctx, msg, statedb, header, vmCfg)
+	evm, vmError, err := b.GetEVM(ctx, msg, statedb, header, vmCfg)
 	if err != nil {
 		return nil, 0, false, err
 	}
+	// Override the state after the EVM is set up, so that the overrides take
+	// precedence over the funding of the sender
+	if err := overrides.Apply(statedb); err != nil {
+		return nil, 0, false, err
+	}
 	// Wait for the context to be done and cancel the evm. Even if the
 	// EVM has finished, cancelling may be done (repeatedly)
 	go func() {
@@ -1087,14 +1366,16 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 
 // Call executes the given transaction on the state for the given block number.
 // It doesn't make and changes in the state/blockchain and is useful to execute and retrieve values.
-func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
-	result, _, _, err := s.doCall(ctx, args, blockNr, vm.Config{}, 5*time.Second)
+// The optional state overrides are applied to the state before the execution.
+func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, overrides *StateOverride) (hexutil.Bytes, error) {
+	result, _, _, err := DoCall(ctx, s.b, args, blockNr, overrides, vm.Config{}, 5*time.Second)
 	return (hexutil.Bytes)(result), err
 }
 
 // EstimateGas returns an estimate of the amount of gas needed to execute the
-// given transaction against the current pending block.
-func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (hexutil.Uint64, error) {
+// given transaction against the current pending block, with the optional state
+// overrides applied.
+func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs, overrides *StateOverride) (hexutil.Uint64, error) {
 	// Binary search the gas requirement, as it may be higher than the amount used
 	var (
 		lo  uint64 = params.TxGas - 1
@@ -1117,7 +1398,7 @@ func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (h
 	executable := func(gas uint64) bool {
 		args.Gas = hexutil.Uint64(gas)
 
-		_, _, failed, err := s.doCall(ctx, args, rpc.LatestBlockNumber, vm.Config{}, 0)
+		_, _, failed, err := DoCall(ctx, s.b, args, rpc.LatestBlockNumber, overrides, vm.Config{}, 0)
 		if err != nil || failed {
 			return false
 		}
@@ -1141,6 +1422,183 @@ func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (h
 	return hexutil.Uint64(hi), nil
 }
 
+// BundleTxArgs represents a transaction of a simulated bundle, given either as a
+// signed RLP encoded transaction or as the arguments of an unsigned call.
+type BundleTxArgs struct {
+	CallArgs
+	Raw hexutil.Bytes `json:"raw"`
+}
+
+// BundleResult is the result of an eth_callBundle simulation.
+type BundleResult struct {
+	BlockHash   common.Hash      `json:"blockHash"`
+	BlockNumber hexutil.Uint64   `json:"blockNumber"`
+	GasUsed     hexutil.Uint64   `json:"gasUsed"`
+	Results     []BundleTxResult `json:"results"`
+}
+
+// BundleTxResult is the outcome of a single transaction of a simulated bundle,
+// along with the changes it made to the state.
+type BundleTxResult struct {
+	TxHash      *common.Hash        `json:"txHash,omitempty"`
+	From        common.Address      `json:"from"`
+	To          *common.Address     `json:"to"`
+	GasUsed     hexutil.Uint64      `json:"gasUsed"`
+	Failed      bool                `json:"failed"`
+	ReturnValue hexutil.Bytes       `json:"returnValue"`
+	Logs        []*types.Log        `json:"logs"`
+	StateDiff   []AccountDiffResult `json:"stateDiff"`
+}
+
+// CallBundle simulates the given transactions in order on top of the state of the
+// given block, with the optional state overrides applied first. Signed transactions
+// are executed as they would be in a block, their nonces checked. Unsigned calls
+// are executed like eth_call, except that their senders aren't funded: the fees
+// and balances they transfer must be present in the state or overridden, calls
+// without a gas price paying no fees. The fees of both are paid by the TRC21 token
+// issuers when applicable. Like in a block, the transactions share the gas limit
+// of the header, calls without a gas allowance receiving all of the remaining gas,
+// and a bundle not fitting is rejected. None of the changes are persisted.
+func (s *PublicBlockChainAPI) CallBundle(ctx context.Context, txs []BundleTxArgs, blockNr rpc.BlockNumber, overrides *StateOverride) (*BundleResult, error) {
+	defer func(start time.Time) { log.Debug("Executing EVM bundle finished", "runtime", time.Since(start)) }(time.Now())
+
+	if len(txs) == 0 {
+		return nil, errors.New("bundle missing transactions")
+	}
+	if len(txs) > maxBundleTxs {
+		return nil, fmt.Errorf("bundle too large: have %d transactions, max %d", len(txs), maxBundleTxs)
+	}
+	statedb, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	if err := overrides.Apply(statedb); err != nil {
+		return nil, err
+	}
+	// Finalise the overrides, so they're not reported as changes of the first transaction
+	statedb.Finalise(false)
+
+	// Setup context so it may be cancelled when the bundle has completed, aborting
+	// the transaction being executed at the time
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var (
+		evm  *vm.EVM
+		lock sync.Mutex
+	)
+	go func() {
+		<-ctx.Done()
+
+		lock.Lock()
+		defer lock.Unlock()
+		if evm != nil {
+			evm.Cancel()
+		}
+	}()
+	var (
+		config      = s.b.ChainConfig()
+		signer      = types.MakeSigner(config, header.Number)
+		feeCapacity = state.GetTRC21FeeCapacityFromState(statedb)
+		gp          = new(core.GasPool).AddGas(header.GasLimit)
+		result      = &BundleResult{
+			BlockHash:   header.Hash(),
+			BlockNumber: hexutil.Uint64(header.Number.Uint64()),
+			Results:     make([]BundleTxResult, 0, len(txs)),
+		}
+	)
+	for i, args := range txs {
+		// Assemble the message of either the signed transaction or the call
+		var (
+			msg        types.Message
+			hash       common.Hash
+			balanceFee *big.Int
+			tokenFee   bool
+		)
+		if len(args.Raw) > 0 {
+			tx := new(types.Transaction)
+			if err := rlp.DecodeBytes(args.Raw, tx); err != nil {
+				return nil, fmt.Errorf("transaction %d: %v", i, err)
+			}
+			if tx.To() != nil {
+				balanceFee, tokenFee = feeCapacity[*tx.To()]
+			}
+			if msg, err = tx.AsMessage(signer, balanceFee); err != nil {
+				return nil, fmt.Errorf("transaction %d: %v", i, err)
+			}
+			hash = tx.Hash()
+		} else {
+			if args.To != nil {
+				balanceFee, tokenFee = feeCapacity[*args.To]
+			}
+			// Calls only pay for their gas if they set a price for it
+			addr, gas, gasPrice := args.defaults(s.b)
+			if args.Gas == 0 {
+				gas = gp.Gas()
+			}
+			if args.GasPrice.ToInt().Sign() == 0 {
+				gasPrice = new(big.Int)
+			}
+			msg = types.NewMessage(addr, args.To, 0, args.Value.ToInt(), gas, gasPrice, args.Data, false, balanceFee)
+		}
+		if msg.Gas() > gp.Gas() {
+			return nil, fmt.Errorf("transaction %d: gas limit %d exceeds the %d gas left in block", i, msg.Gas(), gp.Gas())
+		}
+		statedb.Prepare(hash, header.Hash(), i)
+		logged := len(statedb.GetLogs(hash))
+
+		// GetEVM funds the sender for eth_call, bundles run with the real balances
+		balance := statedb.GetBalance(msg.From())
+		env, vmError, err := s.b.GetEVM(ctx, msg, statedb, header, vm.Config{})
+		if err != nil {
+			return nil, err
+		}
+		statedb.SetBalance(msg.From(), balance)
+
+		lock.Lock()
+		if evm = env; ctx.Err() != nil {
+			evm.Cancel()
+		}
+		lock.Unlock()
+
+		ret, gas, failed, err := core.ApplyMessage(env, msg, gp)
+		if err := vmError(); err != nil {
+			return nil, err
+		}
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %v", i, err)
+		}
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("transaction %d: execution aborted: %v", i, ctx.Err())
+		}
+		// Charge the fee of the transaction to the TRC21 token issuer, as blocks do
+		if tokenFee {
+			if failed {
+				state.PayFeeWithTRC21TxFail(statedb, msg.From(), *msg.To())
+			}
+			feeCapacity[*msg.To()] = new(big.Int).Sub(feeCapacity[*msg.To()], new(big.Int).SetUint64(gas))
+			state.UpdateTRC21Fee(statedb, map[common.Address]*big.Int{*msg.To(): feeCapacity[*msg.To()]}, gas)
+		}
+		diff := statedb.FinaliseDiff(config.IsEIP158(header.Number))
+
+		res := BundleTxResult{
+			From:        msg.From(),
+			To:          msg.To(),
+			GasUsed:     hexutil.Uint64(gas),
+			Failed:      failed,
+			ReturnValue: ret,
+			Logs:        statedb.GetLogs(hash)[logged:],
+			StateDiff:   FormatStateDiff(diff),
+		}
+		if len(args.Raw) > 0 {
+			res.TxHash = &hash
+		}
+		result.GasUsed += hexutil.Uint64(gas)
+		result.Results = append(result.Results, res)
+	}
+	return result, nil
+}
+
 // ExecutionResult groups all structured logs emitted by the EVM
 // while replaying a transaction in debug mode as well as transaction
 // execution status, the amount of gas used and the return value
@@ -1202,6 +1660,66 @@ func FormatLogs(logs []vm.StructLog) []StructLogRes {
 	return formatted
 }
 
+// AccountDiffResult is the change of a single account. Only the changed fields
+// are set, apart from the existence flags.
+type AccountDiffResult struct {
+	Address    common.Address           `json:"address"`
+	Existed    bool                     `json:"existed"`
+	Exists     bool                     `json:"exists"`
+	Destructed bool                     `json:"destructed"`
+	Balance    *bigDiff                 `json:"balance,omitempty"`
+	Nonce      *nonceDiff               `json:"nonce,omitempty"`
+	CodeHash   *hashDiff                `json:"codeHash,omitempty"`
+	Code       hexutil.Bytes            `json:"code,omitempty"`
+	Storage    map[common.Hash]hashDiff `json:"storage,omitempty"`
+}
+
+type bigDiff struct {
+	From *hexutil.Big `json:"from"`
+	To   *hexutil.Big `json:"to"`
+}
+
+type nonceDiff struct {
+	From hexutil.Uint64 `json:"from"`
+	To   hexutil.Uint64 `json:"to"`
+}
+
+type hashDiff struct {
+	From common.Hash `json:"from"`
+	To   common.Hash `json:"to"`
+}
+
+// FormatStateDiff converts the state changes into their RPC representation.
+func FormatStateDiff(diff *state.StateDiff) []AccountDiffResult {
+	accounts := make([]AccountDiffResult, 0, len(diff.Accounts))
+	for _, account := range diff.Accounts {
+		entry := AccountDiffResult{
+			Address:    account.Address,
+			Existed:    account.Existed,
+			Exists:     account.Exists,
+			Destructed: account.Destructed,
+			Code:       account.Code,
+		}
+		if account.BalanceFrom.Cmp(account.BalanceTo) != 0 {
+			entry.Balance = &bigDiff{From: (*hexutil.Big)(account.BalanceFrom), To: (*hexutil.Big)(account.BalanceTo)}
+		}
+		if account.NonceFrom != account.NonceTo {
+			entry.Nonce = &nonceDiff{From: hexutil.Uint64(account.NonceFrom), To: hexutil.Uint64(account.NonceTo)}
+		}
+		if account.CodeHashFrom != account.CodeHashTo {
+			entry.CodeHash = &hashDiff{From: account.CodeHashFrom, To: account.CodeHashTo}
+		}
+		if len(account.Storage) > 0 {
+			entry.Storage = make(map[common.Hash]hashDiff, len(account.Storage))
+			for _, slot := range account.Storage {
+				entry.Storage[slot.Key] = hashDiff{From: slot.From, To: slot.To}
+			}
+		}
+		accounts = append(accounts, entry)
+	}
+	return accounts
+}
+
 // rpcOutputBlock converts the given block to the RPC output which depends on fullTx. If inclTx is true transactions are
 // returned. When fullTx is true the returned block contains full transaction details, otherwise it will only contain
 // transaction hashes.
@@ -1977,32 +2495,21 @@ func NewPrivateDebugAPI(b Backend) *PrivateDebugAPI {
 	return &PrivateDebugAPI{b: b}
 }
 
-// ChaindbProperty returns leveldb properties of the chain database.
+// ChaindbProperty returns properties of the chain database. Properties without
+// a backend prefix are treated as leveldb properties.
 func (api *PrivateDebugAPI) ChaindbProperty(property string) (string, error) {
-	ldb, ok := api.b.ChainDb().(interface {
-		LDB() *leveldb.DB
-	})
-	if !ok {
-		return "", fmt.Errorf("chaindbProperty does not work for memory databases")
-	}
 	if property == "" {
 		property = "leveldb.stats"
-	} else if !strings.HasPrefix(property, "leveldb.") {
+	} else if !strings.Contains(property, ".") {
 		property = "leveldb." + property
 	}
-	return ldb.LDB().GetProperty(property)
+	return api.b.ChainDb().Stat(property)
 }
 
 func (api *PrivateDebugAPI) ChaindbCompact() error {
-	ldb, ok := api.b.ChainDb().(interface {
-		LDB() *leveldb.DB
-	})
-	if !ok {
-		return fmt.Errorf("chaindbCompact does not work for memory databases")
-	}
 	for b := byte(0); b < 255; b++ {
 		log.Info("Compacting chain database", "range", fmt.Sprintf("0x%0.2X-0x%0.2X", b, b+1))
-		err := ldb.LDB().CompactRange(util.Range{Start: []byte{b}, Limit: []byte{b + 1}})
+		err := api.b.ChainDb().Compact([]byte{b}, []byte{b + 1})
 		if err != nil {
 			log.Error("Database compaction failed", "err", err)
 			return err
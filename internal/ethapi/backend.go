@@ -43,6 +43,7 @@ type Backend interface {
 	Downloader() *downloader.Downloader
 	ProtocolVersion() int
 	SuggestPrice(ctx context.Context) (*big.Int, error)
+	FeeHistory(ctx context.Context, blocks uint64, lastBlock rpc.BlockNumber, percentiles []float64) (*big.Int, [][]*big.Int, []float64, error)
 	ChainDb() ethdb.Database
 	EventMux() *event.TypeMux
 	AccountManager() *accounts.Manager
@@ -62,12 +63,15 @@ type Backend interface {
 
 	// TxPool API
 	SendTx(ctx context.Context, signedTx *types.Transaction) error
+	SendRemoteTxs(ctx context.Context, txs types.Transactions) []error
 	GetPoolTransactions() (types.Transactions, error)
 	GetPoolTransaction(txHash common.Hash) *types.Transaction
 	GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error)
 	Stats() (pending int, queued int)
 	TxPoolContent() (map[common.Address]types.Transactions, map[common.Address]types.Transactions)
 	SubscribeTxPreEvent(chan<- core.TxPreEvent) event.Subscription
+	GetPoolLifecycle(txHash common.Hash) (core.TxStatus, *core.TxDrop)
+	SubscribeTxDropEvent(chan<- core.TxDropEvent) event.Subscription
 
 	ChainConfig() *params.ChainConfig
 	CurrentBlock() *types.Block
@@ -176,6 +176,17 @@ web3._extend({
 			call: 'admin_sleepBlocks',
 			params: 2
 		}),
+		new web3._extend.Method({
+			name: 'setTxPoolPolicy',
+			call: 'admin_setTxPoolPolicy',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'pruneState',
+			call: 'admin_pruneState',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
 		new web3._extend.Method({
 			name: 'startRPC',
 			call: 'admin_startRPC',
@@ -210,6 +221,10 @@ web3._extend({
 			name: 'datadir',
 			getter: 'admin_datadir'
 		}),
+		new web3._extend.Property({
+			name: 'txPoolPolicy',
+			getter: 'admin_txPoolPolicy'
+		}),
 	]
 });
 `
@@ -388,12 +403,24 @@ web3._extend({
 			params: 2,
 			inputFormatter: [null, null]
 		}),
+		new web3._extend.Method({
+			name: 'traceBlockFinalize',
+			call: 'debug_traceBlockFinalize',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
 		new web3._extend.Method({
 			name: 'traceTransaction',
 			call: 'debug_traceTransaction',
 			params: 2,
 			inputFormatter: [null, null]
 		}),
+		new web3._extend.Method({
+			name: 'traceCall',
+			call: 'debug_traceCall',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputCallFormatter, web3._extend.formatters.inputDefaultBlockNumberFormatter, null]
+		}),
 		new web3._extend.Method({
 			name: 'preimage',
 			call: 'debug_preimage',
@@ -422,6 +449,35 @@ web3._extend({
 			params: 2,
 			inputFormatter:[null, null],
 		}),
+		new web3._extend.Method({
+			name: 'getStateDiff',
+			call: 'debug_getStateDiff',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getStorageHistory',
+			call: 'debug_getStorageHistory',
+			params: 4,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getCandidateCapHistory',
+			call: 'debug_getCandidateCapHistory',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'evmProfile',
+			call: 'debug_evmProfile',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Method({
+			name: 'writeEvmProfile',
+			call: 'debug_writeEvmProfile',
+			params: 1
+		}),
 	],
 	properties: []
 });
@@ -473,6 +529,24 @@ web3._extend({
 			params: 2,
 			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.utils.toHex]
 		}),
+		new web3._extend.Method({
+			name: 'getProof',
+			call: 'eth_getProof',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'feeHistory',
+			call: 'eth_feeHistory',
+			params: 3,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'callBundle',
+			call: 'eth_callBundle',
+			params: 3,
+			inputFormatter: [null, web3._extend.formatters.inputDefaultBlockNumberFormatter, null]
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -650,7 +724,22 @@ web3._extend({
 const TxPool_JS = `
 web3._extend({
 	property: 'txpool',
-	methods: [],
+	methods: [
+		new web3._extend.Method({
+			name: 'export',
+			call: 'txpool_export'
+		}),
+		new web3._extend.Method({
+			name: 'import',
+			call: 'txpool_import',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getTransactionStatus',
+			call: 'txpool_getTransactionStatus',
+			params: 1
+		}),
+	],
 	properties:
 	[
 		new web3._extend.Property({
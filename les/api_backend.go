@@ -110,6 +110,14 @@ func (b *LesApiBackend) SendTx(ctx context.Context, signedTx *types.Transaction)
 	return b.eth.txPool.Add(ctx, signedTx)
 }
 
+func (b *LesApiBackend) SendRemoteTxs(ctx context.Context, txs types.Transactions) []error {
+	errs := make([]error, len(txs))
+	for i, tx := range txs {
+		errs[i] = b.eth.txPool.Add(ctx, tx)
+	}
+	return errs
+}
+
 func (b *LesApiBackend) RemoveTx(txHash common.Hash) {
 	b.eth.txPool.RemoveTx(txHash)
 }
@@ -138,6 +146,22 @@ func (b *LesApiBackend) SubscribeTxPreEvent(ch chan<- core.TxPreEvent) event.Sub
 	return b.eth.txPool.SubscribeTxPreEvent(ch)
 }
 
+func (b *LesApiBackend) GetPoolLifecycle(hash common.Hash) (core.TxStatus, *core.TxDrop) {
+	// The light pool only tracks the node's own pending transactions
+	if b.eth.txPool.GetTransaction(hash) != nil {
+		return core.TxStatusPending, nil
+	}
+	return core.TxStatusUnknown, nil
+}
+
+func (b *LesApiBackend) SubscribeTxDropEvent(ch chan<- core.TxDropEvent) event.Subscription {
+	// The light pool never drops transactions, return a subscription that never fires
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
 func (b *LesApiBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
 	return b.eth.blockchain.SubscribeChainEvent(ch)
 }
@@ -170,6 +194,10 @@ func (b *LesApiBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	return b.gpo.SuggestPrice(ctx)
 }
 
+func (b *LesApiBackend) FeeHistory(ctx context.Context, blocks uint64, lastBlock rpc.BlockNumber, percentiles []float64) (*big.Int, [][]*big.Int, []float64, error) {
+	return b.gpo.FeeHistory(ctx, blocks, lastBlock, percentiles)
+}
+
 func (b *LesApiBackend) ChainDb() ethdb.Database {
 	return b.eth.chainDb
 }
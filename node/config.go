@@ -30,6 +30,7 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/usbwallet"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/discover"
@@ -66,6 +67,11 @@ type Config struct {
 	// in memory.
 	DataDir string
 
+	// DBEngine is the backend of the databases created in the data directory,
+	// either "leveldb" or "bolt". If empty, the backend of an existing database
+	// is used, defaulting to LevelDB for new ones.
+	DBEngine string `toml:",omitempty"`
+
 	// Configuration of peer-to-peer networking.
 	P2P p2p.Config
 
@@ -287,6 +293,30 @@ func (c *Config) resolvePath(path string) string {
 	return filepath.Join(c.instanceDir(), path)
 }
 
+// openDatabaseWithFreezer opens a database in the data directory with the
+// configured backend and attaches an ancient store to it.
+func (c *Config) openDatabaseWithFreezer(name string, cache, handles int, freezer string, tables []string) (ethdb.Database, error) {
+	kvdb, err := ethdb.NewDatabase(c.DBEngine, c.resolvePath(name), cache, handles)
+	if err != nil {
+		return nil, err
+	}
+	db, err := ethdb.NewDatabaseWithFreezer(kvdb, c.resolveFreezer(name, freezer), tables)
+	if err != nil {
+		kvdb.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// resolveFreezer returns the directory of the ancient store of a database. An
+// empty freezer path places it inside the database directory.
+func (c *Config) resolveFreezer(name, freezer string) string {
+	if freezer == "" {
+		return filepath.Join(c.resolvePath(name), "ancient")
+	}
+	return c.resolvePath(freezer)
+}
+
 func (c *Config) instanceDir() string {
 	if c.DataDir == "" {
 		return ""
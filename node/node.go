@@ -645,7 +645,18 @@ func (n *Node) OpenDatabase(name string, cache, handles int) (ethdb.Database, er
 	if n.config.DataDir == "" {
 		return ethdb.NewMemDatabase()
 	}
-	return ethdb.NewLDBDatabase(n.config.resolvePath(name), cache, handles)
+	return ethdb.NewDatabase(n.config.DBEngine, n.config.resolvePath(name), cache, handles)
+}
+
+// OpenDatabaseWithFreezer opens an existing database with the given name (or
+// creates one if no previous can be found) from within the node's instance
+// directory, attaching an ancient store with the given tables. If the node is
+// ephemeral, a memory database without an ancient store is returned.
+func (n *Node) OpenDatabaseWithFreezer(name string, cache, handles int, freezer string, tables []string) (ethdb.Database, error) {
+	if n.config.DataDir == "" {
+		return ethdb.NewMemDatabase()
+	}
+	return n.config.openDatabaseWithFreezer(name, cache, handles, freezer, tables)
 }
 
 // ResolvePath returns the absolute path of a resource in the instance directory.
@@ -43,11 +43,20 @@ func (ctx *ServiceContext) OpenDatabase(name string, cache int, handles int) (et
 	if ctx.config.DataDir == "" {
 		return ethdb.NewMemDatabase()
 	}
-	db, err := ethdb.NewLDBDatabase(ctx.config.resolvePath(name), cache, handles)
-	if err != nil {
-		return nil, err
+	return ethdb.NewDatabase(ctx.config.DBEngine, ctx.config.resolvePath(name), cache, handles)
+}
+
+// OpenDatabaseWithFreezer opens an existing database with the given name (or
+// creates one if no previous can be found) from within the node's data directory,
+// attaching an ancient store with the given tables. If the freezer directory is
+// empty, the ancient store is placed inside the database directory, otherwise a
+// relative path is resolved into the data directory. If the node is an ephemeral
+// one, a memory database without an ancient store is returned.
+func (ctx *ServiceContext) OpenDatabaseWithFreezer(name string, cache int, handles int, freezer string, tables []string) (ethdb.Database, error) {
+	if ctx.config.DataDir == "" {
+		return ethdb.NewMemDatabase()
 	}
-	return db, nil
+	return ctx.config.openDatabaseWithFreezer(name, cache, handles, freezer, tables)
 }
 
 // ResolvePath resolves a user path into the data directory if that was relative
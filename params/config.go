@@ -39,6 +39,10 @@ var (
 		EIP155Block:    big.NewInt(3),
 		EIP158Block:    big.NewInt(3),
 		ByzantiumBlock: big.NewInt(4),
+
+		TIPConstantinopleBlock: big.NewInt(13500000),
+		TIPIstanbulBlock:       big.NewInt(13860000),
+
 		Posv: &PosvConfig{
 			Period:              2,
 			Epoch:               900,
@@ -102,16 +106,16 @@ var (
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, new(EthashConfig), nil, nil}
+	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, new(EthashConfig), nil, nil}
 
 	// AllPosvProtocolChanges contains every protocol change (EIPs) introduced
 	// and accepted by the Ethereum core developers into the Posv consensus.
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllPosvProtocolChanges   = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, &PosvConfig{Period: 0, Epoch: 30000}}
-	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}, nil}
-	TestChainConfig          = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, new(EthashConfig), nil, nil}
+	AllPosvProtocolChanges   = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, nil, nil, &PosvConfig{Period: 0, Epoch: 30000}}
+	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}, nil}
+	TestChainConfig          = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, new(EthashConfig), nil, nil}
 	TestRules                = TestChainConfig.Rules(new(big.Int))
 )
 
@@ -137,6 +141,13 @@ type ChainConfig struct {
 
 	ByzantiumBlock      *big.Int `json:"byzantiumBlock,omitempty"`      // Byzantium switch block (nil = no fork, 0 = already on byzantium)
 	ConstantinopleBlock *big.Int `json:"constantinopleBlock,omitempty"` // Constantinople switch block (nil = no fork, 0 = already activated)
+	PetersburgBlock     *big.Int `json:"petersburgBlock,omitempty"`     // Petersburg switch block (nil = same as Constantinople)
+	IstanbulBlock       *big.Int `json:"istanbulBlock,omitempty"`       // Istanbul switch block (nil = no fork, 0 = already on istanbul)
+
+	// TomoChain forks enabling the Ethereum rule sets of the same name. Istanbul
+	// builds on top of Constantinople, so it must not be scheduled before it.
+	TIPConstantinopleBlock *big.Int `json:"tipConstantinopleBlock,omitempty"` // TomoChain Constantinople switch block (nil = no fork, 0 = already activated)
+	TIPIstanbulBlock       *big.Int `json:"tipIstanbulBlock,omitempty"`       // TomoChain Istanbul switch block (nil = no fork, 0 = already activated)
 
 	// Various consensus engines
 	Ethash *EthashConfig `json:"ethash,omitempty"`
@@ -189,7 +200,7 @@ func (c *ChainConfig) String() string {
 	default:
 		engine = "unknown"
 	}
-	return fmt.Sprintf("{ChainID: %v Homestead: %v DAO: %v DAOSupport: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Constantinople: %v Engine: %v}",
+	return fmt.Sprintf("{ChainID: %v Homestead: %v DAO: %v DAOSupport: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Constantinople: %v Petersburg: %v Istanbul: %v TIPConstantinople: %v TIPIstanbul: %v Engine: %v}",
 		c.ChainId,
 		c.HomesteadBlock,
 		c.DAOForkBlock,
@@ -199,6 +210,10 @@ func (c *ChainConfig) String() string {
 		c.EIP158Block,
 		c.ByzantiumBlock,
 		c.ConstantinopleBlock,
+		c.PetersburgBlock,
+		c.IstanbulBlock,
+		c.TIPConstantinopleBlock,
+		c.TIPIstanbulBlock,
 		engine,
 	)
 }
@@ -233,6 +248,18 @@ func (c *ChainConfig) IsConstantinople(num *big.Int) bool {
 	return isForked(c.ConstantinopleBlock, num)
 }
 
+// IsPetersburg returns whether num is either
+// - equal to or greater than the PetersburgBlock fork block,
+// - OR is nil, and Constantinople is active
+func (c *ChainConfig) IsPetersburg(num *big.Int) bool {
+	return isForked(c.PetersburgBlock, num) || c.PetersburgBlock == nil && isForked(c.ConstantinopleBlock, num)
+}
+
+// IsIstanbul returns whether num is either equal to the Istanbul fork block or greater.
+func (c *ChainConfig) IsIstanbul(num *big.Int) bool {
+	return isForked(c.IstanbulBlock, num)
+}
+
 func (c *ChainConfig) IsTIP2019(num *big.Int) bool {
 	return isForked(common.TIP2019Block, num)
 }
@@ -245,6 +272,40 @@ func (c *ChainConfig) IsTIPRandomize(num *big.Int) bool {
 	return isForked(common.TIPRandomize, num)
 }
 
+// IsTIPCleanSigners returns whether num is past the fork clearing the sign
+// records of the BlockSigners contract once they were rewarded.
+func (c *ChainConfig) IsTIPCleanSigners(num *big.Int) bool {
+	return isForked(common.TIPCleanSigners, num)
+}
+
+// IsTIPConstantinople returns whether num is past the TomoChain fork enabling
+// CREATE2, EXTCODEHASH and the bitwise shifts of Constantinople. The fork follows
+// Petersburg, the EIP-1283 net gas metering is not enabled, storage writes keep
+// their legacy pricing until the EIP-2200 metering of the TomoChain Istanbul fork.
+func (c *ChainConfig) IsTIPConstantinople(num *big.Int) bool {
+	return isForked(c.TIPConstantinopleBlock, num)
+}
+
+// IsTIPIstanbul returns whether num is past the TomoChain fork enabling CHAINID,
+// SELFBALANCE, the EIP-1884 and EIP-2200 storage repricing and the cheaper bn256
+// precompiles of Istanbul. The Istanbul rules extend the Constantinople ones,
+// the fork must not be scheduled before the TomoChain Constantinople fork.
+func (c *ChainConfig) IsTIPIstanbul(num *big.Int) bool {
+	return isForked(c.TIPIstanbulBlock, num)
+}
+
+// IsTIPCryptoPrecompiles returns whether num is past the fork adding the BLAKE2b
+// compression and BLS12-381 precompiled contracts.
+func (c *ChainConfig) IsTIPCryptoPrecompiles(num *big.Int) bool {
+	return isForked(common.TIPCryptoPrecompiles, num)
+}
+
+// IsTIPConsensusPrecompile returns whether num is past the fork adding the
+// precompiled contract exposing the consensus state to contracts.
+func (c *ChainConfig) IsTIPConsensusPrecompile(num *big.Int) bool {
+	return isForked(common.TIPConsensusPrecompile, num)
+}
+
 // GasTable returns the gas table corresponding to the current phase (homestead or homestead reprice).
 //
 // The returned GasTable's fields shouldn't, under any circumstances, be changed.
@@ -253,6 +314,10 @@ func (c *ChainConfig) GasTable(num *big.Int) GasTable {
 		return GasTableHomestead
 	}
 	switch {
+	case c.IsIstanbul(num) || c.IsTIPIstanbul(num):
+		return GasTableIstanbul
+	case c.IsConstantinople(num) || c.IsTIPConstantinople(num):
+		return GasTableConstantinople
 	case c.IsEIP158(num):
 		return GasTableEIP158
 	case c.IsEIP150(num):
@@ -308,6 +373,18 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.ConstantinopleBlock, newcfg.ConstantinopleBlock, head) {
 		return newCompatError("Constantinople fork block", c.ConstantinopleBlock, newcfg.ConstantinopleBlock)
 	}
+	if isForkIncompatible(c.PetersburgBlock, newcfg.PetersburgBlock, head) {
+		return newCompatError("Petersburg fork block", c.PetersburgBlock, newcfg.PetersburgBlock)
+	}
+	if isForkIncompatible(c.IstanbulBlock, newcfg.IstanbulBlock, head) {
+		return newCompatError("Istanbul fork block", c.IstanbulBlock, newcfg.IstanbulBlock)
+	}
+	if isForkIncompatible(c.TIPConstantinopleBlock, newcfg.TIPConstantinopleBlock, head) {
+		return newCompatError("TomoChain Constantinople fork block", c.TIPConstantinopleBlock, newcfg.TIPConstantinopleBlock)
+	}
+	if isForkIncompatible(c.TIPIstanbulBlock, newcfg.TIPIstanbulBlock, head) {
+		return newCompatError("TomoChain Istanbul fork block", c.TIPIstanbulBlock, newcfg.TIPIstanbulBlock)
+	}
 	return nil
 }
 
@@ -372,9 +449,11 @@ func (err *ConfigCompatError) Error() string {
 // Rules is a one time interface meaning that it shouldn't be used in between transition
 // phases.
 type Rules struct {
-	ChainId                                   *big.Int
-	IsHomestead, IsEIP150, IsEIP155, IsEIP158 bool
-	IsByzantium                               bool
+	ChainId                                     *big.Int
+	IsHomestead, IsEIP150, IsEIP155, IsEIP158   bool
+	IsByzantium, IsConstantinople, IsPetersburg bool
+	IsIstanbul, IsTIPCryptoPrecompiles          bool
+	IsTIPConsensusPrecompile                    bool
 }
 
 func (c *ChainConfig) Rules(num *big.Int) Rules {
@@ -382,5 +461,5 @@ func (c *ChainConfig) Rules(num *big.Int) Rules {
 	if chainId == nil {
 		chainId = new(big.Int)
 	}
-	return Rules{ChainId: new(big.Int).Set(chainId), IsHomestead: c.IsHomestead(num), IsEIP150: c.IsEIP150(num), IsEIP155: c.IsEIP155(num), IsEIP158: c.IsEIP158(num), IsByzantium: c.IsByzantium(num)}
+	return Rules{ChainId: new(big.Int).Set(chainId), IsHomestead: c.IsHomestead(num), IsEIP150: c.IsEIP150(num), IsEIP155: c.IsEIP155(num), IsEIP158: c.IsEIP158(num), IsByzantium: c.IsByzantium(num), IsConstantinople: c.IsConstantinople(num) || c.IsTIPConstantinople(num), IsPetersburg: c.IsPetersburg(num) || c.IsTIPConstantinople(num), IsIstanbul: c.IsIstanbul(num) || c.IsTIPIstanbul(num), IsTIPCryptoPrecompiles: c.IsTIPCryptoPrecompiles(num), IsTIPConsensusPrecompile: c.IsTIPConsensusPrecompile(num)}
 }
@@ -0,0 +1,106 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// istanbulStateTest is a general state test exercising the instructions and
+// repricings of the Istanbul rule set, which the upstream fixtures bundled in
+// testdata predate. The contract at 0x1000 stores CHAINID into slot 0, SELFBALANCE
+// into slot 1, the success of a bn256 point addition into slot 2 and the gas left
+// afterwards into slot 3. Before Istanbul CHAINID is invalid and the call fails.
+const istanbulStateTest = `{
+	"env": {
+		"currentCoinbase": "2adc25665018aa1fe0e6bc666dac8fc2697ff9ba",
+		"currentDifficulty": "0x020000",
+		"currentGasLimit": "0x7fffffffffffffff",
+		"currentNumber": "0x01",
+		"currentTimestamp": "0x03e8"
+	},
+	"pre": {
+		"0x0000000000000000000000000000000000001000": {
+			"balance": "0x0de0b6b3a7640000",
+			"code": "0x46600055476001556040600060806000600060065af16002555a60035500",
+			"nonce": "0x00",
+			"storage": {}
+		},
+		"0xa94f5374fce5edbc8e2a8697c15331677e6ebf0b": {
+			"balance": "0x0de0b6b3a7640000",
+			"code": "0x",
+			"nonce": "0x00",
+			"storage": {}
+		}
+	},
+	"transaction": {
+		"data": ["0x"],
+		"gasLimit": ["0x030d40"],
+		"gasPrice": "0x01",
+		"nonce": "0x00",
+		"secretKey": "0x45a915e4d060149eb4365960e6a7a45f334393093061116b197e3240065ff2d8",
+		"to": "0x0000000000000000000000000000000000001000",
+		"value": ["0x01"]
+	},
+	"post": {
+		"ConstantinopleFix": [{
+			"hash": "7c05cc8da4e706c49eac8e58cfab3e17a1fda9460d9f35d4ace7b04ee45b3ab6",
+			"logs": "1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347",
+			"indexes": {"data": 0, "gas": 0, "value": 0}
+		}],
+		"Istanbul": [{
+			"hash": "5aa56f77cd42910760e2270214db87d22173a63d9ffddacd9661e645259e27e9",
+			"logs": "1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347",
+			"indexes": {"data": 0, "gas": 0, "value": 0}
+		}]
+	}
+}`
+
+// Tests the Istanbul rule set against a general state test, checking both the
+// post state root and the values stored by the contract.
+func TestIstanbulState(t *testing.T) {
+	var test StateTest
+	if err := json.Unmarshal([]byte(istanbulStateTest), &test); err != nil {
+		t.Fatalf("failed to parse state test: %v", err)
+	}
+	contract := common.HexToAddress("0x1000")
+	for _, subtest := range test.Subtests() {
+		subtest := subtest
+		t.Run(fmt.Sprintf("%s/%d", subtest.Fork, subtest.Index), func(t *testing.T) {
+			statedb, err := test.Run(subtest, vm.Config{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []*big.Int{new(big.Int), new(big.Int), new(big.Int)}
+			if subtest.Fork == "Istanbul" {
+				// Chain id 1, the contract balance including the transferred wei, success
+				want = []*big.Int{big.NewInt(1), new(big.Int).Add(test.json.Pre[contract].Balance, big.NewInt(1)), big.NewInt(1)}
+			}
+			for slot, value := range want {
+				if have := statedb.GetState(contract, common.BigToHash(big.NewInt(int64(slot)))).Big(); have.Cmp(value) != 0 {
+					t.Errorf("slot %d mismatch: have %v, want %v", slot, have, value)
+				}
+			}
+		})
+	}
+}
@@ -57,6 +57,8 @@ type Database struct {
 	nodesSize     common.StorageSize // Storage size of the nodes cache
 	preimagesSize common.StorageSize // Storage size of the preimages cache
 
+	committed func(common.Hash) // Callback notified of every node flushed to disk
+
 	lock sync.RWMutex
 }
 
@@ -173,6 +175,30 @@ func (db *Database) Nodes() []common.Hash {
 	return hashes
 }
 
+// References retrieves the roots of the tries referenced by the metaroot, which
+// are kept alive in memory by the reference counting until dereferenced or
+// committed.
+func (db *Database) References() []common.Hash {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	var roots = make([]common.Hash, 0, len(db.nodes[common.Hash{}].children))
+	for hash := range db.nodes[common.Hash{}].children {
+		roots = append(roots, hash)
+	}
+	return roots
+}
+
+// SetCommitHook sets a callback to notify of the hash of every node before it is
+// flushed to disk, removing any previous one. The callback runs with the database
+// read locked, so it must not call back into the database.
+func (db *Database) SetCommitHook(hook func(common.Hash)) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.committed = hook
+}
+
 // Reference adds a new reference from a parent node to a child node.
 func (db *Database) Reference(child common.Hash, parent common.Hash) {
 	db.lock.RLock()
@@ -314,6 +340,9 @@ func (db *Database) commit(hash common.Hash, batch ethdb.Batch) error {
 			return err
 		}
 	}
+	if db.committed != nil {
+		db.committed(hash)
+	}
 	if err := batch.Put(hash[:], node.blob); err != nil {
 		return err
 	}